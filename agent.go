@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// Run states reported by the agent API.
+const (
+	runQueued  = "queued"
+	runRunning = "running"
+	runDone    = "done"
+	runFailed  = "failed"
+)
+
+// runRequest is the body of POST /runs. Empty fields select the defaults.
+type runRequest struct {
+	Drivers []string `json:"drivers"`
+	Sizes   []int    `json:"sizes"`
+}
+
+// agentRun is the state of one run as returned by GET /runs/{id}.
+type agentRun struct {
+	ID     int64       `json:"id"`
+	Status string      `json:"status"`
+	Report *JSONReport `json:"report,omitempty"`
+	Error  string      `json:"error,omitempty"`
+}
+
+// agentRunsKept is how many finished runs the agent keeps, with their
+// reports, for clients to fetch. Older ones are forgotten, so a long-lived
+// agent does not grow without bound; store runs with -db to keep them all.
+const agentRunsKept = 100
+
+// agent executes benchmark runs requested over HTTP one at a time, so
+// concurrent requests never skew each other's measurements.
+type agent struct {
+	mu     sync.Mutex
+	runs   map[int64]*agentRun
+	nextID int64
+	// finished are the IDs of the finished runs still kept, oldest first.
+	finished []int64
+
+	// exec serializes benchmark execution.
+	exec sync.Mutex
+	// record is called with each finished run, e.g. to store it.
+	record func(RunMetadata, []BenchmarkResult) error
+}
+
+func newAgent(record func(RunMetadata, []BenchmarkResult) error) *agent {
+	return &agent{runs: map[int64]*agentRun{}, record: record}
+}
+
+// runAgent implements the agent subcommand.
+func runAgent(args []string) {
+	fs := flag.NewFlagSet("agent", flag.ExitOnError)
+	addr := fs.String("addr", "localhost:8081", "address to listen on")
+	dbPath := fs.String("db", "", "results store to append finished runs to (none if empty)")
+	fs.Parse(args)
+
+	var record func(RunMetadata, []BenchmarkResult) error
+	if *dbPath != "" {
+		record = func(meta RunMetadata, results []BenchmarkResult) error {
+			db, err := openResultsStore(*dbPath)
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+			_, err = saveRun(db, meta, results)
+			return err
+		}
+	}
+
+	slog.Info("Benchmark agent listening", "addr", "http://"+*addr)
+	fatal("Agent stopped", "err", http.ListenAndServe(*addr, newAgent(record).handler()))
+}
+
+func (a *agent) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /runs", a.handleCreate)
+	mux.HandleFunc("GET /runs", a.handleList)
+	mux.HandleFunc("GET /runs/{id}", a.handleGet)
+	return mux
+}
+
+func (a *agent) handleCreate(w http.ResponseWriter, r *http.Request) {
+	var req runRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid run request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	cfg := defaultConfig()
+	if len(req.Drivers) > 0 {
+		cfg.Drivers = req.Drivers
+	}
+	if len(req.Sizes) > 0 {
+		cfg.Sizes = req.Sizes
+	}
+	if err := cfg.validate(); err != nil {
+		http.Error(w, "invalid run request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	a.mu.Lock()
+	a.nextID++
+	run := &agentRun{ID: a.nextID, Status: runQueued}
+	a.runs[run.ID] = run
+	snapshot := *run
+	a.mu.Unlock()
+
+	go a.execute(run, cfg)
+
+	w.Header().Set("Location", "/runs/"+strconv.FormatInt(snapshot.ID, 10))
+	writeJSON(w, http.StatusAccepted, snapshot)
+}
+
+func (a *agent) execute(run *agentRun, cfg Config) {
+	a.exec.Lock()
+	defer a.exec.Unlock()
+
+	a.setStatus(run, runRunning, nil, nil)
+
+	meta := collectMetadata()
+	meta.Config = &cfg
+	runner := cfg.runner()
+	capabilities, err := driverCapabilities(context.Background(), cfg.Drivers)
+	var results []BenchmarkResult
+	if err == nil {
+		runner.Capabilities = capabilities
+		results, err = runner.Run(context.Background())
+	}
+	report := &JSONReport{SchemaVersion: jsonSchemaVersion, Metadata: meta, Results: results}
+	if err != nil {
+		// The report keeps the cells that did succeed.
+		a.setStatus(run, runFailed, report, err)
+		return
+	}
+	if a.record != nil {
+		if err := a.record(meta, results); err != nil {
+			slog.Error("Failed to store run", "id", run.ID, "err", err)
+			a.setStatus(run, runFailed, report, fmt.Errorf("storing run: %w", err))
+			return
+		}
+	}
+
+	a.setStatus(run, runDone, report, nil)
+}
+
+// setStatus updates run at once, so a client never sees a failed run
+// without its error. Once run finishes, the oldest finished run beyond
+// agentRunsKept is forgotten.
+func (a *agent) setStatus(run *agentRun, status string, report *JSONReport, err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	run.Status = status
+	run.Report = report
+	if err != nil {
+		run.Error = err.Error()
+	}
+	if status == runDone || status == runFailed {
+		a.finished = append(a.finished, run.ID)
+		if len(a.finished) > agentRunsKept {
+			delete(a.runs, a.finished[0])
+			a.finished = a.finished[1:]
+		}
+	}
+}
+
+func (a *agent) handleGet(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid run id", http.StatusBadRequest)
+		return
+	}
+
+	a.mu.Lock()
+	run, ok := a.runs[id]
+	var snapshot agentRun
+	if ok {
+		snapshot = *run
+	}
+	a.mu.Unlock()
+
+	if !ok {
+		http.Error(w, "run not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, snapshot)
+}
+
+// handleList returns the runs kept, without their reports.
+func (a *agent) handleList(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	list := make([]agentRun, 0, len(a.runs))
+	for _, run := range a.runs {
+		list = append(list, agentRun{ID: run.ID, Status: run.Status})
+	}
+	a.mu.Unlock()
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+
+	writeJSON(w, http.StatusOK, list)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Warn("Failed to write response", "err", err)
+	}
+}
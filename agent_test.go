@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startAgentRun requests a run of mattn at size 64 from srv and waits for
+// it to finish.
+func startAgentRun(t *testing.T, srv *httptest.Server) agentRun {
+	t.Helper()
+	resp, err := http.Post(srv.URL+"/runs", "application/json", strings.NewReader(`{"drivers":["mattn"],"sizes":[64]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var created agentRun
+	json.NewDecoder(resp.Body).Decode(&created)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted || created.ID == 0 {
+		t.Fatalf("POST /runs = %d %+v", resp.StatusCode, created)
+	}
+
+	deadline := time.Now().Add(30 * time.Second)
+	for {
+		resp, err := http.Get(srv.URL + "/runs/" + strconv.FormatInt(created.ID, 10))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var run agentRun
+		json.NewDecoder(resp.Body).Decode(&run)
+		resp.Body.Close()
+
+		if run.Status == runDone || run.Status == runFailed {
+			return run
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("run did not finish, last status %q", run.Status)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestAgentRun(t *testing.T) {
+	srv := httptest.NewServer(newAgent(nil).handler())
+	defer srv.Close()
+
+	run := startAgentRun(t, srv)
+	if run.ID != 1 || run.Status != runDone || run.Report == nil || len(run.Report.Results) != 2 {
+		t.Fatalf("unexpected run: %+v", run)
+	}
+}
+
+func TestAgentRecordError(t *testing.T) {
+	record := func(RunMetadata, []BenchmarkResult) error { return errors.New("disk full") }
+	srv := httptest.NewServer(newAgent(record).handler())
+	defer srv.Close()
+
+	run := startAgentRun(t, srv)
+	if run.Status != runFailed || !strings.Contains(run.Error, "disk full") || run.Report == nil {
+		t.Fatalf("unexpected run: %+v", run)
+	}
+}
+
+func TestAgentUnknownDriver(t *testing.T) {
+	srv := httptest.NewServer(newAgent(nil).handler())
+	defer srv.Close()
+
+	resp, err := http.Post(srv.URL+"/runs", "application/json", strings.NewReader(`{"drivers":["nope"]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestAgentForgetsOldRuns(t *testing.T) {
+	a := newAgent(nil)
+	for i := 0; i < agentRunsKept+5; i++ {
+		a.nextID++
+		run := &agentRun{ID: a.nextID, Status: runQueued}
+		a.runs[run.ID] = run
+		a.setStatus(run, runDone, &JSONReport{}, nil)
+	}
+	if len(a.runs) != agentRunsKept {
+		t.Fatalf("kept %d runs, want %d", len(a.runs), agentRunsKept)
+	}
+	if _, ok := a.runs[5]; ok {
+		t.Error("run 5 still kept")
+	}
+	if _, ok := a.runs[6]; !ok {
+		t.Error("run 6 forgotten")
+	}
+}
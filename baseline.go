@@ -0,0 +1,32 @@
+package main
+
+// resultKey identifies a benchmark cell across runs.
+type resultKey struct {
+	Driver    string
+	Operation string
+	DataSize  int
+}
+
+func keyOf(r BenchmarkResult) resultKey {
+	return resultKey{r.Driver, r.Operation, r.DataSize}
+}
+
+// indexResults maps each result to its key. Later results win if a run
+// contains the same cell more than once.
+func indexResults(results []BenchmarkResult) map[resultKey]BenchmarkResult {
+	index := make(map[resultKey]BenchmarkResult, len(results))
+	for _, r := range results {
+		index[keyOf(r)] = r
+	}
+	return index
+}
+
+// percentChange returns how much slower (positive) or faster (negative) the
+// per-op time of current is compared to old, in percent.
+func percentChange(old, current BenchmarkResult) float64 {
+	before := float64(perOp(old))
+	if before == 0 {
+		return 0
+	}
+	return (float64(perOp(current)) - before) / before * 100
+}
@@ -1,200 +1,114 @@
 package main
 
 import (
-	"context"
-	"database/sql"
+	"encoding/csv"
+	"flag"
 	"fmt"
-	"log"
+	"io"
 	"os"
-	"testing"
-	"time"
+	"strconv"
 
-	_ "github.com/mattn/go-sqlite3"
-	_ "modernc.org/sqlite"
+	"sqlite_benchmark/sqlitebench"
 )
 
-type BenchmarkResult struct {
-	Driver    string
-	Operation string
-	DataSize  int
-	Duration  time.Duration
-}
-
-// func main() {
-// 	dataSizes := []int{64, 256, 1024, 4096, 1024 * 1024} // in bytes
-//
-// 	results := []BenchmarkResult{}
-//
-// 	drivers := map[string]string{
-// 		"modernc": "sqlite",
-// 		"mattn":   "sqlite3",
-// 	}
-//
-// 	for driverName, driverImport := range drivers {
-// 		for _, dataSize := range dataSizes {
-// 			writeDuration := benchmarkWrite(driverImport, dataSize)
-// 			readDuration := benchmarkRead(driverImport, dataSize)
-//
-// 			results = append(results, BenchmarkResult{Driver: driverName, Operation: "write", DataSize: dataSize, Duration: writeDuration})
-// 			results = append(results, BenchmarkResult{Driver: driverName, Operation: "read", DataSize: dataSize, Duration: readDuration})
-//
-// 			log.Printf("Driver: %s, Operation: write, DataSize: %d bytes, Duration: %v\n", driverName, dataSize, writeDuration)
-// 			log.Printf("Driver: %s, Operation: read, DataSize: %d bytes, Duration: %v\n", driverName, dataSize, readDuration)
-// 		}
-// 	}
-//
-// 	saveResultsToCSV(results)
-// }
-
-func benchmarkWrite(driver string, dataSize int) time.Duration {
-	db, err := sql.Open(driver, "file::memory:?cache=shared")
-	if err != nil {
-		log.Fatalf("Failed to open database: %v", err)
-	}
-	defer db.Close()
-
-	ctx := context.Background()
-	_, err = db.ExecContext(ctx, "CREATE TABLE test (data BLOB)")
-	if err != nil {
-		log.Fatalf("Failed to create table: %v", err)
-	}
+// BenchmarkResult is the library's result type, under the name the
+// reports in this package were written against.
+type BenchmarkResult = sqlitebench.Result
 
-	data := make([]byte, dataSize)
+var outputFormat = flag.String("format", "csv", "comma-separated results output formats (badges, benchstat, console, csv, influx, json, junit, markdown, prometheus); overrides the config file")
 
-	start := time.Now()
-	for i := 0; i < 100; i++ { // Number of insert operations
-		_, err := db.ExecContext(ctx, "INSERT INTO test (data) VALUES (?)", data)
-		if err != nil {
-			log.Fatalf("Failed to insert data: %v", err)
-		}
-	}
-	duration := time.Since(start)
-
-	return duration
-}
+var pushgatewayURL = flag.String("pushgateway", "http://localhost:9091", "Prometheus Pushgateway URL used by -format prometheus")
 
-func benchmarkRead(driver string, dataSize int) time.Duration {
-	db, err := sql.Open(driver, "file::memory:?cache=shared")
-	if err != nil {
-		log.Fatalf("Failed to open database: %v", err)
-	}
-	defer db.Close()
-
-	ctx := context.Background()
-	_, err = db.ExecContext(ctx, "CREATE TABLE test (data BLOB)")
-	if err != nil {
-		log.Fatalf("Failed to create table: %v", err)
-	}
+var (
+	baselinePath        = flag.String("baseline", "", "results file (JSON or CSV) to compare against, or \"latest\" for the last stored run")
+	regressionThreshold = flag.Float64("regression-threshold", 10, "percent slowdown versus -baseline that counts as a regression")
+	gate                = flag.Bool("gate", false, "exit with status 1 if any benchmark regresses versus -baseline")
+)
 
-	data := make([]byte, dataSize)
-	for i := 0; i < 100; i++ { // Insert data for reading
-		_, err := db.ExecContext(ctx, "INSERT INTO test (data) VALUES (?)", data)
-		if err != nil {
-			log.Fatalf("Failed to insert data: %v", err)
-		}
-	}
+var webhookURL = flag.String("webhook", "", "Slack-compatible webhook URL to post a run summary to")
 
-	start := time.Now()
-	for i := 0; i < 100; i++ { // Number of read operations
-		rows, err := db.QueryContext(ctx, "SELECT data FROM test LIMIT 1")
-		if err != nil {
-			log.Fatalf("Failed to query data: %v", err)
-		}
-		rows.Close()
-	}
-	duration := time.Since(start)
+var tui = flag.Bool("tui", false, "show a live dashboard while benchmarks run")
 
-	return duration
+// csvHeader lists the columns of the CSV output. Durations are integer
+// nanoseconds; counter columns are empty when counters were not collected.
+var csvHeader = []string{
+	"driver", "operation", "data_size_bytes", "iterations",
+	"duration_ns", "ns_per_op", "ops_per_sec", "allocs_per_op", "bytes_per_op",
+	"instructions", "cache_misses", "branch_misses",
 }
 
-func saveResultsToCSV(results []BenchmarkResult) {
-	file, err := os.Create("benchmark_results.csv")
-	if err != nil {
-		log.Fatalf("Failed to create CSV file: %v", err)
-	}
-	defer file.Close()
-
-	fmt.Fprintln(file, "Driver,Operation,DataSize,Duration")
-
-	for _, result := range results {
-		fmt.Fprintf(file, "%s,%s,%d,%v\n", result.Driver, result.Operation, result.DataSize, result.Duration)
-	}
+// csvReporter writes the results as CSV, one row as each is reported, with
+// the run metadata in a companion benchmark_results.meta.json so the CSV
+// itself stays a plain table.
+type csvReporter struct {
+	meta RunMetadata
+	file *os.File
+	cw   *csv.Writer
 }
 
-// Benchmark functions
-func BenchmarkWrite(b *testing.B, driver string, dataSize int) {
-	b.Helper()
-
-	db, err := sql.Open(driver, "file::memory:?cache=shared")
+func (c *csvReporter) Start(meta RunMetadata) error {
+	file, err := os.Create(outputPath("benchmark_results.csv"))
 	if err != nil {
-		b.Fatalf("Failed to open database: %v", err)
-	}
-	defer db.Close()
-
-	ctx := context.Background()
-	_, err = db.ExecContext(ctx, "CREATE TABLE test (data BLOB)")
-	if err != nil {
-		b.Fatalf("Failed to create table: %v", err)
-	}
-
-	data := make([]byte, dataSize)
-
-	for i := 0; i < b.N; i++ {
-		_, err := db.ExecContext(ctx, "INSERT INTO test (data) VALUES (?)", data)
-		if err != nil {
-			b.Fatalf("Failed to insert data: %v", err)
-		}
+		return fmt.Errorf("creating CSV file: %w", err)
 	}
+	c.meta, c.file, c.cw = meta, file, csv.NewWriter(file)
+	return c.cw.Write(csvHeader)
 }
 
-func BenchmarkRead(b *testing.B, driver string, dataSize int) {
-	b.Helper()
+func (c *csvReporter) Report(r BenchmarkResult) error {
+	return c.cw.Write(csvRecord(r))
+}
 
-	db, err := sql.Open(driver, "file::memory:?cache=shared")
-	if err != nil {
-		b.Fatalf("Failed to open database: %v", err)
+func (c *csvReporter) Finish() error {
+	defer c.file.Close()
+	c.cw.Flush()
+	if err := c.cw.Error(); err != nil {
+		return fmt.Errorf("writing CSV: %w", err)
 	}
-	defer db.Close()
-
-	ctx := context.Background()
-	_, err = db.ExecContext(ctx, "CREATE TABLE test (data BLOB)")
-	if err != nil {
-		b.Fatalf("Failed to create table: %v", err)
+	if err := c.file.Close(); err != nil {
+		return err
 	}
+	return saveMetadataJSON(outputPath("benchmark_results.meta.json"), c.meta)
+}
 
-	data := make([]byte, dataSize)
-	for i := 0; i < 100; i++ { // Insert data for reading
-		_, err := db.ExecContext(ctx, "INSERT INTO test (data) VALUES (?)", data)
-		if err != nil {
-			b.Fatalf("Failed to insert data: %v", err)
-		}
+// writeCSV writes the results as a CSV table.
+func writeCSV(w io.Writer, results []BenchmarkResult) error {
+	cw := csv.NewWriter(w)
+	cw.Write(csvHeader)
+	for _, r := range results {
+		cw.Write(csvRecord(r))
 	}
 
-	for i := 0; i < b.N; i++ {
-		rows, err := db.QueryContext(ctx, "SELECT data FROM test LIMIT 1")
-		if err != nil {
-			b.Fatalf("Failed to query data: %v", err)
-		}
-		rows.Close()
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("writing CSV: %w", err)
 	}
+	return nil
 }
 
-func BenchmarkDrivers(b *testing.B) {
-	dataSizes := []int{64, 256, 1024, 4096, 1024 * 1024}
-
-	drivers := map[string]string{
-		"modernc": "sqlite",
-		"mattn":   "sqlite3",
-	}
-
-	for driverName, driverImport := range drivers {
-		for _, dataSize := range dataSizes {
-			b.Run(fmt.Sprintf("%s_Write_%dBytes", driverName, dataSize), func(b *testing.B) {
-				BenchmarkWrite(b, driverImport, dataSize)
-			})
-			b.Run(fmt.Sprintf("%s_Read_%dBytes", driverName, dataSize), func(b *testing.B) {
-				BenchmarkRead(b, driverImport, dataSize)
-			})
-		}
+// csvRecord returns the CSV row of a result.
+func csvRecord(r BenchmarkResult) []string {
+	n := uint64(iterations(r))
+
+	var instructions, cacheMisses, branchMisses string
+	if r.Counters != nil {
+		instructions = strconv.FormatUint(r.Counters.Instructions, 10)
+		cacheMisses = strconv.FormatUint(r.Counters.CacheMisses, 10)
+		branchMisses = strconv.FormatUint(r.Counters.BranchMisses, 10)
+	}
+
+	return []string{
+		r.Driver,
+		r.Operation,
+		strconv.Itoa(r.DataSize),
+		strconv.FormatUint(n, 10),
+		strconv.FormatInt(r.Duration.Nanoseconds(), 10),
+		strconv.FormatInt(perOp(r).Nanoseconds(), 10),
+		strconv.FormatFloat(opsPerSec(r), 'f', 2, 64),
+		strconv.FormatUint(r.Allocs/n, 10),
+		strconv.FormatUint(r.Bytes/n, 10),
+		instructions,
+		cacheMisses,
+		branchMisses,
 	}
 }
@@ -1,7 +1,113 @@
 package main
 
-import "testing"
+import (
+	"context"
+	"log/slog"
+	"testing"
 
+	"sqlite_benchmark/sqlitebench"
+)
+
+// BenchmarkSqlite runs every registered driver and workload at each size
+// as a go test benchmark, named like the benchstat output, e.g.
+//
+//	go test -bench . -benchmem
+//	go test -bench 'Sqlite/mattn_Read' -args -sizes 1K,1M -rows 10000
+//
+// The -sizes, -filter, -rows, -seed, -compressibility and -verify flags of
+// the run subcommand apply.
 func BenchmarkSqlite(b *testing.B) {
-	BenchmarkDrivers(b)
+	sizes, err := flagSizes()
+	if err != nil {
+		b.Fatalf("Invalid -sizes: %v", err)
+	}
+	cfg := defaultConfig()
+	cfg.Workloads = sqlitebench.WorkloadNames()
+	cfg.Sizes = sizes
+	cfg.Filter = *benchFilter
+	cfg.Rows = *rowsFlag
+	cfg.Seed = *seedFlag
+	cfg.Compressibility = *compressibilityFlag
+	cfg.Verify = *verifyFlag
+	if err := cfg.validate(); err != nil {
+		b.Fatalf("Invalid flags: %v", err)
+	}
+
+	runner := cfg.runner()
+	runner.Capabilities, err = driverCapabilities(context.Background(), cfg.Drivers)
+	if err != nil {
+		b.Fatal(err)
+	}
+	for _, c := range runner.Cells() {
+		name := benchstatName(BenchmarkResult{Driver: c.Driver, Operation: c.Operation(), DataSize: c.DataSize})
+		b.Run(name, func(b *testing.B) {
+			benchmarkCell(b, runner, c)
+		})
+	}
+}
+
+// benchmarkCell times b.N operations of a cell. Preparing the database,
+// e.g. populating it for reads, is not timed but reported as setup-ns.
+func benchmarkCell(b *testing.B, runner *sqlitebench.Runner, c sqlitebench.Cell) {
+	s, err := runner.OpenCell(context.Background(), c)
+	if err != nil {
+		b.Fatalf("Failed to prepare %s: %v", c, err)
+	}
+	defer s.Close()
+
+	b.ReportAllocs()
+	perf := beginPerf()
+	b.ResetTimer()
+	for i := range b.N {
+		if err := s.Run(i); err != nil {
+			b.Fatalf("Operation %d failed: %v", i, err)
+		}
+	}
+	b.StopTimer()
+	reportPerf(b, endPerf(perf))
+	b.ReportMetric(float64(s.SetupTime().Nanoseconds()), "setup-ns")
+}
+
+// beginPerf starts the hardware counters if they are enabled. If they cannot
+// be opened (unsupported platform, perf_event_paranoid, missing PMU in a VM)
+// the failure is logged and counters stay off for the rest of the run.
+func beginPerf() *sqlitebench.PerfGroup {
+	if !perfEnabled {
+		return nil
+	}
+
+	g, err := sqlitebench.StartPerfCounters()
+	if err != nil {
+		slog.Warn("Disabling hardware counters", "err", err)
+		perfEnabled = false
+		return nil
+	}
+	return g
+}
+
+// endPerf stops the counters started by beginPerf. It returns nil if no
+// counters were running.
+func endPerf(g *sqlitebench.PerfGroup) *sqlitebench.PerfCounters {
+	if g == nil {
+		return nil
+	}
+
+	counters, err := g.Stop()
+	if err != nil {
+		slog.Warn("Failed to read hardware counters", "err", err)
+		return nil
+	}
+	return counters
+}
+
+// reportPerf attaches per-op counter values to a testing benchmark.
+func reportPerf(b *testing.B, counters *sqlitebench.PerfCounters) {
+	if counters == nil || b.N == 0 {
+		return
+	}
+
+	n := float64(b.N)
+	b.ReportMetric(float64(counters.Instructions)/n, "instructions/op")
+	b.ReportMetric(float64(counters.CacheMisses)/n, "cache-misses/op")
+	b.ReportMetric(float64(counters.BranchMisses)/n, "branch-misses/op")
 }
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/plotutil"
+	"gonum.org/v1/plot/vg"
+)
+
+// runChart implements the chart subcommand. It renders, per operation, a
+// line chart of time per op against data size and a bar chart comparing
+// drivers at each size.
+func runChart(args []string) {
+	fs := flag.NewFlagSet("chart", flag.ExitOnError)
+	in := fs.String("in", "benchmark_results.json", "results file (JSON or CSV) to chart")
+	out := fs.String("out", "charts", "directory to write charts to")
+	imageType := fs.String("type", "svg", "image type (svg, png)")
+	fs.Parse(args)
+
+	if *imageType != "svg" && *imageType != "png" {
+		fatal("Unknown image type", "type", *imageType)
+	}
+
+	if err := os.MkdirAll(*out, 0o755); err != nil {
+		fatal("Failed to create chart directory", "err", err)
+	}
+
+	report := loadResults(*in)
+	t := buildComparisonTable(report.Results)
+
+	for _, op := range chartOperations(t) {
+		line := filepath.Join(*out, fmt.Sprintf("%s_line.%s", op, *imageType))
+		saveLineChart(t, op, line)
+		slog.Info("Wrote chart", "path", line)
+
+		bar := filepath.Join(*out, fmt.Sprintf("%s_bar.%s", op, *imageType))
+		saveBarChart(t, op, bar)
+		slog.Info("Wrote chart", "path", bar)
+	}
+}
+
+// chartOperations returns the distinct operations in the table, in order.
+func chartOperations(t comparisonTable) []string {
+	var ops []string
+	seen := map[string]bool{}
+	for _, w := range t.Workloads {
+		if !seen[w.Operation] {
+			seen[w.Operation] = true
+			ops = append(ops, w.Operation)
+		}
+	}
+	return ops
+}
+
+// chartSizes returns the data sizes measured for an operation, ascending.
+func chartSizes(t comparisonTable, op string) []int {
+	var sizes []int
+	for _, w := range t.Workloads {
+		if w.Operation == op {
+			sizes = append(sizes, w.DataSize)
+		}
+	}
+	sort.Ints(sizes)
+	return sizes
+}
+
+func saveLineChart(t comparisonTable, op, path string) {
+	p := plot.New()
+	p.Title.Text = fmt.Sprintf("%s: time per operation", op)
+	p.X.Label.Text = "data size (bytes)"
+	p.X.Scale = plot.LogScale{}
+	p.X.Tick.Marker = plot.LogTicks{Prec: -1}
+	p.Y.Label.Text = "µs/op"
+	p.Y.Scale = plot.LogScale{}
+	p.Y.Tick.Marker = plot.LogTicks{Prec: -1}
+	p.Legend.Top = true
+	p.Legend.Left = true
+
+	var lines []interface{}
+	for _, driver := range t.Drivers {
+		var pts plotter.XYs
+		for _, size := range chartSizes(t, op) {
+			r, ok := t.Cells[workload{op, size}][driver]
+			if !ok {
+				continue
+			}
+			pts = append(pts, plotter.XY{X: float64(size), Y: float64(perOp(r).Nanoseconds()) / 1e3})
+		}
+		if len(pts) > 0 {
+			lines = append(lines, driver, pts)
+		}
+	}
+
+	if err := plotutil.AddLinePoints(p, lines...); err != nil {
+		fatal("Failed to build line chart", "err", err)
+	}
+	if err := p.Save(8*vg.Inch, 5*vg.Inch, path); err != nil {
+		fatal("Failed to save chart", "err", err)
+	}
+}
+
+func saveBarChart(t comparisonTable, op, path string) {
+	sizes := chartSizes(t, op)
+
+	p := plot.New()
+	p.Title.Text = fmt.Sprintf("%s: driver comparison", op)
+	p.Y.Label.Text = "µs/op"
+	p.Legend.Top = true
+
+	width := vg.Points(20)
+	for i, driver := range t.Drivers {
+		values := make(plotter.Values, len(sizes))
+		for j, size := range sizes {
+			if r, ok := t.Cells[workload{op, size}][driver]; ok {
+				values[j] = float64(perOp(r).Nanoseconds()) / 1e3
+			}
+		}
+
+		bars, err := plotter.NewBarChart(values, width)
+		if err != nil {
+			fatal("Failed to build bar chart", "err", err)
+		}
+		bars.LineStyle.Width = 0
+		bars.Color = plotutil.Color(i)
+		bars.Offset = width * vg.Length(2*i-len(t.Drivers)+1) / 2
+
+		p.Add(bars)
+		p.Legend.Add(driver, bars)
+	}
+
+	labels := make([]string, len(sizes))
+	for i, size := range sizes {
+		labels[i] = formatSize(size)
+	}
+	p.NominalX(labels...)
+	p.X.Min = -0.5
+	p.X.Max = float64(len(sizes)) - 0.5
+
+	if err := p.Save(8*vg.Inch, 5*vg.Inch, path); err != nil {
+		fatal("Failed to save chart", "err", err)
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"sqlite_benchmark/sqlitebench"
+)
+
+var resume = flag.Bool("resume", false, "continue the last unfinished run with the same config from the results store")
+
+// checkpoint records every completed cell run of an unfinished run in the
+// results store, keyed by its position in the schedule, so the run can be
+// resumed after a crash or interruption. It observes the runner to see
+// each cell run as it completes.
+type checkpoint struct {
+	db   *sql.DB
+	id   int64
+	done map[int]BenchmarkResult
+	// err is the first failure to save a cell run. Cells done after it
+	// are not saved, as a resumed run could not tell which are missing.
+	err error
+}
+
+// checkpointKey identifies runs that can be resumed from each other's
+// checkpoints. Output formats do not affect what is measured, so they are
+// left out.
+func checkpointKey(cfg Config) (string, error) {
+	cfg.Formats = nil
+	key, err := json.Marshal(cfg)
+	if err != nil {
+		return "", fmt.Errorf("encoding config: %w", err)
+	}
+	return string(key), nil
+}
+
+// openCheckpoint starts a checkpoint for a run of cfg. With resume it
+// continues the most recent unfinished checkpoint for the same config, if
+// there is one.
+func openCheckpoint(db *sql.DB, cfg Config, resume bool) (*checkpoint, error) {
+	key, err := checkpointKey(cfg)
+	if err != nil {
+		return nil, err
+	}
+	cp := &checkpoint{db: db, done: map[int]BenchmarkResult{}}
+
+	if resume {
+		err := db.QueryRow("SELECT id FROM checkpoints WHERE config = ? ORDER BY id DESC LIMIT 1", key).Scan(&cp.id)
+		switch {
+		case err == sql.ErrNoRows:
+			slog.Warn("No unfinished run to resume; starting from the beginning")
+		case err != nil:
+			return nil, fmt.Errorf("looking up checkpoint: %w", err)
+		default:
+			if err := cp.load(); err != nil {
+				return nil, err
+			}
+			slog.Info("Resuming run", "checkpoint", cp.id, "done", len(cp.done), "total", cfg.cells())
+			return cp, nil
+		}
+	}
+
+	res, err := db.Exec("INSERT INTO checkpoints (config, started_at) VALUES (?, ?)", key, time.Now().Format(timeFormat))
+	if err != nil {
+		return nil, fmt.Errorf("creating checkpoint: %w", err)
+	}
+	cp.id, _ = res.LastInsertId()
+	return cp, nil
+}
+
+func (cp *checkpoint) load() error {
+	rows, err := cp.db.Query("SELECT seq, result FROM checkpoint_cells WHERE checkpoint_id = ?", cp.id)
+	if err != nil {
+		return fmt.Errorf("querying checkpoint: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var seq int
+		var data string
+		if err := rows.Scan(&seq, &data); err != nil {
+			return fmt.Errorf("reading checkpoint: %w", err)
+		}
+		var r BenchmarkResult
+		if err := json.Unmarshal([]byte(data), &r); err != nil {
+			return fmt.Errorf("decoding checkpointed result: %w", err)
+		}
+		cp.done[seq] = r
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("reading checkpoint: %w", err)
+	}
+	return nil
+}
+
+func (cp *checkpoint) CellStarted(int, sqlitebench.Cell) {}
+
+// CellFailed records nothing, so a resumed run tries the cell again.
+func (cp *checkpoint) CellFailed(int, sqlitebench.Cell, error) {}
+
+// CellDone records the result of the cell run at position seq of the
+// schedule. A failure is kept in err for the caller to report.
+func (cp *checkpoint) CellDone(seq int, _ sqlitebench.Cell, r BenchmarkResult) {
+	if cp.err != nil {
+		return
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		cp.err = fmt.Errorf("encoding result: %w", err)
+		return
+	}
+	if _, err := cp.db.Exec("INSERT INTO checkpoint_cells (checkpoint_id, seq, result) VALUES (?, ?, ?)", cp.id, seq, string(data)); err != nil {
+		cp.err = fmt.Errorf("saving checkpoint: %w", err)
+		return
+	}
+	cp.done[seq] = r
+}
+
+// remove deletes the checkpoint once its run has been stored in full.
+func (cp *checkpoint) remove() {
+	if _, err := cp.db.Exec("DELETE FROM checkpoints WHERE id = ?", cp.id); err != nil {
+		slog.Warn("Failed to delete checkpoint", "err", err)
+	}
+}
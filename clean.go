@@ -0,0 +1,32 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"sqlite_benchmark/sqlitebench"
+)
+
+// runClean implements the clean subcommand: remove the temporary database
+// directories that runs killed before they could clean up left behind.
+func runClean(args []string) {
+	fs := flag.NewFlagSet("clean", flag.ExitOnError)
+	dir := fs.String("dir", "", "also look for leftover directories in this one")
+	fs.Parse(args)
+
+	parents := sqlitebench.TempDirParents()
+	if *dir != "" {
+		parents = append(parents, *dir)
+	}
+	removed, err := sqlitebench.CleanTempDirs(parents)
+	for _, dir := range removed {
+		fmt.Println("removed", dir)
+	}
+	if err != nil {
+		fatal("Failed to remove some directories", "err", err)
+	}
+	if len(removed) == 0 {
+		fmt.Fprintln(os.Stderr, "nothing to clean")
+	}
+}
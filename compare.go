@@ -0,0 +1,139 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"text/tabwriter"
+)
+
+// significanceLevel is the p-value below which a delta is reported as real
+// rather than noise.
+const significanceLevel = 0.05
+
+// runCompare implements `compare old.json new.json`.
+func runCompare(args []string) {
+	fs := flag.NewFlagSet("compare", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: compare old.json new.json")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	old := loadResults(fs.Arg(0))
+	current := loadResults(fs.Arg(1))
+
+	if len(old.Metadata.Tags) > 0 || len(current.Metadata.Tags) > 0 {
+		fmt.Printf("old: %s\nnew: %s\n\n", old.Metadata.Tags, current.Metadata.Tags)
+	}
+	printComparison(os.Stdout, old.Results, current.Results)
+}
+
+// printComparison writes per-benchmark deltas between two result sets. The
+// delta is shown as "~" when the per-op samples are not significantly
+// different according to a Mann-Whitney U test.
+func printComparison(w io.Writer, old, current []BenchmarkResult) {
+	oldIndex := indexResults(old)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Benchmark\told/op\tnew/op\tdelta\tp")
+
+	for _, r := range current {
+		prev, ok := oldIndex[keyOf(r)]
+		if !ok {
+			fmt.Fprintf(tw, "%s\t-\t%v\t(new)\t\n", benchstatName(r), perOp(r))
+			continue
+		}
+
+		p := mannWhitneyU(sampleValues(prev), sampleValues(r))
+		delta := "~"
+		// Results loaded from CSV have no samples to test; their delta is
+		// shown untested.
+		if p < significanceLevel || len(prev.Samples) == 0 || len(r.Samples) == 0 {
+			delta = fmt.Sprintf("%+.2f%%", percentChange(prev, r))
+		}
+
+		fmt.Fprintf(tw, "%s\t%v\t%v\t%s\t%s\n", benchstatName(r), perOp(prev), perOp(r), delta, formatPValue(p))
+	}
+
+	tw.Flush()
+}
+
+func formatPValue(p float64) string {
+	if math.IsNaN(p) {
+		return "n/a"
+	}
+	return fmt.Sprintf("%.3f", p)
+}
+
+// sampleValues returns the per-op samples of a result in nanoseconds.
+func sampleValues(r BenchmarkResult) []float64 {
+	values := make([]float64, len(r.Samples))
+	for i, s := range r.Samples {
+		values[i] = float64(s.Nanoseconds())
+	}
+	return values
+}
+
+// mannWhitneyU returns the two-sided p-value of the Mann-Whitney U test for
+// samples a and b, using the normal approximation with tie correction. It
+// returns NaN when either sample is empty.
+func mannWhitneyU(a, b []float64) float64 {
+	n1, n2 := float64(len(a)), float64(len(b))
+	if n1 == 0 || n2 == 0 {
+		return math.NaN()
+	}
+
+	type value struct {
+		v     float64
+		fromA bool
+	}
+	all := make([]value, 0, len(a)+len(b))
+	for _, v := range a {
+		all = append(all, value{v, true})
+	}
+	for _, v := range b {
+		all = append(all, value{v, false})
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].v < all[j].v })
+
+	// Assign average ranks to ties and accumulate the tie correction term.
+	var rankSumA, tieTerm float64
+	for i := 0; i < len(all); {
+		j := i
+		for j < len(all) && all[j].v == all[i].v {
+			j++
+		}
+		rank := float64(i+j+1) / 2
+		for k := i; k < j; k++ {
+			if all[k].fromA {
+				rankSumA += rank
+			}
+		}
+		t := float64(j - i)
+		tieTerm += t*t*t - t
+		i = j
+	}
+
+	u := rankSumA - n1*(n1+1)/2
+	n := n1 + n2
+	mean := n1 * n2 / 2
+	variance := n1 * n2 / 12 * ((n + 1) - tieTerm/(n*(n-1)))
+	if variance <= 0 {
+		return 1
+	}
+
+	z := (math.Abs(u-mean) - 0.5) / math.Sqrt(variance)
+	if z < 0 {
+		z = 0
+	}
+	return math.Erfc(z / math.Sqrt2)
+}
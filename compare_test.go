@@ -0,0 +1,59 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMannWhitneyU(t *testing.T) {
+	same := []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	if p := mannWhitneyU(same, same); p < 0.9 {
+		t.Errorf("identical samples: p = %f, want ~1", p)
+	}
+
+	shifted := []float64{11, 12, 13, 14, 15, 16, 17, 18, 19, 20}
+	if p := mannWhitneyU(same, shifted); p > 0.001 {
+		t.Errorf("disjoint samples: p = %f, want < 0.001", p)
+	}
+}
+
+func TestPrintComparison(t *testing.T) {
+	samples := func(d time.Duration) []time.Duration {
+		s := make([]time.Duration, 20)
+		for i := range s {
+			s[i] = d + time.Duration(i)
+		}
+		return s
+	}
+	old := []BenchmarkResult{{Driver: "mattn", Operation: "write", DataSize: 64, Duration: 20 * time.Microsecond, Samples: samples(time.Microsecond)}}
+	current := []BenchmarkResult{{Driver: "mattn", Operation: "write", DataSize: 64, Duration: 40 * time.Microsecond, Samples: samples(2 * time.Microsecond)}}
+
+	var sb strings.Builder
+	printComparison(&sb, old, current)
+
+	if !strings.Contains(sb.String(), "+100.00%") {
+		t.Errorf("expected a significant +100%% delta:\n%s", sb.String())
+	}
+}
+
+func TestFindRegressions(t *testing.T) {
+	base := BenchmarkResult{Driver: "mattn", Operation: "read", DataSize: 64, Duration: 100 * time.Microsecond, Samples: make([]time.Duration, 10)}
+	slower := base
+	slower.Duration = 115 * time.Microsecond
+
+	baseline := indexResults([]BenchmarkResult{base})
+	if regs := findRegressions(baseline, []BenchmarkResult{slower}, 20); len(regs) != 0 {
+		t.Errorf("15%% slowdown flagged at 20%% threshold: %+v", regs)
+	}
+
+	regs := findRegressions(baseline, []BenchmarkResult{slower}, 10)
+	if len(regs) != 1 {
+		t.Fatalf("got %d regressions at 10%% threshold, want 1", len(regs))
+	}
+
+	var sb strings.Builder
+	if code := reportRegressions(&sb, regs, 10); code != 1 {
+		t.Errorf("exit code = %d, want 1", code)
+	}
+}
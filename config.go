@@ -0,0 +1,569 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+
+	"sqlite_benchmark/sqlitebench"
+)
+
+// Config describes a benchmark matrix. It can be loaded from a YAML or TOML
+// file with -config; fields left out keep their defaults.
+type Config struct {
+	// Drivers are names from the drivers registry, benchmarked in order.
+	Drivers []string `yaml:"drivers" toml:"drivers" json:"drivers"`
+	// Workloads are names from the workloads registry.
+	Workloads []string `yaml:"workloads" toml:"workloads" json:"workloads"`
+	// Categories, if set, keeps only the registered workloads of these
+	// categories, e.g. ["write"].
+	Categories []string `yaml:"categories" toml:"categories" json:"categories,omitempty"`
+	// KVStores are names from the KV store registry, e.g. "pebble". They run
+	// the kv workloads, which are added to Workloads when it names none of
+	// them, so the stores can be compared with the drivers.
+	KVStores []string `yaml:"kv_stores" toml:"kv_stores" json:"kv_stores,omitempty"`
+	// OLAP are names from the OLAP backend registry, e.g. "duckdb", which
+	// needs a build with -tags duckdb. They run the analytical workloads,
+	// which are added to Workloads when it names none of them.
+	OLAP []string `yaml:"olap" toml:"olap" json:"olap,omitempty"`
+	// Servers maps client/server database kinds, "postgres", "mysql",
+	// "libsql" or "libsql-replica", to DSNs of databases to run every
+	// workload on as a networked baseline. The DSN's user must be allowed
+	// to drop and recreate a schema (MySQL: database) named sqlitebench; a
+	// libsql server has every table dropped. libsql-replica runs on an
+	// embedded replica of a libsql server and needs a build with -tags
+	// libsql_replica,libsqlite3. DSNs can hold passwords, so they are left
+	// out of saved configs.
+	Servers map[string]string `yaml:"servers" toml:"servers" json:"-"`
+	// Scenarios are scenario files, each run as one more workload named
+	// after the scenario.
+	Scenarios []string `yaml:"scenarios" toml:"scenarios" json:"scenarios,omitempty"`
+	// Schema adds insert, read and update workloads on the user's own
+	// table, named after it, e.g. "orders.read".
+	Schema *SchemaConfig `yaml:"schema" toml:"schema" json:"schema,omitempty"`
+	// Extension adds the extension.load and extension.call workloads for
+	// a compiled SQLite extension. Drivers that cannot load extensions
+	// skip them.
+	Extension *ExtensionConfig `yaml:"extension" toml:"extension" json:"extension,omitempty"`
+	// Vector adds the vector.insert and vector.query workloads of vector
+	// search with the sqlite-vec extension. Drivers that cannot load
+	// extensions skip them.
+	Vector *VectorConfig `yaml:"vector" toml:"vector" json:"vector,omitempty"`
+	// KVMix sets the weights of the kv.mix workload's gets, puts and
+	// deletes and the skew of its keys.
+	KVMix *KVMixConfig `yaml:"kv_mix" toml:"kv_mix" json:"kv_mix,omitempty"`
+	// Cache sets the share of the cache workload's lookups that miss.
+	Cache *CacheConfig `yaml:"cache" toml:"cache" json:"cache,omitempty"`
+	// Graph sets the fan-out of the graph workloads' nodes and the hops
+	// graph.khop reaches.
+	Graph *GraphConfig `yaml:"graph" toml:"graph" json:"graph,omitempty"`
+	// Files sets the size of the files workloads' files and of
+	// files.chunked's chunks.
+	Files *FilesConfig `yaml:"files" toml:"files" json:"files,omitempty"`
+	// FTSCorpus is the file of the documents the fts workloads index, a
+	// Wikipedia abstract dump or a document per line; see
+	// sqlitebench.LoadCorpus. Left empty, they index the bundled corpus.
+	FTSCorpus string `yaml:"fts_corpus" toml:"fts_corpus" json:"fts_corpus,omitempty"`
+	// Sizes are the payload sizes in bytes.
+	Sizes SizeList `yaml:"sizes" toml:"sizes" json:"sizes"`
+	// JournalModes, Synchronous, PageSizes, CacheSizes, Concurrency and
+	// Storage are further matrix dimensions: the journal_mode, synchronous,
+	// page_size and cache_size pragmas (a negative cache size is in KiB),
+	// the number of connections running operations at once, and where
+	// databases live: "memory" (a shared-cache in-memory database),
+	// "memdb" (the memdb VFS), "tmpfs" (a file in /dev/shm) or "file". A
+	// dimension left empty is not varied.
+	JournalModes []string `yaml:"journal_modes" toml:"journal_modes" json:"journal_modes,omitempty"`
+	Synchronous  []string `yaml:"synchronous" toml:"synchronous" json:"synchronous,omitempty"`
+	PageSizes    []int    `yaml:"page_sizes" toml:"page_sizes" json:"page_sizes,omitempty"`
+	CacheSizes   []int    `yaml:"cache_sizes" toml:"cache_sizes" json:"cache_sizes,omitempty"`
+	Concurrency  []int    `yaml:"concurrency" toml:"concurrency" json:"concurrency,omitempty"`
+	Storage      []string `yaml:"storage" toml:"storage" json:"storage,omitempty"`
+	// SQLiteVersions pins drivers to SQLite versions: the run stops
+	// before measuring if a driver runs another version. A version such as
+	// "3.46" matches every 3.46.x release.
+	SQLiteVersions map[string]string `yaml:"sqlite_versions" toml:"sqlite_versions" json:"sqlite_versions,omitempty"`
+	// Replication measures the cost of continuous replication: "off",
+	// "wal-copy" or "litestream" for each cell, all on WAL-mode database
+	// files so "off" is the baseline the others are reported against.
+	Replication []string `yaml:"replication" toml:"replication" json:"replication,omitempty"`
+	// Exclude drops the matrix combinations matching any of its rules,
+	// e.g. {storage: memory, journal_mode: wal}. Include adds combinations
+	// naming a value for every dimension.
+	Exclude []sqlitebench.Rule `yaml:"exclude" toml:"exclude" json:"exclude,omitempty"`
+	Include []sqlitebench.Rule `yaml:"include" toml:"include" json:"include,omitempty"`
+	// Ops is the number of measured operations per cell.
+	Ops int `yaml:"ops" toml:"ops" json:"ops"`
+	// Duration, if set, runs each cell for this wall time instead of a
+	// fixed number of operations, e.g. "10s".
+	Duration time.Duration `yaml:"duration" toml:"duration" json:"duration_ns,omitempty"`
+	// Timeout bounds each cell, including preparing its table; a cell
+	// that exceeds it is recorded as timed out. Zero means no limit.
+	Timeout time.Duration `yaml:"timeout" toml:"timeout" json:"timeout_ns,omitempty"`
+	// Timeouts overrides Timeout for individual workloads, e.g.
+	// {"read": "5m"}.
+	Timeouts map[string]time.Duration `yaml:"timeouts" toml:"timeouts" json:"timeouts_ns,omitempty"`
+	// Rows is the number of rows inserted before read workloads.
+	Rows int `yaml:"rows" toml:"rows" json:"rows"`
+	// Seed makes the generated payloads reproducible.
+	Seed int64 `yaml:"seed" toml:"seed" json:"seed"`
+	// Compressibility is the fraction of each payload that is zero-filled,
+	// from 0 (random bytes) to 1 (all zeros).
+	Compressibility float64 `yaml:"compressibility" toml:"compressibility" json:"compressibility"`
+	// Verify makes read workloads scan every blob and check it against
+	// the payloads written, at the cost of slower reads.
+	Verify bool `yaml:"verify" toml:"verify" json:"verify,omitempty"`
+	// IOCounters counts the reads, writes and syncs SQLite makes for the
+	// drivers that can be given a counting VFS.
+	IOCounters bool `yaml:"io_counters" toml:"io_counters" json:"io_counters,omitempty"`
+	// BusyRetries retries an operation up to this many times while it
+	// finds the database locked, as applications do, and reports the
+	// retries and the time they took.
+	BusyRetries int `yaml:"busy_retries" toml:"busy_retries" json:"busy_retries,omitempty"`
+	// Differential runs this many random statements on every driver
+	// before measuring and reports those whose results differ.
+	Differential int `yaml:"differential" toml:"differential" json:"differential,omitempty"`
+	// Repeat runs every cell this many times, in rounds over the whole
+	// matrix, and merges the repetitions into one result.
+	Repeat int `yaml:"repeat" toml:"repeat" json:"repeat"`
+	// Shuffle, if non-zero, seeds a random cell order for each round
+	// instead of the fixed driver, size, workload order.
+	Shuffle int64 `yaml:"shuffle" toml:"shuffle" json:"shuffle,omitempty"`
+	// Pragmas are executed as "PRAGMA <p>" on every benchmark database,
+	// e.g. "journal_mode=WAL".
+	Pragmas []string `yaml:"pragmas" toml:"pragmas" json:"pragmas,omitempty"`
+	// Formats are the output formats written after the run.
+	Formats []string `yaml:"formats" toml:"formats" json:"formats"`
+	// OTel exports the run as OpenTelemetry traces to an OTLP collector.
+	OTel *OTelConfig `yaml:"otel" toml:"otel" json:"otel,omitempty"`
+	// Filter is a regular expression selecting cells by their
+	// "driver/workload/size" name, e.g. "mattn/write/.*".
+	Filter string `yaml:"filter" toml:"filter" json:"filter,omitempty"`
+}
+
+var configPath = flag.String("config", "", "YAML or TOML file describing the benchmark matrix")
+
+var (
+	opsFlag      = flag.Int("ops", 100, "measured operations per cell")
+	durationFlag = flag.Duration("duration", 0, "run each cell for this wall time instead of -ops operations, e.g. 10s")
+	timeoutFlag  = flag.Duration("timeout", 0, "give up on a cell that takes longer than this, e.g. 5m (0 = no limit)")
+)
+
+var verifyFlag = flag.Bool("verify", false, "check every blob read against the payloads written")
+
+var ioFlag = flag.Bool("io", false, "count the file reads, writes and syncs of each cell through a wrapping VFS (modernc, and mattn with cgo)")
+
+var busyRetriesFlag = flag.Int("busy-retries", 0, "retry an operation up to this many times while the database is locked, counting the retries")
+
+var diffFlag = flag.Int("diff", 0, "run this many random statements on every driver first and report where their results differ (0 = off)")
+
+var (
+	repeatFlag  = flag.Int("repeat", 1, "run every cell this many times, interleaved in rounds, and merge the samples")
+	shuffleFlag = flag.Int64("shuffle", 0, "seed for shuffling the cell order of each round (0 = fixed order)")
+)
+
+// seedFlag and compressibilityFlag apply to both the run subcommand and
+// BenchmarkSqlite.
+var (
+	seedFlag            = flag.Int64("seed", 1, "seed for generated payloads")
+	compressibilityFlag = flag.Float64("compressibility", 0, "fraction of each payload that is zero-filled (0 = random, 1 = all zeros)")
+)
+
+// rowsFlag applies to both the run subcommand and BenchmarkSqlite.
+var rowsFlag = flag.Int("rows", 100, "rows inserted before read workloads (e.g. 100 up to 10000000)")
+
+// sizesFlag applies to both the run subcommand and BenchmarkSqlite.
+var sizesFlag = flag.String("sizes", "", "comma-separated payload sizes, e.g. 64,4K,1M,16M (default 64,256,1K,4K,1M)")
+
+// flagSizes returns the sizes given with -sizes, or the defaults.
+func flagSizes() ([]int, error) {
+	if *sizesFlag == "" {
+		return sqlitebench.DefaultSizes, nil
+	}
+	return parseSizes(*sizesFlag)
+}
+
+// benchFilter applies to both the run subcommand and BenchmarkSqlite, e.g.
+// go test -bench . -args -filter 'mattn/read/.*'.
+var benchFilter = flag.String("filter", "", "only run cells whose driver/workload/size name matches this regexp")
+
+var kvFlag = flag.String("kv", "", "comma-separated KV stores to compare with the drivers on the kv workloads, e.g. bbolt,badger,pebble")
+
+var olapFlag = flag.String("olap", "", "comma-separated OLAP backends to compare with the drivers on the analytical workloads, e.g. duckdb (needs -tags duckdb)")
+
+var (
+	postgresFlag      = flag.String("postgres", "", "DSN of a PostgreSQL database to run the workloads on as a baseline, e.g. postgres://bench@localhost/bench")
+	mysqlFlag         = flag.String("mysql", "", "DSN of a MySQL database to run the workloads on as a baseline, e.g. bench:secret@tcp(localhost)/")
+	libsqlFlag        = flag.String("libsql", "", "URL of a libsql server (sqld) to run the workloads on over its remote protocol; every table in it is dropped, e.g. http://127.0.0.1:8080")
+	libsqlReplicaFlag = flag.String("libsql-replica", "", "URL of a libsql server (sqld) to run the workloads on through an embedded replica synced after every write; every table in it is dropped (needs -tags libsql_replica,libsqlite3)")
+)
+
+var replicationFlag = flag.String("replication", "", "comma-separated replication modes to compare: off, wal-copy, litestream")
+
+var categoryFlag = flag.String("category", "", "comma-separated workload categories to run, e.g. write,read")
+
+var sqliteVersionFlag = tagSet{}
+
+func init() {
+	flag.Var(tagFlag(sqliteVersionFlag), "sqlite-version", "pin a driver to a SQLite version, e.g. mattn=3.46 (repeatable)")
+}
+
+func defaultConfig() Config {
+	names := make([]string, 0, len(sqlitebench.Drivers))
+	for name := range sqlitebench.Drivers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return Config{
+		Drivers:   names,
+		Workloads: []string{"write", "read"},
+		Sizes:     sqlitebench.DefaultSizes,
+		Ops:       100,
+		Rows:      100,
+		Seed:      1,
+		Repeat:    1,
+		Formats:   []string{"csv"},
+	}
+}
+
+// loadConfig reads a config file on top of the defaults. The format is
+// chosen by extension: .toml for TOML, anything else is parsed as YAML.
+// Relative scenario and schema paths are resolved against the config file's
+// directory.
+func loadConfig(path string) (Config, error) {
+	cfg := defaultConfig()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".toml") {
+		err = toml.Unmarshal(data, &cfg)
+	} else {
+		err = yaml.Unmarshal(data, &cfg)
+	}
+	if err != nil {
+		return cfg, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	for i, scenario := range cfg.Scenarios {
+		if !filepath.IsAbs(scenario) {
+			cfg.Scenarios[i] = filepath.Join(filepath.Dir(path), scenario)
+		}
+	}
+	if cfg.Schema != nil && cfg.Schema.File != "" && !filepath.IsAbs(cfg.Schema.File) {
+		cfg.Schema.File = filepath.Join(filepath.Dir(path), cfg.Schema.File)
+	}
+	if cfg.Extension != nil && cfg.Extension.Path != "" && !filepath.IsAbs(cfg.Extension.Path) {
+		// dlopen looks up relative names in the library path instead.
+		if cfg.Extension.Path, err = filepath.Abs(filepath.Join(filepath.Dir(path), cfg.Extension.Path)); err != nil {
+			return cfg, err
+		}
+	}
+	if cfg.Vector != nil && cfg.Vector.Path != "" && !filepath.IsAbs(cfg.Vector.Path) {
+		if cfg.Vector.Path, err = filepath.Abs(filepath.Join(filepath.Dir(path), cfg.Vector.Path)); err != nil {
+			return cfg, err
+		}
+	}
+
+	return cfg, cfg.validate()
+}
+
+// validate reports the first invalid setting in the config.
+func (c Config) validate() error {
+	for _, name := range c.Drivers {
+		if _, ok := sqlitebench.Drivers[name]; !ok {
+			return fmt.Errorf("unknown driver %q", name)
+		}
+	}
+	for _, name := range c.Workloads {
+		if _, ok := sqlitebench.Workloads[name]; !ok {
+			return fmt.Errorf("unknown workload %q", name)
+		}
+	}
+	for name, version := range c.SQLiteVersions {
+		if _, ok := sqlitebench.Drivers[name]; !ok {
+			return fmt.Errorf("SQLite version pinned for unknown driver %q", name)
+		}
+		if version == "" {
+			return fmt.Errorf("empty SQLite version pinned for %s", name)
+		}
+	}
+	for _, name := range c.KVStores {
+		if _, ok := sqlitebench.KVStores[name]; !ok {
+			return fmt.Errorf("unknown KV store %q", name)
+		}
+	}
+	for _, name := range c.OLAP {
+		if _, ok := sqlitebench.OLAPBackends[name]; !ok {
+			return fmt.Errorf("unknown OLAP backend %q (DuckDB needs a build with -tags duckdb)", name)
+		}
+	}
+	for kind := range c.Servers {
+		if _, err := sqlitebench.NewServerBackend(kind, ""); err != nil {
+			return err
+		}
+	}
+	for _, category := range c.Categories {
+		if !slices.Contains(sqlitebench.Categories, category) {
+			return fmt.Errorf("unknown category %q (want one of %s)", category, strings.Join(sqlitebench.Categories, ", "))
+		}
+	}
+	if c.KVMix != nil {
+		w := sqlitebench.NewKVMix()
+		c.KVMix.apply(w)
+		if err := w.Validate(); err != nil {
+			return fmt.Errorf("invalid kv_mix: %w", err)
+		}
+	}
+	if c.Cache != nil {
+		w := sqlitebench.NewCache()
+		c.Cache.apply(w)
+		if err := w.Validate(); err != nil {
+			return fmt.Errorf("invalid cache: %w", err)
+		}
+	}
+	if c.Graph != nil {
+		w := sqlitebench.NewGraph()
+		c.Graph.apply(w)
+		if err := w.Validate(); err != nil {
+			return fmt.Errorf("invalid graph: %w", err)
+		}
+	}
+	if c.OTel != nil && c.OTel.Endpoint != "" {
+		if u, err := url.Parse(c.OTel.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("otel endpoint must be an http or https URL, got %q", c.OTel.Endpoint)
+		}
+	}
+	if c.Files != nil {
+		w := sqlitebench.NewFiles()
+		c.Files.apply(w)
+		if err := w.Validate(); err != nil {
+			return fmt.Errorf("invalid files: %w", err)
+		}
+	}
+	if c.FTSCorpus != "" {
+		if _, err := os.Stat(c.FTSCorpus); err != nil {
+			return fmt.Errorf("invalid fts_corpus: %w", err)
+		}
+	}
+	extra, err := c.extraWorkloads()
+	if err != nil {
+		return err
+	}
+	var extraNames []string
+	for _, w := range extra {
+		if _, ok := sqlitebench.Workloads[w.Name()]; ok || slices.Contains(extraNames, w.Name()) {
+			return fmt.Errorf("workload %s is defined twice", w.Name())
+		}
+		extraNames = append(extraNames, w.Name())
+	}
+	for _, name := range c.Formats {
+		if _, ok := reporters[name]; !ok {
+			return fmt.Errorf("unknown output format %q", name)
+		}
+	}
+	for _, size := range c.Sizes {
+		if size < 0 {
+			return fmt.Errorf("invalid data size %d", size)
+		}
+	}
+	for _, mode := range c.JournalModes {
+		if !slices.Contains(journalModes, strings.ToLower(mode)) {
+			return fmt.Errorf("unknown journal mode %q", mode)
+		}
+	}
+	for _, sync := range c.Synchronous {
+		if !slices.Contains(synchronousModes, strings.ToLower(sync)) {
+			return fmt.Errorf("unknown synchronous setting %q", sync)
+		}
+	}
+	for _, n := range c.PageSizes {
+		if n < 512 || n > 65536 || n&(n-1) != 0 {
+			return fmt.Errorf("page size must be a power of two from 512 to 65536, got %d", n)
+		}
+	}
+	for _, n := range c.CacheSizes {
+		if n == 0 {
+			return fmt.Errorf("cache size must not be 0")
+		}
+	}
+	for _, n := range c.Concurrency {
+		if n <= 0 {
+			return fmt.Errorf("concurrency must be positive, got %d", n)
+		}
+	}
+	for _, mode := range c.Replication {
+		if !slices.Contains(sqlitebench.Replications, mode) {
+			return fmt.Errorf("unknown replication %q (want one of %s)", mode, strings.Join(sqlitebench.Replications, ", "))
+		}
+	}
+	if len(c.Replication) > 0 && slices.ContainsFunc(c.JournalModes, func(mode string) bool { return !strings.EqualFold(mode, "wal") }) {
+		return fmt.Errorf("replication needs journal_mode wal; remove the other journal modes")
+	}
+	for _, storage := range c.Storage {
+		if !slices.Contains(sqlitebench.Storages, storage) {
+			return fmt.Errorf("unknown storage %q (want one of %s)", storage, strings.Join(sqlitebench.Storages, ", "))
+		}
+	}
+	// runner compiles the filter, so it is checked first.
+	if _, err := regexp.Compile(c.Filter); err != nil {
+		return fmt.Errorf("invalid filter: %w", err)
+	}
+	if err := c.runner().Matrix().Validate(); err != nil {
+		return err
+	}
+	if c.BusyRetries < 0 {
+		return fmt.Errorf("busy_retries must not be negative, got %d", c.BusyRetries)
+	}
+	if c.Differential < 0 {
+		return fmt.Errorf("differential must not be negative, got %d", c.Differential)
+	}
+	if c.Ops <= 0 && c.Duration <= 0 {
+		return fmt.Errorf("ops must be positive, got %d", c.Ops)
+	}
+	if c.Duration < 0 {
+		return fmt.Errorf("duration must not be negative, got %v", c.Duration)
+	}
+	if c.Timeout < 0 {
+		return fmt.Errorf("timeout must not be negative, got %v", c.Timeout)
+	}
+	for name, timeout := range c.Timeouts {
+		if _, ok := sqlitebench.Workloads[name]; !ok && !slices.Contains(extraNames, name) {
+			return fmt.Errorf("timeout for unknown workload %q", name)
+		}
+		if timeout < 0 {
+			return fmt.Errorf("timeout for %s must not be negative, got %v", name, timeout)
+		}
+	}
+	if c.Rows <= 0 {
+		return fmt.Errorf("rows must be positive, got %d", c.Rows)
+	}
+	if c.Repeat <= 0 {
+		return fmt.Errorf("repeat must be positive, got %d", c.Repeat)
+	}
+	if c.Compressibility < 0 || c.Compressibility > 1 {
+		return fmt.Errorf("compressibility must be between 0 and 1, got %g", c.Compressibility)
+	}
+	return nil
+}
+
+// journalModes and synchronousModes are the values the journal_modes and
+// synchronous dimensions accept.
+var (
+	journalModes     = []string{"delete", "truncate", "persist", "memory", "wal", "off"}
+	synchronousModes = []string{"off", "normal", "full", "extra"}
+)
+
+// kvWorkloads and analyticalWorkloads are the workloads added for KV
+// stores and OLAP backends when the config names none that run on them.
+var (
+	kvWorkloads         = []string{"kv.put", "kv.get", "kv.delete", "kv.mix"}
+	analyticalWorkloads = []string{"scan", "aggregate", "join"}
+)
+
+// workloads returns the registered workloads the config runs.
+func (c Config) workloads() []string {
+	workloads := c.Workloads
+	for _, family := range []struct {
+		backends, workloads []string
+	}{
+		{c.KVStores, kvWorkloads},
+		{c.OLAP, analyticalWorkloads},
+	} {
+		if len(family.backends) > 0 && !slices.ContainsFunc(c.Workloads, func(name string) bool { return slices.Contains(family.workloads, name) }) {
+			workloads = slices.Concat(workloads, family.workloads)
+		}
+	}
+	return workloads
+}
+
+// runner returns a sqlitebench runner for the config. Observers, the
+// profiling hook and resumed results are left to the caller.
+func (c Config) runner() *sqlitebench.Runner {
+	r := sqlitebench.NewRunner()
+	r.Drivers = c.Drivers
+	r.KVStores = c.KVStores
+	r.OLAP = c.OLAP
+	if len(c.Servers) > 0 {
+		r.Servers = map[string]sqlitebench.Backend{}
+		for kind, dsn := range c.Servers {
+			// The kind was checked by validate.
+			r.Servers[kind], _ = sqlitebench.NewServerBackend(kind, dsn)
+		}
+	}
+	r.Sizes = c.Sizes
+	r.JournalModes = c.JournalModes
+	r.Synchronous = c.Synchronous
+	r.PageSizes = c.PageSizes
+	r.CacheSizes = c.CacheSizes
+	r.Concurrency = c.Concurrency
+	r.Storage = c.Storage
+	r.Replication = c.Replication
+	r.Exclude = c.Exclude
+	r.Include = c.Include
+	if c.Filter != "" {
+		r.Filter = regexp.MustCompile(c.Filter)
+	}
+	for _, name := range c.workloads() {
+		b := sqlitebench.Workloads[name]
+		if len(c.Categories) > 0 && !slices.Contains(c.Categories, b.Category) {
+			continue
+		}
+		w := b.New()
+		if m, ok := w.(*sqlitebench.KVMix); ok && c.KVMix != nil {
+			c.KVMix.apply(m)
+		}
+		if cw, ok := w.(*sqlitebench.Cache); ok && c.Cache != nil {
+			c.Cache.apply(cw)
+		}
+		if g, ok := w.(*sqlitebench.Graph); ok && c.Graph != nil {
+			c.Graph.apply(g)
+		}
+		if f, ok := w.(*sqlitebench.Files); ok && c.Files != nil {
+			c.Files.apply(f)
+		}
+		if f, ok := w.(*sqlitebench.FTS); ok {
+			f.Corpus = c.FTSCorpus
+		}
+		r.Add(w)
+	}
+	// The files were checked by validate.
+	extra, err := c.extraWorkloads()
+	if err != nil {
+		fatal("Invalid config", "err", err)
+	}
+	r.Add(extra...)
+	r.Ops = c.Ops
+	r.Duration = c.Duration
+	r.Timeout = c.Timeout
+	r.Timeouts = c.Timeouts
+	r.Rows = c.Rows
+	r.Seed = c.Seed
+	r.Compressibility = c.Compressibility
+	r.Pragmas = c.Pragmas
+	r.Verify = c.Verify
+	r.IOCounters = c.IOCounters
+	r.BusyRetries = c.BusyRetries
+	r.Repeat = c.Repeat
+	r.Shuffle = c.Shuffle
+	r.PerfCounters = perfEnabled
+	return r
+}
+
+// cells returns the number of cell runs the config expands to, counting
+// every repetition.
+func (c Config) cells() int {
+	return len(c.runner().Schedule())
+}
@@ -0,0 +1,469 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"sqlite_benchmark/sqlitebench"
+)
+
+func writeTempFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	yamlPath := writeTempFile(t, "matrix.yaml", `
+drivers: [mattn]
+sizes: [64, 4096]
+rows: 1000
+pragmas: ["journal_mode=WAL"]
+`)
+	tomlPath := writeTempFile(t, "matrix.toml", `
+drivers = ["mattn"]
+sizes = [64, 4096]
+rows = 1000
+pragmas = ["journal_mode=WAL"]
+`)
+
+	want := defaultConfig()
+	want.Drivers = []string{"mattn"}
+	want.Sizes = []int{64, 4096}
+	want.Rows = 1000
+	want.Pragmas = []string{"journal_mode=WAL"}
+
+	for _, path := range []string{yamlPath, tomlPath} {
+		got, err := loadConfig(path)
+		if err != nil {
+			t.Fatalf("loadConfig(%s): %v", filepath.Base(path), err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("loadConfig(%s) = %+v, want %+v", filepath.Base(path), got, want)
+		}
+	}
+}
+
+func TestLoadConfigInvalid(t *testing.T) {
+	path := writeTempFile(t, "bad.yaml", "drivers: [nope]\n")
+	if _, err := loadConfig(path); err == nil {
+		t.Error("expected an error for an unknown driver")
+	}
+}
+
+func TestSQLiteVersionPins(t *testing.T) {
+	path := writeTempFile(t, "pins.yaml", "sqlite_versions: {nope: \"3.46\"}\n")
+	if _, err := loadConfig(path); err == nil {
+		t.Error("expected an error for a pin of an unknown driver")
+	}
+
+	info := sqlitebench.DriverInfo{SQLiteVersion: "3.46.1"}
+	for pin, ok := range map[string]bool{"": true, "3.46": true, "3.46.1": true, "3.4": false, "3.45": false} {
+		if err := checkSQLiteVersion("mattn", info, pin); (err == nil) != ok {
+			t.Errorf("checkSQLiteVersion(%q) = %v, want ok %v", pin, err, ok)
+		}
+	}
+}
+
+func TestExampleConfig(t *testing.T) {
+	if _, err := loadConfig("config.example.yaml"); err != nil {
+		t.Errorf("config.example.yaml: %v", err)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	cases := []struct {
+		filter string
+		want   bool
+	}{
+		{"", true},
+		{"mattn", true},
+		{"^modernc/", false},
+		{"/read/", false},
+		{"/write/64$", true},
+		{"/write/6$", false},
+	}
+	for _, c := range cases {
+		cfg := defaultConfig()
+		cfg.Drivers, cfg.Workloads, cfg.Sizes = []string{"mattn"}, []string{"write"}, SizeList{64}
+		cfg.Filter = c.filter
+		if got := len(cfg.runner().Cells()) == 1; got != c.want {
+			t.Errorf("filter %q selects mattn/write/64: %v, want %v", c.filter, got, c.want)
+		}
+	}
+
+	cfg := defaultConfig()
+	cfg.Filter = "mattn/read"
+	if n := cfg.cells(); n != len(cfg.Sizes) {
+		t.Errorf("cells() = %d, want %d", n, len(cfg.Sizes))
+	}
+}
+
+func TestValidateFilter(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.Filter = "("
+	if err := cfg.validate(); err == nil || !strings.Contains(err.Error(), "invalid filter") {
+		t.Errorf("validate() = %v, want an invalid filter error", err)
+	}
+}
+
+func TestLoadConfigDuration(t *testing.T) {
+	for name, content := range map[string]string{
+		"d.yaml": "duration: 10s\n",
+		"d.toml": "duration = \"10s\"\n",
+	} {
+		cfg, err := loadConfig(writeTempFile(t, name, content))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if cfg.Duration != 10*time.Second {
+			t.Errorf("%s: duration = %v, want 10s", name, cfg.Duration)
+		}
+	}
+}
+
+func TestPrintPlan(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.Sizes = SizeList{64, 1024}
+	cfg.Filter = "mattn/"
+
+	var sb strings.Builder
+	printPlan(&sb, cfg)
+	out := sb.String()
+
+	for _, want := range []string{
+		"mattn/write/64",
+		"mattn/read/1024",
+		"100 rows (100.0KiB)",
+		"4 cells, 400 measured ops, 200 rows (106.2KiB) populated",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("plan missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "modernc/") {
+		t.Errorf("plan includes filtered-out driver:\n%s", out)
+	}
+}
+
+func TestLoadConfigTimeouts(t *testing.T) {
+	path := writeTempFile(t, "bench.yaml", "timeout: 2m\ntimeouts:\n  read: 5m\n")
+	cfg, err := loadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Timeout != 2*time.Minute {
+		t.Errorf("timeout = %v, want 2m", cfg.Timeout)
+	}
+	if got := cfg.Timeouts["read"]; got != 5*time.Minute {
+		t.Errorf("read timeout = %v, want 5m", got)
+	}
+}
+
+func TestExampleScenario(t *testing.T) {
+	s, err := loadScenario("scenario.example.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Name != "orders" || len(s.Steps) != 2 {
+		t.Errorf("scenario = %+v, want orders with 2 steps", s)
+	}
+}
+
+func TestLoadConfigScenarios(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "kv.yaml"), []byte("steps:\n  - sql: SELECT 1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "matrix.yaml")
+	if err := os.WriteFile(path, []byte("scenarios: [kv.yaml]\ntimeouts:\n  kv: 1m\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := loadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cells := cfg.runner().Cells()
+	if cells[len(cells)-1].Workload != "kv" {
+		t.Errorf("cells = %v, want the kv scenario among them", cells)
+	}
+}
+
+func TestLoadConfigSchema(t *testing.T) {
+	sql, err := filepath.Abs("schema.example.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := writeTempFile(t, "matrix.yaml", `
+workloads: []
+schema:
+  file: `+sql+`
+  table: orders
+  columns:
+    customer: zipf(500)
+    email: email
+    placed_at: timestamp
+    total: float(1, 250)
+timeouts:
+  orders.read: 1m
+`)
+	cfg, err := loadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, c := range cfg.runner().Cells()[:3] {
+		names = append(names, c.Workload)
+	}
+	if want := []string{"orders.insert", "orders.read", "orders.update"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("workloads = %v, want %v", names, want)
+	}
+
+	cfg.Schema.Columns["total"] = "money(2)"
+	if err := cfg.validate(); err == nil {
+		t.Error("expected an error for an unknown generator")
+	}
+}
+
+func TestLoadConfigDimensions(t *testing.T) {
+	path := writeTempFile(t, "matrix.yaml", `
+drivers: [modernc]
+workloads: [write]
+sizes: [64, 1024]
+journal_modes: [delete, wal]
+storage: [file]
+exclude:
+  - {journal_mode: wal, size: 1024}
+`)
+	cfg, err := loadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, c := range cfg.runner().Cells() {
+		names = append(names, c.String())
+	}
+	want := []string{
+		"modernc/write,journal_mode=delete,storage=file/64",
+		"modernc/write,journal_mode=wal,storage=file/64",
+		"modernc/write,journal_mode=delete,storage=file/1024",
+	}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("cells = %v, want %v", names, want)
+	}
+
+	cfg.JournalModes = []string{"fast"}
+	if err := cfg.validate(); err == nil {
+		t.Error("expected an error for an unknown journal mode")
+	}
+	cfg.JournalModes = []string{"wal"}
+	cfg.PageSizes = []int{3000}
+	if err := cfg.validate(); err == nil {
+		t.Error("expected an error for a page size that is not a power of two")
+	}
+	cfg.PageSizes = nil
+	cfg.JournalModes = nil
+	if err := cfg.validate(); err == nil {
+		t.Error("expected an error for a rule naming a dimension that is not varied")
+	}
+}
+
+func TestLoadConfigCategories(t *testing.T) {
+	path := writeTempFile(t, "categories.yaml", `
+drivers: [modernc]
+sizes: [64]
+categories: [read]
+`)
+	cfg, err := loadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, c := range cfg.runner().Cells() {
+		names = append(names, c.String())
+	}
+	if want := []string{"modernc/read/64"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("cells = %v, want %v", names, want)
+	}
+
+	cfg.Categories = []string{"fast"}
+	if err := cfg.validate(); err == nil {
+		t.Error("expected an error for an unknown category")
+	}
+}
+
+func TestLoadConfigExtension(t *testing.T) {
+	path := writeTempFile(t, "extension.yaml", `
+drivers: [mattn]
+workloads: []
+sizes: [64]
+extension:
+  path: libhalf.so
+  call: SELECT half(?)
+`)
+	cfg, err := loadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(filepath.Dir(path), "libhalf.so"); cfg.Extension.Path != want || !filepath.IsAbs(want) {
+		t.Errorf("path = %q, want %q", cfg.Extension.Path, want)
+	}
+	var names []string
+	for _, c := range cfg.runner().Cells() {
+		names = append(names, c.String())
+	}
+	if want := []string{"mattn/extension.load/64", "mattn/extension.call/64"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("cells = %v, want %v", names, want)
+	}
+
+	cfg.Extension.Path = ""
+	if err := cfg.validate(); err == nil {
+		t.Error("expected an error for an extension without a path")
+	}
+}
+
+func TestConfigKVStores(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.KVStores = []string{"pebble"}
+	if err := cfg.validate(); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"write", "read", "kv.put", "kv.get", "kv.delete", "kv.mix"}; !reflect.DeepEqual(cfg.workloads(), want) {
+		t.Errorf("workloads = %v, want %v", cfg.workloads(), want)
+	}
+	cfg.Workloads = []string{"kv.get"}
+	if want := []string{"kv.get"}; !reflect.DeepEqual(cfg.workloads(), want) {
+		t.Errorf("workloads = %v, want %v", cfg.workloads(), want)
+	}
+
+	cfg.KVStores = []string{"leveldb"}
+	if err := cfg.validate(); err == nil {
+		t.Error("expected an error for an unknown KV store")
+	}
+}
+
+func TestConfigKVMix(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.KVMix = &KVMixConfig{Get: 1, Put: 1}
+	if err := cfg.validate(); err != nil {
+		t.Fatal(err)
+	}
+	w := sqlitebench.NewKVMix()
+	cfg.KVMix.apply(w)
+	if w.Gets != 1 || w.Puts != 1 || w.Deletes != 0 || w.Skew != 1.1 {
+		t.Errorf("mix = %d:%d:%d, skew %g; want 1:1:0, skew 1.1", w.Gets, w.Puts, w.Deletes, w.Skew)
+	}
+
+	for _, mc := range []KVMixConfig{{Get: -1, Put: 2}, {Skew: 0.5}} {
+		cfg.KVMix = &mc
+		if err := cfg.validate(); err == nil {
+			t.Errorf("expected an error for kv_mix %+v", mc)
+		}
+	}
+}
+
+func TestConfigCache(t *testing.T) {
+	cfg := defaultConfig()
+	ratio := 0.0
+	cfg.Cache = &CacheConfig{MissRatio: &ratio}
+	if err := cfg.validate(); err != nil {
+		t.Fatal(err)
+	}
+	w := sqlitebench.NewCache()
+	cfg.Cache.apply(w)
+	if w.MissRatio != 0 {
+		t.Errorf("miss ratio = %g; want 0", w.MissRatio)
+	}
+
+	ratio = 1.5
+	if err := cfg.validate(); err == nil {
+		t.Error("expected an error for a miss ratio above 1")
+	}
+}
+
+func TestConfigGraph(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.Graph = &GraphConfig{FanOut: 16}
+	if err := cfg.validate(); err != nil {
+		t.Fatal(err)
+	}
+	w := sqlitebench.NewGraph()
+	cfg.Graph.apply(w)
+	if w.FanOut != 16 || w.Hops != 3 {
+		t.Errorf("fan-out %d, %d hops; want 16 and 3", w.FanOut, w.Hops)
+	}
+
+	cfg.Graph = &GraphConfig{Hops: -1}
+	if err := cfg.validate(); err == nil {
+		t.Error("expected an error for negative hops")
+	}
+}
+
+func TestConfigFiles(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.Files = &FilesConfig{ChunkSize: 1 << 20}
+	if err := cfg.validate(); err != nil {
+		t.Fatal(err)
+	}
+	w := sqlitebench.NewFiles()
+	cfg.Files.apply(w)
+	if w.FileSize != 4<<20 || w.ChunkSize != 1<<20 {
+		t.Errorf("file size %d, chunk size %d; want 4 MiB and 1 MiB", w.FileSize, w.ChunkSize)
+	}
+
+	cfg.Files = &FilesConfig{FileSize: 1 << 30}
+	if err := cfg.validate(); err == nil {
+		t.Error("expected an error for a 1 GiB file")
+	}
+}
+
+func TestLoadConfigVector(t *testing.T) {
+	path := writeTempFile(t, "vector.yaml", `
+drivers: [mattn]
+workloads: []
+sizes: [64]
+vector:
+  path: vec0.so
+`)
+	cfg, err := loadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(filepath.Dir(path), "vec0.so"); cfg.Vector.Path != want {
+		t.Errorf("path = %q, want %q", cfg.Vector.Path, want)
+	}
+	var names []string
+	for _, c := range cfg.runner().Cells() {
+		names = append(names, c.String())
+	}
+	if want := []string{"mattn/vector.insert/64", "mattn/vector.query/64"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("cells = %v, want %v", names, want)
+	}
+
+	cfg.Vector.Dimensions = -1
+	if err := cfg.validate(); err == nil {
+		t.Error("expected an error for negative dimensions")
+	}
+}
+
+func TestConfigOTel(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.OTel = &OTelConfig{Endpoint: "http://localhost:4318", Statements: true}
+	if err := cfg.validate(); err != nil {
+		t.Fatal(err)
+	}
+	cfg.OTel.Endpoint = "localhost:4318"
+	if err := cfg.validate(); err == nil {
+		t.Error("expected an error for an endpoint without a scheme")
+	}
+}
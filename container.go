@@ -0,0 +1,71 @@
+package main
+
+import (
+	"flag"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+var (
+	inContainer      = flag.Bool("in-container", false, "build the pinned image from ./Dockerfile and run the benchmark inside it, with the working directory mounted for results")
+	containerRuntime = flag.String("container-runtime", "docker", "container CLI used by -in-container, e.g. docker or podman")
+)
+
+// containerImage is the tag the benchmark image is built as.
+const containerImage = "sqlite-benchmark"
+
+// containerEnv is set inside the container to the ID of the image the run
+// uses. It is recorded in the run metadata and keeps the run inside the
+// container from starting another one.
+const containerEnv = "SQLITE_BENCHMARK_IMAGE"
+
+// runInContainer builds the image from the Dockerfile in the working
+// directory and runs the run subcommand inside it with the same arguments.
+// The working directory is mounted at the same path, so relative config,
+// output and store paths resolve as they would outside; paths outside it do
+// not exist in the container. It exits with the container's status.
+func runInContainer(args []string) {
+	if _, err := os.Stat("Dockerfile"); err != nil {
+		fatal("-in-container needs the Dockerfile; run it from the repository checkout", "err", err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		fatal("Failed to get working directory", "err", err)
+	}
+
+	build := exec.Command(*containerRuntime, "build", "-t", containerImage, ".")
+	build.Stdout, build.Stderr = os.Stderr, os.Stderr
+	slog.Info("Building benchmark image", "runtime", *containerRuntime, "image", containerImage)
+	if err := build.Run(); err != nil {
+		fatal("Failed to build benchmark image", "err", err)
+	}
+	id, err := exec.Command(*containerRuntime, "image", "inspect", "-f", "{{.Id}}", containerImage).Output()
+	if err != nil {
+		fatal("Failed to inspect benchmark image", "err", err)
+	}
+
+	runArgs := []string{"run", "--rm", "--init",
+		"-v", wd + ":" + wd, "-w", wd,
+		"-e", containerEnv + "=" + strings.TrimSpace(string(id)),
+	}
+	// Results written in the mount belong to the caller, not root.
+	if uid := os.Getuid(); uid >= 0 {
+		runArgs = append(runArgs, "--user", strconv.Itoa(uid)+":"+strconv.Itoa(os.Getgid()))
+	}
+	if useColor(os.Stdout) {
+		runArgs = append(runArgs, "-t")
+	}
+	runArgs = append(runArgs, containerImage, "run")
+
+	cmd := exec.Command(*containerRuntime, append(runArgs, args...)...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		if exit, ok := err.(*exec.ExitError); ok {
+			os.Exit(exit.ExitCode())
+		}
+		fatal("Failed to run benchmark container", "err", err)
+	}
+}
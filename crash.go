@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"sqlite_benchmark/sqlitebench"
+)
+
+// runCrash implements the crash subcommand: a writer process, a re-exec of
+// this binary, is killed with SIGKILL while it commits transactions, then
+// the database is recovered and checked for lost or torn transactions,
+// several rounds per driver, journal mode and synchronous setting. The same
+// subcommand with -writer runs the writer. With -power-loss, each round
+// instead cuts the power at a random point of a logged run, dropping and
+// reordering the writes not yet synced.
+func runCrash(args []string) {
+	fs := flag.NewFlagSet("crash", flag.ExitOnError)
+	drivers := fs.String("drivers", strings.Join(defaultConfig().Drivers, ","), "comma-separated drivers to test")
+	journals := fs.String("journal", "delete,wal", "comma-separated journal modes to test")
+	syncs := fs.String("synchronous", "full,normal,off", "comma-separated synchronous settings to test")
+	rounds := fs.Int("rounds", 5, "kills per driver, journal mode and synchronous setting")
+	txRows := fs.Int("tx-rows", 100, "rows inserted per transaction")
+	size := fs.String("size", "1K", "payload size, e.g. 64 or 4K")
+	killAfter := fs.Duration("kill-after", 100*time.Millisecond, "kill the writer at a random time up to this long after its first commit")
+	dir := fs.String("dir", "", "directory for the database file (default a temporary directory)")
+	seed := fs.Int64("seed", 1, "seed for payloads and kill times")
+	powerLoss := fs.Bool("power-loss", false, "simulate power cuts instead of killing the writer")
+	txs := fs.Int("txs", 20, "transactions committed per setting with -power-loss")
+	drop := fs.Float64("drop", 0.5, "probability of losing each write not yet synced with -power-loss")
+	writer := fs.Bool("writer", false, "run as the writer process (used internally)")
+	spec := fs.String("spec", "", "writer spec as JSON (used internally)")
+	fs.Parse(args)
+
+	if *writer {
+		runCrashWriter(*spec)
+		return
+	}
+
+	dataSize, err := parseSize(*size)
+	if err != nil {
+		fatal("Invalid -size", "err", err)
+	}
+	if *rounds <= 0 || *txRows <= 0 || *killAfter < 0 {
+		fatal("Invalid flags: -rounds and -tx-rows must be positive and -kill-after not negative")
+	}
+	if *txs <= 0 || *drop < 0 || *drop > 1 {
+		fatal("Invalid flags: -txs must be positive and -drop between 0 and 1")
+	}
+
+	// fatal would skip the deferred cleanup of run, so its errors are
+	// reported once it returns.
+	run := func() error {
+		if *dir == "" {
+			tmp, remove, err := sqlitebench.MakeTempDir("", "crash-")
+			if err != nil {
+				return fmt.Errorf("creating directory: %w", err)
+			}
+			defer remove()
+			*dir = tmp
+		}
+
+		ctx, cancel := interruptContext()
+		defer cancel()
+
+		rng := rand.New(rand.NewPCG(uint64(*seed), 0))
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		defer tw.Flush()
+		fmt.Fprintln(tw, "Driver\tJournal\tSynchronous\tRounds\tAcked\tLost\tTorn\tCorrupt\tRecovery mean\tmax")
+		for _, driver := range strings.Split(*drivers, ",") {
+			for _, journal := range strings.Split(*journals, ",") {
+				for _, sync := range strings.Split(*syncs, ",") {
+					c := &sqlitebench.CrashTest{
+						Path:        filepath.Join(*dir, "crash.db"),
+						Driver:      driver,
+						JournalMode: journal,
+						Synchronous: sync,
+						TxRows:      *txRows,
+						DataSize:    dataSize,
+						Seed:        *seed,
+					}
+					setting := fmt.Sprintf("%s with journal mode %s and synchronous %s", driver, journal, sync)
+					if *powerLoss {
+						p := &sqlitebench.PowerLossTest{CrashTest: *c, Txs: *txs, Drop: *drop}
+						results, err := p.Run(ctx, *rounds)
+						if err != nil {
+							return fmt.Errorf("power loss test of %s: %w", setting, err)
+						}
+						printCrashRow(tw, c, results)
+						continue
+					}
+					var results []sqlitebench.CrashResult
+					for range *rounds {
+						delay := time.Duration(rng.Int64N(int64(*killAfter) + 1))
+						result, err := crashRound(ctx, c, delay)
+						if err != nil {
+							return fmt.Errorf("crash round of %s: %w", setting, err)
+						}
+						results = append(results, result)
+					}
+					printCrashRow(tw, c, results)
+				}
+			}
+		}
+		return nil
+	}
+	if err := run(); err != nil {
+		fatal("Crash test failed", "err", err)
+	}
+}
+
+// crashRound prepares the file, starts a writer, kills it delay after its
+// first commit and recovers the database.
+func crashRound(ctx context.Context, c *sqlitebench.CrashTest, delay time.Duration) (sqlitebench.CrashResult, error) {
+	if err := c.Prepare(ctx); err != nil {
+		return sqlitebench.CrashResult{}, fmt.Errorf("preparing %s: %w", c.Path, err)
+	}
+	spec, err := json.Marshal(c)
+	if err != nil {
+		return sqlitebench.CrashResult{}, err
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return sqlitebench.CrashResult{}, err
+	}
+
+	cmd := exec.CommandContext(ctx, exe, "crash", "-writer", "-spec", string(spec))
+	cmd.Stderr = os.Stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return sqlitebench.CrashResult{}, err
+	}
+	if err := cmd.Start(); err != nil {
+		return sqlitebench.CrashResult{}, fmt.Errorf("starting writer: %w", err)
+	}
+
+	// The writer prints a line per committed transaction.
+	first := make(chan struct{})
+	acked := make(chan int)
+	go func() {
+		n := 0
+		lines := bufio.NewScanner(stdout)
+		for lines.Scan() {
+			if n++; n == 1 {
+				close(first)
+			}
+		}
+		if n == 0 {
+			close(first)
+		}
+		acked <- n
+	}()
+
+	<-first
+	time.Sleep(delay)
+	cmd.Process.Kill()
+	n := <-acked
+	cmd.Wait()
+	if n == 0 {
+		return sqlitebench.CrashResult{}, fmt.Errorf("writer exited before committing")
+	}
+	return c.Recover(ctx, n)
+}
+
+// runCrashWriter is the body of the writer process: it commits until it is
+// killed, printing the number of every committed transaction to stdout.
+func runCrashWriter(spec string) {
+	var c sqlitebench.CrashTest
+	if err := json.Unmarshal([]byte(spec), &c); err != nil {
+		fatal("Invalid writer spec", "err", err)
+	}
+	err := c.Write(context.Background(), func(tx int) {
+		fmt.Fprintln(os.Stdout, tx)
+	})
+	fatal("Writer failed", "err", err)
+}
+
+// printCrashRow writes the combined results of the rounds of one setting.
+func printCrashRow(w io.Writer, c *sqlitebench.CrashTest, results []sqlitebench.CrashResult) {
+	var acked, lost, torn, corrupt int
+	var total, longest time.Duration
+	for _, r := range results {
+		acked += r.Acked
+		lost += r.Lost
+		if r.Torn {
+			torn++
+		}
+		if r.Integrity != "ok" {
+			corrupt++
+		}
+		total += r.Recovery
+		longest = max(longest, r.Recovery)
+	}
+
+	fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\t%d\t%d\t%d\t%v\t%v\n",
+		c.Driver, c.JournalMode, c.Synchronous, len(results), acked, lost, torn, corrupt,
+		(total / time.Duration(len(results))).Round(time.Microsecond), longest.Round(time.Microsecond))
+}
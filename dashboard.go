@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"sqlite_benchmark/sqlitebench"
+)
+
+// dashboardTick is how often the dashboard samples the live op count.
+const dashboardTick = 250 * time.Millisecond
+
+type cellStartedMsg struct {
+	Driver    string
+	Operation string
+	DataSize  int
+}
+
+type cellDoneMsg struct {
+	Result BenchmarkResult
+}
+
+type cellFailedMsg struct {
+	Err error
+}
+
+type dashboardTickMsg time.Time
+
+// dashboardModel is the bubbletea model behind -tui.
+type dashboardModel struct {
+	total   int
+	current *cellStartedMsg
+	results []BenchmarkResult
+	failed  []error
+	perDrv  map[string]int
+
+	lastOps  int64
+	lastTick time.Time
+	opsRate  float64
+
+	interrupted bool
+	liveOps     func() int64
+}
+
+func (m *dashboardModel) Init() tea.Cmd {
+	return dashboardTickCmd()
+}
+
+func dashboardTickCmd() tea.Cmd {
+	return tea.Tick(dashboardTick, func(t time.Time) tea.Msg { return dashboardTickMsg(t) })
+}
+
+func (m *dashboardModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" {
+			m.interrupted = true
+			return m, tea.Quit
+		}
+
+	case cellStartedMsg:
+		m.current = &msg
+		m.lastOps, m.lastTick, m.opsRate = 0, time.Now(), 0
+
+	case cellDoneMsg:
+		m.current = nil
+		m.results = append(m.results, msg.Result)
+		m.perDrv[msg.Result.Driver]++
+
+	case cellFailedMsg:
+		m.current = nil
+		m.failed = append(m.failed, msg.Err)
+
+	case dashboardTickMsg:
+		now := time.Time(msg)
+		ops := m.liveOps()
+		if elapsed := now.Sub(m.lastTick).Seconds(); elapsed > 0 && ops >= m.lastOps {
+			m.opsRate = float64(ops-m.lastOps) / elapsed
+		}
+		m.lastOps, m.lastTick = ops, now
+		return m, dashboardTickCmd()
+	}
+
+	return m, nil
+}
+
+func (m *dashboardModel) View() string {
+	var sb strings.Builder
+
+	done := len(m.results) + len(m.failed)
+	width := 30
+	filled := 0
+	if m.total > 0 {
+		filled = done * width / m.total
+	}
+	fmt.Fprintf(&sb, "SQLite driver benchmark  [%s%s] %d/%d\n\n", strings.Repeat("#", filled), strings.Repeat("-", width-filled), done, m.total)
+
+	if m.current != nil {
+		fmt.Fprintf(&sb, "Running  %s %s %s   %.0f ops/s (%d ops)\n\n",
+			m.current.Driver, m.current.Operation, formatSize(m.current.DataSize), m.opsRate, m.liveOps())
+	}
+
+	if len(m.perDrv) > 0 {
+		t := buildComparisonTable(m.results)
+		for _, driver := range t.Drivers {
+			fmt.Fprintf(&sb, "  %-10s %d cells done\n", driver, m.perDrv[driver])
+		}
+		sb.WriteString("\n")
+		printComparisonTable(&sb, m.results, true)
+	}
+
+	for _, err := range m.failed {
+		fmt.Fprintf(&sb, "\nFailed   %v", err)
+	}
+
+	return sb.String()
+}
+
+// dashboard drives a live terminal UI from the benchmark loop.
+type dashboard struct {
+	program *tea.Program
+	done    chan struct{}
+}
+
+// startDashboard takes over the terminal for a run of total cells.
+// liveOps reports the operations completed in the current cell; Ctrl+C
+// calls interrupt.
+func startDashboard(total int, liveOps func() int64, interrupt func()) *dashboard {
+	d := &dashboard{
+		program: tea.NewProgram(&dashboardModel{total: total, perDrv: map[string]int{}, liveOps: liveOps}),
+		done:    make(chan struct{}),
+	}
+
+	go func() {
+		defer close(d.done)
+		// The dashboard owns the terminal, so Ctrl+C arrives as a key
+		// press rather than SIGINT; treat it the way SIGINT would be.
+		final, _ := d.program.Run()
+		if m, ok := final.(*dashboardModel); ok && m.interrupted {
+			interrupt()
+		}
+	}()
+
+	return d
+}
+
+func (d *dashboard) CellStarted(_ int, c sqlitebench.Cell) {
+	d.program.Send(cellStartedMsg{c.Driver, c.Workload, c.DataSize})
+}
+
+func (d *dashboard) CellDone(_ int, _ sqlitebench.Cell, r BenchmarkResult) {
+	d.program.Send(cellDoneMsg{r})
+}
+
+func (d *dashboard) CellFailed(_ int, _ sqlitebench.Cell, err error) {
+	d.program.Send(cellFailedMsg{err})
+}
+
+// stop restores the terminal, leaving the final view on screen.
+func (d *dashboard) stop() {
+	d.program.Quit()
+	<-d.done
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"sqlite_benchmark/sqlitebench"
+)
+
+// runDiskFull implements the diskfull subcommand: transactions are written
+// until the database runs out of space, per driver, journal mode and
+// synchronous setting, showing the error each driver returns, whether the
+// connection commits again once space is freed and whether the database is
+// intact. The space is limited by the driver's counting VFS unless
+// -max-size is 0, when -dir must be on a small filesystem to fill.
+func runDiskFull(args []string) {
+	fs := flag.NewFlagSet("diskfull", flag.ExitOnError)
+	drivers := fs.String("drivers", strings.Join(defaultConfig().Drivers, ","), "comma-separated drivers to test")
+	journals := fs.String("journal", "delete,wal", "comma-separated journal modes to test")
+	syncs := fs.String("synchronous", "full", "comma-separated synchronous settings to test")
+	txRows := fs.Int("tx-rows", 10, "rows inserted per transaction")
+	size := fs.String("size", "4K", "payload size, e.g. 64 or 4K")
+	maxSize := fs.String("max-size", "4M", "size each database file is limited to, or 0 to fill the filesystem of -dir")
+	ballast := fs.String("ballast", "1M", "size of the file removed to free space when filling a filesystem")
+	dir := fs.String("dir", "", "directory for the database file (default a temporary directory)")
+	seed := fs.Int64("seed", 1, "seed for payloads")
+	fs.Parse(args)
+
+	dataSize, err := parseSize(*size)
+	if err != nil {
+		fatal("Invalid -size", "err", err)
+	}
+	limit, err := parseSize(*maxSize)
+	if err != nil {
+		fatal("Invalid -max-size", "err", err)
+	}
+	ballastSize, err := parseSize(*ballast)
+	if err != nil {
+		fatal("Invalid -ballast", "err", err)
+	}
+	if *txRows <= 0 {
+		fatal("Invalid flags: -tx-rows must be positive")
+	}
+	if limit == 0 && *dir == "" {
+		fatal("Filling a filesystem needs -dir on a small one, e.g. a tmpfs mounted with size=")
+	}
+	if *dir == "" {
+		var remove func()
+		if *dir, remove, err = sqlitebench.MakeTempDir("", "diskfull-"); err != nil {
+			fatal("Failed to create directory", "err", err)
+		}
+		defer remove()
+	}
+
+	ctx, cancel := interruptContext()
+	defer cancel()
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Driver\tJournal\tSynchronous\tCommitted\tSize\tFailed in\tRecovery\tLost\tTorn\tIntegrity\tError")
+	for _, driver := range strings.Split(*drivers, ",") {
+		for _, journal := range strings.Split(*journals, ",") {
+			for _, sync := range strings.Split(*syncs, ",") {
+				d := &sqlitebench.DiskFullTest{
+					CrashTest: sqlitebench.CrashTest{
+						Path:        filepath.Join(*dir, "diskfull.db"),
+						Driver:      driver,
+						JournalMode: journal,
+						Synchronous: sync,
+						TxRows:      *txRows,
+						DataSize:    dataSize,
+						Seed:        *seed,
+					},
+					MaxSize: int64(limit),
+					Ballast: int64(ballastSize),
+				}
+				r, err := d.Run(ctx)
+				if err != nil {
+					tw.Flush()
+					fatal("Disk full test failed", "driver", driver, "journal", journal, "synchronous", sync, "err", err)
+				}
+				recovery := r.Recovery.Round(time.Microsecond).String()
+				if r.RecoveryErr != "" {
+					recovery = "failed: " + r.RecoveryErr
+				}
+				msg := r.Err
+				if !r.Full {
+					msg = "not SQLITE_FULL: " + msg
+				}
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\t%v\t%s\t%d\t%v\t%s\t%s\n",
+					driver, journal, sync, r.Committed, formatSize(int(r.Size)), r.Failed.Round(time.Microsecond),
+					recovery, r.Lost, r.Torn, r.Integrity, msg)
+			}
+		}
+	}
+	tw.Flush()
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"html"
+	"io"
+	"sort"
+)
+
+const (
+	flameWidth     = 1200.0
+	flameRowHeight = 16.0
+	flameMinWidth  = 0.5 // frames narrower than this many pixels are skipped
+)
+
+// flameNode is a frame in the merged call tree.
+type flameNode struct {
+	Name     string
+	Count    int64
+	Children map[string]*flameNode
+}
+
+func buildFlameTree(stacks []foldedStack) *flameNode {
+	root := &flameNode{Name: "all", Children: map[string]*flameNode{}}
+	for _, s := range stacks {
+		root.Count += s.Count
+		node := root
+		for _, frame := range s.Frames {
+			child, ok := node.Children[frame]
+			if !ok {
+				child = &flameNode{Name: frame, Children: map[string]*flameNode{}}
+				node.Children[frame] = child
+			}
+			child.Count += s.Count
+			node = child
+		}
+	}
+	return root
+}
+
+func (n *flameNode) depth() int {
+	d := 0
+	for _, c := range n.Children {
+		d = max(d, c.depth())
+	}
+	return d + 1
+}
+
+// writeFlamegraph renders the stacks as a static SVG flame graph with the
+// root at the bottom and children sorted by name, like flamegraph.pl.
+func writeFlamegraph(w io.Writer, title string, stacks []foldedStack) {
+	root := buildFlameTree(stacks)
+	depth := root.depth()
+	height := float64(depth+2) * flameRowHeight
+
+	fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" width="%.0f" height="%.0f" font-family="monospace" font-size="11">`+"\n", flameWidth, height)
+	fmt.Fprintf(w, `<text x="%.0f" y="12" text-anchor="middle" font-size="13">%s</text>`+"\n", flameWidth/2, html.EscapeString(title))
+
+	if root.Count > 0 {
+		scale := flameWidth / float64(root.Count)
+		var draw func(n *flameNode, x float64, level int)
+		draw = func(n *flameNode, x float64, level int) {
+			width := float64(n.Count) * scale
+			if width < flameMinWidth {
+				return
+			}
+			y := height - float64(level+1)*flameRowHeight
+
+			fmt.Fprintf(w, `<g><title>%s (%d samples, %.2f%%)</title>`, html.EscapeString(n.Name), n.Count, float64(n.Count)/float64(root.Count)*100)
+			fmt.Fprintf(w, `<rect x="%.2f" y="%.2f" width="%.2f" height="%.2f" fill="%s" stroke="white" stroke-width="0.5"/>`, x, y, width, flameRowHeight-1, flameColor(n.Name))
+			if label := fitLabel(n.Name, width); label != "" {
+				fmt.Fprintf(w, `<text x="%.2f" y="%.2f">%s</text>`, x+3, y+flameRowHeight-4, html.EscapeString(label))
+			}
+			fmt.Fprintln(w, "</g>")
+
+			names := make([]string, 0, len(n.Children))
+			for name := range n.Children {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			for _, name := range names {
+				child := n.Children[name]
+				draw(child, x, level+1)
+				x += float64(child.Count) * scale
+			}
+		}
+		draw(root, 0, 0)
+	}
+
+	fmt.Fprintln(w, "</svg>")
+}
+
+// fitLabel truncates a frame name to the space available (about 7px per
+// character), or returns "" if not even a few characters fit.
+func fitLabel(name string, width float64) string {
+	chars := int((width - 6) / 7)
+	switch {
+	case chars < 3:
+		return ""
+	case len(name) <= chars:
+		return name
+	default:
+		return name[:chars-2] + ".."
+	}
+}
+
+// flameColor picks a stable warm color per function name.
+func flameColor(name string) string {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	v := h.Sum32()
+	return fmt.Sprintf("rgb(%d,%d,%d)", 205+v%50, 80+(v>>8)%130, 30+(v>>16)%40)
+}
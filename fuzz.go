@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"sqlite_benchmark/sqlitebench"
+)
+
+// fuzzTally is what the fuzz subcommand found for one driver.
+type fuzzTally struct {
+	rounds, statements, errors, panics, crashes int
+}
+
+// runFuzz implements the fuzz subcommand: rounds of random statements,
+// each from its own seed, run on every driver in a child process, a
+// re-exec of this binary, until -duration passes. Panics, crashes of the
+// child and statements whose outcomes differ between drivers are printed
+// with the seed that reproduces them. The same subcommand with -worker
+// runs one driver's round.
+func runFuzz(args []string) {
+	fs := flag.NewFlagSet("fuzz", flag.ExitOnError)
+	drivers := fs.String("drivers", strings.Join(defaultConfig().Drivers, ","), "comma-separated drivers to fuzz")
+	duration := fs.Duration("duration", time.Minute, "how long to run rounds for")
+	rounds := fs.Int("rounds", 0, "stop after this many rounds (0 runs until -duration)")
+	statements := fs.Int("statements", 200, "statements per round")
+	seed := fs.Int64("seed", 1, "seed of the first round; the next rounds count up from it")
+	worker := fs.String("worker", "", "run one round on this driver (used internally)")
+	fs.Parse(args)
+
+	if *worker != "" {
+		runFuzzWorker(*worker, *seed, *statements)
+		return
+	}
+	if *statements <= 0 || *duration <= 0 || *rounds < 0 {
+		fatal("Invalid flags: -statements and -duration must be positive and -rounds not negative")
+	}
+
+	ctx, cancel := interruptContext()
+	defer cancel()
+	ctx, stop := context.WithTimeout(ctx, *duration)
+	defer stop()
+
+	names := strings.Split(*drivers, ",")
+	tallies := make(map[string]*fuzzTally)
+	for _, driver := range names {
+		tallies[driver] = &fuzzTally{}
+	}
+	differing := 0
+	for round := 0; ctx.Err() == nil && (*rounds == 0 || round < *rounds); round++ {
+		s := *seed + int64(round)
+		var runs []sqlitebench.FuzzRun
+		var findings []string
+		for _, driver := range names {
+			run, crash, err := fuzzRound(ctx, driver, s, *statements)
+			if ctx.Err() != nil {
+				break
+			}
+			if err != nil {
+				fatal("Fuzz round failed", "driver", driver, "seed", s, "err", err)
+			}
+			t := tallies[driver]
+			t.rounds++
+			if crash != "" {
+				t.crashes++
+				findings = append(findings, fmt.Sprintf("%s crashed: %s", driver, crash))
+				continue
+			}
+			t.statements += len(run.Keys)
+			t.errors += run.Errors()
+			if run.Panic != "" {
+				t.panics++
+				findings = append(findings, fmt.Sprintf("%s panicked after %d statements: %s", driver, len(run.Keys), run.Panic))
+			}
+			runs = append(runs, run)
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		diffs := sqlitebench.FuzzDifferences(s, *statements, runs)
+		differing += len(diffs)
+		if len(findings) > 0 || len(diffs) > 0 {
+			fmt.Printf("Seed %d (rerun with -seed %d -rounds 1):\n", s, s)
+			for _, f := range findings {
+				fmt.Println("  " + f)
+			}
+			printDifferences(os.Stdout, diffs)
+			fmt.Println()
+		}
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Driver\tRounds\tStatements\tErrors\tPanics\tCrashes")
+	for _, driver := range names {
+		t := tallies[driver]
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%d\n", driver, t.rounds, t.statements, t.errors, t.panics, t.crashes)
+	}
+	tw.Flush()
+	fmt.Printf("Statements with differing outcomes: %d\n", differing)
+}
+
+// fuzzRound runs a round on driver in a child process. crash describes how
+// the child died if it did without a result.
+func fuzzRound(ctx context.Context, driver string, seed int64, statements int) (run sqlitebench.FuzzRun, crash string, err error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return run, "", err
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, exe, "fuzz", "-worker", driver, "-seed", strconv.FormatInt(seed, 10), "-statements", strconv.Itoa(statements))
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return run, "", ctx.Err()
+		}
+		// The last lines of stderr usually name the fault.
+		lines := strings.Split(strings.TrimSpace(stderr.String()), "\n")
+		return run, fmt.Sprintf("%v: %s", err, strings.Join(lines[max(len(lines)-5, 0):], "\n")), nil
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &run); err != nil {
+		return run, "", fmt.Errorf("reading result: %w", err)
+	}
+	return run, "", nil
+}
+
+// runFuzzWorker is the body of a child process: it runs the round and
+// writes the run as JSON to stdout.
+func runFuzzWorker(driver string, seed int64, statements int) {
+	run, err := sqlitebench.Fuzz(context.Background(), driver, seed, statements)
+	if err != nil {
+		fatal("Fuzz worker failed", "driver", driver, "err", err)
+	}
+	if err := json.NewEncoder(os.Stdout).Encode(run); err != nil {
+		fatal("Failed to write fuzz result", "err", err)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+// regression is a benchmark whose per-op time grew beyond the threshold.
+type regression struct {
+	Old, New BenchmarkResult
+	Change   float64
+}
+
+// loadBaseline resolves a -baseline value: either a results file or
+// "latest" for the most recent run in the results store.
+func loadBaseline(spec string) (map[resultKey]BenchmarkResult, error) {
+	if spec != "latest" {
+		return indexResults(loadResults(spec).Results), nil
+	}
+
+	db, err := openResultsStore(*resultsDBPath)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	runID, err := latestRunID(db)
+	if err != nil {
+		return nil, err
+	}
+	if runID == 0 {
+		return nil, fmt.Errorf("no runs to use as baseline in %s", *resultsDBPath)
+	}
+	results, err := loadRun(db, runID)
+	if err != nil {
+		return nil, err
+	}
+	return indexResults(results), nil
+}
+
+// findRegressions returns the results that are more than threshold percent
+// slower per op than their baseline. Cells missing from the baseline are
+// ignored.
+func findRegressions(baseline map[resultKey]BenchmarkResult, results []BenchmarkResult, threshold float64) []regression {
+	var regressions []regression
+	for _, r := range results {
+		old, ok := baseline[keyOf(r)]
+		if !ok {
+			continue
+		}
+		if change := percentChange(old, r); change > threshold {
+			regressions = append(regressions, regression{old, r, change})
+		}
+	}
+	return regressions
+}
+
+// reportRegressions prints the regressions and returns the exit code the
+// gate should use: 1 if there are any, 0 otherwise.
+func reportRegressions(w io.Writer, regressions []regression, threshold float64) int {
+	if len(regressions) == 0 {
+		fmt.Fprintf(w, "No regressions beyond %.1f%%\n", threshold)
+		return 0
+	}
+
+	fmt.Fprintf(w, "%d benchmark(s) regressed beyond %.1f%%:\n", len(regressions), threshold)
+	for _, reg := range regressions {
+		fmt.Fprintf(w, "  %s: %v/op -> %v/op (%+.2f%%)\n", benchstatName(reg.New), perOp(reg.Old), perOp(reg.New), reg.Change)
+	}
+	return 1
+}
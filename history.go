@@ -0,0 +1,202 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/plotutil"
+	"gonum.org/v1/plot/vg"
+)
+
+// historyPoint is the per-op time of one benchmark cell in one stored run.
+type historyPoint struct {
+	RunID int64
+	Time  time.Time
+	Tags  tagSet
+	Key   resultKey
+	PerOp time.Duration
+}
+
+// loadHistory returns every stored result, in the order the runs were
+// stored.
+func loadHistory(db *sql.DB) ([]historyPoint, error) {
+	rows, err := db.Query(`
+		SELECT runs.id, runs.started_at, results.driver, results.operation, results.data_size,
+		       results.duration_ns / max(results.iterations, 1)
+		FROM results JOIN runs ON runs.id = results.run_id
+		ORDER BY runs.id, results.id`)
+	if err != nil {
+		return nil, fmt.Errorf("querying history: %w", err)
+	}
+	defer rows.Close()
+
+	tags, err := loadRunTags(db)
+	if err != nil {
+		return nil, err
+	}
+	var points []historyPoint
+	for rows.Next() {
+		var p historyPoint
+		var startedAt string
+		var perOpNs int64
+		if err := rows.Scan(&p.RunID, &startedAt, &p.Key.Driver, &p.Key.Operation, &p.Key.DataSize, &perOpNs); err != nil {
+			return nil, fmt.Errorf("reading history: %w", err)
+		}
+		p.Time, err = time.Parse(timeFormat, startedAt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timestamp of run %d: %w", p.RunID, err)
+		}
+		p.PerOp = time.Duration(perOpNs)
+		p.Tags = tags[p.RunID]
+		points = append(points, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("reading history: %w", err)
+	}
+
+	return points, nil
+}
+
+// filterHistory keeps the points of runs whose tags match filter.
+func filterHistory(points []historyPoint, filter tagSet) []historyPoint {
+	var kept []historyPoint
+	for _, p := range points {
+		if p.Tags.match(filter) {
+			kept = append(kept, p)
+		}
+	}
+	return kept
+}
+
+// groupHistory splits points into one series per benchmark cell, keeping
+// the order in which cells first appear.
+func groupHistory(points []historyPoint) ([]resultKey, map[resultKey][]historyPoint) {
+	var keys []resultKey
+	series := map[resultKey][]historyPoint{}
+	for _, p := range points {
+		if _, ok := series[p.Key]; !ok {
+			keys = append(keys, p.Key)
+		}
+		series[p.Key] = append(series[p.Key], p)
+	}
+	return keys, series
+}
+
+// runHistory implements the history subcommand: a per-benchmark summary of
+// all stored runs and, optionally, trend charts.
+func runHistory(args []string) {
+	fs := flag.NewFlagSet("history", flag.ExitOnError)
+	dbPath := fs.String("db", "results.db", "results store to read")
+	chartDir := fs.String("chart", "", "directory to write trend charts to (none if empty)")
+	imageType := fs.String("type", "svg", "chart image type (svg, png)")
+	filter := tagSet{}
+	addTagFlags(fs, filter, "only include runs with this")
+	fs.Parse(args)
+
+	db, err := openResultsStore(*dbPath)
+	if err != nil {
+		fatal("Failed to open results store", "err", err)
+	}
+	defer db.Close()
+
+	points, err := loadHistory(db)
+	if err != nil {
+		fatal("Failed to load history", "err", err)
+	}
+	keys, series := groupHistory(filterHistory(points, filter))
+	printHistory(os.Stdout, keys, series)
+
+	if *chartDir != "" {
+		if err := os.MkdirAll(*chartDir, 0o755); err != nil {
+			fatal("Failed to create chart directory", "err", err)
+		}
+		for _, op := range historyOperations(keys) {
+			path := filepath.Join(*chartDir, fmt.Sprintf("%s_trend.%s", op, *imageType))
+			saveTrendChart(op, keys, series, path)
+			slog.Info("Wrote chart", "path", path)
+		}
+	}
+}
+
+func printHistory(w io.Writer, keys []resultKey, series map[resultKey][]historyPoint) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Benchmark\truns\tfirst\tlatest\tchange")
+
+	for _, key := range keys {
+		s := series[key]
+		first, latest := s[0], s[len(s)-1]
+
+		change := "~"
+		if first.PerOp > 0 && len(s) > 1 {
+			change = fmt.Sprintf("%+.2f%%", (float64(latest.PerOp)-float64(first.PerOp))/float64(first.PerOp)*100)
+		}
+
+		name := benchstatName(BenchmarkResult{Driver: key.Driver, Operation: key.Operation, DataSize: key.DataSize})
+		fmt.Fprintf(tw, "%s\t%d\t%v\t%v\t%s\n", name, len(s), first.PerOp, latest.PerOp, change)
+	}
+
+	tw.Flush()
+}
+
+func historyOperations(keys []resultKey) []string {
+	var ops []string
+	seen := map[string]bool{}
+	for _, k := range keys {
+		if !seen[k.Operation] {
+			seen[k.Operation] = true
+			ops = append(ops, k.Operation)
+		}
+	}
+	return ops
+}
+
+// saveTrendChart plots time per op against run time, one line per
+// driver and data size.
+func saveTrendChart(op string, keys []resultKey, series map[resultKey][]historyPoint, path string) {
+	var opKeys []resultKey
+	for _, key := range keys {
+		if key.Operation == op {
+			opKeys = append(opKeys, key)
+		}
+	}
+
+	p := newTrendPlot(fmt.Sprintf("%s: time per operation over time", op), opKeys, series)
+	if err := p.Save(10*vg.Inch, 6*vg.Inch, path); err != nil {
+		fatal("Failed to save chart", "err", err)
+	}
+}
+
+// newTrendPlot builds a time-per-op over run time plot with one line per key.
+func newTrendPlot(title string, keys []resultKey, series map[resultKey][]historyPoint) *plot.Plot {
+	p := plot.New()
+	p.Title.Text = title
+	p.X.Tick.Marker = plot.TimeTicks{Format: "2006-01-02"}
+	p.Y.Label.Text = "µs/op"
+	p.Y.Scale = plot.LogScale{}
+	p.Y.Tick.Marker = plot.LogTicks{Prec: -1}
+	p.Legend.Top = true
+	p.Legend.Left = true
+
+	var lines []interface{}
+	for _, key := range keys {
+		pts := make(plotter.XYs, len(series[key]))
+		for i, point := range series[key] {
+			pts[i] = plotter.XY{X: float64(point.Time.Unix()), Y: float64(point.PerOp.Nanoseconds()) / 1e3}
+		}
+		lines = append(lines, fmt.Sprintf("%s %s %s", key.Driver, key.Operation, formatSize(key.DataSize)), pts)
+	}
+
+	if err := plotutil.AddLinePoints(p, lines...); err != nil {
+		fatal("Failed to build trend chart", "err", err)
+	}
+	return p
+}
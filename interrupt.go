@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"sqlite_benchmark/sqlitebench"
+)
+
+// interruptContext returns a context that is cancelled by the first SIGINT
+// or SIGTERM, so the run can abandon the current cell and keep what it has
+// measured. A second signal aborts a run that does not stop, removing the
+// temporary directories of the cells still open.
+func interruptContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case s := <-sig:
+			slog.Warn("Interrupted; saving the results measured so far (interrupt again to abort)", "signal", s.String())
+			cancel()
+		case <-ctx.Done():
+			signal.Stop(sig)
+			return
+		}
+		s := <-sig
+		slog.Error("Aborted", "signal", s.String())
+		sqlitebench.RemoveTempDirs()
+		os.Exit(1)
+	}()
+
+	return ctx, cancel
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"sqlite_benchmark/sqlitebench"
+)
+
+var (
+	verbose   = flag.Bool("v", false, "verbose output: log debug messages")
+	quiet     = flag.Bool("q", false, "quiet output: log only warnings and errors, no progress")
+	logFormat = flag.String("log-format", "text", "log format on stderr: text or json (json also emits progress events)")
+)
+
+// setupLogging installs the default slog logger on stderr according to
+// -v, -q and -log-format.
+func setupLogging() error {
+	level := slog.LevelInfo
+	switch {
+	case *verbose && *quiet:
+		return fmt.Errorf("-v and -q are mutually exclusive")
+	case *verbose:
+		level = slog.LevelDebug
+	case *quiet:
+		level = slog.LevelWarn
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+	switch *logFormat {
+	case "text":
+		slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, opts)))
+	case "json":
+		slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stderr, opts)))
+	default:
+		return fmt.Errorf("unknown -log-format %q", *logFormat)
+	}
+	return nil
+}
+
+// fatal logs msg at error level with the given attributes and exits.
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	sqlitebench.RemoveTempDirs()
+	os.Exit(1)
+}
+
+// eventLog is an observer that reports progress as structured log
+// records, for tools wrapping the benchmark that want to parse it.
+type eventLog struct {
+	level slog.Level
+	total int
+	index int
+}
+
+func (e *eventLog) CellStarted(_ int, c sqlitebench.Cell) {
+	e.index++
+	slog.Log(context.Background(), e.level, "cell started",
+		"driver", c.Driver, "workload", c.Workload, "size", c.DataSize,
+		"index", e.index, "total", e.total)
+}
+
+func (e *eventLog) CellDone(_ int, c sqlitebench.Cell, r BenchmarkResult) {
+	slog.Log(context.Background(), e.level, "cell done",
+		"driver", c.Driver, "workload", c.Workload, "size", c.DataSize,
+		"index", e.index, "total", e.total,
+		"ns_per_op", perOp(r).Nanoseconds())
+}
+
+func (e *eventLog) CellFailed(_ int, c sqlitebench.Cell, err error) {
+	slog.Error("cell failed",
+		"driver", c.Driver, "workload", c.Workload, "size", c.DataSize,
+		"index", e.index, "total", e.total,
+		"err", err)
+}
@@ -0,0 +1,482 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"sqlite_benchmark/sqlitebench"
+)
+
+// commands maps subcommand names to their implementations. Each receives
+// the arguments following the subcommand name.
+var commands = map[string]func(args []string){
+	"agent":    runAgent,
+	"chart":    runChart,
+	"clean":    runClean,
+	"compare":  runCompare,
+	"crash":    runCrash,
+	"diskfull": runDiskFull,
+	"fuzz":     runFuzz,
+	"history":  runHistory,
+	"list":     runList,
+	"portable": runPortable,
+	"procs":    runProcs,
+	"remote":   runRemote,
+	"replay":   runReplay,
+	"report":   runReport,
+	"run":      runRun,
+	"serve":    runServe,
+}
+
+func main() {
+	if err := setupLogging(); err != nil {
+		fatal("Invalid logging flags", "err", err)
+	}
+
+	args := os.Args[1:]
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		// No subcommand: flags apply to run, as before subcommands existed.
+		if len(args) > 0 && (args[0] == "-h" || args[0] == "-help" || args[0] == "--help") {
+			usage()
+			os.Exit(0)
+		}
+		runRun(args)
+		return
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", args[0])
+		usage()
+		os.Exit(2)
+	}
+	cmd(args[1:])
+}
+
+func usage() {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(os.Stderr, "usage: %s <command> [flags]\n\ncommands:\n", os.Args[0])
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %s\n", name)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun '%s <command> -h' for the flags of a command. Without a command, run is assumed.\n", os.Args[0])
+}
+
+// runRun implements the run subcommand: benchmark every driver, print a
+// comparison, save the results and optionally gate on regressions. Its
+// flags are the package-level flags on flag.CommandLine.
+func runRun(args []string) {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s run [flags]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.CommandLine.Parse(args)
+	if err := setupLogging(); err != nil {
+		fatal("Invalid logging flags", "err", err)
+	}
+	if *inContainer && os.Getenv(containerEnv) == "" {
+		runInContainer(args)
+		return
+	}
+
+	cfg := defaultConfig()
+	if *configPath != "" {
+		var err error
+		if cfg, err = loadConfig(*configPath); err != nil {
+			fatal("Invalid config", "err", err)
+		}
+	}
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "format":
+			cfg.Formats = strings.Split(*outputFormat, ",")
+		case "filter":
+			cfg.Filter = *benchFilter
+		case "kv":
+			cfg.KVStores = strings.Split(*kvFlag, ",")
+		case "olap":
+			cfg.OLAP = strings.Split(*olapFlag, ",")
+		case "postgres", "mysql", "libsql", "libsql-replica":
+			if cfg.Servers == nil {
+				cfg.Servers = map[string]string{}
+			}
+			cfg.Servers[f.Name] = f.Value.String()
+		case "sqlite-version":
+			cfg.SQLiteVersions = sqliteVersionFlag
+		case "replication":
+			cfg.Replication = strings.Split(*replicationFlag, ",")
+		case "category":
+			cfg.Categories = strings.Split(*categoryFlag, ",")
+		case "ops":
+			cfg.Ops = *opsFlag
+		case "duration":
+			cfg.Duration = *durationFlag
+		case "timeout":
+			cfg.Timeout = *timeoutFlag
+		case "verify":
+			cfg.Verify = *verifyFlag
+		case "io":
+			cfg.IOCounters = *ioFlag
+		case "busy-retries":
+			cfg.BusyRetries = *busyRetriesFlag
+		case "diff":
+			cfg.Differential = *diffFlag
+		case "repeat":
+			cfg.Repeat = *repeatFlag
+		case "shuffle":
+			cfg.Shuffle = *shuffleFlag
+		case "rows":
+			cfg.Rows = *rowsFlag
+		case "seed":
+			cfg.Seed = *seedFlag
+		case "compressibility":
+			cfg.Compressibility = *compressibilityFlag
+		case "scenario":
+			cfg.Workloads = nil
+			cfg.Scenarios = strings.Split(*scenarioFlag, ",")
+		case "sizes":
+			sizes, err := flagSizes()
+			if err != nil {
+				fatal("Invalid -sizes", "err", err)
+			}
+			cfg.Sizes = sizes
+		}
+	})
+	if err := cfg.validate(); err != nil {
+		fatal("Invalid config", "err", err)
+	}
+	if len(cfg.Sizes) > 0 {
+		if largest := slices.Max(cfg.Sizes); int64(largest)*int64(cfg.Rows) > 1<<30 {
+			slog.Warn("Read workloads will hold a large table in memory",
+				"bytes", formatSize(largest*cfg.Rows), "rows", cfg.Rows, "size", formatSize(largest))
+		}
+	}
+
+	driverInfo := map[string]sqlitebench.DriverInfo{}
+	var differences []sqlitebench.Difference
+	if !*dryRun {
+		cfg.Drivers = slices.DeleteFunc(cfg.Drivers, func(name string) bool {
+			info, err := sqlitebench.CheckDriver(context.Background(), name)
+			if err != nil {
+				slog.Error("Driver failed its self-check; not benchmarking it", "driver", name, "err", err)
+				return true
+			}
+			driverInfo[name] = info
+			return false
+		})
+		if len(cfg.Drivers) == 0 {
+			fatal("No driver passed its self-check")
+		}
+		for _, name := range cfg.Drivers {
+			if err := checkSQLiteVersion(name, driverInfo[name], cfg.SQLiteVersions[name]); err != nil {
+				fatal("Driver runs another SQLite version than pinned", "driver", name, "err", err)
+			}
+		}
+		if cfg.Differential > 0 && len(cfg.Drivers) > 1 {
+			var err error
+			differences, err = sqlitebench.DiffDrivers(context.Background(), cfg.Drivers, cfg.Seed, cfg.Differential)
+			if err != nil {
+				fatal("Failed to run the differential check", "err", err)
+			}
+			if len(differences) > 0 {
+				slog.Warn("Drivers returned different results", "statements", len(differences), "of", cfg.Differential)
+			}
+		}
+		for kind, dsn := range cfg.Servers {
+			if err := checkServer(kind, dsn); err != nil {
+				slog.Error("Server is unreachable; not benchmarking it", "server", kind, "err", err)
+				delete(cfg.Servers, kind)
+			}
+		}
+	}
+
+	if *dryRun {
+		printPlan(os.Stdout, cfg)
+		return
+	}
+
+	var baseline map[resultKey]BenchmarkResult
+	if *baselinePath != "" {
+		var err error
+		if baseline, err = loadBaseline(*baselinePath); err != nil {
+			fatal("Failed to load baseline", "err", err)
+		}
+	}
+
+	runner := cfg.runner()
+	runner.Capabilities = map[string][]string{}
+	runner.SQLiteVersions = map[string]string{}
+	for name, info := range driverInfo {
+		runner.Capabilities[name] = info.Capabilities
+		runner.SQLiteVersions[name] = info.SQLiteVersion
+	}
+	var obs observers
+	var cp *checkpoint
+	remaining := len(runner.Schedule())
+	if skipped := cfg.cells() - remaining; skipped > 0 {
+		slog.Warn("Skipping cells whose driver lacks a capability their workload requires", "cells", skipped)
+	}
+	if *resultsDBPath != "" {
+		store, err := openResultsStore(*resultsDBPath)
+		if err != nil {
+			fatal("Failed to open results store", "err", err)
+		}
+		defer store.Close()
+		if cp, err = openCheckpoint(store, cfg, *resume); err != nil {
+			fatal("Failed to open checkpoint", "err", err)
+		}
+		runner.Done = cp.done
+		remaining -= len(cp.done)
+		obs = append(obs, cp)
+	} else if *resume {
+		fatal("-resume needs a results store; set -db")
+	}
+
+	meta := collectMetadata()
+	if *outRoot != "" {
+		outputDir = filepath.Join(*outRoot, meta.Timestamp.Format(runFolderFormat))
+		if err := os.MkdirAll(outputDir, 0o755); err != nil {
+			fatal("Failed to create output directory", "err", err)
+		}
+		if *profileDir != "" && !filepath.IsAbs(*profileDir) {
+			*profileDir = filepath.Join(outputDir, *profileDir)
+		}
+		slog.Info("Writing results", "dir", outputDir)
+	}
+	if *profileDir != "" {
+		runner.Profile = func(name string) func() {
+			p := beginProfile()
+			return func() { endProfile(p, name) }
+		}
+	}
+
+	ctx, cancel := interruptContext()
+	defer cancel()
+
+	var dash *dashboard
+	switch {
+	case *tui:
+		dash = startDashboard(remaining, runner.LiveOps, cancel)
+		obs = append(obs, dash)
+	case *logFormat == "json":
+		obs = append(obs, &eventLog{level: slog.LevelInfo, total: remaining})
+	case *verbose:
+		obs = append(obs, &eventLog{level: slog.LevelDebug, total: remaining})
+	case *quiet:
+	default:
+		obs = append(obs, newProgressBar(remaining))
+	}
+	if *metricsAddr != "" {
+		metrics := newLiveMetrics(runner)
+		if err := metrics.serve(*metricsAddr); err != nil {
+			fatal("Failed to serve metrics", "err", err)
+		}
+		defer metrics.stop()
+		obs = append(obs, metrics)
+	}
+	var tracer *otelTracer
+	if cfg.OTel != nil {
+		var err error
+		if tracer, err = startOTel(ctx, *cfg.OTel); err != nil {
+			fatal("Failed to start tracing", "err", err)
+		}
+		obs = append(obs, tracer)
+		if cfg.OTel.Statements {
+			runner.WrapConn = tracer.wrap
+		}
+	}
+	runner.Observer = obs
+	results, err := runner.Run(ctx)
+	if tracer != nil {
+		// The run may have been interrupted, but its spans are still
+		// exported.
+		if err := tracer.end(context.Background(), err); err != nil {
+			slog.Error("Failed to export traces", "err", err)
+		}
+	}
+	if dash != nil {
+		dash.stop()
+	}
+	// Cells that failed are missing from the results; the rest are still
+	// reported, but the run exits with status 1.
+	exitCode := 0
+	if err != nil && ctx.Err() == nil {
+		slog.Error("Some benchmark cells failed", "err", err)
+		exitCode = 1
+	}
+	if cp != nil && cp.err != nil {
+		slog.Error("Failed to checkpoint the run; it cannot be resumed", "err", cp.err)
+		exitCode = 1
+	}
+
+	meta.Config = &cfg
+	meta.Drivers = driverInfo
+	meta.Differences = differences
+	meta.Tags = runTags
+	meta.Interrupted = ctx.Err() != nil
+	// The comparison table is always printed, whatever else is written.
+	formats := cfg.Formats
+	if !slices.Contains(formats, "console") {
+		formats = append([]string{"console"}, formats...)
+	}
+	if err := writeReports(formats, meta, results); err != nil {
+		slog.Error("Failed to save results", "err", err)
+		exitCode = 1
+	}
+	if outputDir != "" {
+		if err := saveMetadataJSON(outputPath("benchmark_results.meta.json"), meta); err != nil {
+			slog.Error("Failed to save run metadata", "err", err)
+			exitCode = 1
+		}
+	}
+	if meta.Interrupted {
+		// A partial run is saved for inspection but kept out of the
+		// history, where it could become the baseline for later runs.
+		slog.Warn("Run interrupted; saved partial results, continue it with -resume", "cells", len(results), "total", len(runner.Cells()))
+		os.Exit(130)
+	}
+	if err := recordHistory(meta, results); err != nil {
+		fatal("Failed to store run", "err", err)
+	}
+	if cp != nil {
+		cp.remove()
+	}
+
+	if *webhookURL != "" {
+		notifyWebhook(*webhookURL, results, baseline)
+	}
+
+	if *gate {
+		exitCode = max(exitCode, reportRegressions(os.Stdout, findRegressions(baseline, results, *regressionThreshold), *regressionThreshold))
+	}
+	if exitCode != 0 {
+		os.Exit(exitCode)
+	}
+}
+
+// runReport implements the report subcommand, which renders a saved
+// results file in another output format.
+func runReport(args []string) {
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	in := fs.String("in", "benchmark_results.json", "results file (JSON or CSV) to report on")
+	format := fs.String("format", "console", "comma-separated report formats (any run -format)")
+	fs.Parse(args)
+
+	report := loadResults(*in)
+	if err := writeReports(strings.Split(*format, ","), report.Metadata, report.Results); err != nil {
+		fatal("Failed to save report", "err", err)
+	}
+}
+
+// runList implements the list subcommand: the registered drivers with the
+// SQLite version each one links, the KV stores, servers and OLAP backends,
+// the workloads and the output formats.
+func runList(args []string) {
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	fs.Parse(args)
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "drivers:")
+	for _, name := range defaultConfig().Drivers {
+		impl := "pure Go"
+		if sqlitebench.DriverCgo[name] {
+			impl = "cgo"
+		}
+		status := "unavailable: "
+		info, err := sqlitebench.CheckDriver(context.Background(), name)
+		if err != nil {
+			status += err.Error()
+		} else {
+			status = "SQLite " + info.SQLiteVersion + " " + info.SQLiteBuild
+			if len(info.Capabilities) > 0 {
+				status += " (" + strings.Join(info.Capabilities, ", ") + ")"
+			}
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\n", name, sqlitebench.Drivers[name], impl, status)
+	}
+
+	tw.Flush()
+	fmt.Printf("kv stores (with -kv):\n  %s\n", strings.Join(sqlitebench.KVStoreNames(), ", "))
+	fmt.Printf("servers (with -postgres, -mysql, -libsql or -libsql-replica):\n  %s\n", strings.Join(sqlitebench.ServerKinds(), ", "))
+	olap := "none; build with -tags duckdb for duckdb"
+	if names := sqlitebench.OLAPBackendNames(); len(names) > 0 {
+		olap = strings.Join(names, ", ")
+	}
+	fmt.Printf("olap backends (with -olap):\n  %s\n", olap)
+
+	fmt.Fprintln(tw, "workloads:")
+	for _, name := range sqlitebench.WorkloadNames() {
+		b := sqlitebench.Workloads[name]
+		requires := "-"
+		if len(b.Requires) > 0 {
+			requires = "requires " + strings.Join(b.Requires, ", ")
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\n", b.ID, b.Category, requires, b.Description)
+	}
+	tw.Flush()
+
+	formats := make([]string, 0, len(reporters))
+	for name := range reporters {
+		formats = append(formats, name)
+	}
+	sort.Strings(formats)
+	fmt.Printf("formats:\n  %s\n", strings.Join(formats, ", "))
+}
+
+// driverCapabilities self-checks drivers for Runner.Capabilities, so cells
+// whose workload requires a capability their driver lacks are skipped.
+func driverCapabilities(ctx context.Context, drivers []string) (map[string][]string, error) {
+	capabilities := map[string][]string{}
+	for _, name := range drivers {
+		info, err := sqlitebench.CheckDriver(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("driver %s failed its self-check: %w", name, err)
+		}
+		capabilities[name] = info.Capabilities
+	}
+	return capabilities, nil
+}
+
+// checkSQLiteVersion checks that a driver runs the SQLite version pinned
+// for it, if any. A pin such as "3.46" matches every 3.46.x release.
+func checkSQLiteVersion(name string, info sqlitebench.DriverInfo, pin string) error {
+	have := info.SQLiteVersion
+	if pin == "" || have == pin || strings.HasPrefix(have, pin+".") {
+		return nil
+	}
+	hint := "pin the driver module's release in go.mod"
+	if name == "mattn" {
+		hint = "build with -tags libsqlite3 to link the system SQLite"
+	}
+	return fmt.Errorf("runs SQLite %s (%s), want %s; %s", have, info.SQLiteBuild, pin, hint)
+}
+
+// checkServer connects to a server backend once, which also checks that
+// its benchmark schema can be recreated.
+func checkServer(kind, dsn string) error {
+	b, err := sqlitebench.NewServerBackend(kind, dsn)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	db, err := b.Open(ctx, "")
+	if err != nil {
+		return err
+	}
+	return db.Close()
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"sqlite_benchmark/sqlitebench"
+)
+
+var metricsAddr = flag.String("metrics", "", "serve live Prometheus metrics at /metrics on this address while benchmarks run, e.g. :9464")
+
+// liveCounts are the live counts of the cell being measured, as the
+// runner's Live methods report them.
+type liveCounts interface {
+	LiveOps() int64
+	LiveErrors() int64
+	LiveTimes() []time.Duration
+}
+
+// driverTotals are the operations and cells of one driver so far.
+type driverTotals struct {
+	ops, errors  int64
+	done, failed int
+}
+
+// liveMetrics serves the progress of a run at /metrics in the Prometheus
+// text format, for long runs to be watched, e.g. in Grafana, while they
+// last: the operations, failures and latency quantiles of the cell being
+// measured, and per driver the operations, failures and cells so far. The
+// operations are counters, so rate() gives the throughput. It observes the
+// runner for the cells.
+type liveMetrics struct {
+	live liveCounts
+
+	mu      sync.Mutex
+	current *sqlitebench.Cell
+	totals  map[string]*driverTotals
+
+	server *http.Server
+}
+
+func newLiveMetrics(live liveCounts) *liveMetrics {
+	return &liveMetrics{live: live, totals: map[string]*driverTotals{}}
+}
+
+// serve serves the metrics on addr until stop is called.
+func (m *liveMetrics) serve(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		m.write(w)
+	})
+	m.server = &http.Server{Addr: ln.Addr().String(), Handler: mux}
+	go func() {
+		if err := m.server.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("Metrics server failed", "err", err)
+		}
+	}()
+	return nil
+}
+
+func (m *liveMetrics) stop() {
+	if m.server != nil {
+		m.server.Close()
+	}
+}
+
+func (m *liveMetrics) driver(name string) *driverTotals {
+	t, ok := m.totals[name]
+	if !ok {
+		t = &driverTotals{}
+		m.totals[name] = t
+	}
+	return t
+}
+
+func (m *liveMetrics) CellStarted(seq int, c sqlitebench.Cell) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.current = &c
+	m.driver(c.Driver)
+}
+
+// finish moves the live counts of the cell that ended, which the runner
+// keeps until the next cell starts, into its driver's totals, so the
+// driver's counters never drop.
+func (m *liveMetrics) finish(c sqlitebench.Cell) *driverTotals {
+	m.current = nil
+	t := m.driver(c.Driver)
+	t.ops += m.live.LiveOps()
+	t.errors += m.live.LiveErrors()
+	return t
+}
+
+func (m *liveMetrics) CellDone(seq int, c sqlitebench.Cell, r BenchmarkResult) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.finish(c).done++
+}
+
+func (m *liveMetrics) CellFailed(seq int, c sqlitebench.Cell, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.finish(c).failed++
+}
+
+// liveQuantiles are the latency quantiles published for the current cell.
+var liveQuantiles = []float64{0.5, 0.9, 0.99}
+
+// write writes the metrics.
+func (m *liveMetrics) write(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var ops, errs int64
+	if c := m.current; c != nil {
+		ops, errs = m.live.LiveOps(), m.live.LiveErrors()
+		labels := fmt.Sprintf("driver=%q,operation=%q,data_size=\"%d\"", c.Driver, c.Operation(), c.DataSize)
+		fmt.Fprintf(w, "# HELP sqlite_bench_live_ops_total Operations of the cell being measured so far.\n")
+		fmt.Fprintf(w, "# TYPE sqlite_bench_live_ops_total counter\n")
+		fmt.Fprintf(w, "sqlite_bench_live_ops_total{%s} %d\n", labels, ops)
+		errorRatio := 0.0
+		if ops+errs > 0 {
+			errorRatio = float64(errs) / float64(ops+errs)
+		}
+		fmt.Fprintf(w, "# HELP sqlite_bench_live_error_ratio Share of the operations of the cell being measured that failed.\n")
+		fmt.Fprintf(w, "# TYPE sqlite_bench_live_error_ratio gauge\n")
+		fmt.Fprintf(w, "sqlite_bench_live_error_ratio{%s} %g\n", labels, errorRatio)
+		if times := m.live.LiveTimes(); len(times) > 0 {
+			fmt.Fprintf(w, "# HELP sqlite_bench_live_latency_seconds Latency quantiles of the latest operations of the cell being measured.\n")
+			fmt.Fprintf(w, "# TYPE sqlite_bench_live_latency_seconds gauge\n")
+			for _, q := range liveQuantiles {
+				fmt.Fprintf(w, "sqlite_bench_live_latency_seconds{%s,quantile=\"%g\"} %g\n", labels, q, sqlitebench.Percentile(times, q*100).Seconds())
+			}
+		}
+	}
+
+	drivers := make([]string, 0, len(m.totals))
+	for name := range m.totals {
+		drivers = append(drivers, name)
+	}
+	sort.Strings(drivers)
+	counters := []struct {
+		name, help string
+		value      func(driver string, t *driverTotals) string
+	}{
+		{"sqlite_bench_ops_total", "Operations measured so far.", func(driver string, t *driverTotals) string {
+			n := t.ops
+			if m.current != nil && m.current.Driver == driver {
+				n += ops
+			}
+			return fmt.Sprintf("sqlite_bench_ops_total{driver=%q} %d", driver, n)
+		}},
+		{"sqlite_bench_errors_total", "Operations failed with an expected error, such as SQLITE_BUSY, so far.", func(driver string, t *driverTotals) string {
+			n := t.errors
+			if m.current != nil && m.current.Driver == driver {
+				n += errs
+			}
+			return fmt.Sprintf("sqlite_bench_errors_total{driver=%q} %d", driver, n)
+		}},
+		{"sqlite_bench_cells_total", "Cells finished so far, by status.", func(driver string, t *driverTotals) string {
+			return fmt.Sprintf("sqlite_bench_cells_total{driver=%q,status=\"done\"} %d\nsqlite_bench_cells_total{driver=%q,status=\"failed\"} %d", driver, t.done, driver, t.failed)
+		}},
+	}
+	for _, c := range counters {
+		fmt.Fprintf(w, "# HELP %s %s\n", c.name, c.help)
+		fmt.Fprintf(w, "# TYPE %s counter\n", c.name)
+		for _, driver := range drivers {
+			fmt.Fprintln(w, c.value(driver, m.totals[driver]))
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"sqlite_benchmark/sqlitebench"
+)
+
+type fakeLive struct {
+	ops, errors int64
+	times       []time.Duration
+}
+
+func (f *fakeLive) LiveOps() int64             { return f.ops }
+func (f *fakeLive) LiveErrors() int64          { return f.errors }
+func (f *fakeLive) LiveTimes() []time.Duration { return f.times }
+
+func TestLiveMetrics(t *testing.T) {
+	live := &fakeLive{}
+	m := newLiveMetrics(live)
+	if err := m.serve("127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	defer m.stop()
+
+	m.CellStarted(0, sqlitebench.Cell{Driver: "mattn", Workload: "write", DataSize: 64})
+	live.ops = 100
+	m.CellDone(0, sqlitebench.Cell{Driver: "mattn"}, BenchmarkResult{})
+	// The runner resets the live counts before the next cell's Setup.
+	live.ops = 0
+	m.CellStarted(1, sqlitebench.Cell{Driver: "mattn", Workload: "read", DataSize: 64})
+	var sb strings.Builder
+	m.write(&sb)
+	if !strings.Contains(sb.String(), `sqlite_bench_ops_total{driver="mattn"} 100`) {
+		t.Errorf("driver's operations dropped during the next cell's setup:\n%s", sb.String())
+	}
+	live.ops, live.errors = 30, 10
+	for i := range 100 {
+		live.times = append(live.times, time.Duration(i+1)*time.Millisecond)
+	}
+
+	resp, err := http.Get("http://" + m.server.Addr + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(body)
+	for _, want := range []string{
+		`sqlite_bench_live_error_ratio{driver="mattn",operation="read",data_size="64"} 0.25`,
+		`sqlite_bench_live_latency_seconds{driver="mattn",operation="read",data_size="64",quantile="0.5"} 0.05`,
+		`sqlite_bench_live_latency_seconds{driver="mattn",operation="read",data_size="64",quantile="0.99"} 0.099`,
+		`sqlite_bench_ops_total{driver="mattn"} 130`,
+		`sqlite_bench_errors_total{driver="mattn"} 10`,
+		`sqlite_bench_cells_total{driver="mattn",status="done"} 1`,
+		`sqlite_bench_live_ops_total{driver="mattn",operation="read",data_size="64"} 30`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("metrics lack %q:\n%s", want, out)
+		}
+	}
+
+	// A failed cell's operations stay counted.
+	m.CellFailed(1, sqlitebench.Cell{Driver: "mattn"}, io.EOF)
+	sb.Reset()
+	m.write(&sb)
+	if strings.Contains(sb.String(), "sqlite_bench_live_") {
+		t.Errorf("live metrics published between cells:\n%s", sb.String())
+	}
+	for _, want := range []string{
+		`sqlite_bench_ops_total{driver="mattn"} 130`,
+		`sqlite_bench_errors_total{driver="mattn"} 10`,
+		`sqlite_bench_cells_total{driver="mattn",status="failed"} 1`,
+	} {
+		if !strings.Contains(sb.String(), want) {
+			t.Errorf("metrics after the failed cell lack %q:\n%s", want, sb.String())
+		}
+	}
+}
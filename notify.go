@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// maxNotifiedRegressions caps how many regressions a notification lists.
+const maxNotifiedRegressions = 5
+
+// notifyWebhook posts a run summary to a Slack-compatible incoming webhook
+// (a JSON body with a "text" field).
+func notifyWebhook(url string, results []BenchmarkResult, baseline map[resultKey]BenchmarkResult) {
+	body, err := json.Marshal(map[string]string{"text": runSummary(results, baseline)})
+	if err != nil {
+		fatal("Failed to encode webhook payload", "err", err)
+	}
+
+	resp, err := http.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		slog.Warn("Failed to send webhook notification", "err", err)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(resp.Body)
+		slog.Warn("Webhook failed", "status", resp.Status, "body", string(msg))
+	}
+}
+
+// runSummary describes the overall winner per operation and, when a
+// baseline is available, the largest regressions.
+func runSummary(results []BenchmarkResult, baseline map[resultKey]BenchmarkResult) string {
+	var sb strings.Builder
+	sb.WriteString("*SQLite driver benchmark finished*\n")
+
+	t := buildComparisonTable(results)
+	for _, op := range chartOperations(t) {
+		wins := map[string]int{}
+		total := 0
+		for _, w := range t.Workloads {
+			if w.Operation == op {
+				wins[t.fastest(w)]++
+				total++
+			}
+		}
+
+		winner := ""
+		for _, driver := range t.Drivers {
+			if winner == "" || wins[driver] > wins[winner] {
+				winner = driver
+			}
+		}
+		fmt.Fprintf(&sb, "• %s: %s fastest in %d/%d sizes\n", op, winner, wins[winner], total)
+	}
+
+	if baseline != nil {
+		regressions := findRegressions(baseline, results, 0)
+		sort.Slice(regressions, func(i, j int) bool { return regressions[i].Change > regressions[j].Change })
+
+		if len(regressions) == 0 {
+			sb.WriteString("No regressions against the baseline.\n")
+		} else {
+			sb.WriteString("Top regressions:\n")
+			for i, reg := range regressions {
+				if i == maxNotifiedRegressions {
+					break
+				}
+				fmt.Fprintf(&sb, "• %s: %v → %v (%+.1f%%)\n", benchstatName(reg.New), perOp(reg.Old), perOp(reg.New), reg.Change)
+			}
+		}
+	}
+
+	return sb.String()
+}
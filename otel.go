@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	"sqlite_benchmark/sqlitebench"
+)
+
+// OTelConfig exports the run as OpenTelemetry traces over OTLP/HTTP: a
+// span for the run with one for every cell run below it.
+type OTelConfig struct {
+	// Endpoint is the collector's URL, e.g. http://localhost:4318. Left
+	// out, the OTEL_EXPORTER_OTLP_ENDPOINT variables or their default
+	// apply, as do the other OTEL_ variables either way.
+	Endpoint string `yaml:"endpoint" toml:"endpoint" json:"endpoint,omitempty"`
+	// Statements adds a span for every SQL statement of the cells' timed
+	// loops and teardowns. Creating them costs time the operations are
+	// measured with, and long cells fill the exporter's queue, which then
+	// drops spans.
+	Statements bool `yaml:"statements" toml:"statements" json:"statements,omitempty"`
+}
+
+// otelTracer traces a run; it is the runner's Observer for the cell
+// spans and wraps its connections for the statement spans.
+type otelTracer struct {
+	provider *sdktrace.TracerProvider
+	tracer   trace.Tracer
+	run      trace.Span
+	// ctx carries the run's span.
+	ctx context.Context
+
+	// mu guards cells, the spans of the cells running.
+	mu    sync.Mutex
+	cells map[int]trace.Span
+	// current is the span of the cell last started, the parent of the
+	// statement spans; cells run one at a time.
+	current trace.Span
+}
+
+// startOTel starts tracing the run to the collector cfg names.
+func startOTel(ctx context.Context, cfg OTelConfig) (*otelTracer, error) {
+	var opts []otlptracehttp.Option
+	if cfg.Endpoint != "" {
+		opts = append(opts, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+	}
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	// Variables such as OTEL_SERVICE_NAME override the service name.
+	res, err := resource.New(ctx, resource.WithAttributes(attribute.String("service.name", "sqlite_benchmark")),
+		resource.WithFromEnv(), resource.WithTelemetrySDK())
+	if err != nil {
+		return nil, err
+	}
+	return newOTelTracer(ctx, sdktrace.WithBatcher(exporter), sdktrace.WithResource(res)), nil
+}
+
+// newOTelTracer starts the run's span with a provider of the options.
+func newOTelTracer(ctx context.Context, opts ...sdktrace.TracerProviderOption) *otelTracer {
+	t := &otelTracer{provider: sdktrace.NewTracerProvider(opts...), cells: map[int]trace.Span{}}
+	t.tracer = t.provider.Tracer("sqlite_benchmark")
+	t.ctx, t.run = t.tracer.Start(ctx, "benchmark run")
+	return t
+}
+
+// end ends the run's span, marking it failed if err is set, and exports
+// the spans not yet exported.
+func (t *otelTracer) end(ctx context.Context, err error) error {
+	if err != nil {
+		t.run.SetStatus(codes.Error, err.Error())
+	}
+	t.run.End()
+	return t.provider.Shutdown(ctx)
+}
+
+func (t *otelTracer) CellStarted(seq int, c sqlitebench.Cell) {
+	_, span := t.tracer.Start(t.ctx, "cell "+c.String(), trace.WithAttributes(
+		attribute.Int("sqlitebench.seq", seq),
+		attribute.String("sqlitebench.driver", c.Driver),
+		attribute.String("sqlitebench.workload", c.Workload),
+		attribute.String("sqlitebench.operation", c.Operation()),
+		attribute.Int("sqlitebench.data_size", c.DataSize),
+	))
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.cells[seq] = span
+	t.current = span
+}
+
+// cell returns and forgets the span of cell run seq.
+func (t *otelTracer) cell(seq int) trace.Span {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	span := t.cells[seq]
+	delete(t.cells, seq)
+	return span
+}
+
+func (t *otelTracer) CellDone(seq int, c sqlitebench.Cell, r BenchmarkResult) {
+	span := t.cell(seq)
+	if span == nil {
+		return
+	}
+	span.SetAttributes(
+		attribute.Int("sqlitebench.ops", iterations(r)),
+		attribute.Int64("sqlitebench.duration_ns", r.Duration.Nanoseconds()),
+		attribute.Int64("sqlitebench.setup_ns", r.Setup.Nanoseconds()),
+		attribute.Int64("sqlitebench.allocs", int64(r.Allocs)),
+		attribute.Bool("sqlitebench.timed_out", r.TimedOut),
+	)
+	if r.SQLiteVersion != "" {
+		span.SetAttributes(attribute.String("sqlitebench.sqlite_version", r.SQLiteVersion))
+	}
+	span.End()
+}
+
+func (t *otelTracer) CellFailed(seq int, c sqlitebench.Cell, err error) {
+	span := t.cell(seq)
+	if span == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	span.End()
+}
+
+// wrap wraps the connection of the cell running, for Runner.WrapConn.
+func (t *otelTracer) wrap(c sqlitebench.Cell, conn sqlitebench.Conn) sqlitebench.Conn {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return &tracedConn{Conn: conn, t: t, cell: t.current}
+}
+
+// tracedConn adds a span below its cell's for every statement run on the
+// connection it wraps.
+type tracedConn struct {
+	sqlitebench.Conn
+	t    *otelTracer
+	cell trace.Span
+}
+
+func (c *tracedConn) Unwrap() sqlitebench.Conn { return c.Conn }
+
+// start starts the span of query, named after its first keyword, e.g.
+// "SELECT", as the database semantic conventions have it.
+func (c *tracedConn) start(ctx context.Context, query string) trace.Span {
+	name, _, _ := strings.Cut(strings.TrimSpace(query), " ")
+	_, span := c.t.tracer.Start(trace.ContextWithSpan(ctx, c.cell), strings.ToUpper(name), trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("db.system", "sqlite"), attribute.String("db.query.text", query)))
+	return span
+}
+
+// end ends span, marking it failed if err is set, and returns err.
+func end(span trace.Span, err error) error {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+	return err
+}
+
+func (c *tracedConn) Exec(ctx context.Context, query string, args ...any) error {
+	span := c.start(ctx, query)
+	return end(span, c.Conn.Exec(ctx, query, args...))
+}
+
+// Query's span ends when the rows are closed, so it covers reading them.
+func (c *tracedConn) Query(ctx context.Context, query string, args ...any) (sqlitebench.Rows, error) {
+	span := c.start(ctx, query)
+	rows, err := c.Conn.Query(ctx, query, args...)
+	if err != nil {
+		return nil, end(span, err)
+	}
+	return &tracedRows{Rows: rows, span: span}, nil
+}
+
+func (c *tracedConn) Begin(ctx context.Context) (sqlitebench.Tx, error) {
+	span := c.start(ctx, "BEGIN")
+	tx, err := c.Conn.Begin(ctx)
+	if err := end(span, err); err != nil {
+		return nil, err
+	}
+	return &tracedTx{Tx: tx, c: c, ctx: ctx}, nil
+}
+
+type tracedRows struct {
+	sqlitebench.Rows
+	span trace.Span
+	once sync.Once
+}
+
+func (r *tracedRows) Close() error {
+	err := r.Rows.Close()
+	r.once.Do(func() {
+		if err == nil {
+			err = r.Rows.Err()
+		}
+		end(r.span, err)
+	})
+	return err
+}
+
+// tracedTx traces the statements of a transaction, with the context it
+// was begun with for its commit or rollback.
+type tracedTx struct {
+	sqlitebench.Tx
+	c   *tracedConn
+	ctx context.Context
+}
+
+func (tx *tracedTx) Exec(ctx context.Context, query string, args ...any) error {
+	span := tx.c.start(ctx, query)
+	return end(span, tx.Tx.Exec(ctx, query, args...))
+}
+
+func (tx *tracedTx) Commit() error {
+	span := tx.c.start(tx.ctx, "COMMIT")
+	return end(span, tx.Tx.Commit())
+}
+
+func (tx *tracedTx) Rollback() error {
+	span := tx.c.start(tx.ctx, "ROLLBACK")
+	return end(span, tx.Tx.Rollback())
+}
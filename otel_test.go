@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"sqlite_benchmark/sqlitebench"
+)
+
+func TestOTelTracer(t *testing.T) {
+	ctx := context.Background()
+	recorder := tracetest.NewSpanRecorder()
+	tracer := newOTelTracer(ctx, sdktrace.WithSpanProcessor(recorder))
+
+	runner := sqlitebench.NewRunner()
+	runner.Drivers, runner.Sizes, runner.Ops = []string{"modernc"}, []int{64}, 3
+	runner.Add(sqlitebench.Workloads["write"].New())
+	runner.Observer = tracer
+	runner.WrapConn = tracer.wrap
+	if _, err := runner.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := tracer.end(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := recorder.Ended()
+	byName := map[string]sdktrace.ReadOnlySpan{}
+	statements := 0
+	for _, s := range spans {
+		byName[s.Name()] = s
+		if s.Name() == "INSERT" {
+			statements++
+		}
+	}
+	run, ok := byName["benchmark run"]
+	if !ok {
+		t.Fatalf("no run span among %d spans", len(spans))
+	}
+	cell, ok := byName["cell modernc/write/64"]
+	if !ok {
+		t.Fatalf("no cell span among %d spans", len(spans))
+	}
+	if cell.Parent().SpanID() != run.SpanContext().SpanID() {
+		t.Error("cell span is not below the run's")
+	}
+	attrs := map[string]any{}
+	for _, a := range cell.Attributes() {
+		attrs[string(a.Key)] = a.Value.AsInterface()
+	}
+	if attrs["sqlitebench.driver"] != "modernc" || attrs["sqlitebench.workload"] != "write" || attrs["sqlitebench.data_size"] != int64(64) || attrs["sqlitebench.ops"] != int64(3) {
+		t.Errorf("cell span attributes %v", attrs)
+	}
+
+	// The runner's warm-up operations run before the timed ones, so
+	// there are at least three inserts, all below the cell.
+	if statements < 3 {
+		t.Errorf("%d INSERT spans, want one per operation", statements)
+	}
+	if insert := byName["INSERT"]; insert.Parent().SpanID() != cell.SpanContext().SpanID() {
+		t.Error("statement span is not below the cell's")
+	}
+}
@@ -0,0 +1,6 @@
+package main
+
+import "os"
+
+// perfEnabled turns on hardware counters (SQLITE_BENCH_PERF=1).
+var perfEnabled = os.Getenv("SQLITE_BENCH_PERF") != ""
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"cmp"
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"sqlite_benchmark/sqlitebench"
+)
+
+var dryRun = flag.Bool("dry-run", false, "print the benchmark matrix without running it")
+
+// populatedWorkloads insert Config.Rows payloads before they are measured.
+var populatedWorkloads = []string{"read", "read.hot", "kv.get", "kv.delete", "kv.mix", "serialize", "deserialize", "logs", "sessions", "cache", "ecommerce", "sync", "rtree.query", "graph.khop", "graph.path", "vector.query"}
+
+// printPlan writes every cell run the config would make, in order, with
+// the work each one does, followed by totals. It opens no databases.
+func printPlan(w io.Writer, cfg Config) {
+	measured := fmt.Sprintf("%d ops", cfg.Ops)
+	if cfg.Duration > 0 {
+		measured = cfg.Duration.String()
+	}
+
+	fmt.Fprintf(w, "drivers:   %s\n", strings.Join(cfg.Drivers, ", "))
+	if workloads := cfg.workloads(); len(workloads) > 0 {
+		fmt.Fprintf(w, "workloads: %s\n", strings.Join(workloads, ", "))
+	}
+	if len(cfg.KVStores) > 0 {
+		fmt.Fprintf(w, "kv stores: %s\n", strings.Join(cfg.KVStores, ", "))
+	}
+	if len(cfg.Servers) > 0 {
+		var kinds []string
+		for kind := range cfg.Servers {
+			kinds = append(kinds, kind)
+		}
+		slices.Sort(kinds)
+		fmt.Fprintf(w, "servers:   %s\n", strings.Join(kinds, ", "))
+	}
+	if len(cfg.OLAP) > 0 {
+		fmt.Fprintf(w, "olap:      %s\n", strings.Join(cfg.OLAP, ", "))
+	}
+	if len(cfg.Categories) > 0 {
+		fmt.Fprintf(w, "categories: %s\n", strings.Join(cfg.Categories, ", "))
+	}
+	if len(cfg.Scenarios) > 0 {
+		fmt.Fprintf(w, "scenarios: %s\n", strings.Join(cfg.Scenarios, ", "))
+	}
+	if cfg.Extension != nil {
+		fmt.Fprintf(w, "extension: %s\n", cfg.Extension.Path)
+	}
+	if cfg.Vector != nil {
+		fmt.Fprintf(w, "vector:    %s\n", cfg.Vector.Path)
+	}
+	if cfg.KVMix != nil {
+		m := sqlitebench.NewKVMix()
+		cfg.KVMix.apply(m)
+		fmt.Fprintf(w, "kv mix:    %d:%d:%d gets:puts:deletes, skew %g\n", m.Gets, m.Puts, m.Deletes, m.Skew)
+	}
+	if cfg.Cache != nil {
+		cw := sqlitebench.NewCache()
+		cfg.Cache.apply(cw)
+		fmt.Fprintf(w, "cache:     %g%% of lookups miss\n", cw.MissRatio*100)
+	}
+	if cfg.Graph != nil {
+		g := sqlitebench.NewGraph()
+		cfg.Graph.apply(g)
+		fmt.Fprintf(w, "graph:     fan-out %d, %d hops\n", g.FanOut, g.Hops)
+	}
+	if cfg.Files != nil {
+		f := sqlitebench.NewFiles()
+		cfg.Files.apply(f)
+		fmt.Fprintf(w, "files:     %s files, %s chunks\n", formatSize(f.FileSize), formatSize(f.ChunkSize))
+	}
+	if cfg.OTel != nil {
+		endpoint := cmp.Or(cfg.OTel.Endpoint, "from OTEL_EXPORTER_OTLP_ENDPOINT")
+		if cfg.OTel.Statements {
+			endpoint += ", with statements"
+		}
+		fmt.Fprintf(w, "otel:      %s\n", endpoint)
+	}
+	if cfg.FTSCorpus != "" {
+		fmt.Fprintf(w, "fts corpus: %s\n", cfg.FTSCorpus)
+	}
+	if cfg.Schema != nil {
+		fmt.Fprintf(w, "schema:    table %s from %s\n", cfg.Schema.Table, cfg.Schema.File)
+	}
+	if dims := planDimensions(cfg); dims != "" {
+		fmt.Fprintf(w, "matrix:    %s\n", dims)
+	}
+	fmt.Fprintf(w, "measure:   %s per cell, %d rows for reads, seed %d, compressibility %g\n",
+		measured, cfg.Rows, cfg.Seed, cfg.Compressibility)
+	if len(cfg.Pragmas) > 0 {
+		fmt.Fprintf(w, "pragmas:   %s\n", strings.Join(cfg.Pragmas, ", "))
+	}
+	if cfg.Filter != "" {
+		fmt.Fprintf(w, "filter:    %s\n", cfg.Filter)
+	}
+	if cfg.Repeat > 1 || cfg.Shuffle != 0 {
+		order := "fixed order"
+		if cfg.Shuffle != 0 {
+			order = fmt.Sprintf("shuffled with seed %d", cfg.Shuffle)
+		}
+		fmt.Fprintf(w, "order:     %d rounds, %s\n", cfg.Repeat, order)
+	}
+	fmt.Fprintln(w)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "#\tCell\tMeasured\tPopulated")
+	runner := cfg.runner()
+	matrix := runner.Cells()
+	var cells, ops, populated int
+	var populatedBytes int64
+	for _, i := range runner.Schedule() {
+		c := matrix[i]
+		cells++
+
+		rows := "-"
+		switch {
+		case slices.Contains(populatedWorkloads, c.Workload) || slices.Contains(analyticalWorkloads, c.Workload):
+			rows = fmt.Sprintf("%d rows (%s)", cfg.Rows, approxSize(int64(cfg.Rows)*int64(c.DataSize)))
+			populated += cfg.Rows
+			populatedBytes += int64(cfg.Rows) * int64(c.DataSize)
+		case cfg.Schema != nil && (c.Workload == cfg.Schema.Table+".read" || c.Workload == cfg.Schema.Table+".update"):
+			// Generated rows have no fixed size.
+			rows = fmt.Sprintf("%d rows", cfg.Rows)
+			populated += cfg.Rows
+		case strings.HasPrefix(c.Workload, "files."):
+			// The files are the same size whatever the payload size.
+			f := sqlitebench.NewFiles()
+			if cfg.Files != nil {
+				cfg.Files.apply(f)
+			}
+			size := int64(sqlitebench.FilesStored) * int64(f.FileSize)
+			rows = fmt.Sprintf("%d files (%s)", sqlitebench.FilesStored, approxSize(size))
+			populated += sqlitebench.FilesStored
+			populatedBytes += size
+		case strings.HasPrefix(c.Workload, "fts."):
+			rows = fmt.Sprintf("%d documents", cfg.Rows)
+			populated += cfg.Rows
+		case strings.HasPrefix(c.Workload, "star."):
+			// Facts have no payload.
+			rows = fmt.Sprintf("%d facts", cfg.Rows)
+			populated += cfg.Rows
+		case strings.HasPrefix(c.Workload, "timeseries"):
+			// Each row is a scrape of points without a payload.
+			rows = fmt.Sprintf("%d scrapes", cfg.Rows)
+			populated += cfg.Rows
+		}
+		ops += cfg.Ops
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", cells, c, measured, rows)
+	}
+	tw.Flush()
+
+	fmt.Fprintf(w, "\n%d cells", cells)
+	if cfg.Duration > 0 {
+		fmt.Fprintf(w, ", at least %s of measurement", cfg.Duration*time.Duration(cells))
+	} else {
+		fmt.Fprintf(w, ", %d measured ops", ops)
+	}
+	fmt.Fprintf(w, ", %d rows (%s) populated\n", populated, approxSize(populatedBytes))
+}
+
+// planDimensions describes the matrix dimensions beyond drivers, workloads
+// and sizes, and the rules adjusting the matrix.
+func planDimensions(cfg Config) string {
+	var dims []string
+	add := func(name string, values []string) {
+		if len(values) > 0 {
+			dims = append(dims, name+" "+strings.Join(values, "|"))
+		}
+	}
+	add("journal_mode", cfg.JournalModes)
+	add("synchronous", cfg.Synchronous)
+	add("page_size", itoas(cfg.PageSizes))
+	add("cache_size", itoas(cfg.CacheSizes))
+	add("concurrency", itoas(cfg.Concurrency))
+	add("storage", cfg.Storage)
+	add("replication", cfg.Replication)
+	if len(cfg.Exclude) > 0 {
+		dims = append(dims, fmt.Sprintf("%d exclude rules", len(cfg.Exclude)))
+	}
+	if len(cfg.Include) > 0 {
+		dims = append(dims, fmt.Sprintf("%d include rules", len(cfg.Include)))
+	}
+	return strings.Join(dims, ", ")
+}
+
+func itoas(ns []int) []string {
+	s := make([]string, len(ns))
+	for i, n := range ns {
+		s[i] = strconv.Itoa(n)
+	}
+	return s
+}
+
+// approxSize formats n bytes with one decimal in the largest binary unit,
+// for totals that formatSize would print as an unwieldy exact count.
+func approxSize(n int64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB"}
+	f := float64(n)
+	i := 0
+	for f >= 1024 && i < len(units)-1 {
+		f /= 1024
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%dB", n)
+	}
+	return fmt.Sprintf("%.1f%s", f, units[i])
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"sqlite_benchmark/sqlitebench"
+)
+
+// defaultTargets are the GOOS/GOARCH pairs the portable subcommand builds
+// for unless given others.
+var defaultTargets = []string{
+	"linux/amd64", "linux/arm64", "linux/386", "linux/riscv64",
+	"darwin/amd64", "darwin/arm64", "windows/amd64", "windows/arm64",
+	"freebsd/amd64", "js/wasm", "wasip1/wasm",
+}
+
+// portableBuild is the outcome of building one driver for one target.
+type portableBuild struct {
+	Driver string `json:"driver"`
+	Target string `json:"target"`
+	Cgo    bool   `json:"cgo"`
+	OK     bool   `json:"ok"`
+	// Error is the first line of the compiler's output for a failed
+	// build.
+	Error string `json:"error,omitempty"`
+}
+
+// portableSource is a program using one driver, whose package is
+// substituted.
+const portableSource = `package main
+
+import (
+	"database/sql"
+
+	_ %q
+)
+
+func main() { _ = sql.Drivers() }
+`
+
+// runPortable implements the portable subcommand: it cross-compiles a
+// program using each driver alone for every target and prints which build.
+// Cgo drivers are built with CGO_ENABLED=1, as without cgo some build a
+// stub failing at run time, so building them for other targets needs a C
+// cross compiler in CC. It runs in the source tree, as the programs are
+// built as part of this module.
+func runPortable(args []string) {
+	fs := flag.NewFlagSet("portable", flag.ExitOnError)
+	drivers := fs.String("drivers", strings.Join(defaultConfig().Drivers, ","), "comma-separated drivers to build")
+	targets := fs.String("targets", strings.Join(defaultTargets, ","), "comma-separated GOOS/GOARCH targets")
+	asJSON := fs.Bool("json", false, "print the builds as JSON instead of a table")
+	fs.Parse(args)
+
+	root, err := moduleRoot()
+	if err != nil {
+		fatal("The portable subcommand runs in the sqlite_benchmark source tree", "err", err)
+	}
+	ctx, cancel := interruptContext()
+	defer cancel()
+
+	var builds []portableBuild
+	for _, driver := range strings.Split(*drivers, ",") {
+		pkg, ok := sqlitebench.DriverPackages[driver]
+		if !ok {
+			fatal("Unknown driver", "driver", driver)
+		}
+		for _, target := range strings.Split(*targets, ",") {
+			slog.Info("Building", "driver", driver, "target", target)
+			b, err := buildPortable(ctx, root, pkg, target, sqlitebench.DriverCgo[driver])
+			if err != nil {
+				fatal("Failed to build", "driver", driver, "target", target, "err", err)
+			}
+			b.Driver = driver
+			builds = append(builds, b)
+		}
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(builds); err != nil {
+			fatal("Failed to write builds", "err", err)
+		}
+		return
+	}
+	printPortable(builds, strings.Split(*targets, ","))
+}
+
+// moduleRoot returns the directory of this module's go.mod.
+func moduleRoot() (string, error) {
+	out, err := exec.Command("go", "env", "GOMOD").Output()
+	if err != nil {
+		return "", err
+	}
+	gomod := strings.TrimSpace(string(out))
+	if gomod == "" || gomod == os.DevNull {
+		return "", fmt.Errorf("not in a module")
+	}
+	data, err := os.ReadFile(gomod)
+	if err != nil {
+		return "", err
+	}
+	if !bytes.HasPrefix(data, []byte("module sqlite_benchmark\n")) {
+		return "", fmt.Errorf("%s is another module's", gomod)
+	}
+	return filepath.Dir(gomod), nil
+}
+
+// buildPortable builds a program importing pkg for target, a GOOS/GOARCH
+// pair, in a temporary directory under root. It fails only if the build
+// could not be tried.
+func buildPortable(ctx context.Context, root, pkg, target string, cgo bool) (portableBuild, error) {
+	b := portableBuild{Target: target, Cgo: cgo}
+	goos, goarch, ok := strings.Cut(target, "/")
+	if !ok {
+		return b, fmt.Errorf("target %q is not GOOS/GOARCH", target)
+	}
+	// The underscore keeps the directory out of ./... patterns.
+	dir, err := os.MkdirTemp(root, "_portable-")
+	if err != nil {
+		return b, err
+	}
+	defer os.RemoveAll(dir)
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte(fmt.Sprintf(portableSource, pkg)), 0o644); err != nil {
+		return b, err
+	}
+
+	cgoEnabled := "0"
+	if cgo {
+		cgoEnabled = "1"
+	}
+	cmd := exec.CommandContext(ctx, "go", "build", "-o", filepath.Join(dir, "out"), "./"+filepath.Base(dir))
+	cmd.Dir = root
+	cmd.Env = append(os.Environ(), "GOOS="+goos, "GOARCH="+goarch, "CGO_ENABLED="+cgoEnabled)
+	out, err := cmd.CombinedOutput()
+	if ctx.Err() != nil {
+		return b, ctx.Err()
+	}
+	if err != nil {
+		b.Error = firstBuildError(string(out))
+		if b.Error == "" {
+			b.Error = err.Error()
+		}
+		return b, nil
+	}
+	b.OK = true
+	return b, nil
+}
+
+// firstBuildError returns the first error in go build's output, skipping
+// "# package" headings and the chain of imports leading to a package that
+// cannot be built.
+func firstBuildError(out string) string {
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "package ") {
+			continue
+		}
+		if rest, ok := strings.CutPrefix(line, "imports "); ok {
+			if !strings.Contains(rest, ": ") {
+				continue
+			}
+			line = rest
+		}
+		return line
+	}
+	return ""
+}
+
+// printPortable writes a table of drivers by target, followed by why the
+// failed builds failed.
+func printPortable(builds []portableBuild, targets []string) {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprint(tw, "Driver")
+	for _, target := range targets {
+		fmt.Fprint(tw, "\t"+target)
+	}
+	fmt.Fprintln(tw)
+	var failed []portableBuild
+	for i, b := range builds {
+		if i%len(targets) == 0 {
+			if i > 0 {
+				fmt.Fprintln(tw)
+			}
+			fmt.Fprint(tw, b.Driver)
+		}
+		mark := "ok"
+		if !b.OK {
+			mark = "FAIL"
+			failed = append(failed, b)
+		}
+		fmt.Fprint(tw, "\t"+mark)
+	}
+	fmt.Fprintln(tw)
+	tw.Flush()
+
+	if len(failed) > 0 {
+		fmt.Println("\nFailed builds:")
+		for _, b := range failed {
+			note := ""
+			if b.Cgo {
+				note = " (cgo; set CC to a cross compiler)"
+			}
+			fmt.Printf("  %s %s%s: %s\n", b.Driver, b.Target, note, b.Error)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"runtime"
+	"testing"
+)
+
+func TestFirstBuildError(t *testing.T) {
+	for _, tc := range []struct{ out, want string }{
+		{"# example.com/x\n./main.go:3:2: undefined: y\n", "./main.go:3:2: undefined: y"},
+		{"package x\n\timports modernc.org/libc\n\timports modernc.org/libc/errno: build constraints exclude all Go files\n",
+			"modernc.org/libc/errno: build constraints exclude all Go files"},
+	} {
+		out, want := tc.out, tc.want
+		if got := firstBuildError(out); got != want {
+			t.Errorf("firstBuildError(%q) = %q, want %q", out, got, want)
+		}
+	}
+}
+
+func TestBuildPortable(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds a program")
+	}
+	root, err := moduleRoot()
+	if err != nil {
+		t.Skip(err)
+	}
+	target := runtime.GOOS + "/" + runtime.GOARCH
+	b, err := buildPortable(context.Background(), root, "modernc.org/sqlite", target, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !b.OK {
+		t.Errorf("building modernc for %s failed: %s", target, b.Error)
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"sqlite_benchmark/sqlitebench"
+)
+
+// procsStartDelay gives every worker process time to start and open the
+// database before they all begin at the same instant.
+const procsStartDelay = 500 * time.Millisecond
+
+// runProcs implements the procs subcommand: several OS processes, re-execs
+// of this binary, work on one database file at once, once per driver and
+// journal mode. The same subcommand with -worker runs a single worker.
+func runProcs(args []string) {
+	fs := flag.NewFlagSet("procs", flag.ExitOnError)
+	procs := fs.Int("procs", 4, "number of worker processes")
+	drivers := fs.String("drivers", strings.Join(defaultConfig().Drivers, ","), "comma-separated drivers to benchmark")
+	journals := fs.String("journal", "delete,wal", "comma-separated journal modes to benchmark")
+	ops := fs.Int("ops", 1000, "operations per worker process")
+	rows := fs.Int("rows", 1000, "rows inserted before the workers start")
+	size := fs.String("size", "1K", "payload size, e.g. 64 or 4K")
+	writes := fs.Float64("writes", 0.1, "fraction of operations that insert rather than read")
+	busyTimeout := fs.Duration("busy-timeout", 5*time.Second, "how long a worker waits for another process's lock")
+	dir := fs.String("dir", "", "directory for the shared database file (default a temporary directory)")
+	seed := fs.Int64("seed", 1, "seed for payloads and the read/write mix")
+	worker := fs.Int("worker", -1, "run as worker process N (used internally)")
+	spec := fs.String("spec", "", "worker spec as JSON (used internally)")
+	start := fs.Int64("start", 0, "Unix time in nanoseconds at which workers begin (used internally)")
+	fs.Parse(args)
+
+	if *worker >= 0 {
+		runProcsWorker(*worker, *spec, time.Unix(0, *start))
+		return
+	}
+
+	dataSize, err := parseSize(*size)
+	if err != nil {
+		fatal("Invalid -size", "err", err)
+	}
+	if *procs <= 0 || *ops <= 0 || *writes < 0 || *writes > 1 {
+		fatal("Invalid flags: -procs and -ops must be positive and -writes between 0 and 1")
+	}
+
+	// The run returns its error rather than exiting, so the temporary
+	// directory is removed first.
+	run := func() error {
+		if *dir == "" {
+			tmp, remove, err := sqlitebench.MakeTempDir("", "procs-")
+			if err != nil {
+				return fmt.Errorf("creating directory: %w", err)
+			}
+			defer remove()
+			*dir = tmp
+		}
+
+		ctx, cancel := interruptContext()
+		defer cancel()
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		defer tw.Flush()
+		fmt.Fprintln(tw, "Driver\tJournal\tProcs\tOps\tWrites\tErrors\tWall\tops/s\tp50\tp99")
+		for _, driver := range strings.Split(*drivers, ",") {
+			for _, journal := range strings.Split(*journals, ",") {
+				f := &sqlitebench.SharedFile{
+					Path:        filepath.Join(*dir, "shared.db"),
+					Driver:      driver,
+					JournalMode: journal,
+					BusyTimeout: *busyTimeout,
+					Rows:        *rows,
+					DataSize:    dataSize,
+					Ops:         *ops,
+					WriteRatio:  *writes,
+					Seed:        *seed,
+				}
+				results, wall, err := runSharedFile(ctx, f, *procs)
+				if err != nil {
+					return fmt.Errorf("%s with journal mode %s: %w", driver, journal, err)
+				}
+				printProcsRow(tw, f, results, wall)
+			}
+		}
+		return nil
+	}
+	if err := run(); err != nil {
+		fatal("Multi-process run failed", "err", err)
+	}
+}
+
+// runSharedFile prepares the file and runs one worker process per proc,
+// returning their results and the wall time from the common start until
+// the last one finished.
+func runSharedFile(ctx context.Context, f *sqlitebench.SharedFile, procs int) ([]sqlitebench.WorkerResult, time.Duration, error) {
+	if err := f.Prepare(ctx); err != nil {
+		return nil, 0, fmt.Errorf("preparing %s: %w", f.Path, err)
+	}
+	spec, err := json.Marshal(f)
+	if err != nil {
+		return nil, 0, err
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	start := time.Now().Add(procsStartDelay)
+	cmds := make([]*exec.Cmd, procs)
+	outputs := make([]bytes.Buffer, procs)
+	for i := range cmds {
+		cmds[i] = exec.CommandContext(ctx, exe, "procs", "-worker", strconv.Itoa(i), "-spec", string(spec), "-start", strconv.FormatInt(start.UnixNano(), 10))
+		cmds[i].Stdout = &outputs[i]
+		cmds[i].Stderr = os.Stderr
+		if err := cmds[i].Start(); err != nil {
+			return nil, 0, fmt.Errorf("starting worker %d: %w", i, err)
+		}
+	}
+
+	var failed error
+	for i, cmd := range cmds {
+		if err := cmd.Wait(); err != nil && failed == nil {
+			failed = fmt.Errorf("worker %d: %w", i, err)
+		}
+	}
+	wall := time.Since(start)
+	if failed != nil {
+		return nil, 0, failed
+	}
+
+	results := make([]sqlitebench.WorkerResult, procs)
+	for i := range outputs {
+		if err := json.Unmarshal(outputs[i].Bytes(), &results[i]); err != nil {
+			return nil, 0, fmt.Errorf("reading result of worker %d: %w", i, err)
+		}
+	}
+	return results, wall, nil
+}
+
+// runProcsWorker is the body of a worker process: it waits for the common
+// start, works and writes its result as JSON to stdout.
+func runProcsWorker(worker int, spec string, start time.Time) {
+	var f sqlitebench.SharedFile
+	if err := json.Unmarshal([]byte(spec), &f); err != nil {
+		fatal("Invalid worker spec", "err", err)
+	}
+
+	time.Sleep(time.Until(start))
+	result, err := f.Work(context.Background(), worker)
+	if err != nil {
+		fatal("Worker failed", "worker", worker, "err", err)
+	}
+	if err := json.NewEncoder(os.Stdout).Encode(result); err != nil {
+		fatal("Failed to write worker result", "err", err)
+	}
+}
+
+// printProcsRow writes the combined results of one multi-process run.
+func printProcsRow(w io.Writer, f *sqlitebench.SharedFile, results []sqlitebench.WorkerResult, wall time.Duration) {
+	var samples []time.Duration
+	var writes, errs int
+	for _, r := range results {
+		samples = append(samples, r.Samples...)
+		writes += r.Writes
+		errs += r.Errors
+	}
+
+	fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%d\t%v\t%.0f\t%v\t%v\n",
+		f.Driver, f.JournalMode, len(results), len(samples), writes, errs,
+		wall.Round(time.Millisecond), float64(len(samples))/wall.Seconds(),
+		sqlitebench.Percentile(samples, 50), sqlitebench.Percentile(samples, 99))
+}
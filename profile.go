@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"sort"
+	"strings"
+
+	"github.com/google/pprof/profile"
+)
+
+var profileDir = flag.String("profile-dir", "", "write a CPU profile, folded stacks and flamegraph SVG per benchmark to this directory (inside the run folder with -out, if relative)")
+
+// cpuProfile is a CPU profile being captured around a timed region.
+type cpuProfile struct {
+	buf bytes.Buffer
+}
+
+// beginProfile starts CPU profiling if -profile-dir is set.
+func beginProfile() *cpuProfile {
+	if *profileDir == "" {
+		return nil
+	}
+
+	p := &cpuProfile{}
+	if err := pprof.StartCPUProfile(&p.buf); err != nil {
+		slog.Warn("Failed to start CPU profile", "err", err)
+		return nil
+	}
+	return p
+}
+
+// endProfile stops profiling and writes name.pprof, name.folded and
+// name.svg to the profile directory.
+func endProfile(p *cpuProfile, name string) {
+	if p == nil {
+		return
+	}
+	pprof.StopCPUProfile()
+
+	if err := os.MkdirAll(*profileDir, 0o755); err != nil {
+		fatal("Failed to create profile directory", "err", err)
+	}
+
+	base := filepath.Join(*profileDir, name)
+	if err := os.WriteFile(base+".pprof", p.buf.Bytes(), 0o644); err != nil {
+		fatal("Failed to write CPU profile", "err", err)
+	}
+
+	prof, err := profile.Parse(bytes.NewReader(p.buf.Bytes()))
+	if err != nil {
+		fatal("Failed to parse CPU profile", "err", err)
+	}
+	stacks := foldStacks(prof)
+
+	folded, err := os.Create(base + ".folded")
+	if err != nil {
+		fatal("Failed to create folded stacks file", "err", err)
+	}
+	defer folded.Close()
+	writeFolded(folded, stacks)
+
+	svg, err := os.Create(base + ".svg")
+	if err != nil {
+		fatal("Failed to create flamegraph", "err", err)
+	}
+	defer svg.Close()
+	writeFlamegraph(svg, name, stacks)
+}
+
+// foldedStack is one unique call stack, root first, with its sample count.
+type foldedStack struct {
+	Frames []string
+	Count  int64
+}
+
+// foldStacks collapses the profile into unique stacks in the format used by
+// Brendan Gregg's flamegraph.pl. Inlined frames are expanded.
+func foldStacks(p *profile.Profile) []foldedStack {
+	counts := map[string]int64{}
+	for _, s := range p.Sample {
+		var frames []string
+		for i := len(s.Location) - 1; i >= 0; i-- {
+			loc := s.Location[i]
+			for j := len(loc.Line) - 1; j >= 0; j-- {
+				if fn := loc.Line[j].Function; fn != nil {
+					frames = append(frames, fn.Name)
+				}
+			}
+		}
+		if len(frames) > 0 && len(s.Value) > 0 {
+			counts[strings.Join(frames, ";")] += s.Value[0]
+		}
+	}
+
+	stacks := make([]foldedStack, 0, len(counts))
+	for key, count := range counts {
+		stacks = append(stacks, foldedStack{strings.Split(key, ";"), count})
+	}
+	sort.Slice(stacks, func(i, j int) bool {
+		return strings.Join(stacks[i].Frames, ";") < strings.Join(stacks[j].Frames, ";")
+	})
+	return stacks
+}
+
+func writeFolded(w io.Writer, stacks []foldedStack) {
+	for _, s := range stacks {
+		fmt.Fprintf(w, "%s %d\n", strings.Join(s.Frames, ";"), s.Count)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteFolded(t *testing.T) {
+	stacks := []foldedStack{
+		{Frames: []string{"main", "benchmarkWrite", "sqlite3_step"}, Count: 3},
+		{Frames: []string{"main", "benchmarkWrite"}, Count: 1},
+	}
+
+	var sb strings.Builder
+	writeFolded(&sb, stacks)
+
+	want := "main;benchmarkWrite;sqlite3_step 3\nmain;benchmarkWrite 1\n"
+	if sb.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", sb.String(), want)
+	}
+}
+
+func TestWriteFlamegraph(t *testing.T) {
+	stacks := []foldedStack{
+		{Frames: []string{"main", "benchmarkWrite", "sqlite3_step"}, Count: 3},
+		{Frames: []string{"main", "benchmarkRead"}, Count: 1},
+	}
+
+	root := buildFlameTree(stacks)
+	if root.Count != 4 || root.Children["main"].Count != 4 || root.depth() != 4 {
+		t.Errorf("unexpected tree: count=%d depth=%d", root.Count, root.depth())
+	}
+
+	var sb strings.Builder
+	writeFlamegraph(&sb, "test <profile>", stacks)
+
+	svg := sb.String()
+	if !strings.HasPrefix(svg, "<svg") || !strings.Contains(svg, "test &lt;profile&gt;") {
+		t.Errorf("unexpected SVG header:\n%s", svg)
+	}
+	if !strings.Contains(svg, "sqlite3_step (3 samples, 75.00%)") {
+		t.Errorf("missing frame title:\n%s", svg)
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"sqlite_benchmark/sqlitebench"
+)
+
+// progressBar reports completed/total cells and an ETA on stderr. On a
+// terminal it redraws a single line; otherwise it logs one line per cell.
+type progressBar struct {
+	w        io.Writer
+	terminal bool
+	total    int
+	done     int
+	start    time.Time
+}
+
+func newProgressBar(total int) *progressBar {
+	return &progressBar{
+		w:        os.Stderr,
+		terminal: useColor(os.Stderr),
+		total:    total,
+		start:    time.Now(),
+	}
+}
+
+// eta estimates the remaining time from the mean time per completed cell.
+func (p *progressBar) eta() string {
+	if p.done == 0 {
+		return "--"
+	}
+	perCell := time.Since(p.start) / time.Duration(p.done)
+	return (perCell * time.Duration(p.total-p.done)).Round(time.Second).String()
+}
+
+func (p *progressBar) CellStarted(_ int, c sqlitebench.Cell) {
+	current := fmt.Sprintf("%s %s %s", c.Driver, c.Workload, formatSize(c.DataSize))
+
+	if !p.terminal {
+		fmt.Fprintf(p.w, "[%d/%d] %s (ETA %s)\n", p.done+1, p.total, current, p.eta())
+		return
+	}
+
+	const width = 30
+	filled := 0
+	if p.total > 0 {
+		filled = p.done * width / p.total
+	}
+	fmt.Fprintf(p.w, "\r\033[K[%s%s] %d/%d  %s  ETA %s",
+		strings.Repeat("#", filled), strings.Repeat("-", width-filled), p.done, p.total, current, p.eta())
+}
+
+func (p *progressBar) CellDone(int, sqlitebench.Cell, BenchmarkResult) {
+	p.done++
+	if p.terminal && p.done == p.total {
+		fmt.Fprintf(p.w, "\r\033[K")
+	}
+}
+
+func (p *progressBar) CellFailed(seq int, c sqlitebench.Cell, _ error) {
+	p.CellDone(seq, c, BenchmarkResult{})
+}
+
+// observers fans progress notifications out to several observers.
+type observers []sqlitebench.Observer
+
+func (o observers) CellStarted(seq int, c sqlitebench.Cell) {
+	for _, obs := range o {
+		obs.CellStarted(seq, c)
+	}
+}
+
+func (o observers) CellDone(seq int, c sqlitebench.Cell, r BenchmarkResult) {
+	for _, obs := range o {
+		obs.CellDone(seq, c, r)
+	}
+}
+
+func (o observers) CellFailed(seq int, c sqlitebench.Cell, err error) {
+	for _, obs := range o {
+		obs.CellFailed(seq, c, err)
+	}
+}
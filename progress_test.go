@@ -0,0 +1,29 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"sqlite_benchmark/sqlitebench"
+)
+
+func TestProgressBarLines(t *testing.T) {
+	var sb strings.Builder
+	p := &progressBar{w: &sb, total: 2, start: time.Now()}
+
+	p.CellStarted(0, sqlitebench.Cell{Driver: "mattn", Workload: "write", DataSize: 64})
+	p.CellDone(0, sqlitebench.Cell{}, BenchmarkResult{})
+	p.CellStarted(1, sqlitebench.Cell{Driver: "mattn", Workload: "read", DataSize: 64})
+
+	lines := strings.Split(strings.TrimSpace(sb.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2:\n%s", len(lines), sb.String())
+	}
+	if lines[0] != "[1/2] mattn write 64B (ETA --)" {
+		t.Errorf("first line = %q", lines[0])
+	}
+	if !strings.HasPrefix(lines[1], "[2/2] mattn read 64B (ETA ") {
+		t.Errorf("second line = %q", lines[1])
+	}
+}
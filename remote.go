@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// remoteBinary is the name the benchmark binary is copied to on a host.
+const remoteBinary = "sqlite_benchmark"
+
+// runRemote implements the remote subcommand: it copies the benchmark binary
+// to every host over SSH, runs the matrix there and collects each host's
+// JSON results, so one suite can be compared across different hardware.
+// Arguments after the flags are passed on to run on every host. The ssh and
+// scp commands of the local machine are used, with their usual config and
+// agent.
+func runRemote(args []string) {
+	fs := flag.NewFlagSet("remote", flag.ExitOnError)
+	hosts := fs.String("hosts", "", "comma-separated SSH destinations, e.g. bench@arm1,bench@x86")
+	config := fs.String("config", "", "config file copied to every host and passed to run (scenario and schema files it names are not copied)")
+	binary := fs.String("binary", "", "benchmark binary to copy, built for the hosts' OS and architecture (default this executable)")
+	outDir := fs.String("out", "remote_results", "directory the hosts' JSON results are collected in")
+	sshFlags := fs.String("ssh-flags", "", "extra flags for ssh and scp, e.g. \"-i key -o Port=2222\"")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s remote -hosts h1,h2 [flags] [-- run flags]\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *hosts == "" {
+		fatal("remote needs -hosts")
+	}
+	if *binary == "" {
+		exe, err := os.Executable()
+		if err != nil {
+			fatal("Failed to find this executable", "err", err)
+		}
+		*binary = exe
+	}
+	if *config != "" {
+		if _, err := loadConfig(*config); err != nil {
+			fatal("Invalid config", "err", err)
+		}
+	}
+	if err := os.MkdirAll(*outDir, 0o755); err != nil {
+		fatal("Failed to create output directory", "err", err)
+	}
+
+	ctx, cancel := interruptContext()
+	defer cancel()
+
+	// Hosts share no hardware, so they all run at once.
+	names := strings.Split(*hosts, ",")
+	paths := make([]string, len(names))
+	errs := make([]error, len(names))
+	var wg sync.WaitGroup
+	for i, host := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s := &remoteSession{host: host, flags: strings.Fields(*sshFlags), log: &prefixWriter{w: os.Stderr, prefix: host + ": "}}
+			paths[i], errs[i] = s.benchmark(ctx, *binary, *config, fs.Args(), *outDir)
+			s.log.Flush()
+		}()
+	}
+	wg.Wait()
+
+	failed := false
+	for i, host := range names {
+		if errs[i] != nil {
+			slog.Error("Remote run failed", "host", host, "err", errs[i])
+			failed = true
+			continue
+		}
+		report := loadResults(paths[i])
+		fmt.Printf("\n%s (%s):\n", host, paths[i])
+		printComparisonTable(os.Stdout, report.Results, useColor(os.Stdout))
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// remoteSession runs commands on one host with the local ssh and scp.
+type remoteSession struct {
+	host  string
+	flags []string
+	// log receives the commands' stderr, including the remote run's log.
+	log *prefixWriter
+}
+
+// benchmark copies the binary and config to a temporary directory on the
+// host, runs the benchmark there with the given run arguments and fetches
+// its JSON results into outDir, returning the local path. A run that fails
+// after writing results, e.g. because some cells failed, still has them
+// collected.
+func (s *remoteSession) benchmark(ctx context.Context, binary, config string, runArgs []string, outDir string) (string, error) {
+	dir, err := s.run(ctx, "mktemp -d")
+	if err != nil {
+		return "", fmt.Errorf("creating work directory: %w", err)
+	}
+	dir = strings.TrimSpace(dir)
+	defer s.run(context.Background(), "rm -rf "+shellQuote(dir))
+
+	if err := s.put(ctx, binary, dir+"/"+remoteBinary); err != nil {
+		return "", fmt.Errorf("copying binary: %w", err)
+	}
+	var args []string
+	if config != "" {
+		name := "config" + filepath.Ext(config)
+		if err := s.put(ctx, config, dir+"/"+name); err != nil {
+			return "", fmt.Errorf("copying config: %w", err)
+		}
+		args = append(args, "-config", name)
+	}
+	// JSON results in the work directory are what gets collected, whatever
+	// the run arguments ask for.
+	args = append(append(args, runArgs...), "-format", "json", "-out=")
+
+	command := "cd " + shellQuote(dir) + " && ./" + remoteBinary + " run"
+	for _, arg := range args {
+		command += " " + shellQuote(arg)
+	}
+	_, runErr := s.run(ctx, command)
+
+	local := filepath.Join(outDir, hostFileName(s.host)+".json")
+	if err := s.get(ctx, dir+"/benchmark_results.json", local); err != nil {
+		if runErr != nil {
+			return "", fmt.Errorf("running benchmark: %w", runErr)
+		}
+		return "", fmt.Errorf("fetching results: %w", err)
+	}
+	if runErr != nil {
+		slog.Warn("Remote run failed; collected its partial results", "host", s.host, "err", runErr)
+	}
+	return local, nil
+}
+
+// run runs a shell command on the host and returns its stdout.
+func (s *remoteSession) run(ctx context.Context, command string) (string, error) {
+	var stdout bytes.Buffer
+	cmd := exec.CommandContext(ctx, "ssh", slices.Concat(s.flags, []string{s.host, command})...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = s.log
+	err := cmd.Run()
+	return stdout.String(), err
+}
+
+// put copies a local file to a path on the host.
+func (s *remoteSession) put(ctx context.Context, local, remote string) error {
+	return s.scp(ctx, local, s.host+":"+remote)
+}
+
+// get copies a file on the host to a local path.
+func (s *remoteSession) get(ctx context.Context, remote, local string) error {
+	return s.scp(ctx, s.host+":"+remote, local)
+}
+
+func (s *remoteSession) scp(ctx context.Context, from, to string) error {
+	cmd := exec.CommandContext(ctx, "scp", slices.Concat([]string{"-q"}, s.flags, []string{from, to})...)
+	cmd.Stderr = s.log
+	return cmd.Run()
+}
+
+// shellQuote quotes s as a single word for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9.-]+`)
+
+// hostFileName turns an SSH destination such as "bench@arm1:22" into a
+// file name.
+func hostFileName(host string) string {
+	return unsafeFileChars.ReplaceAllString(host, "_")
+}
+
+// prefixWriter writes every line it is given to w with a prefix, so the
+// output of several hosts can be told apart.
+type prefixWriter struct {
+	w      io.Writer
+	prefix string
+
+	mu  sync.Mutex
+	buf []byte
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.buf = append(p.buf, b...)
+	for {
+		i := bytes.IndexByte(p.buf, '\n')
+		if i < 0 {
+			return len(b), nil
+		}
+		if _, err := io.WriteString(p.w, p.prefix+string(p.buf[:i+1])); err != nil {
+			return len(b), err
+		}
+		p.buf = p.buf[i+1:]
+	}
+}
+
+// Flush writes a final line that has no newline.
+func (p *prefixWriter) Flush() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.buf) > 0 {
+		io.WriteString(p.w, p.prefix+string(p.buf)+"\n")
+		p.buf = nil
+	}
+}
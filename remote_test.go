@@ -0,0 +1,36 @@
+package main
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestShellQuote(t *testing.T) {
+	for _, s := range []string{"", "plain", "two words", "it's", `$HOME "x" \n`, "-out="} {
+		out, err := exec.Command("sh", "-c", "printf %s "+shellQuote(s)).Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != s {
+			t.Errorf("sh read %q as %q", s, out)
+		}
+	}
+}
+
+func TestHostFileName(t *testing.T) {
+	if got := hostFileName("bench@arm-1.lan:22"); got != "bench_arm-1.lan_22" {
+		t.Errorf("hostFileName = %q", got)
+	}
+}
+
+func TestPrefixWriter(t *testing.T) {
+	var b strings.Builder
+	p := &prefixWriter{w: &b, prefix: "h: "}
+	p.Write([]byte("one\ntw"))
+	p.Write([]byte("o\nthree"))
+	p.Flush()
+	if want := "h: one\nh: two\nh: three\n"; b.String() != want {
+		t.Errorf("output = %q, want %q", b.String(), want)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"sqlite_benchmark/sqlitebench"
+)
+
+// replayStatementWidth is how much of each statement the replay report shows.
+const replayStatementWidth = 60
+
+// runReplay implements `replay trace.jsonl`: replay a recorded application
+// trace against every driver and report statement latencies.
+func runReplay(args []string) {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: replay [flags] trace")
+		fs.PrintDefaults()
+	}
+	drivers := fs.String("drivers", strings.Join(defaultConfig().Drivers, ","), "comma-separated drivers to replay against")
+	realtime := fs.Bool("realtime", false, "keep the trace's original timing instead of replaying at full speed")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	file, err := os.Open(fs.Arg(0))
+	if err != nil {
+		fatal("Failed to open trace", "err", err)
+	}
+	trace, err := sqlitebench.ReadTrace(file)
+	file.Close()
+	if err != nil {
+		fatal("Failed to read trace", "path", fs.Arg(0), "err", err)
+	}
+
+	ctx, cancel := interruptContext()
+	defer cancel()
+
+	replay := &sqlitebench.Replay{Trace: trace, Realtime: *realtime}
+	for _, driver := range strings.Split(*drivers, ",") {
+		result, err := replay.Run(ctx, driver)
+		if result != nil {
+			printReplay(os.Stdout, result)
+		}
+		if err != nil {
+			fatal("Replay failed", "driver", driver, "err", err)
+		}
+	}
+}
+
+// printReplay writes the aggregate latency of a replay followed by one line
+// per distinct statement.
+func printReplay(w io.Writer, r *sqlitebench.ReplayResult) {
+	all := r.Samples()
+	fmt.Fprintf(w, "%s: %d statements in %v, %d errors, p50 %v, p99 %v\n",
+		r.Driver, len(all)+r.Errors(), r.Duration, r.Errors(),
+		sqlitebench.Percentile(all, 50), sqlitebench.Percentile(all, 99))
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Statement\tCount\tErrors\tMean\tp50\tp99")
+	for _, s := range r.Statements {
+		var total int64
+		for _, d := range s.Samples {
+			total += int64(d)
+		}
+		if len(s.Samples) == 0 {
+			fmt.Fprintf(tw, "%s\t0\t%d\t-\t-\t-\n", shortStatement(s.SQL), s.Errors)
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%v\t%v\t%v\n", shortStatement(s.SQL), len(s.Samples), s.Errors,
+			time.Duration(total/int64(len(s.Samples))),
+			sqlitebench.Percentile(s.Samples, 50), sqlitebench.Percentile(s.Samples, 99))
+	}
+	tw.Flush()
+	fmt.Fprintln(w)
+}
+
+// shortStatement collapses whitespace and truncates a statement for display.
+func shortStatement(sql string) string {
+	s := strings.Join(strings.Fields(sql), " ")
+	if len(s) > replayStatementWidth {
+		s = s[:replayStatementWidth-3] + "..."
+	}
+	return s
+}
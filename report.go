@@ -0,0 +1,273 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"time"
+
+	"sqlite_benchmark/sqlitebench"
+)
+
+// timeFormat is used wherever timestamps are stored as text.
+const timeFormat = time.RFC3339Nano
+
+// RunMetadata describes the environment a set of results was produced in.
+type RunMetadata struct {
+	Timestamp time.Time         `json:"timestamp"`
+	GoVersion string            `json:"go_version"`
+	GOOS      string            `json:"goos"`
+	GOARCH    string            `json:"goarch"`
+	NumCPU    int               `json:"num_cpu"`
+	CPU       string            `json:"cpu,omitempty"`
+	Hostname  string            `json:"hostname"`
+	Modules   map[string]string `json:"modules"`
+	Config    *Config           `json:"config,omitempty"`
+	// Drivers describes each benchmarked driver as found by its
+	// pre-flight self-check.
+	Drivers map[string]sqlitebench.DriverInfo `json:"drivers,omitempty"`
+	// Differences are the statements of the differential check whose
+	// results differed between drivers.
+	Differences []sqlitebench.Difference `json:"differences,omitempty"`
+	// Tags are the -tag and -label values the run was started with.
+	Tags tagSet `json:"tags,omitempty"`
+	// Interrupted marks a run that was stopped before it covered the
+	// whole matrix.
+	Interrupted bool `json:"interrupted,omitempty"`
+	// Container is the ID of the image the run was executed in with
+	// -in-container.
+	Container string `json:"container,omitempty"`
+}
+
+// collectMetadata captures the current environment, including the versions
+// of the benchmarked driver modules linked into the binary.
+func collectMetadata() RunMetadata {
+	hostname, _ := os.Hostname()
+
+	meta := RunMetadata{
+		Timestamp: time.Now().UTC(),
+		GoVersion: runtime.Version(),
+		GOOS:      runtime.GOOS,
+		GOARCH:    runtime.GOARCH,
+		NumCPU:    runtime.NumCPU(),
+		CPU:       cpuModel(),
+		Hostname:  hostname,
+		Modules:   map[string]string{},
+		Container: os.Getenv(containerEnv),
+	}
+
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, dep := range info.Deps {
+			switch dep.Path {
+			case "github.com/mattn/go-sqlite3", "modernc.org/sqlite":
+				meta.Modules[dep.Path] = dep.Version
+			}
+		}
+	}
+
+	return meta
+}
+
+// cpuModel returns the processor name from /proc/cpuinfo, or "" where that
+// is unavailable.
+func cpuModel() string {
+	data, err := os.ReadFile("/proc/cpuinfo")
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if ok && strings.TrimSpace(key) == "model name" {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+// Reporter writes results in one output format. Start is called once with
+// the run's metadata, Report with every result in order and Finish after
+// the last one.
+type Reporter interface {
+	Start(meta RunMetadata) error
+	Report(r BenchmarkResult) error
+	Finish() error
+}
+
+// reporters maps the -format values to constructors of their reporters.
+var reporters = map[string]func() Reporter{
+	"console":   func() Reporter { return &consoleReporter{w: os.Stdout, color: useColor(os.Stdout)} },
+	"csv":       func() Reporter { return &csvReporter{} },
+	"badges":    batch(saveResultsToBadges),
+	"benchstat": batch(saveResultsToBenchstat),
+	"influx":    batch(saveResultsToInflux),
+	"json":      batch(saveResultsToJSON),
+	"junit":     batch(saveResultsToJUnit),
+	"markdown":  batch(saveResultsToMarkdown),
+	"prometheus": batch(func(_ RunMetadata, results []BenchmarkResult) error {
+		return pushToGateway(*pushgatewayURL, results)
+	}),
+}
+
+// batchReporter collects the results and saves them all at once, for
+// formats that need the whole run to write anything.
+type batchReporter struct {
+	save    func(RunMetadata, []BenchmarkResult) error
+	meta    RunMetadata
+	results []BenchmarkResult
+}
+
+// batch returns a constructor of reporters saving with save.
+func batch(save func(RunMetadata, []BenchmarkResult) error) func() Reporter {
+	return func() Reporter { return &batchReporter{save: save} }
+}
+
+func (b *batchReporter) Start(meta RunMetadata) error { b.meta = meta; return nil }
+
+func (b *batchReporter) Report(r BenchmarkResult) error {
+	b.results = append(b.results, r)
+	return nil
+}
+
+func (b *batchReporter) Finish() error { return b.save(b.meta, b.results) }
+
+var outRoot = flag.String("out", "", "write each run's results, metadata, reports and profiles to a timestamped folder in this directory")
+
+// outputDir is the directory result files are written to. Empty means the
+// working directory; with -out every run gets its own folder.
+var outputDir string
+
+// runFolderFormat names the per-run folders created under -out.
+const runFolderFormat = "2006-01-02T15-04-05Z"
+
+// outputPath returns where the named result file goes.
+func outputPath(name string) string {
+	return filepath.Join(outputDir, name)
+}
+
+// writeReports passes the results through a reporter for each format. A
+// failing reporter does not stop the others; the returned error joins
+// their failures.
+func writeReports(formats []string, meta RunMetadata, results []BenchmarkResult) error {
+	var errs []error
+	for _, format := range formats {
+		if err := writeReport(format, meta, results); err != nil {
+			errs = append(errs, fmt.Errorf("writing %s results: %w", format, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func writeReport(format string, meta RunMetadata, results []BenchmarkResult) error {
+	newReporter, ok := reporters[format]
+	if !ok {
+		return fmt.Errorf("unknown output format %q", format)
+	}
+	rep := newReporter()
+	if err := rep.Start(meta); err != nil {
+		return err
+	}
+	for _, r := range results {
+		if err := rep.Report(r); err != nil {
+			rep.Finish()
+			return err
+		}
+	}
+	return rep.Finish()
+}
+
+// formatSize renders a byte count using binary units, e.g. 4096 -> "4KiB".
+func formatSize(n int) string {
+	units := []string{"B", "KiB", "MiB", "GiB"}
+	i := 0
+	for n >= 1024 && n%1024 == 0 && i < len(units)-1 {
+		n /= 1024
+		i++
+	}
+	return fmt.Sprintf("%d%s", n, units[i])
+}
+
+// iterations returns the number of operations measured in the result.
+func iterations(r BenchmarkResult) int {
+	if r.Iterations > 0 {
+		return r.Iterations
+	}
+	if len(r.Samples) == 0 {
+		return 1
+	}
+	return len(r.Samples)
+}
+
+// perOp returns the mean duration of a single operation in the result.
+func perOp(r BenchmarkResult) time.Duration {
+	return r.Duration / time.Duration(iterations(r))
+}
+
+// opsPerSec returns the throughput of the result in operations per second.
+func opsPerSec(r BenchmarkResult) float64 {
+	if r.Duration <= 0 {
+		return 0
+	}
+	return float64(iterations(r)) * 1e9 / float64(r.Duration.Nanoseconds())
+}
+
+// workload identifies a benchmark independently of the driver it ran on.
+type workload struct {
+	Operation string
+	DataSize  int
+}
+
+func (w workload) String() string {
+	return fmt.Sprintf("%s %s", w.Operation, formatSize(w.DataSize))
+}
+
+// comparisonTable groups results by workload and driver. Workloads keep the
+// order they were first seen in; drivers are sorted by name.
+type comparisonTable struct {
+	Drivers   []string
+	Workloads []workload
+	Cells     map[workload]map[string]BenchmarkResult
+}
+
+func buildComparisonTable(results []BenchmarkResult) comparisonTable {
+	t := comparisonTable{Cells: map[workload]map[string]BenchmarkResult{}}
+	seenDriver := map[string]bool{}
+
+	for _, r := range results {
+		w := workload{r.Operation, r.DataSize}
+		if t.Cells[w] == nil {
+			t.Cells[w] = map[string]BenchmarkResult{}
+			t.Workloads = append(t.Workloads, w)
+		}
+		t.Cells[w][r.Driver] = r
+
+		if !seenDriver[r.Driver] {
+			seenDriver[r.Driver] = true
+			t.Drivers = append(t.Drivers, r.Driver)
+		}
+	}
+	sort.Strings(t.Drivers)
+
+	return t
+}
+
+// fastest returns the driver with the lowest per-op time for the workload,
+// ignoring cells that timed out. It returns "" if there is none.
+func (t comparisonTable) fastest(w workload) string {
+	best := ""
+	for _, driver := range t.Drivers {
+		r, ok := t.Cells[w][driver]
+		if !ok || r.TimedOut {
+			continue
+		}
+		if best == "" || perOp(r) < perOp(t.Cells[w][best]) {
+			best = driver
+		}
+	}
+	return best
+}
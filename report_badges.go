@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// badgeDir is where -format badges writes its endpoint files.
+const badgeDir = "badges"
+
+// shieldsBadge is the shields.io endpoint badge schema
+// (https://shields.io/badges/endpoint-badge).
+type shieldsBadge struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	Label         string `json:"label"`
+	Message       string `json:"message"`
+	Color         string `json:"color"`
+}
+
+// saveResultsToBadges writes one shields.io endpoint file per result, e.g.
+// badges/mattn_Write_1048576Bytes.json. The fastest driver for each workload
+// gets a green badge.
+func saveResultsToBadges(_ RunMetadata, results []BenchmarkResult) error {
+	if err := os.MkdirAll(outputPath(badgeDir), 0o755); err != nil {
+		return fmt.Errorf("creating badge directory: %w", err)
+	}
+
+	t := buildComparisonTable(results)
+	for _, r := range results {
+		badge := resultBadge(r, t.fastest(workload{r.Operation, r.DataSize}) == r.Driver)
+
+		data, err := json.Marshal(badge)
+		if err != nil {
+			return fmt.Errorf("encoding badge: %w", err)
+		}
+
+		path := filepath.Join(outputPath(badgeDir), benchstatName(r)+".json")
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			return fmt.Errorf("writing badge: %w", err)
+		}
+	}
+	return nil
+}
+
+func resultBadge(r BenchmarkResult, fastest bool) shieldsBadge {
+	color := "orange"
+	if fastest {
+		color = "brightgreen"
+	}
+	return shieldsBadge{
+		SchemaVersion: 1,
+		Label:         fmt.Sprintf("%s %s %s", r.Driver, r.Operation, formatSize(r.DataSize)),
+		Message:       perOp(r).String(),
+		Color:         color,
+	}
+}
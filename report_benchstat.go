@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+)
+
+func saveResultsToBenchstat(meta RunMetadata, results []BenchmarkResult) error {
+	file, err := os.Create(outputPath("benchmark_results.txt"))
+	if err != nil {
+		return fmt.Errorf("creating benchmark text file: %w", err)
+	}
+	defer file.Close()
+
+	writeBenchstat(file, meta, results)
+	return file.Close()
+}
+
+// writeBenchstat emits results in the Go benchmark text format, using the
+// same names as the BenchmarkSqlite sub-benchmarks so standalone and
+// `go test -bench` runs can be compared with benchstat.
+func writeBenchstat(w io.Writer, meta RunMetadata, results []BenchmarkResult) {
+	fmt.Fprintf(w, "goos: %s\n", meta.GOOS)
+	fmt.Fprintf(w, "goarch: %s\n", meta.GOARCH)
+	fmt.Fprintf(w, "pkg: sqlite_benchmark\n")
+	if meta.CPU != "" {
+		fmt.Fprintf(w, "cpu: %s\n", meta.CPU)
+	}
+
+	suffix := ""
+	if procs := runtime.GOMAXPROCS(0); procs > 1 {
+		suffix = fmt.Sprintf("-%d", procs)
+	}
+
+	for _, r := range results {
+		n := iterations(r)
+
+		fmt.Fprintf(w, "BenchmarkSqlite/%s%s \t%8d\t%10d ns/op", benchstatName(r), suffix, n, r.Duration.Nanoseconds()/int64(n))
+		fmt.Fprintf(w, "\t%8d B/op\t%8d allocs/op", r.Bytes/uint64(n), r.Allocs/uint64(n))
+		if r.Counters != nil {
+			fmt.Fprintf(w, "\t%10.0f instructions/op", float64(r.Counters.Instructions)/float64(n))
+			fmt.Fprintf(w, "\t%10.2f cache-misses/op", float64(r.Counters.CacheMisses)/float64(n))
+			fmt.Fprintf(w, "\t%10.2f branch-misses/op", float64(r.Counters.BranchMisses)/float64(n))
+		}
+		if r.BytesRead != 0 {
+			fmt.Fprintf(w, "\t%10.0f row-B/op", float64(r.BytesRead)/float64(n))
+		}
+		if r.Errors != nil {
+			fmt.Fprintf(w, "\t%10.4f errors/op\t%10.4f retries/op", float64(r.Errors.Total())/float64(n), float64(r.Errors.Retries)/float64(n))
+		}
+		if r.IO != nil {
+			fmt.Fprintf(w, "\t%10.2f reads/op\t%10.2f writes/op\t%10.2f syncs/op", float64(r.IO.Reads)/float64(n), float64(r.IO.Writes)/float64(n), float64(r.IO.Syncs)/float64(n))
+			fmt.Fprintf(w, "\t%10.0f read-B/op\t%10.0f written-B/op", float64(r.IO.ReadBytes)/float64(n), float64(r.IO.WrittenBytes)/float64(n))
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// benchstatName mirrors the sub-benchmark names used by BenchmarkDrivers,
+// e.g. "mattn_Write_64Bytes".
+func benchstatName(r BenchmarkResult) string {
+	op := r.Operation
+	if op != "" {
+		op = strings.ToUpper(op[:1]) + op[1:]
+	}
+	return fmt.Sprintf("%s_%s_%dBytes", r.Driver, op, r.DataSize)
+}
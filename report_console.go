@@ -0,0 +1,343 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode/utf8"
+
+	"sqlite_benchmark/sqlitebench"
+)
+
+const (
+	ansiReset = "\033[0m"
+	ansiBold  = "\033[1m"
+	ansiGreen = "\033[32m"
+	ansiRed   = "\033[31m"
+)
+
+// useColor reports whether f is a terminal that should get ANSI colors.
+// NO_COLOR (https://no-color.org) always disables them.
+func useColor(f *os.File) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// consoleReporter prints the comparison table once every result is in.
+type consoleReporter struct {
+	w           io.Writer
+	color       bool
+	results     []BenchmarkResult
+	differences []sqlitebench.Difference
+}
+
+func (c *consoleReporter) Start(meta RunMetadata) error {
+	c.differences = meta.Differences
+	return nil
+}
+
+func (c *consoleReporter) Report(r BenchmarkResult) error {
+	c.results = append(c.results, r)
+	return nil
+}
+
+func (c *consoleReporter) Finish() error {
+	printComparisonTable(c.w, c.results, c.color)
+	printReplicationOverhead(c.w, c.results)
+	printSQLiteVersions(c.w, c.results)
+	printErrorCounts(c.w, c.results)
+	printPhaseLatency(c.w, c.results)
+	printGrowth(c.w, c.results)
+	printDifferences(c.w, c.differences)
+	return nil
+}
+
+// maxPrintedDifferences bounds the differences printed; the JSON metadata
+// keeps them all.
+const maxPrintedDifferences = 10
+
+// printDifferences writes the statements of the differential check whose
+// results differed between drivers, with every driver's outcome.
+func printDifferences(w io.Writer, diffs []sqlitebench.Difference) {
+	if len(diffs) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\nDrivers differed on %d statements:\n", len(diffs))
+	for _, d := range diffs[:min(len(diffs), maxPrintedDifferences)] {
+		fmt.Fprintf(w, "  #%d %s", d.Step, d.Statement)
+		if len(d.Args) > 0 {
+			fmt.Fprintf(w, " [%s]", strings.Join(d.Args, ", "))
+		}
+		fmt.Fprintln(w)
+		drivers := make([]string, 0, len(d.Outcomes))
+		for driver := range d.Outcomes {
+			drivers = append(drivers, driver)
+		}
+		sort.Strings(drivers)
+		for _, driver := range drivers {
+			fmt.Fprintf(w, "    %s: %s\n", driver, d.Outcomes[driver])
+		}
+	}
+	if len(diffs) > maxPrintedDifferences {
+		fmt.Fprintf(w, "  ... and %d more\n", len(diffs)-maxPrintedDifferences)
+	}
+}
+
+// printErrorCounts writes the operations that failed with an expected
+// error, such as SQLITE_BUSY, for every result that had any, and the share
+// of all operations they were, with the retries of operations that found
+// the database locked. It writes nothing if none did.
+func printErrorCounts(w io.Writer, results []BenchmarkResult) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	header := false
+	for _, r := range results {
+		if r.Errors == nil {
+			continue
+		}
+		if !header {
+			fmt.Fprintln(tw, "\nFailed operations\tbusy\tconstraint\ttimeout\tfailure rate\tretried\tretries\tretry time")
+			header = true
+		}
+		failed := r.Errors.Total()
+		fmt.Fprintf(tw, "%s %s %s\t%d\t%d\t%d\t%.2f%%\t%d\t%d\t%v\n",
+			r.Driver, r.Operation, formatSize(r.DataSize),
+			r.Errors.Busy, r.Errors.Constraint, r.Errors.Timeout,
+			float64(failed)/float64(failed+uint64(iterations(r)))*100,
+			r.Errors.Retried, r.Errors.Retries, r.Errors.RetryTime.Round(time.Microsecond))
+	}
+	tw.Flush()
+}
+
+// printPhaseLatency writes the rate and the median and p99 latency of the
+// operations of each phase, for every result timed by phase, with how much
+// slower the p99 of a phase was than that of the phase it is named after,
+// e.g. "lookup.sweep" than "lookup". It writes nothing if no result was.
+func printPhaseLatency(w io.Writer, results []BenchmarkResult) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	header := false
+	for _, r := range results {
+		phases := make([]string, 0, len(r.Phases))
+		for phase := range r.Phases {
+			phases = append(phases, phase)
+		}
+		sort.Strings(phases)
+		for _, phase := range phases {
+			if !header {
+				fmt.Fprintln(tw, "\nPhase latency\tphase\tops\tops/s\tp50\tp99\tvs base")
+				header = true
+			}
+			times := r.Phases[phase]
+			p99 := sqlitebench.Percentile(times, 99)
+			change := "-"
+			if base, _, ok := strings.Cut(phase, "."); ok && len(r.Phases[base]) > 0 {
+				change = formatChange(float64(p99), float64(sqlitebench.Percentile(r.Phases[base], 99)))
+			}
+			rate := "-"
+			if r.Duration > 0 {
+				rate = fmt.Sprintf("%.0f", float64(len(times))/r.Duration.Seconds())
+			}
+			fmt.Fprintf(tw, "%s %s %s\t%s\t%d\t%s\t%v\t%v\t%s\n",
+				r.Driver, r.Operation, formatSize(r.DataSize), phase, len(times), rate,
+				sqlitebench.Percentile(times, 50), p99, change)
+		}
+	}
+	tw.Flush()
+}
+
+// printGrowth writes, for every result with size samples, the size of the
+// database and its freelist at the last sample, how much it grew per
+// operation from the first sample to the last, and the size of the sampled
+// index with the share of it its entries fill. It writes nothing if no
+// result has samples.
+func printGrowth(w io.Writer, results []BenchmarkResult) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	header := false
+	for _, r := range results {
+		if len(r.Growth) == 0 {
+			continue
+		}
+		if !header {
+			fmt.Fprintln(tw, "\nDatabase growth\tops\tsize\tfree\tper op\tindex\tindex fill")
+			header = true
+		}
+		first, last := r.Growth[0], r.Growth[len(r.Growth)-1]
+		perOp := "-"
+		if last.Op > first.Op {
+			perOp = fmt.Sprintf("%.0fB", float64(last.Bytes-first.Bytes)/float64(last.Op-first.Op))
+		}
+		index, fill := "-", "-"
+		if last.IndexBytes > 0 {
+			index = approxSize(last.IndexBytes)
+			fill = fmt.Sprintf("%.1f%%", float64(last.IndexPayload)/float64(last.IndexBytes)*100)
+		}
+		fmt.Fprintf(tw, "%s %s %s\t%d\t%s\t%s\t%s\t%s\t%s\n",
+			r.Driver, r.Operation, formatSize(r.DataSize), last.Op+1,
+			approxSize(last.Bytes), approxSize(last.FreeBytes), perOp, index, fill)
+	}
+	tw.Flush()
+}
+
+// printSQLiteVersions notes the SQLite version every driver ran when they
+// differ, as comparing them then measures SQLite changes along with driver
+// overhead. It writes nothing when all drivers ran the same version.
+func printSQLiteVersions(w io.Writer, results []BenchmarkResult) {
+	versions := map[string]string{}
+	var drivers []string
+	for _, r := range results {
+		if _, ok := versions[r.Driver]; !ok && r.SQLiteVersion != "" {
+			versions[r.Driver] = r.SQLiteVersion
+			drivers = append(drivers, r.Driver)
+		}
+	}
+	same := true
+	for _, d := range drivers {
+		same = same && versions[d] == versions[drivers[0]]
+	}
+	if same {
+		return
+	}
+	parts := make([]string, len(drivers))
+	for i, d := range drivers {
+		parts[i] = d + " " + versions[d]
+	}
+	fmt.Fprintf(w, "\nDrivers ran different SQLite versions (%s): differences include SQLite's, not only the drivers'. Pin them with sqlite_versions to compare driver overhead alone.\n",
+		strings.Join(parts, ", "))
+}
+
+// replicationOp matches the replication part of an operation name.
+var replicationOp = regexp.MustCompile(`,replication=([^,]+)`)
+
+// printReplicationOverhead writes, for every result measured with a
+// replicator, the throughput and p99 latency it cost against the same cell
+// with replication off. It writes nothing for runs without replication.
+func printReplicationOverhead(w io.Writer, results []BenchmarkResult) {
+	baseline := map[resultKey]BenchmarkResult{}
+	for _, r := range results {
+		if m := replicationOp.FindStringSubmatch(r.Operation); m != nil && m[1] == sqlitebench.ReplicationOff {
+			baseline[keyOf(r)] = r
+		}
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	header := false
+	for _, r := range results {
+		m := replicationOp.FindStringSubmatch(r.Operation)
+		if m == nil || m[1] == sqlitebench.ReplicationOff {
+			continue
+		}
+		off := r
+		off.Operation = strings.Replace(r.Operation, m[0], ",replication="+sqlitebench.ReplicationOff, 1)
+		base, ok := baseline[keyOf(off)]
+		if !ok || r.TimedOut || base.TimedOut {
+			continue
+		}
+		if !header {
+			fmt.Fprintln(tw, "\nReplication overhead\tops/s\tvs off\tp99\tvs off")
+			header = true
+		}
+		p99 := sqlitebench.Percentile(r.Samples, 99)
+		baseP99 := sqlitebench.Percentile(base.Samples, 99)
+		fmt.Fprintf(tw, "%s %s %s\t%.0f\t%s\t%v\t%s\n",
+			r.Driver, r.Operation, formatSize(r.DataSize),
+			opsPerSec(r), formatChange(opsPerSec(r), opsPerSec(base)),
+			p99, formatChange(float64(p99), float64(baseP99)))
+	}
+	tw.Flush()
+}
+
+// formatChange formats the change from base to v, e.g. "-12.5%".
+func formatChange(v, base float64) string {
+	if base == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%+.1f%%", (v-base)/base*100)
+}
+
+// printComparisonTable writes an aligned table with the time per op for
+// every driver and how much slower the other drivers are than the winner.
+func printComparisonTable(w io.Writer, results []BenchmarkResult, color bool) {
+	t := buildComparisonTable(results)
+
+	paint := func(s, code string) string {
+		if !color || code == "" {
+			return s
+		}
+		return code + s + ansiReset
+	}
+
+	header := append([]string{"Workload"}, t.Drivers...)
+	header = append(header, "Comparison")
+
+	type cell struct{ text, color string }
+	rows := [][]cell{}
+	for _, wl := range t.Workloads {
+		fastest := t.fastest(wl)
+		best := perOp(t.Cells[wl][fastest])
+
+		row := []cell{{text: wl.String()}}
+		var notes []string
+		for _, driver := range t.Drivers {
+			r, ok := t.Cells[wl][driver]
+			switch {
+			case !ok:
+				row = append(row, cell{text: "-"})
+			case r.TimedOut:
+				row = append(row, cell{"timeout", ansiRed})
+			case driver == fastest:
+				row = append(row, cell{perOp(r).String(), ansiBold + ansiGreen})
+			default:
+				row = append(row, cell{text: perOp(r).String()})
+				if best > 0 {
+					notes = append(notes, fmt.Sprintf("%s %.1fx slower", driver, float64(perOp(r))/float64(best)))
+				}
+			}
+		}
+
+		comparison := cell{text: fastest + " fastest", color: ansiGreen}
+		switch {
+		case fastest == "":
+			comparison = cell{text: "-"}
+		case len(notes) > 0:
+			comparison = cell{strings.Join(notes, ", "), ansiRed}
+		}
+		rows = append(rows, append(row, comparison))
+	}
+
+	widths := make([]int, len(header))
+	for i, h := range header {
+		widths[i] = utf8.RuneCountInString(h)
+	}
+	for _, row := range rows {
+		for i, c := range row {
+			widths[i] = max(widths[i], utf8.RuneCountInString(c.text))
+		}
+	}
+
+	pad := func(s string, i int) string {
+		if i == len(widths)-1 {
+			return s
+		}
+		return s + strings.Repeat(" ", widths[i]-utf8.RuneCountInString(s))
+	}
+
+	cells := make([]string, len(header))
+	for i, h := range header {
+		cells[i] = paint(pad(h, i), ansiBold)
+	}
+	fmt.Fprintln(w, strings.Join(cells, "  "))
+
+	for _, row := range rows {
+		for i, c := range row {
+			cells[i] = paint(pad(c.text, i), c.color)
+		}
+		fmt.Fprintln(w, strings.Join(cells, "  "))
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// influxMeasurement is the measurement name all points are written under.
+const influxMeasurement = "sqlite_benchmark"
+
+func saveResultsToInflux(meta RunMetadata, results []BenchmarkResult) error {
+	file, err := os.Create(outputPath("benchmark_results.lp"))
+	if err != nil {
+		return fmt.Errorf("creating line protocol file: %w", err)
+	}
+	defer file.Close()
+
+	writeInfluxLines(file, meta, results)
+	return file.Close()
+}
+
+// writeInfluxLines renders one InfluxDB line protocol point per result, all
+// stamped with the run's start time so a run shows up as a single instant.
+func writeInfluxLines(w io.Writer, meta RunMetadata, results []BenchmarkResult) {
+	for _, r := range results {
+		n := iterations(r)
+		tags := ""
+		if r.Category != "" {
+			tags = ",category=" + influxTag(r.Category)
+		}
+		fmt.Fprintf(w, "%s,driver=%s,operation=%s,data_size=%d,host=%s%s duration_ns=%di,iterations=%di,ns_per_op=%g,ops_per_sec=%g,allocs_per_op=%g,bytes_per_op=%g %d\n",
+			influxMeasurement,
+			influxTag(r.Driver), influxTag(r.Operation), r.DataSize, influxTag(meta.Hostname), tags,
+			r.Duration.Nanoseconds(), n,
+			float64(perOp(r).Nanoseconds()), opsPerSec(r),
+			float64(r.Allocs)/float64(n), float64(r.Bytes)/float64(n),
+			meta.Timestamp.UnixNano())
+	}
+}
+
+var influxTagEscaper = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+
+// influxTag escapes a tag value for line protocol. Empty tag values are not
+// allowed, so they are replaced by "unknown".
+func influxTag(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+	return influxTagEscaper.Replace(s)
+}
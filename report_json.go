@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// jsonSchemaVersion is bumped whenever a field in the JSON output is renamed
+// or removed. Adding fields does not change it.
+const jsonSchemaVersion = 1
+
+// JSONReport is the top-level document written by -format json.
+type JSONReport struct {
+	SchemaVersion int               `json:"schema_version"`
+	Metadata      RunMetadata       `json:"metadata"`
+	Results       []BenchmarkResult `json:"results"`
+}
+
+func saveResultsToJSON(meta RunMetadata, results []BenchmarkResult) error {
+	file, err := os.Create(outputPath("benchmark_results.json"))
+	if err != nil {
+		return fmt.Errorf("creating JSON file: %w", err)
+	}
+	defer file.Close()
+
+	enc := json.NewEncoder(file)
+	enc.SetIndent("", "  ")
+
+	err = enc.Encode(JSONReport{SchemaVersion: jsonSchemaVersion, Metadata: meta, Results: results})
+	if err != nil {
+		return fmt.Errorf("writing JSON file: %w", err)
+	}
+	return file.Close()
+}
+
+// saveMetadataJSON writes run metadata on its own, as a companion to formats
+// that have no place for it.
+func saveMetadataJSON(path string, meta RunMetadata) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("creating metadata file: %w", err)
+	}
+	defer file.Close()
+
+	enc := json.NewEncoder(file)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(meta); err != nil {
+		return fmt.Errorf("writing metadata file: %w", err)
+	}
+	return file.Close()
+}
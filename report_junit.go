@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+)
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Time      float64         `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	Hostname  string          `xml:"hostname,attr,omitempty"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Time      float64       `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+}
+
+func saveResultsToJUnit(meta RunMetadata, results []BenchmarkResult) error {
+	var baseline map[resultKey]BenchmarkResult
+	if *baselinePath != "" {
+		var err error
+		if baseline, err = loadBaseline(*baselinePath); err != nil {
+			return fmt.Errorf("loading JUnit baseline: %w", err)
+		}
+	}
+
+	file, err := os.Create(outputPath("benchmark_results.xml"))
+	if err != nil {
+		return fmt.Errorf("creating JUnit file: %w", err)
+	}
+	defer file.Close()
+
+	if err := writeJUnit(file, meta, results, baseline, *regressionThreshold); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// writeJUnit renders each result as a test case. When a baseline is given,
+// cases whose per-op time grew by more than threshold percent are failed.
+func writeJUnit(w io.Writer, meta RunMetadata, results []BenchmarkResult, baseline map[resultKey]BenchmarkResult, threshold float64) error {
+	failed := map[resultKey]regression{}
+	for _, reg := range findRegressions(baseline, results, threshold) {
+		failed[keyOf(reg.New)] = reg
+	}
+
+	suite := junitTestSuite{
+		Name:      "sqlite_benchmark",
+		Timestamp: meta.Timestamp.Format("2006-01-02T15:04:05"),
+		Hostname:  meta.Hostname,
+	}
+
+	for _, r := range results {
+		tc := junitTestCase{
+			ClassName: "sqlite_benchmark." + r.Driver,
+			Name:      fmt.Sprintf("%s_%dBytes", r.Operation, r.DataSize),
+			Time:      r.Duration.Seconds(),
+			SystemOut: fmt.Sprintf("%d ops, %v/op, %.0f ops/s", iterations(r), perOp(r), opsPerSec(r)),
+		}
+
+		if reg, ok := failed[keyOf(r)]; ok {
+			tc.Failure = &junitFailure{
+				Message: fmt.Sprintf("%v/op is %.1f%% slower than baseline %v/op (threshold %.1f%%)", perOp(r), reg.Change, perOp(reg.Old), threshold),
+				Type:    "regression",
+			}
+			suite.Failures++
+		}
+
+		suite.Tests++
+		suite.Time += tc.Time
+		suite.Cases = append(suite.Cases, tc)
+	}
+
+	io.WriteString(w, xml.Header)
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(junitTestSuites{Suites: []junitTestSuite{suite}}); err != nil {
+		return fmt.Errorf("writing JUnit XML: %w", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+func saveResultsToMarkdown(_ RunMetadata, results []BenchmarkResult) error {
+	file, err := os.Create(outputPath("benchmark_results.md"))
+	if err != nil {
+		return fmt.Errorf("creating Markdown file: %w", err)
+	}
+	defer file.Close()
+
+	writeMarkdownTable(file, results)
+	return file.Close()
+}
+
+// writeMarkdownTable renders one row per workload and one column per driver,
+// showing the mean time per operation with the fastest driver in bold.
+func writeMarkdownTable(w io.Writer, results []BenchmarkResult) {
+	t := buildComparisonTable(results)
+
+	fmt.Fprintf(w, "| Workload | %s |\n", strings.Join(t.Drivers, " | "))
+	fmt.Fprintf(w, "|---|%s\n", strings.Repeat("---:|", len(t.Drivers)))
+
+	for _, wl := range t.Workloads {
+		fastest := t.fastest(wl)
+
+		cells := make([]string, len(t.Drivers))
+		for i, driver := range t.Drivers {
+			r, ok := t.Cells[wl][driver]
+			switch {
+			case !ok:
+				cells[i] = "–"
+			case r.TimedOut:
+				cells[i] = "timeout"
+			case driver == fastest:
+				cells[i] = fmt.Sprintf("**%v**", perOp(r))
+			default:
+				cells[i] = fmt.Sprint(perOp(r))
+			}
+		}
+
+		fmt.Fprintf(w, "| %s | %s |\n", wl, strings.Join(cells, " | "))
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// pushgatewayJob is the job label the metrics are grouped under; a new push
+// replaces the metrics of the previous run.
+const pushgatewayJob = "sqlite_benchmark"
+
+// pushToGateway replaces the job's metrics on a Prometheus Pushgateway with
+// the given results.
+func pushToGateway(gatewayURL string, results []BenchmarkResult) error {
+	var body bytes.Buffer
+	writePrometheusMetrics(&body, results)
+
+	url := strings.TrimRight(gatewayURL, "/") + "/metrics/job/" + pushgatewayJob
+	req, err := http.NewRequest(http.MethodPut, url, &body)
+	if err != nil {
+		return fmt.Errorf("creating Pushgateway request: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("pushing metrics: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("push rejected by Pushgateway: %s: %s", resp.Status, msg)
+	}
+	return nil
+}
+
+// writePrometheusMetrics renders results in the Prometheus text exposition
+// format, one gauge family per metric, labeled by driver/operation/size.
+func writePrometheusMetrics(w io.Writer, results []BenchmarkResult) {
+	metrics := []struct {
+		name, help string
+		value      func(BenchmarkResult) float64
+	}{
+		{"sqlite_bench_op_duration_seconds", "Mean time per operation.", func(r BenchmarkResult) float64 {
+			return perOp(r).Seconds()
+		}},
+		{"sqlite_bench_ops_per_second", "Operations per second.", func(r BenchmarkResult) float64 {
+			return opsPerSec(r)
+		}},
+		{"sqlite_bench_allocs_per_op", "Heap allocations per operation.", func(r BenchmarkResult) float64 {
+			return float64(r.Allocs) / float64(iterations(r))
+		}},
+		{"sqlite_bench_alloc_bytes_per_op", "Heap bytes allocated per operation.", func(r BenchmarkResult) float64 {
+			return float64(r.Bytes) / float64(iterations(r))
+		}},
+	}
+
+	for _, m := range metrics {
+		fmt.Fprintf(w, "# HELP %s %s\n", m.name, m.help)
+		fmt.Fprintf(w, "# TYPE %s gauge\n", m.name)
+		for _, r := range results {
+			fmt.Fprintf(w, "%s{driver=%q,operation=%q,data_size=\"%d\"} %g\n", m.name, r.Driver, r.Operation, r.DataSize, m.value(r))
+		}
+	}
+}
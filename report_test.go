@@ -0,0 +1,296 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"sqlite_benchmark/sqlitebench"
+)
+
+func TestFormatSize(t *testing.T) {
+	cases := map[int]string{
+		64:          "64B",
+		1000:        "1000B",
+		4096:        "4KiB",
+		1024 * 1024: "1MiB",
+	}
+	for n, want := range cases {
+		if got := formatSize(n); got != want {
+			t.Errorf("formatSize(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestWriteMarkdownTable(t *testing.T) {
+	results := []BenchmarkResult{
+		{Driver: "modernc", Operation: "write", DataSize: 64, Duration: 2 * time.Millisecond, Samples: make([]time.Duration, 100)},
+		{Driver: "mattn", Operation: "write", DataSize: 64, Duration: time.Millisecond, Samples: make([]time.Duration, 100)},
+	}
+
+	var sb strings.Builder
+	writeMarkdownTable(&sb, results)
+
+	want := "| Workload | mattn | modernc |\n" +
+		"|---|---:|---:|\n" +
+		"| write 64B | **10µs** | 20µs |\n"
+	if sb.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", sb.String(), want)
+	}
+}
+
+func TestWriteBenchstat(t *testing.T) {
+	meta := RunMetadata{GOOS: "linux", GOARCH: "amd64"}
+	results := []BenchmarkResult{
+		{Driver: "mattn", Operation: "read", DataSize: 1024, Duration: 150 * time.Microsecond, Samples: make([]time.Duration, 100)},
+	}
+
+	var sb strings.Builder
+	writeBenchstat(&sb, meta, results)
+
+	if !strings.Contains(sb.String(), "goos: linux\ngoarch: amd64\n") {
+		t.Errorf("missing header:\n%s", sb.String())
+	}
+	if !strings.Contains(sb.String(), "BenchmarkSqlite/mattn_Read_1024Bytes") || !strings.Contains(sb.String(), "      1500 ns/op") {
+		t.Errorf("unexpected benchmark line:\n%s", sb.String())
+	}
+}
+
+func TestPushToGateway(t *testing.T) {
+	var method, path, body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		method, path, body = r.Method, r.URL.Path, string(data)
+	}))
+	defer srv.Close()
+
+	err := pushToGateway(srv.URL, []BenchmarkResult{
+		{Driver: "mattn", Operation: "write", DataSize: 64, Duration: time.Second, Samples: make([]time.Duration, 1000), Allocs: 3000},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if method != http.MethodPut || path != "/metrics/job/sqlite_benchmark" {
+		t.Errorf("got %s %s, want PUT /metrics/job/sqlite_benchmark", method, path)
+	}
+	if !strings.Contains(body, `sqlite_bench_allocs_per_op{driver="mattn",operation="write",data_size="64"} 3`) {
+		t.Errorf("unexpected metrics body:\n%s", body)
+	}
+}
+
+func TestPushToGatewayRejected(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad metrics", http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	if err := pushToGateway(srv.URL, nil); err == nil || !strings.Contains(err.Error(), "bad metrics") {
+		t.Errorf("err = %v, want the Pushgateway's rejection", err)
+	}
+}
+
+func TestWriteInfluxLines(t *testing.T) {
+	meta := RunMetadata{Timestamp: time.Unix(1700000000, 0), Hostname: "bench box"}
+	results := []BenchmarkResult{
+		{Driver: "modernc", Operation: "read", DataSize: 4096, Duration: time.Millisecond, Samples: make([]time.Duration, 100)},
+	}
+
+	var sb strings.Builder
+	writeInfluxLines(&sb, meta, results)
+
+	want := `sqlite_benchmark,driver=modernc,operation=read,data_size=4096,host=bench\ box duration_ns=1000000i,iterations=100i,ns_per_op=10000,ops_per_sec=100000,allocs_per_op=0,bytes_per_op=0 1700000000000000000` + "\n"
+	if sb.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", sb.String(), want)
+	}
+}
+
+func TestWriteJUnitRegression(t *testing.T) {
+	old := BenchmarkResult{Driver: "mattn", Operation: "write", DataSize: 64, Duration: time.Millisecond, Samples: make([]time.Duration, 100)}
+	slow := old
+	slow.Duration = 2 * time.Millisecond
+
+	var sb strings.Builder
+	if err := writeJUnit(&sb, RunMetadata{}, []BenchmarkResult{slow}, indexResults([]BenchmarkResult{old}), 10); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(sb.String(), `tests="1" failures="1"`) {
+		t.Errorf("expected one failing test case:\n%s", sb.String())
+	}
+	if !strings.Contains(sb.String(), `type="regression"`) {
+		t.Errorf("expected regression failure:\n%s", sb.String())
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	results := []BenchmarkResult{
+		{Driver: "mattn", Operation: "write", DataSize: 64, Duration: time.Millisecond, Samples: make([]time.Duration, 100), Allocs: 700, Bytes: 15200},
+	}
+
+	var sb strings.Builder
+	if err := writeCSV(&sb, results); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "driver,operation,data_size_bytes,iterations,duration_ns,ns_per_op,ops_per_sec,allocs_per_op,bytes_per_op,instructions,cache_misses,branch_misses\n" +
+		"mattn,write,64,100,1000000,10000,100000.00,7,152,,,\n"
+	if sb.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", sb.String(), want)
+	}
+}
+
+func TestPrintComparisonTable(t *testing.T) {
+	results := []BenchmarkResult{
+		{Driver: "modernc", Operation: "write", DataSize: 64, Duration: 18 * time.Millisecond, Samples: make([]time.Duration, 1000)},
+		{Driver: "mattn", Operation: "write", DataSize: 64, Duration: 10 * time.Millisecond, Samples: make([]time.Duration, 1000)},
+	}
+
+	var sb strings.Builder
+	printComparisonTable(&sb, results, false)
+
+	want := "Workload   mattn  modernc  Comparison\n" +
+		"write 64B  10µs   18µs     modernc 1.8x slower\n"
+	if sb.String() != want {
+		t.Errorf("got:\n%q\nwant:\n%q", sb.String(), want)
+	}
+}
+
+func TestRunSummary(t *testing.T) {
+	fast := BenchmarkResult{Driver: "mattn", Operation: "write", DataSize: 64, Duration: time.Millisecond, Samples: make([]time.Duration, 10)}
+	slow := BenchmarkResult{Driver: "modernc", Operation: "write", DataSize: 64, Duration: 2 * time.Millisecond, Samples: make([]time.Duration, 10)}
+	old := slow
+	old.Duration = time.Millisecond
+
+	summary := runSummary([]BenchmarkResult{fast, slow}, indexResults([]BenchmarkResult{old}))
+
+	if !strings.Contains(summary, "write: mattn fastest in 1/1 sizes") {
+		t.Errorf("missing winner line:\n%s", summary)
+	}
+	if !strings.Contains(summary, "modernc_Write_64Bytes: 100µs → 200µs (+100.0%)") {
+		t.Errorf("missing regression line:\n%s", summary)
+	}
+}
+
+func TestResultBadge(t *testing.T) {
+	r := BenchmarkResult{Driver: "mattn", Operation: "write", DataSize: 1024 * 1024, Duration: 21 * time.Millisecond, Samples: make([]time.Duration, 100)}
+
+	got := resultBadge(r, true)
+	want := shieldsBadge{SchemaVersion: 1, Label: "mattn write 1MiB", Message: "210µs", Color: "brightgreen"}
+	if got != want {
+		t.Errorf("resultBadge = %+v, want %+v", got, want)
+	}
+}
+
+// recordingReporter records the calls made to it.
+type recordingReporter struct{ calls []string }
+
+func (r *recordingReporter) Start(meta RunMetadata) error {
+	r.calls = append(r.calls, "start")
+	return nil
+}
+func (r *recordingReporter) Report(res BenchmarkResult) error {
+	r.calls = append(r.calls, res.Driver)
+	return nil
+}
+func (r *recordingReporter) Finish() error { r.calls = append(r.calls, "finish"); return nil }
+
+func TestWriteReports(t *testing.T) {
+	rec := &recordingReporter{}
+	reporters["recording"] = func() Reporter { return rec }
+	defer delete(reporters, "recording")
+	outputDir = t.TempDir()
+	defer func() { outputDir = "" }()
+
+	results := []BenchmarkResult{{Driver: "mattn", Operation: "write"}, {Driver: "modernc", Operation: "write"}}
+	err := writeReports([]string{"recording", "nope", "csv"}, RunMetadata{}, results)
+	if err == nil || !strings.Contains(err.Error(), `unknown output format "nope"`) {
+		t.Errorf("err = %v, want the unknown format reported", err)
+	}
+	if want := []string{"start", "mattn", "modernc", "finish"}; !reflect.DeepEqual(rec.calls, want) {
+		t.Errorf("calls = %v, want %v", rec.calls, want)
+	}
+
+	got, err := os.ReadFile(filepath.Join(outputDir, "benchmark_results.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want strings.Builder
+	writeCSV(&want, results)
+	if string(got) != want.String() {
+		t.Errorf("csv reporter wrote:\n%s\nwant:\n%s", got, want.String())
+	}
+}
+
+func TestPrintReplicationOverhead(t *testing.T) {
+	samples := func(d time.Duration) []time.Duration { return []time.Duration{d, d} }
+	results := []BenchmarkResult{
+		{Driver: "mattn", Operation: "write,replication=off", DataSize: 64, Duration: 2 * time.Microsecond, Samples: samples(time.Microsecond)},
+		{Driver: "mattn", Operation: "write,replication=wal-copy", DataSize: 64, Duration: 4 * time.Microsecond, Samples: samples(2 * time.Microsecond)},
+	}
+	var sb strings.Builder
+	printReplicationOverhead(&sb, results)
+	if !strings.Contains(sb.String(), "mattn write,replication=wal-copy 64B  500000  -50.0%  2µs  +100.0%") {
+		t.Errorf("overhead table is\n%s", sb.String())
+	}
+
+	sb.Reset()
+	printReplicationOverhead(&sb, results[:1])
+	if sb.Len() != 0 {
+		t.Errorf("wrote %q for a run without replication", sb.String())
+	}
+}
+
+func TestPrintGrowth(t *testing.T) {
+	results := []BenchmarkResult{
+		{Driver: "mattn", Operation: "write", DataSize: 64},
+		{Driver: "modernc", Operation: "audit", DataSize: 64, Growth: []sqlitebench.SizeSample{
+			{Op: 999, Bytes: 1 << 20, IndexBytes: 1 << 18, IndexPayload: 1 << 17},
+			{Op: 1999, Bytes: 1<<20 + 100_000, FreeBytes: 4096, IndexBytes: 1 << 19, IndexPayload: 3 << 17},
+		}},
+	}
+	var sb strings.Builder
+	printGrowth(&sb, results)
+	want := "modernc audit 64B  2000  1.1MiB  4.0KiB  100B    512.0KiB  75.0%"
+	if !strings.Contains(sb.String(), want) {
+		t.Errorf("growth table is\n%s\nwant a line %q", sb.String(), want)
+	}
+
+	sb.Reset()
+	printGrowth(&sb, results[:1])
+	if sb.Len() != 0 {
+		t.Errorf("wrote %q for a run without samples", sb.String())
+	}
+}
+
+func TestPrintPhaseLatency(t *testing.T) {
+	results := []BenchmarkResult{
+		{Driver: "mattn", Operation: "write", DataSize: 64},
+		{Driver: "mattn", Operation: "sessions", DataSize: 64, Duration: time.Millisecond, Phases: map[string][]time.Duration{
+			"lookup":       {time.Microsecond, time.Microsecond},
+			"lookup.sweep": {3 * time.Microsecond},
+		}},
+	}
+	var sb strings.Builder
+	printPhaseLatency(&sb, results)
+	for _, want := range []string{
+		"mattn sessions 64B  lookup        2    2000   1µs  1µs  -",
+		"mattn sessions 64B  lookup.sweep  1    1000   3µs  3µs  +200.0%",
+	} {
+		if !strings.Contains(sb.String(), want) {
+			t.Errorf("phase table is\n%s\nwant a line %q", sb.String(), want)
+		}
+	}
+
+	sb.Reset()
+	printPhaseLatency(&sb, results[:1])
+	if sb.Len() != 0 {
+		t.Errorf("wrote %q for a run without phases", sb.String())
+	}
+}
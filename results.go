@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"sqlite_benchmark/sqlitebench"
+)
+
+// loadResults reads a results file written by any version of the run
+// subcommand: a JSON report of a supported schema version, a CSV file with
+// its companion .meta.json if that exists, or the CSV of the original
+// go test benchmark, which has per-op averages only.
+func loadResults(path string) JSONReport {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fatal("Failed to open results file", "err", err)
+	}
+
+	var report JSONReport
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		report, err = decodeResultsJSON(data)
+	} else {
+		report.Results, err = decodeResultsCSV(data)
+		if err == nil {
+			report.Metadata, err = loadCSVMetadata(path)
+		}
+	}
+	if err != nil {
+		fatal("Failed to parse results file", "path", path, "err", err)
+	}
+	return report
+}
+
+// decodeResultsJSON decodes a JSON report, refusing versions newer than
+// this build understands. Older versions are upgraded by upgradeReport.
+func decodeResultsJSON(data []byte) (JSONReport, error) {
+	var version struct {
+		SchemaVersion int `json:"schema_version"`
+	}
+	if err := json.Unmarshal(data, &version); err != nil {
+		return JSONReport{}, err
+	}
+	switch {
+	case version.SchemaVersion == 0:
+		return JSONReport{}, errors.New("not a results report: no schema_version")
+	case version.SchemaVersion > jsonSchemaVersion:
+		return JSONReport{}, fmt.Errorf("schema version %d is newer than this build reads (up to %d)", version.SchemaVersion, jsonSchemaVersion)
+	}
+
+	var report JSONReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return JSONReport{}, err
+	}
+	return upgradeReport(report)
+}
+
+// upgradeReport brings a report of an older schema version up to the
+// current one. Version 1 is the only version so far; when a field is renamed
+// or removed, jsonSchemaVersion is bumped and the old field mapped here.
+func upgradeReport(report JSONReport) (JSONReport, error) {
+	report.SchemaVersion = jsonSchemaVersion
+	return report, nil
+}
+
+// legacyCSVHeader is the header of the CSV written by the original go test
+// benchmark, before the run subcommand existed.
+var legacyCSVHeader = []string{"Benchmark", "ns/op", "B/op", "allocs/op"}
+
+// legacyBenchmarkName matches its benchmark names, e.g.
+// "modernc_Write_64Bytes".
+var legacyBenchmarkName = regexp.MustCompile(`^([^_]+)_([^_]+)_(\d+)Bytes$`)
+
+// decodeResultsCSV decodes the current CSV format or the legacy one. The
+// CSV formats keep no samples, so results carry their iteration count
+// instead.
+func decodeResultsCSV(data []byte) ([]BenchmarkResult, error) {
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, errors.New("empty CSV file")
+	}
+
+	var parse func([]string) (BenchmarkResult, error)
+	switch header := records[0]; {
+	case slices.Equal(header, csvHeader):
+		parse = parseCSVRecord
+	case slices.Equal(header, legacyCSVHeader):
+		parse = parseLegacyCSVRecord
+	default:
+		return nil, fmt.Errorf("unknown CSV header %q", strings.Join(header, ","))
+	}
+
+	results := make([]BenchmarkResult, 0, len(records)-1)
+	for i, record := range records[1:] {
+		r, err := parse(record)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+2, err)
+		}
+		results = append(results, r)
+	}
+	return results, nil
+}
+
+// parseCSVRecord parses a row written by writeCSV.
+func parseCSVRecord(record []string) (BenchmarkResult, error) {
+	var ints [12]int64
+	for i, field := range record {
+		switch i {
+		case 0, 1, 6:
+			// Text, or derived from the other columns.
+			continue
+		}
+		if field == "" {
+			continue
+		}
+		n, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			return BenchmarkResult{}, fmt.Errorf("%s: %w", csvHeader[i], err)
+		}
+		ints[i] = n
+	}
+
+	n := max(ints[3], 1)
+	r := BenchmarkResult{
+		Driver:     record[0],
+		Operation:  record[1],
+		DataSize:   int(ints[2]),
+		Iterations: int(n),
+		Duration:   time.Duration(ints[4]),
+		Allocs:     uint64(ints[7] * n),
+		Bytes:      uint64(ints[8] * n),
+	}
+	if record[9] != "" {
+		r.Counters = &sqlitebench.PerfCounters{
+			Instructions: uint64(ints[9]),
+			CacheMisses:  uint64(ints[10]),
+			BranchMisses: uint64(ints[11]),
+		}
+	}
+	return r, nil
+}
+
+// parseLegacyCSVRecord parses a row of the original benchmark CSV, which
+// has averages of an unknown number of operations; it becomes a result of
+// one operation.
+func parseLegacyCSVRecord(record []string) (BenchmarkResult, error) {
+	m := legacyBenchmarkName.FindStringSubmatch(record[0])
+	if m == nil {
+		return BenchmarkResult{}, fmt.Errorf("unrecognized benchmark name %q", record[0])
+	}
+	var ints [3]int64
+	for i, field := range record[1:] {
+		n, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			return BenchmarkResult{}, fmt.Errorf("%s: %w", legacyCSVHeader[i+1], err)
+		}
+		ints[i] = n
+	}
+
+	size, _ := strconv.Atoi(m[3])
+	return BenchmarkResult{
+		Driver:     m[1],
+		Operation:  strings.ToLower(m[2]),
+		DataSize:   size,
+		Iterations: 1,
+		Duration:   time.Duration(ints[0]),
+		Bytes:      uint64(ints[1]),
+		Allocs:     uint64(ints[2]),
+	}, nil
+}
+
+// loadCSVMetadata reads the metadata saved next to a CSV results file,
+// which older versions did not write.
+func loadCSVMetadata(path string) (RunMetadata, error) {
+	var meta RunMetadata
+	data, err := os.ReadFile(strings.TrimSuffix(path, ".csv") + ".meta.json")
+	if errors.Is(err, os.ErrNotExist) {
+		return meta, nil
+	}
+	if err != nil {
+		return meta, err
+	}
+	return meta, json.Unmarshal(data, &meta)
+}
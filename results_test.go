@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDecodeResultsCSV(t *testing.T) {
+	want := []BenchmarkResult{
+		{Driver: "mattn", Operation: "write", DataSize: 64, Duration: 4 * time.Millisecond, Iterations: 4, Allocs: 8, Bytes: 400},
+	}
+	var buf bytes.Buffer
+	if err := writeCSV(&buf, want); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := decodeResultsCSV(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Duration != want[0].Duration || iterations(got[0]) != 4 || got[0].Allocs != 8 || got[0].Bytes != 400 {
+		t.Errorf("decoded %+v, want %+v", got, want)
+	}
+}
+
+func TestDecodeLegacyCSV(t *testing.T) {
+	got, err := decodeResultsCSV([]byte("Benchmark,ns/op,B/op,allocs/op\nmodernc_Write_64Bytes,38475,152,7\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Driver != "modernc" || got[0].Operation != "write" || got[0].DataSize != 64 || perOp(got[0]) != 38475 {
+		t.Errorf("decoded %+v, want modernc write 64B at 38475ns/op", got)
+	}
+}
+
+func TestDecodeResultsJSONVersion(t *testing.T) {
+	if _, err := decodeResultsJSON([]byte(`{"schema_version": 1, "results": [{"driver": "mattn"}]}`)); err != nil {
+		t.Errorf("version 1: %v", err)
+	}
+	for _, doc := range []string{`{"results": []}`, `{"schema_version": 99}`} {
+		if _, err := decodeResultsJSON([]byte(doc)); err == nil {
+			t.Errorf("decoding %s: expected an error", doc)
+		} else if !strings.Contains(err.Error(), "schema_version") && !strings.Contains(err.Error(), "newer") {
+			t.Errorf("decoding %s: unexpected error %v", doc, err)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+
+	"sqlite_benchmark/sqlitebench"
+)
+
+var scenarioFlag = flag.String("scenario", "", "comma-separated scenario files to run instead of the built-in workloads")
+
+// loadScenario reads a scenario file, YAML or TOML by extension like
+// config files. A scenario without a name is named after its file.
+func loadScenario(path string) (*sqlitebench.Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var s sqlitebench.Scenario
+	if strings.EqualFold(filepath.Ext(path), ".toml") {
+		err = toml.Unmarshal(data, &s)
+	} else {
+		err = yaml.Unmarshal(data, &s)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	if s.Name == "" {
+		s.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	if err := s.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &s, nil
+}
@@ -0,0 +1,10 @@
+-- Example schema for the schema option in config.example.yaml.
+CREATE TABLE orders (
+    id        INTEGER PRIMARY KEY,
+    customer  INTEGER NOT NULL,
+    email     TEXT NOT NULL,
+    placed_at TEXT NOT NULL,
+    total     REAL NOT NULL,
+    details   TEXT
+);
+CREATE INDEX orders_customer ON orders (customer);
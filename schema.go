@@ -0,0 +1,164 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+
+	"sqlite_benchmark/sqlitebench"
+)
+
+// SchemaConfig points the generic insert, read and update workloads at the
+// user's own table.
+type SchemaConfig struct {
+	// File holds the statements creating the schema, e.g. schema.sql.
+	File string `yaml:"file" toml:"file" json:"file"`
+	// Table is the table the workloads use.
+	Table string `yaml:"table" toml:"table" json:"table"`
+	// Columns maps each column to fill to a generator expression.
+	Columns map[string]string `yaml:"columns" toml:"columns" json:"columns"`
+}
+
+// load reads the schema file and checks the column generators.
+func (sc *SchemaConfig) load() (*sqlitebench.Schema, error) {
+	data, err := os.ReadFile(sc.File)
+	if err != nil {
+		return nil, err
+	}
+	s := &sqlitebench.Schema{SQL: string(data), Table: sc.Table, Columns: sc.Columns}
+	if err := s.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", sc.File, err)
+	}
+	return s, nil
+}
+
+// ExtensionConfig names a compiled SQLite extension to measure; see
+// sqlitebench.Extension.
+type ExtensionConfig struct {
+	// Path is the shared library, e.g. ./libhalf.so.
+	Path string `yaml:"path" toml:"path" json:"path"`
+	// Entry is its entry point, by default derived from the file name.
+	Entry string `yaml:"entry" toml:"entry" json:"entry,omitempty"`
+	// Call is a statement calling one of its functions, e.g.
+	// "SELECT half(?)", bound to the operation number.
+	Call string `yaml:"call" toml:"call" json:"call,omitempty"`
+}
+
+// VectorConfig names the sqlite-vec extension the vector workloads load;
+// see sqlitebench.Vector.
+type VectorConfig struct {
+	// Path is the shared library, e.g. ./vec0.so.
+	Path string `yaml:"path" toml:"path" json:"path"`
+	// Entry is its entry point, by default derived from the file name.
+	Entry string `yaml:"entry" toml:"entry" json:"entry,omitempty"`
+	// Dimensions is the length of the embeddings, 384 if left out.
+	Dimensions int `yaml:"dimensions" toml:"dimensions" json:"dimensions,omitempty"`
+}
+
+// KVMixConfig sets the mix of the kv.mix workload; see sqlitebench.KVMix.
+// Weights left out are 0, unless all are, which keeps the default mix.
+type KVMixConfig struct {
+	Get    int `yaml:"get" toml:"get" json:"get"`
+	Put    int `yaml:"put" toml:"put" json:"put"`
+	Delete int `yaml:"delete" toml:"delete" json:"delete"`
+	// Skew is the zipfian exponent, 1.1 if left out.
+	Skew float64 `yaml:"skew" toml:"skew" json:"skew,omitempty"`
+}
+
+// apply sets the mix of w.
+func (mc *KVMixConfig) apply(w *sqlitebench.KVMix) {
+	if mc.Get+mc.Put+mc.Delete != 0 {
+		w.Gets, w.Puts, w.Deletes = mc.Get, mc.Put, mc.Delete
+	}
+	if mc.Skew != 0 {
+		w.Skew = mc.Skew
+	}
+}
+
+// CacheConfig sets the cache workload's misses; see sqlitebench.Cache.
+type CacheConfig struct {
+	// MissRatio is the share of lookups missing, 0.1 if left out.
+	MissRatio *float64 `yaml:"miss_ratio" toml:"miss_ratio" json:"miss_ratio,omitempty"`
+}
+
+// apply sets the misses of w.
+func (cc *CacheConfig) apply(w *sqlitebench.Cache) {
+	if cc.MissRatio != nil {
+		w.MissRatio = *cc.MissRatio
+	}
+}
+
+// GraphConfig sets the graph of the graph workloads; see
+// sqlitebench.Graph.
+type GraphConfig struct {
+	// FanOut is the number of edges per node, 8 if left out.
+	FanOut int `yaml:"fan_out" toml:"fan_out" json:"fan_out,omitempty"`
+	// Hops is how far graph.khop reaches, 3 if left out.
+	Hops int `yaml:"hops" toml:"hops" json:"hops,omitempty"`
+}
+
+// apply sets the graph of w.
+func (gc *GraphConfig) apply(w *sqlitebench.Graph) {
+	if gc.FanOut != 0 {
+		w.FanOut = gc.FanOut
+	}
+	if gc.Hops != 0 {
+		w.Hops = gc.Hops
+	}
+}
+
+// FilesConfig sets the sizes of the files workloads' files; see
+// sqlitebench.Files.
+type FilesConfig struct {
+	// FileSize is the size of a file in bytes, 4 MiB if left out.
+	FileSize int `yaml:"file_size" toml:"file_size" json:"file_size,omitempty"`
+	// ChunkSize is the size of files.chunked's chunks in bytes, 256 KiB if
+	// left out.
+	ChunkSize int `yaml:"chunk_size" toml:"chunk_size" json:"chunk_size,omitempty"`
+}
+
+// apply sets the sizes of w.
+func (fc *FilesConfig) apply(w *sqlitebench.Files) {
+	if fc.FileSize != 0 {
+		w.FileSize = fc.FileSize
+	}
+	if fc.ChunkSize != 0 {
+		w.ChunkSize = fc.ChunkSize
+	}
+}
+
+// extraWorkloads loads the workloads the config defines in files: one per
+// scenario, those of the user schema, those of the extension and those of
+// the vector extension.
+func (c Config) extraWorkloads() ([]sqlitebench.Workload, error) {
+	var workloads []sqlitebench.Workload
+	for _, path := range c.Scenarios {
+		s, err := loadScenario(path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid scenario: %w", err)
+		}
+		workloads = append(workloads, s.Workload())
+	}
+	if c.Schema != nil {
+		s, err := c.Schema.load()
+		if err != nil {
+			return nil, fmt.Errorf("invalid schema: %w", err)
+		}
+		workloads = append(workloads, s.Workloads()...)
+	}
+	if c.Extension != nil {
+		e := &sqlitebench.Extension{Path: c.Extension.Path, Entry: c.Extension.Entry, Call: c.Extension.Call}
+		if err := e.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid extension: %w", err)
+		}
+		workloads = append(workloads, e.Workloads()...)
+	}
+	if c.Vector != nil {
+		v := &sqlitebench.Vector{Path: c.Vector.Path, Entry: c.Vector.Entry, Dimensions: cmp.Or(c.Vector.Dimensions, 384)}
+		if err := v.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid vector: %w", err)
+		}
+		workloads = append(workloads, v.Workloads()...)
+	}
+	return workloads, nil
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gonum.org/v1/plot/vg"
+)
+
+// resultFilter selects stored results by the dashboard's query parameters.
+// Zero values match everything.
+type resultFilter struct {
+	Driver    string
+	Operation string
+	DataSize  int
+	RunID     int64
+	// Tags selects runs by tag; each ?tag= parameter is key=value or a
+	// bare label.
+	Tags tagSet
+}
+
+func parseResultFilter(r *http.Request) resultFilter {
+	q := r.URL.Query()
+	size, _ := strconv.Atoi(q.Get("size"))
+	run, _ := strconv.ParseInt(q.Get("run"), 10, 64)
+	tags := tagSet{}
+	for _, tag := range q["tag"] {
+		key, value, _ := strings.Cut(tag, "=")
+		tags[key] = value
+	}
+	return resultFilter{
+		Driver:    q.Get("driver"),
+		Operation: q.Get("operation"),
+		DataSize:  size,
+		RunID:     run,
+		Tags:      tags,
+	}
+}
+
+func (f resultFilter) match(p historyPoint) bool {
+	return (f.Driver == "" || p.Key.Driver == f.Driver) &&
+		(f.Operation == "" || p.Key.Operation == f.Operation) &&
+		(f.DataSize == 0 || p.Key.DataSize == f.DataSize) &&
+		(f.RunID == 0 || p.RunID == f.RunID) &&
+		p.Tags.match(f.Tags)
+}
+
+func (f resultFilter) apply(points []historyPoint) []historyPoint {
+	var matched []historyPoint
+	for _, p := range points {
+		if f.match(p) {
+			matched = append(matched, p)
+		}
+	}
+	return matched
+}
+
+// runServe implements the serve subcommand, a read-only web UI over the
+// results store.
+func runServe(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", "localhost:8080", "address to listen on")
+	dbPath := fs.String("db", "results.db", "results store to serve")
+	fs.Parse(args)
+
+	db, err := openResultsStore(*dbPath)
+	if err != nil {
+		fatal("Failed to open results store", "err", err)
+	}
+	defer db.Close()
+
+	slog.Info("Serving results", "db", *dbPath, "addr", "http://"+*addr)
+	fatal("Server stopped", "err", http.ListenAndServe(*addr, newDashboardHandler(db)))
+}
+
+func newDashboardHandler(db *sql.DB) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+
+		points, err := loadHistory(db)
+		if err != nil {
+			slog.Error("Failed to load history", "err", err)
+			http.Error(w, "failed to load history", http.StatusInternalServerError)
+			return
+		}
+		filter := parseResultFilter(r)
+
+		data := dashboardPage{
+			Filter:  filter,
+			Query:   template.URL(r.URL.RawQuery),
+			Points:  filter.apply(points),
+			Drivers: distinct(points, func(p historyPoint) string { return p.Key.Driver }),
+			Ops:     distinct(points, func(p historyPoint) string { return p.Key.Operation }),
+		}
+		for _, size := range distinct(points, func(p historyPoint) string { return strconv.Itoa(p.Key.DataSize) }) {
+			n, _ := strconv.Atoi(size)
+			data.Sizes = append(data.Sizes, n)
+		}
+		sort.Ints(data.Sizes)
+		for _, run := range distinct(points, func(p historyPoint) string { return strconv.FormatInt(p.RunID, 10) }) {
+			n, _ := strconv.ParseInt(run, 10, 64)
+			data.Runs = append(data.Runs, n)
+		}
+		sort.Slice(data.Runs, func(i, j int) bool { return data.Runs[i] > data.Runs[j] })
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := dashboardTemplate.Execute(w, data); err != nil {
+			slog.Warn("Failed to render dashboard", "err", err)
+		}
+	})
+
+	mux.HandleFunc("/chart.svg", func(w http.ResponseWriter, r *http.Request) {
+		points, err := loadHistory(db)
+		if err != nil {
+			slog.Error("Failed to load history", "err", err)
+			http.Error(w, "failed to load history", http.StatusInternalServerError)
+			return
+		}
+		points = parseResultFilter(r).apply(points)
+		if len(points) == 0 {
+			http.Error(w, "no matching results", http.StatusNotFound)
+			return
+		}
+
+		keys, series := groupHistory(points)
+		p := newTrendPlot("time per operation over time", keys, series)
+
+		wt, err := p.WriterTo(10*vg.Inch, 5*vg.Inch, "svg")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "image/svg+xml")
+		wt.WriteTo(w)
+	})
+
+	return mux
+}
+
+// distinct returns the sorted unique values of field across points.
+func distinct(points []historyPoint, field func(historyPoint) string) []string {
+	seen := map[string]bool{}
+	var values []string
+	for _, p := range points {
+		v := field(p)
+		if !seen[v] {
+			seen[v] = true
+			values = append(values, v)
+		}
+	}
+	sort.Strings(values)
+	return values
+}
+
+type dashboardPage struct {
+	Filter  resultFilter
+	Query   template.URL
+	Points  []historyPoint
+	Drivers []string
+	Ops     []string
+	Sizes   []int
+	Runs    []int64
+}
+
+var dashboardTemplate = template.Must(template.New("dashboard").Funcs(template.FuncMap{
+	"size": formatSize,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>SQLite driver benchmarks</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+th, td { padding: 0.2em 0.8em; text-align: right; border-bottom: 1px solid #ddd; }
+th:first-child, td:first-child { text-align: left; }
+form { margin-bottom: 1em; }
+</style>
+</head>
+<body>
+<h1>SQLite driver benchmarks</h1>
+<form method="get">
+<label>Driver <select name="driver"><option value="">all</option>
+{{range .Drivers}}<option{{if eq . $.Filter.Driver}} selected{{end}}>{{.}}</option>{{end}}
+</select></label>
+<label>Workload <select name="operation"><option value="">all</option>
+{{range .Ops}}<option{{if eq . $.Filter.Operation}} selected{{end}}>{{.}}</option>{{end}}
+</select></label>
+<label>Size <select name="size"><option value="">all</option>
+{{range .Sizes}}<option value="{{.}}"{{if eq . $.Filter.DataSize}} selected{{end}}>{{size .}}</option>{{end}}
+</select></label>
+<label>Run <select name="run"><option value="">all</option>
+{{range .Runs}}<option{{if eq . $.Filter.RunID}} selected{{end}}>{{.}}</option>{{end}}
+</select></label>
+<button type="submit">Filter</button>
+</form>
+{{if .Points}}
+<img src="/chart.svg?{{.Query}}" alt="time per operation over time">
+<table>
+<tr><th>Run</th><th>Started</th><th>Driver</th><th>Workload</th><th>Size</th><th>Time/op</th></tr>
+{{range .Points}}<tr><td>{{.RunID}}</td><td>{{.Time.Format "2006-01-02 15:04"}}</td><td>{{.Key.Driver}}</td><td>{{.Key.Operation}}</td><td>{{size .Key.DataSize}}</td><td>{{.PerOp}}</td></tr>
+{{end}}
+</table>
+{{else}}
+<p>No results match the current filters.</p>
+{{end}}
+</body>
+</html>
+`))
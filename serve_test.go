@@ -0,0 +1,63 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDashboardHandler(t *testing.T) {
+	db := openTestStore(t)
+	storeRun(t, db, RunMetadata{Timestamp: time.Now(), Modules: map[string]string{}}, []BenchmarkResult{
+		{Driver: "mattn", Operation: "write", DataSize: 64, Duration: time.Millisecond, Samples: make([]time.Duration, 10)},
+		{Driver: "modernc", Operation: "write", DataSize: 64, Duration: 2 * time.Millisecond, Samples: make([]time.Duration, 10)},
+	})
+
+	srv := httptest.NewServer(newDashboardHandler(db))
+	defer srv.Close()
+
+	resp, err := srv.Client().Get(srv.URL + "/?driver=mattn&size=64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	page := string(body)
+	if !strings.Contains(page, `src="/chart.svg?driver=mattn&amp;size=64"`) {
+		t.Errorf("chart link missing or mis-escaped:\n%s", page)
+	}
+	if strings.Contains(page, "<td>modernc</td>") {
+		t.Errorf("driver filter not applied:\n%s", page)
+	}
+
+	resp, err = srv.Client().Get(srv.URL + "/chart.svg?driver=mattn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "image/svg+xml" {
+		t.Errorf("chart content type = %q", ct)
+	}
+}
+
+func TestDashboardHandlerStoreError(t *testing.T) {
+	db := openTestStore(t)
+	srv := httptest.NewServer(newDashboardHandler(db))
+	defer srv.Close()
+	db.Close()
+
+	for _, path := range []string{"/", "/chart.svg"} {
+		resp, err := srv.Client().Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusInternalServerError {
+			t.Errorf("%s status = %d, want %d", path, resp.StatusCode, http.StatusInternalServerError)
+		}
+	}
+}
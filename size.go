@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// sizeUnits are the accepted size suffixes, all binary multiples. "M", "MB"
+// and "MiB" all mean 1<<20, matching how SQLite and most tools quote blob
+// sizes.
+var sizeUnits = []struct {
+	suffixes []string
+	factor   int
+}{
+	{[]string{"GiB", "GB", "G"}, 1 << 30},
+	{[]string{"MiB", "MB", "M"}, 1 << 20},
+	{[]string{"KiB", "KB", "K"}, 1 << 10},
+	{[]string{"B"}, 1},
+}
+
+// parseSize parses a byte count like "64", "4K", "1MiB" or "16M".
+func parseSize(s string) (int, error) {
+	s = strings.TrimSpace(s)
+	factor := 1
+	number := s
+
+outer:
+	for _, unit := range sizeUnits {
+		for _, suffix := range unit.suffixes {
+			if len(s) > len(suffix) && strings.EqualFold(s[len(s)-len(suffix):], suffix) {
+				factor = unit.factor
+				number = strings.TrimSpace(s[:len(s)-len(suffix)])
+				break outer
+			}
+		}
+	}
+
+	n, err := strconv.Atoi(number)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return n * factor, nil
+}
+
+// parseSizes parses a comma-separated list of sizes.
+func parseSizes(s string) ([]int, error) {
+	var sizes []int
+	for _, part := range strings.Split(s, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		n, err := parseSize(part)
+		if err != nil {
+			return nil, err
+		}
+		sizes = append(sizes, n)
+	}
+	if len(sizes) == 0 {
+		return nil, fmt.Errorf("no sizes in %q", s)
+	}
+	return sizes, nil
+}
+
+// SizeList is a list of byte sizes that config files may write either as
+// integers or as strings with suffixes, e.g. [64, "4K", "16M"].
+type SizeList []int
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (l *SizeList) UnmarshalYAML(value *yaml.Node) error {
+	var raw []any
+	if err := value.Decode(&raw); err != nil {
+		return err
+	}
+	return l.fromValues(raw)
+}
+
+// UnmarshalTOML implements toml.Unmarshaler.
+func (l *SizeList) UnmarshalTOML(v any) error {
+	raw, ok := v.([]any)
+	if !ok {
+		return fmt.Errorf("sizes must be a list, got %T", v)
+	}
+	return l.fromValues(raw)
+}
+
+func (l *SizeList) fromValues(raw []any) error {
+	sizes := make(SizeList, 0, len(raw))
+	for _, v := range raw {
+		switch v := v.(type) {
+		case int:
+			sizes = append(sizes, v)
+		case int64:
+			sizes = append(sizes, int(v))
+		case string:
+			n, err := parseSize(v)
+			if err != nil {
+				return err
+			}
+			sizes = append(sizes, n)
+		default:
+			return fmt.Errorf("invalid size %v", v)
+		}
+	}
+	*l = sizes
+	return nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSize(t *testing.T) {
+	cases := map[string]int{
+		"64":    64,
+		"4K":    4096,
+		"4kb":   4096,
+		"1MiB":  1 << 20,
+		"16M":   16 << 20,
+		"2 GB":  2 << 30,
+		" 256 ": 256,
+		"100B":  100,
+	}
+	for in, want := range cases {
+		got, err := parseSize(in)
+		if err != nil || got != want {
+			t.Errorf("parseSize(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+
+	for _, in := range []string{"", "M", "1.5M", "-1", "12X"} {
+		if _, err := parseSize(in); err == nil {
+			t.Errorf("parseSize(%q) succeeded, want error", in)
+		}
+	}
+}
+
+func TestParseSizes(t *testing.T) {
+	got, err := parseSizes("64,4096,1M,16M")
+	want := []int{64, 4096, 1 << 20, 16 << 20}
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("parseSizes = %v, %v; want %v", got, err, want)
+	}
+}
+
+func TestConfigSizeSuffixes(t *testing.T) {
+	yamlPath := writeTempFile(t, "sizes.yaml", `sizes: [64, "4K", "16M"]`)
+	tomlPath := writeTempFile(t, "sizes.toml", `sizes = [64, "4K", "16M"]`)
+
+	want := SizeList{64, 4096, 16 << 20}
+	for _, path := range []string{yamlPath, tomlPath} {
+		cfg, err := loadConfig(path)
+		if err != nil {
+			t.Fatalf("loadConfig: %v", err)
+		}
+		if !reflect.DeepEqual(cfg.Sizes, want) {
+			t.Errorf("sizes = %v, want %v", cfg.Sizes, want)
+		}
+	}
+}
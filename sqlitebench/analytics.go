@@ -0,0 +1,112 @@
+package sqlitebench
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+)
+
+func init() {
+	for _, q := range analyticalQueries {
+		Register(Benchmark{Category: CategoryAnalytical, New: func() Workload { return &Analytical{query: q} }})
+	}
+}
+
+// analyticsDims is the number of rows in the dimension table the facts
+// table refers to.
+const analyticsDims = 100
+
+// analyticsSchema is understood by SQLite and by the OLAP backends.
+var analyticsSchema = []string{
+	"CREATE TABLE dims (id INTEGER PRIMARY KEY, name TEXT)",
+	"CREATE TABLE facts (id INTEGER PRIMARY KEY, dim INTEGER, value DOUBLE, data BLOB)",
+}
+
+// analyticalQuery is one analytical workload: a read-only query over the
+// whole facts table.
+type analyticalQuery struct {
+	name        string
+	description string
+	sql         string
+	// columns is the number of columns sql selects.
+	columns int
+}
+
+var analyticalQueries = []analyticalQuery{
+	{
+		name:        "scan",
+		description: "filter and count the whole facts table per operation",
+		sql:         "SELECT count(*), sum(value) FROM facts WHERE value > 0.5",
+		columns:     2,
+	},
+	{
+		name:        "aggregate",
+		description: "group the facts table by dimension per operation",
+		sql:         "SELECT dim, count(*), avg(value), max(value) FROM facts GROUP BY dim",
+		columns:     4,
+	},
+	{
+		name:        "join",
+		description: "join facts to dimensions and rank the top ten per operation",
+		sql:         "SELECT d.name, sum(f.value) AS total FROM facts f JOIN dims d ON d.id = f.dim GROUP BY d.name ORDER BY total DESC LIMIT 10",
+		columns:     2,
+	},
+}
+
+// Analytical runs one of the analytical queries over a facts table of
+// Params.Rows rows, each carrying a payload, joined to a small dimension
+// table. It measures SQLite on the work embedded OLAP databases such as
+// DuckDB are built for, and runs on the backends in OLAPBackends too.
+type Analytical struct {
+	query analyticalQuery
+}
+
+func (a *Analytical) Name() string        { return a.query.name }
+func (a *Analytical) Description() string { return a.query.description }
+
+func (a *Analytical) Setup(ctx context.Context, db Conn, p Params) error {
+	for _, stmt := range analyticsSchema {
+		if err := db.Exec(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	for i := 1; i <= analyticsDims; i++ {
+		if err := db.Exec(ctx, "INSERT INTO dims (id, name) VALUES (?, ?)", i, fmt.Sprintf("dim%03d", i)); err != nil {
+			return err
+		}
+	}
+
+	rng := rand.New(rand.NewPCG(uint64(p.Seed), uint64(p.Rows)))
+	return populate(ctx, db, p.Rows, func(tx Tx, i int) error {
+		return tx.Exec(ctx, "INSERT INTO facts (id, dim, value, data) VALUES (?, ?, ?, ?)",
+			i+1, rng.IntN(analyticsDims)+1, rng.Float64(), p.Payloads.Next())
+	})
+}
+
+func (a *Analytical) Run(ctx context.Context, db Conn, n int) error {
+	return runAnalytical(ctx, db, a.query)
+}
+
+// runAnalytical runs q and reads every row of the result, so lazily
+// evaluating backends do the whole query.
+func runAnalytical(ctx context.Context, db Conn, q analyticalQuery) error {
+	rows, err := db.Query(ctx, q.sql)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var cols [5]any
+	dest := []any{&cols[0], &cols[1], &cols[2], &cols[3], &cols[4]}
+	for rows.Next() {
+		if err := rows.Scan(dest[:q.columns]...); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	return rows.Close()
+}
+
+func (*Analytical) Teardown(db Conn) error { return nil }
@@ -0,0 +1,48 @@
+package sqlitebench
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestAnalyticalOLAP(t *testing.T) {
+	// modernc stands in for an OLAP backend; the empty DSN gives it a
+	// private temporary database.
+	OLAPBackends["olap"] = SQLBackend{"sqlite"}
+	t.Cleanup(func() { delete(OLAPBackends, "olap") })
+
+	r := NewRunner()
+	r.Drivers, r.Sizes, r.Ops, r.Rows = []string{"modernc"}, []int{64}, 3, 50
+	r.OLAP = []string{"olap"}
+	r.Synchronous = []string{"off", "normal"}
+	r.Add(&Write{})
+	for _, b := range []string{"scan", "aggregate", "join"} {
+		r.Add(Workloads[b].New())
+	}
+
+	results, err := r.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, res := range results {
+		names = append(names, res.Driver+"/"+res.Operation)
+	}
+	want := []string{
+		"modernc/write,synchronous=off",
+		"modernc/write,synchronous=normal",
+		"modernc/scan,synchronous=off",
+		"modernc/scan,synchronous=normal",
+		"modernc/aggregate,synchronous=off",
+		"modernc/aggregate,synchronous=normal",
+		"modernc/join,synchronous=off",
+		"modernc/join,synchronous=normal",
+		"olap/scan",
+		"olap/aggregate",
+		"olap/join",
+	}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("results = %v, want %v", names, want)
+	}
+}
@@ -0,0 +1,109 @@
+package sqlitebench
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+func init() {
+	Register(Benchmark{Category: CategoryWrite, New: func() Workload { return &Audit{} }})
+}
+
+const (
+	// auditEntities is the number of entities events are about.
+	auditEntities = 1000
+	// auditReadEvery makes every auditReadEvery-th operation read the
+	// latest events of an entity instead of appending one.
+	auditReadEvery = 10
+	// auditSampleEvery makes every auditSampleEvery-th operation sample
+	// the size of the database after its read or append.
+	auditSampleEvery = 1000
+	// auditLatest is the number of events an entity's read returns.
+	auditLatest = 20
+)
+
+// auditActions are the actions of the events, by event number.
+var auditActions = []string{"create", "update", "update", "update", "read", "delete"}
+
+// Audit models an append-only audit log: operations append an event with
+// a strictly increasing timestamp, about a random one of auditEntities
+// entities and with the payload as its details, to a table indexed by
+// entity and time, and every tenth reads the auditLatest latest events of
+// an entity, as an activity page does. Their times are kept in the
+// "append" and "read" phases. Setup appends Params.Rows events first.
+//
+// The rowid grows at the end of its b-tree, but the entity index takes
+// inserts all over, so its pages split half full. Every thousandth
+// operation samples the size of the database and of the index, which
+// Result.Growth keeps; the index's payload against its pages shows how
+// bloated it is. The sampling is part of that operation's time but of no
+// phase's.
+type Audit struct {
+	phaseTimes
+	rowBytes
+	sizeSamples
+
+	payloads *PayloadPool
+	// events numbers the events appended; event n happened at time n.
+	events atomic.Int64
+	rng    lockedRand
+}
+
+func (*Audit) Name() string { return "audit" }
+func (*Audit) Description() string {
+	return "append audit events and read an entity's latest, sampling the database's growth"
+}
+
+func (w *Audit) Setup(ctx context.Context, db Conn, p Params) error {
+	w.reset()
+	w.payloads = p.Payloads
+	w.events.Store(0)
+	w.rng.seed(p)
+	for _, stmt := range []string{
+		"CREATE TABLE audit (id INTEGER PRIMARY KEY, ts INTEGER NOT NULL, entity INTEGER NOT NULL, action TEXT NOT NULL, details BLOB)",
+		"CREATE INDEX audit_entity ON audit (entity, ts)",
+	} {
+		if err := db.Exec(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	w.probe(ctx, db)
+	return populate(ctx, db, p.Rows, func(tx Tx, i int) error {
+		return w.append(ctx, tx.Exec)
+	})
+}
+
+// entity picks a random entity.
+func (w *Audit) entity() int {
+	return w.rng.intN(auditEntities) + 1
+}
+
+// append appends the next event with exec.
+func (w *Audit) append(ctx context.Context, exec func(ctx context.Context, query string, args ...any) error) error {
+	n := w.events.Add(1) - 1
+	return exec(ctx, "INSERT INTO audit (ts, entity, action, details) VALUES (?, ?, ?, ?)",
+		n, w.entity(), auditActions[n%int64(len(auditActions))], w.payloads.Next())
+}
+
+func (w *Audit) Run(ctx context.Context, db Conn, n int) error {
+	start := time.Now()
+	if n%auditReadEvery == auditReadEvery-1 {
+		if err := runStatement(ctx, db, fmt.Sprintf("SELECT id, ts, action, details FROM audit WHERE entity = ? ORDER BY ts DESC LIMIT %d", auditLatest), []any{w.entity()}, &w.rowBytes); err != nil {
+			return err
+		}
+		w.record("read", time.Since(start))
+	} else {
+		if err := w.append(ctx, db.Exec); err != nil {
+			return err
+		}
+		w.record("append", time.Since(start))
+	}
+	if n%auditSampleEvery != auditSampleEvery-1 {
+		return nil
+	}
+	return w.sample(ctx, db, n, "audit_entity")
+}
+
+func (*Audit) Teardown(db Conn) error { return nil }
@@ -0,0 +1,37 @@
+package sqlitebench
+
+import (
+	"context"
+	"testing"
+)
+
+func TestAudit(t *testing.T) {
+	ctx := context.Background()
+	w := &Audit{}
+	db := setupWorkload(t, w, Params{Rows: 500, Seed: 1})
+	runOps(t, w, db, 2000)
+	var events, latest int
+	if err := QueryRow(ctx, db, "SELECT count(*), max(ts) FROM audit", &events, &latest); err != nil {
+		t.Fatal(err)
+	}
+	if events != 2300 || latest != 2299 {
+		t.Errorf("%d events, the latest at %d; want 2300 up to 2299", events, latest)
+	}
+	if phases := w.Phases(); len(phases["append"]) != 1800 || len(phases["read"]) != 200 {
+		t.Errorf("%d appends and %d reads timed, want 1800 and 200", len(phases["append"]), len(phases["read"]))
+	}
+
+	// modernc has the dbstat table, so the index is sampled too.
+	growth := w.Growth()
+	if len(growth) != 2 || growth[0].Op != 999 || growth[1].Op != 1999 {
+		t.Fatalf("sampled %+v, want at operations 999 and 1999", growth)
+	}
+	for _, s := range growth {
+		if s.Bytes == 0 || s.IndexBytes == 0 || s.IndexPayload == 0 || s.IndexPayload > s.IndexBytes {
+			t.Errorf("sample %+v, want sizes with the index's payload within its pages", s)
+		}
+	}
+	if growth[1].Bytes <= growth[0].Bytes {
+		t.Errorf("database shrank from %d to %d bytes while appending", growth[0].Bytes, growth[1].Bytes)
+	}
+}
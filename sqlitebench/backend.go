@@ -0,0 +1,213 @@
+package sqlitebench
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// Backend opens databases through one SQLite driver. Drivers implementing
+// database/sql are wrapped in SQLBackend; drivers with their own API, such
+// as zombiezen.com/go/sqlite, implement Backend directly so they are
+// measured without the database/sql layer in between.
+type Backend interface {
+	// Open opens the database named by dsn, e.g. "file::memory:". The
+	// connection is used by one goroutine at a time.
+	Open(ctx context.Context, dsn string) (Conn, error)
+	// String describes the backend for the list subcommand.
+	String() string
+}
+
+// Conn is an open database connection.
+type Conn interface {
+	Exec(ctx context.Context, query string, args ...any) error
+	Query(ctx context.Context, query string, args ...any) (Rows, error)
+	Begin(ctx context.Context) (Tx, error)
+	Close() error
+}
+
+// Rows iterates over a query result, like *sql.Rows.
+type Rows interface {
+	Next() bool
+	Scan(dest ...any) error
+	ColumnTypes() ([]*sql.ColumnType, error)
+	Err() error
+	Close() error
+}
+
+// Tx is a transaction on a Conn.
+type Tx interface {
+	Exec(ctx context.Context, query string, args ...any) error
+	Commit() error
+	Rollback() error
+}
+
+// ConnWrapper is implemented by connections wrapping another, e.g. to
+// trace its statements. The optional interfaces of connections, such as
+// Serializer, are looked up on the connection wrapped.
+type ConnWrapper interface {
+	Unwrap() Conn
+}
+
+// unwrap returns the connection c wraps, if any, all the way down.
+func unwrap(c Conn) Conn {
+	for {
+		w, ok := c.(ConnWrapper)
+		if !ok {
+			return c
+		}
+		c = w.Unwrap()
+	}
+}
+
+// Serializer is implemented by connections that can snapshot their main
+// database to bytes and replace it with such a snapshot, through
+// sqlite3_serialize and sqlite3_deserialize.
+type Serializer interface {
+	Serialize(ctx context.Context) ([]byte, error)
+	Deserialize(ctx context.Context, data []byte) error
+}
+
+// ExtensionLoader is implemented by connections that can load SQLite
+// extensions compiled as shared libraries.
+type ExtensionLoader interface {
+	// LoadExtension loads the library at path, calling entry to
+	// initialize it.
+	LoadExtension(ctx context.Context, path, entry string) error
+}
+
+// errNoExtensions is returned by sqlConn's LoadExtension for drivers that
+// cannot load extensions, such as modernc, which cannot run native code.
+var errNoExtensions = errors.New("driver cannot load extensions")
+
+// errNoSerialize is returned by sqlConn's Serializer methods for drivers
+// without serialization.
+var errNoSerialize = errors.New("driver cannot serialize databases")
+
+// errModerncDeserialize is returned instead of calling modernc's
+// Deserialize, which in v1.29 hands SQLite a buffer from its TLS stack to
+// free, corrupting the heap when the database is closed or replaced.
+var errModerncDeserialize = errors.New("modernc.org/sqlite cannot safely deserialize databases")
+
+// QueryRow runs a query expected to return one row and scans it into dest.
+func QueryRow(ctx context.Context, c Conn, query string, dest ...any) error {
+	rows, err := c.Query(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return sql.ErrNoRows
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return err
+	}
+	return rows.Close()
+}
+
+// SQLBackend adapts a registered database/sql driver.
+type SQLBackend struct {
+	// Driver is the name the driver registered with database/sql.
+	Driver string
+}
+
+// Open opens a pool limited to one connection, so pragmas and in-memory
+// databases hold for every statement.
+func (b SQLBackend) Open(ctx context.Context, dsn string) (Conn, error) {
+	db, err := sql.Open(b.Driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+	return sqlConn{db}, nil
+}
+
+func (b SQLBackend) String() string {
+	return fmt.Sprintf("database/sql driver %q", b.Driver)
+}
+
+type sqlConn struct{ db *sql.DB }
+
+func (c sqlConn) Exec(ctx context.Context, query string, args ...any) error {
+	_, err := c.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+func (c sqlConn) Query(ctx context.Context, query string, args ...any) (Rows, error) {
+	return c.db.QueryContext(ctx, query, args...)
+}
+
+func (c sqlConn) Begin(ctx context.Context) (Tx, error) {
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return sqlTx{tx}, nil
+}
+
+func (c sqlConn) Close() error { return c.db.Close() }
+
+// Serialize uses the driver connection's own method: modernc's takes no
+// arguments, mattn's the schema name.
+func (c sqlConn) Serialize(ctx context.Context) (data []byte, err error) {
+	err = c.raw(ctx, func(dc any) error {
+		switch dc := dc.(type) {
+		case interface{ Serialize() ([]byte, error) }:
+			data, err = dc.Serialize()
+		case interface{ Serialize(string) ([]byte, error) }:
+			data, err = dc.Serialize("main")
+		default:
+			err = errNoSerialize
+		}
+		return err
+	})
+	return data, err
+}
+
+func (c sqlConn) Deserialize(ctx context.Context, data []byte) error {
+	return c.raw(ctx, func(dc any) error {
+		switch dc := dc.(type) {
+		case interface{ Deserialize([]byte) error }:
+			return errModerncDeserialize
+		case interface{ Deserialize([]byte, string) error }:
+			return dc.Deserialize(data, "main")
+		default:
+			return errNoSerialize
+		}
+	})
+}
+
+func (c sqlConn) LoadExtension(ctx context.Context, path, entry string) error {
+	return c.raw(ctx, func(dc any) error {
+		l, ok := dc.(interface{ LoadExtension(string, string) error })
+		if !ok {
+			return errNoExtensions
+		}
+		return l.LoadExtension(path, entry)
+	})
+}
+
+// raw calls f with the pool's driver connection.
+func (c sqlConn) raw(ctx context.Context, f func(driverConn any) error) error {
+	conn, err := c.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return conn.Raw(f)
+}
+
+type sqlTx struct{ tx *sql.Tx }
+
+func (t sqlTx) Exec(ctx context.Context, query string, args ...any) error {
+	_, err := t.tx.ExecContext(ctx, query, args...)
+	return err
+}
+
+func (t sqlTx) Commit() error   { return t.tx.Commit() }
+func (t sqlTx) Rollback() error { return t.tx.Rollback() }
@@ -0,0 +1,39 @@
+package sqlitebench
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+)
+
+func TestSQLBackend(t *testing.T) {
+	ctx := context.Background()
+	db, err := SQLBackend{"sqlite"}.Open(ctx, ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if err := db.Exec(ctx, "CREATE TABLE t (v INTEGER)"); err != nil {
+		t.Fatal(err)
+	}
+	var v int
+	if err := QueryRow(ctx, db, "SELECT v FROM t", &v); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("QueryRow on an empty table = %v, want sql.ErrNoRows", err)
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Exec(ctx, "INSERT INTO t (v) VALUES (?)", 7); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if err := QueryRow(ctx, db, "SELECT v FROM t", &v); err != nil || v != 7 {
+		t.Errorf("QueryRow = %d, %v; want 7", v, err)
+	}
+}
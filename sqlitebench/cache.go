@@ -0,0 +1,206 @@
+package sqlitebench
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+func init() {
+	Register(Benchmark{Category: CategoryRead, New: func() Workload { return NewCache() }})
+}
+
+// Cache uses SQLite as a local read-through cache of Params.Rows entries:
+// operations look a key up, touching it so eviction is least recently
+// used first, and a miss inserts the value as if fetched from a slower
+// store. Once the cache holds one percent more than its capacity, the
+// miss evicts the least recently used entries in one delete.
+//
+// The time of lookups that hit, of misses with their insert and of
+// evictions are kept in the "hit", "miss" and "evict" phases.
+type Cache struct {
+	phaseTimes
+
+	// MissRatio is the share of lookups of keys never cached, from 0 to 1.
+	// Lookups of entries evicted by a concurrent operation miss too.
+	MissRatio float64
+
+	payloads *PayloadPool
+	capacity int
+	// clock orders the accesses.
+	clock atomic.Int64
+	// mu guards rng and the keys believed cached, as concurrent cells run
+	// on several connections. keys are numbered in the order they were
+	// first looked up, and next is the next number.
+	mu     sync.Mutex
+	rng    *rand.Rand
+	keys   []int
+	index  map[int]int
+	next   int
+	excess int
+}
+
+// NewCache returns a Cache missing 10% of lookups.
+func NewCache() *Cache { return &Cache{MissRatio: 0.1} }
+
+// Validate checks that the miss ratio is a ratio.
+func (w *Cache) Validate() error {
+	if w.MissRatio < 0 || w.MissRatio > 1 {
+		return fmt.Errorf("cache miss ratio must be between 0 and 1, got %v", w.MissRatio)
+	}
+	return nil
+}
+
+func (*Cache) Name() string { return "cache" }
+func (*Cache) Description() string {
+	return "look up cached entries, inserting misses and evicting the least recently used"
+}
+
+func cacheKey(i int) string { return "cache:" + strconv.Itoa(i) }
+
+func (w *Cache) Setup(ctx context.Context, db Conn, p Params) error {
+	w.payloads = p.Payloads
+	w.capacity = max(p.Rows, 1)
+	w.rng = rand.New(rand.NewPCG(uint64(p.Seed), uint64(p.Rows)))
+	w.clock.Store(0)
+	w.keys, w.index, w.next, w.excess = nil, map[int]int{}, 0, 0
+	for _, stmt := range []string{
+		"CREATE TABLE cache (key TEXT PRIMARY KEY, value BLOB NOT NULL, accessed INTEGER NOT NULL)",
+		"CREATE INDEX cache_accessed ON cache (accessed)",
+	} {
+		if err := db.Exec(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	err := populate(ctx, db, p.Rows, func(tx Tx, i int) error {
+		return tx.Exec(ctx, "INSERT INTO cache (key, value, accessed) VALUES (?, ?, ?)", cacheKey(i), w.payloads.Next(), w.clock.Add(1))
+	})
+	if err != nil {
+		return err
+	}
+	for i := range p.Rows {
+		w.cached(i)
+	}
+	w.next = p.Rows
+	return nil
+}
+
+// pick picks the key to look up: a new one with probability MissRatio or
+// if nothing is cached, else a cached one.
+func (w *Cache) pick() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.keys) == 0 || w.rng.Float64() < w.MissRatio {
+		w.next++
+		return w.next - 1
+	}
+	return w.keys[w.rng.IntN(len(w.keys))]
+}
+
+// cached records that key i was inserted and returns the number of
+// entries to evict, clearing it.
+func (w *Cache) cached(i int) (evict int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, ok := w.index[i]; ok {
+		return 0
+	}
+	w.index[i] = len(w.keys)
+	w.keys = append(w.keys, i)
+	if len(w.keys)-w.excess > w.capacity+max(w.capacity/100, 1) {
+		evict = len(w.keys) - w.excess - w.capacity
+		w.excess += evict
+	}
+	return evict
+}
+
+// evicted records that the keys were evicted.
+func (w *Cache) evicted(keys []int, planned int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.excess -= planned
+	for _, i := range keys {
+		j, ok := w.index[i]
+		if !ok {
+			continue
+		}
+		last := w.keys[len(w.keys)-1]
+		w.keys[j], w.index[last] = last, j
+		w.keys = w.keys[:len(w.keys)-1]
+		delete(w.index, i)
+	}
+}
+
+func (w *Cache) Run(ctx context.Context, db Conn, n int) error {
+	i := w.pick()
+	start := time.Now()
+	rows, err := db.Query(ctx, "UPDATE cache SET accessed = ? WHERE key = ? RETURNING value", w.clock.Add(1), cacheKey(i))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	hit := rows.Next()
+	if hit {
+		var value []byte
+		if err := rows.Scan(&value); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+	if hit {
+		w.record("hit", time.Since(start))
+		return nil
+	}
+
+	// A concurrent miss of the same evicted key may have inserted it
+	// already.
+	if err := db.Exec(ctx, "INSERT INTO cache (key, value, accessed) VALUES (?, ?, ?) ON CONFLICT (key) DO NOTHING", cacheKey(i), w.payloads.Next(), w.clock.Add(1)); err != nil {
+		return err
+	}
+	evict := w.cached(i)
+	w.record("miss", time.Since(start))
+	if evict == 0 {
+		return nil
+	}
+	start = time.Now()
+	err = w.evict(ctx, db, evict)
+	w.record("evict", time.Since(start))
+	return err
+}
+
+// evict deletes the n least recently used entries.
+func (w *Cache) evict(ctx context.Context, db Conn, n int) error {
+	var keys []int
+	defer func() { w.evicted(keys, n) }()
+	rows, err := db.Query(ctx, "DELETE FROM cache WHERE key IN (SELECT key FROM cache ORDER BY accessed LIMIT ?) RETURNING key", n)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return err
+		}
+		i, err := strconv.Atoi(key[len("cache:"):])
+		if err != nil {
+			return err
+		}
+		keys = append(keys, i)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	return rows.Close()
+}
+
+func (*Cache) Teardown(db Conn) error { return nil }
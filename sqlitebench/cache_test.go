@@ -0,0 +1,34 @@
+package sqlitebench
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCache(t *testing.T) {
+	ctx := context.Background()
+	for _, ratio := range []float64{0, 0.5} {
+		w := NewCache()
+		w.MissRatio = ratio
+		db := setupWorkload(t, w, Params{Rows: 100, Seed: 1})
+		runOps(t, w, db, 200)
+
+		// Evictions keep the cache within 1% of its 100 entries, and the
+		// entries it holds are those it believes cached.
+		var entries int
+		if err := QueryRow(ctx, db, "SELECT count(*) FROM cache", &entries); err != nil {
+			t.Fatal(err)
+		}
+		phases := w.Phases()
+		hits, misses, evictions := len(phases["hit"]), len(phases["miss"]), len(phases["evict"])
+		if hits+misses != 200 || entries < 100 || entries > 101 || entries != len(w.keys) {
+			t.Errorf("miss ratio %g: %d hits, %d misses, %d entries, %d known; want 200 lookups and 100 or 101 entries", ratio, hits, misses, entries, len(w.keys))
+		}
+		if ratio == 0 && (misses != 0 || evictions != 0) {
+			t.Errorf("miss ratio 0: %d misses and %d evictions; want none", misses, evictions)
+		}
+		if ratio == 0.5 && (misses < 70 || misses > 130 || evictions == 0) {
+			t.Errorf("miss ratio 0.5: %d misses and %d evictions; want about 100 and some", misses, evictions)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package sqlitebench
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	_ "embed"
+	"encoding/xml"
+	"errors"
+	"io"
+	"os"
+	"strings"
+)
+
+// Document is one document of a text corpus.
+type Document struct {
+	Title string
+	Body  string
+}
+
+// goSpec is the bundled corpus: the paragraphs of the Go specification.
+//
+//go:embed corpus/go_spec.txt
+var goSpec []byte
+
+// BundledCorpus returns the corpus used when none is given: the 564
+// paragraphs of the Go specification, titled by their section.
+func BundledCorpus() []Document {
+	docs, _ := readTextCorpus(bytes.NewReader(goSpec), 0)
+	return docs
+}
+
+// LoadCorpus reads up to limit documents, or all if limit is 0, from the
+// file at path. A file ending in .xml is a Wikipedia abstract dump, such
+// as https://dumps.wikimedia.org/enwiki/latest/enwiki-latest-abstract1.xml.gz,
+// each doc element a document; any other file holds a document per line,
+// as a title, a tab and the text or as the text alone, skipping empty lines
+// and those starting with #. Either may be gzipped, with .gz appended to
+// its name.
+func LoadCorpus(path string, limit int) ([]Document, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var r io.Reader = f
+	name := path
+	if strings.HasSuffix(name, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		r, name = gz, strings.TrimSuffix(name, ".gz")
+	}
+	var docs []Document
+	if strings.HasSuffix(name, ".xml") {
+		docs, err = readAbstractCorpus(r, limit)
+	} else {
+		docs, err = readTextCorpus(r, limit)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(docs) == 0 {
+		return nil, errors.New("corpus " + path + " has no documents")
+	}
+	return docs, nil
+}
+
+func readTextCorpus(r io.Reader, limit int) ([]Document, error) {
+	var docs []Document
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, 1<<20)
+	for s.Scan() && (limit == 0 || len(docs) < limit) {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var doc Document
+		if title, body, ok := strings.Cut(line, "\t"); ok {
+			doc = Document{Title: title, Body: body}
+		} else {
+			doc = Document{Body: line}
+		}
+		docs = append(docs, doc)
+	}
+	return docs, s.Err()
+}
+
+// readAbstractCorpus reads the doc elements of a Wikipedia abstract dump,
+// leaving out those without an abstract.
+func readAbstractCorpus(r io.Reader, limit int) ([]Document, error) {
+	var docs []Document
+	d := xml.NewDecoder(r)
+	for limit == 0 || len(docs) < limit {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "doc" {
+			continue
+		}
+		var doc struct {
+			Title    string `xml:"title"`
+			Abstract string `xml:"abstract"`
+		}
+		if err := d.DecodeElement(&doc, &start); err != nil {
+			return nil, err
+		}
+		if doc.Abstract = strings.TrimSpace(doc.Abstract); doc.Abstract != "" {
+			docs = append(docs, Document{Title: strings.TrimPrefix(doc.Title, "Wikipedia: "), Body: doc.Abstract})
+		}
+	}
+	return docs, nil
+}
@@ -0,0 +1,43 @@
+package sqlitebench
+
+import (
+	"context"
+)
+
+func init() {
+	Register(Benchmark{Category: CategoryConcurrency, New: func() Workload { return &Counter{} }})
+}
+
+// counterRows is the number of counters the counter workload increments.
+const counterRows = 8
+
+// Counter increments one of a few counter rows per operation, as view
+// counts, rate limits and sequence tables do, with
+// UPDATE counters SET n = n + 1 WHERE id = ?. Run with a Concurrency above
+// 1, every connection contends for the same rows; SQLite serializes the
+// writes on the database's write lock rather than the rows', which shows
+// in the time per operation, the busy errors and, with Runner.BusyRetries
+// set, the retries. Params.Rows and the payload size are not used.
+type Counter struct {
+	rng lockedRand
+}
+
+func (*Counter) Name() string        { return "counter" }
+func (*Counter) Description() string { return "increment one of a few hot counter rows per operation" }
+
+func (w *Counter) Setup(ctx context.Context, db Conn, p Params) error {
+	w.rng.seed(p)
+	if err := db.Exec(ctx, "CREATE TABLE counters (id INTEGER PRIMARY KEY, n INTEGER NOT NULL)"); err != nil {
+		return err
+	}
+	return populate(ctx, db, counterRows, func(tx Tx, i int) error {
+		return tx.Exec(ctx, "INSERT INTO counters (id, n) VALUES (?, 0)", i+1)
+	})
+}
+
+func (w *Counter) Run(ctx context.Context, db Conn, n int) error {
+	id := w.rng.intN(counterRows) + 1
+	return db.Exec(ctx, "UPDATE counters SET n = n + 1 WHERE id = ?", id)
+}
+
+func (*Counter) Teardown(db Conn) error { return nil }
@@ -0,0 +1,30 @@
+package sqlitebench
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCounter(t *testing.T) {
+	ctx := context.Background()
+	w := &Counter{}
+	db := setupWorkload(t, w, Params{Rows: 100, Seed: 1})
+	runOps(t, w, db, 100)
+	var counters, total, hit int
+	if err := QueryRow(ctx, db, "SELECT count(*), sum(n), count(*) FILTER (WHERE n > 0) FROM counters", &counters, &total, &hit); err != nil {
+		t.Fatal(err)
+	}
+	if counters != counterRows || total != 100 || hit != counterRows {
+		t.Errorf("%d counters summing to %d, %d incremented; want %d summing to 100, all incremented", counters, total, hit, counterRows)
+	}
+
+	// Concurrent increments contend without failing the cell.
+	r := NewRunner()
+	r.Drivers, r.Sizes, r.Ops = []string{"modernc"}, []int{64}, 50
+	r.Concurrency = []int{4}
+	r.BusyRetries = 3
+	r.Add(&Counter{})
+	if _, err := r.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,165 @@
+package sqlitebench
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// CrashTest checks that a driver keeps every committed transaction when
+// the process writing them is killed mid-transaction, and measures how long
+// the database then takes to recover. The caller prepares the file, starts
+// a process calling Write, kills it with SIGKILL and calls Recover.
+//
+// A killed process leaves its writes in the OS page cache, so this tests
+// the driver's and SQLite's journaling, not the disk's: synchronous=off
+// loses nothing here that a power cut could. PowerLossTest simulates those.
+type CrashTest struct {
+	Path   string `json:"path"`
+	Driver string `json:"driver"`
+	// JournalMode and Synchronous are set by the writer when it opens the
+	// file, e.g. "wal" and "normal".
+	JournalMode string `json:"journal_mode"`
+	Synchronous string `json:"synchronous"`
+	// TxRows is the number of rows each transaction inserts. Large
+	// transactions make a kill more likely to land inside one.
+	TxRows   int   `json:"tx_rows"`
+	DataSize int   `json:"data_size"`
+	Seed     int64 `json:"seed"`
+	// VFS is the VFS the writer opens the file through, the default if
+	// empty.
+	VFS string `json:"vfs,omitempty"`
+}
+
+// CrashResult is what Recover found after a kill.
+type CrashResult struct {
+	// Recovery is how long opening the database and reading it back took,
+	// which includes rolling back a hot journal or replaying the WAL.
+	Recovery time.Duration `json:"recovery_ns"`
+	// Acked is the number of transactions the writer reported committed.
+	Acked int `json:"acked"`
+	// Committed is the number of whole transactions in the database.
+	Committed int `json:"committed"`
+	// Lost is the number of acknowledged transactions missing.
+	Lost int `json:"lost"`
+	// Torn is set if a transaction was found partly applied.
+	Torn bool `json:"torn"`
+	// Integrity is the first line of PRAGMA integrity_check, "ok" for an
+	// intact database.
+	Integrity string `json:"integrity"`
+}
+
+// crashTable holds the writer's rows, each tagged with its transaction.
+const crashTable = "CREATE TABLE crash (id INTEGER PRIMARY KEY, tx INTEGER NOT NULL, data BLOB)"
+
+// Prepare creates the database file afresh with the crash table.
+func (c *CrashTest) Prepare(ctx context.Context) error {
+	if err := removeDatabase(c.Path); err != nil {
+		return err
+	}
+	db, err := c.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if err := db.Exec(ctx, crashTable); err != nil {
+		return fmt.Errorf("creating table: %w", err)
+	}
+	return db.Close()
+}
+
+// open opens the file with the journal mode and synchronous setting.
+func (c *CrashTest) open(ctx context.Context) (Conn, error) {
+	b, ok := Drivers[c.Driver]
+	if !ok {
+		return nil, fmt.Errorf("unknown driver %q", c.Driver)
+	}
+	dsn := "file:" + c.Path
+	if c.VFS != "" {
+		dsn = withVFS(dsn, c.VFS)
+	}
+	db, err := b.Open(ctx, dsn)
+	if err != nil {
+		return nil, err
+	}
+	if c.JournalMode != "" {
+		if err := QueryRow(ctx, db, "PRAGMA journal_mode = "+c.JournalMode, new(string)); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("setting journal mode: %w", err)
+		}
+	}
+	if c.Synchronous != "" {
+		if err := db.Exec(ctx, "PRAGMA synchronous = "+c.Synchronous); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("setting synchronous: %w", err)
+		}
+	}
+	return db, nil
+}
+
+// Write commits transactions of TxRows rows until ctx ends or the process
+// is killed, calling ack with the number of every transaction, from 0, once
+// its commit returned.
+func (c *CrashTest) Write(ctx context.Context, ack func(tx int)) error {
+	db, err := c.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	payloads := NewPayloadPool(c.Seed, 0, c.DataSize)
+	for n := 0; ctx.Err() == nil; n++ {
+		if err := c.writeTx(ctx, db, payloads, n); err != nil {
+			return err
+		}
+		ack(n)
+	}
+	return ctx.Err()
+}
+
+// writeTx commits transaction n, rolling it back if an insert fails.
+func (c *CrashTest) writeTx(ctx context.Context, db Conn, payloads *PayloadPool, n int) error {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	for range c.TxRows {
+		if err := tx.Exec(ctx, "INSERT INTO crash (tx, data) VALUES (?, ?)", n, payloads.Next()); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Recover opens the database left by a killed writer that acknowledged
+// acked transactions and checks what survived.
+func (c *CrashTest) Recover(ctx context.Context, acked int) (CrashResult, error) {
+	result := CrashResult{Acked: acked}
+	b, ok := Drivers[c.Driver]
+	if !ok {
+		return result, fmt.Errorf("unknown driver %q", c.Driver)
+	}
+
+	start := time.Now()
+	db, err := b.Open(ctx, "file:"+c.Path)
+	if err != nil {
+		return result, err
+	}
+	defer db.Close()
+	var rows, txs, maxTx int
+	if err := QueryRow(ctx, db, "SELECT count(*), count(DISTINCT tx), coalesce(max(tx), -1) FROM crash", &rows, &txs, &maxTx); err != nil {
+		return result, fmt.Errorf("reading back: %w", err)
+	}
+	result.Recovery = time.Since(start)
+
+	result.Committed = txs
+	result.Lost = max(acked-txs, 0)
+	// Transactions commit in order, so the survivors are 0 to txs-1, each
+	// with all its rows.
+	result.Torn = rows != txs*c.TxRows || maxTx != txs-1
+	if err := QueryRow(ctx, db, "PRAGMA integrity_check", &result.Integrity); err != nil {
+		return result, fmt.Errorf("checking integrity: %w", err)
+	}
+	return result, db.Close()
+}
@@ -0,0 +1,33 @@
+package sqlitebench
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestCrashTestRecover(t *testing.T) {
+	c := &CrashTest{Path: filepath.Join(t.TempDir(), "crash.db"), Driver: "modernc", JournalMode: "wal", Synchronous: "normal", TxRows: 10, DataSize: 64}
+	ctx := context.Background()
+	if err := c.Prepare(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// Stop the writer after five transactions; Recover must find them all
+	// and report one that was acknowledged without committing as lost.
+	writeCtx, cancel := context.WithCancel(ctx)
+	acked := 0
+	c.Write(writeCtx, func(tx int) {
+		if acked++; acked == 5 {
+			cancel()
+		}
+	})
+	got, err := c.Recover(ctx, acked+1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := CrashResult{Recovery: got.Recovery, Acked: 6, Committed: 5, Lost: 1, Integrity: "ok"}
+	if got != want {
+		t.Errorf("Recover = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,248 @@
+package sqlitebench
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Difference is a statement of a differential check whose outcome was not
+// the same on every driver.
+type Difference struct {
+	// Step is the statement's position in the sequence, from 0.
+	Step      int    `json:"step"`
+	Statement string `json:"statement"`
+	// Args are the bound values, formatted like the outcomes.
+	Args []string `json:"args,omitempty"`
+	// Outcomes maps every driver to the rows it returned, "ok" for a
+	// statement returning none, or its error.
+	Outcomes map[string]string `json:"outcomes"`
+}
+
+// diffStatement is a statement of a differential check. Queries returning
+// rows have their number of columns set.
+type diffStatement struct {
+	sql     string
+	args    []any
+	columns int
+}
+
+// diffTable has a column of every type affinity, one without a declared
+// type and one that is unique, so bound values get converted and
+// conflicting inserts fail.
+const diffTable = "CREATE TABLE d (i INTEGER, t TEXT, r REAL, b BLOB, n NUMERIC, a, u TEXT UNIQUE)"
+
+// diffColumns are the columns of diffTable.
+var diffColumns = []string{"i", "t", "r", "b", "n", "a", "u"}
+
+// DiffDrivers runs the same sequence of statements random in seed on a
+// fresh in-memory database of every driver and returns the statements
+// whose outcomes differ: the rows and Go types a query returns, and
+// whether a statement fails. Error messages are only reported, not
+// compared, as the drivers word them differently. The sequence ends with a
+// dump of the whole table, so differences in what was stored show too.
+func DiffDrivers(ctx context.Context, drivers []string, seed int64, statements int) ([]Difference, error) {
+	seq := diffStatements(rand.New(rand.NewPCG(uint64(seed), uint64(statements))), statements)
+
+	outcomes := make([][]diffOutcome, len(drivers))
+	for i, name := range drivers {
+		b, ok := Drivers[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown driver %q", name)
+		}
+		db, err := b.Open(ctx, memoryDSN())
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		for _, st := range seq {
+			outcomes[i] = append(outcomes[i], runDiffStatement(ctx, db, st))
+		}
+		db.Close()
+	}
+	return differences(seq, drivers, outcomes), nil
+}
+
+// differences compares the drivers' outcomes of seq. A driver that stopped
+// early is left out of the steps it did not reach.
+func differences(seq []diffStatement, drivers []string, outcomes [][]diffOutcome) []Difference {
+	var diffs []Difference
+	for step, st := range seq {
+		key, same, reached := "", true, 0
+		for i := range drivers {
+			if step >= len(outcomes[i]) {
+				continue
+			}
+			if reached++; reached == 1 {
+				key = outcomes[i][step].key
+			}
+			same = same && outcomes[i][step].key == key
+		}
+		if same {
+			continue
+		}
+		d := Difference{Step: step, Statement: st.sql, Outcomes: map[string]string{}}
+		for _, arg := range st.args {
+			d.Args = append(d.Args, formatDiffValue(arg))
+		}
+		for i, name := range drivers {
+			if step < len(outcomes[i]) {
+				d.Outcomes[name] = outcomes[i][step].detail
+			}
+		}
+		diffs = append(diffs, d)
+	}
+	return diffs
+}
+
+// diffOutcome is what a statement did on one driver. Outcomes with the same
+// key agree; detail adds the error message.
+type diffOutcome struct {
+	key, detail string
+}
+
+func runDiffStatement(ctx context.Context, db Conn, st diffStatement) diffOutcome {
+	if st.columns == 0 {
+		if err := db.Exec(ctx, st.sql, st.args...); err != nil {
+			return diffOutcome{"error", "error: " + err.Error()}
+		}
+		return diffOutcome{"ok", "ok"}
+	}
+
+	rows, err := db.Query(ctx, st.sql, st.args...)
+	if err != nil {
+		return diffOutcome{"error", "error: " + err.Error()}
+	}
+	defer rows.Close()
+	var lines []string
+	for rows.Next() {
+		values := make([]any, st.columns)
+		dest := make([]any, st.columns)
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return diffOutcome{"error", "error: " + err.Error()}
+		}
+		cells := make([]string, len(values))
+		for i, v := range values {
+			cells[i] = formatDiffValue(v)
+		}
+		lines = append(lines, strings.Join(cells, ", "))
+	}
+	if err := rows.Err(); err != nil {
+		return diffOutcome{"error", "error: " + err.Error()}
+	}
+	out := "[" + strings.Join(lines, "; ") + "]"
+	return diffOutcome{out, out}
+}
+
+// formatDiffValue formats a bound or scanned value with its Go type, which
+// differs between drivers as much as the value does.
+func formatDiffValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case []byte:
+		return fmt.Sprintf("[]byte(%x)", v)
+	case string:
+		return "string(" + strconv.Quote(v) + ")"
+	case time.Time:
+		return "time(" + v.Format(time.RFC3339Nano) + ")"
+	default:
+		return fmt.Sprintf("%T(%v)", v, v)
+	}
+}
+
+// diffStatements returns the table's creation followed by n random
+// statements and the final dump.
+func diffStatements(rng *rand.Rand, n int) []diffStatement {
+	seq := []diffStatement{{sql: diffTable}}
+	rows := 0
+	for range n {
+		var st diffStatement
+		switch k := rng.IntN(10); {
+		case k < 4 || rows == 0:
+			st = diffStatement{sql: "INSERT INTO d (i, t, r, b, n, a, u) VALUES (?, ?, ?, ?, ?, ?, ?)"}
+			for range diffColumns[:6] {
+				st.args = append(st.args, randomDiffValue(rng))
+			}
+			// A few distinct values, so some inserts conflict.
+			st.args = append(st.args, "u"+strconv.Itoa(rng.IntN(n/4+1)))
+			rows++
+		case k < 6:
+			col := diffColumns[rng.IntN(len(diffColumns)-1)]
+			st = diffStatement{sql: "UPDATE d SET " + col + " = ? WHERE rowid = ?", args: []any{randomDiffValue(rng), 1 + rng.IntN(rows)}}
+		case k < 7:
+			st = diffStatement{sql: "DELETE FROM d WHERE rowid = ?", args: []any{1 + rng.IntN(rows)}}
+		case k < 8:
+			col := diffColumns[rng.IntN(len(diffColumns))]
+			st = diffStatement{sql: "SELECT typeof(" + col + "), " + col + " FROM d WHERE rowid = ?", args: []any{1 + rng.IntN(rows)}, columns: 2}
+		default:
+			st = diffExpressions[rng.IntN(len(diffExpressions))]
+			st.args = nil
+			for range strings.Count(st.sql, "?") {
+				st.args = append(st.args, randomDiffValue(rng))
+			}
+		}
+		seq = append(seq, st)
+	}
+
+	dump := "SELECT rowid"
+	for _, col := range diffColumns {
+		dump += ", typeof(" + col + "), " + col
+	}
+	return append(seq, diffStatement{sql: dump + " FROM d ORDER BY rowid", columns: 1 + 2*len(diffColumns)})
+}
+
+// diffExpressions are queries of bound values, to compare how drivers
+// bind, convert and return them.
+var diffExpressions = []diffStatement{
+	{sql: "SELECT ?, typeof(?)", columns: 2},
+	{sql: "SELECT ? + ?", columns: 1},
+	{sql: "SELECT ? || ?", columns: 1},
+	{sql: "SELECT CAST(? AS INTEGER), CAST(? AS REAL), CAST(? AS TEXT), CAST(? AS BLOB)", columns: 4},
+	{sql: "SELECT length(?), hex(?)", columns: 2},
+	{sql: "SELECT ? / 0", columns: 1},
+	{sql: "SELECT ? = ?", columns: 1},
+	{sql: "SELECT upper(?), lower(?)", columns: 2},
+}
+
+// randomDiffValue returns a value to bind of a type drivers convert
+// differently: integers at their limits, special floats, numeric-looking
+// and non-ASCII strings, empty and nil blobs, booleans and times.
+func randomDiffValue(rng *rand.Rand) any {
+	switch rng.IntN(12) {
+	case 0:
+		return nil
+	case 1:
+		return []int64{0, -1, 42, math.MaxInt64, math.MinInt64}[rng.IntN(5)]
+	case 2:
+		return rng.Int64N(1000) - 500
+	case 3:
+		return []float64{0.1, -0.0, 1e300, 3.0, math.Inf(1), math.NaN()}[rng.IntN(6)]
+	case 4:
+		return rng.Float64() * 1000
+	case 5:
+		return []string{"", "42", " 7", "1e3", "0x10", "3.0", "-0"}[rng.IntN(7)]
+	case 6:
+		return []string{"héllo", "日本", "nul\x00byte", "'quoted'", "\xff\xfe"}[rng.IntN(5)]
+	case 7:
+		buf := make([]byte, rng.IntN(8))
+		for i := range buf {
+			buf[i] = byte(rng.Uint32())
+		}
+		return buf
+	case 8:
+		return []byte(nil)
+	case 9:
+		return rng.IntN(2) == 1
+	case 10:
+		return time.Date(2024, 2, 29, 12, 30, 0, int(rng.Int64N(1e9)), time.FixedZone("", 3600*(rng.IntN(5)-2)))
+	default:
+		return strconv.Itoa(rng.IntN(100))
+	}
+}
@@ -0,0 +1,29 @@
+package sqlitebench
+
+import (
+	"context"
+	"testing"
+)
+
+func TestDiffDrivers(t *testing.T) {
+	ctx := context.Background()
+	same, err := DiffDrivers(ctx, []string{"modernc", "modernc"}, 1, 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(same) != 0 {
+		t.Errorf("a driver differed from itself: %+v", same[0])
+	}
+
+	// The drivers format bound times differently.
+	diffs, err := DiffDrivers(ctx, []string{"mattn", "modernc"}, 1, 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) == 0 {
+		t.Fatal("no differences between mattn and modernc")
+	}
+	if d := diffs[0]; d.Outcomes["mattn"] == d.Outcomes["modernc"] {
+		t.Errorf("difference with equal outcomes: %+v", d)
+	}
+}
@@ -0,0 +1,153 @@
+package sqlitebench
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// DiskFullTest fills a database until a write fails for lack of space, to
+// see how the driver reports it, whether the open connection carries on
+// once space is freed, and whether the database stays intact.
+type DiskFullTest struct {
+	CrashTest
+	// MaxSize caps the size of each of the database's files through the
+	// driver's counting VFS, which fails writes beyond it with SQLITE_FULL
+	// as the OS VFS does on ENOSPC. If 0, the database is filled until the
+	// filesystem holding Path is, which should be a small one, e.g. a
+	// tmpfs mounted with size= or a loop device.
+	MaxSize int64 `json:"max_size"`
+	// Ballast is the size of a file written next to the database before
+	// filling it and removed to free space, when MaxSize is 0.
+	Ballast int64 `json:"ballast"`
+}
+
+// DiskFullResult is what a DiskFullTest found.
+type DiskFullResult struct {
+	// Committed is the number of transactions committed before the first
+	// failure.
+	Committed int `json:"committed"`
+	// Size is the size of the database's files when full.
+	Size int64 `json:"size"`
+	// Err is the error of the failed transaction. Full is set if it is
+	// SQLITE_FULL, rather than an I/O error or one of the driver's own.
+	Err  string `json:"err"`
+	Full bool   `json:"full"`
+	// Failed is how long the failed transaction took to fail.
+	Failed time.Duration `json:"failed_ns"`
+	// Recovery is how long the connection took to commit the next
+	// transaction once space was freed, or RecoveryErr why it could not.
+	Recovery    time.Duration `json:"recovery_ns"`
+	RecoveryErr string        `json:"recovery_err,omitempty"`
+	// Lost, Torn and Integrity are as in CrashResult, checked after the
+	// connection was closed.
+	Lost      int    `json:"lost"`
+	Torn      bool   `json:"torn"`
+	Integrity string `json:"integrity"`
+}
+
+// diskFullMu serializes DiskFullTests with a MaxSize, as the limit is
+// process-wide.
+var diskFullMu sync.Mutex
+
+// Run fills the database at Path.
+func (d *DiskFullTest) Run(ctx context.Context) (DiskFullResult, error) {
+	var result DiskFullResult
+	c := d.CrashTest
+	limit := func(int64) {}
+	if d.MaxSize > 0 {
+		v, ok := ioVFSes[c.Driver]
+		if !ok || v.limit == nil {
+			return result, fmt.Errorf("driver %q has no VFS to limit file sizes", c.Driver)
+		}
+		name, _, err := ioVFS(c.Driver)
+		if err != nil {
+			return result, err
+		}
+		diskFullMu.Lock()
+		defer diskFullMu.Unlock()
+		c.VFS, limit = name, v.limit
+		defer limit(0)
+	}
+
+	if err := c.Prepare(ctx); err != nil {
+		return result, fmt.Errorf("preparing %s: %w", c.Path, err)
+	}
+	free := func() error { limit(0); return nil }
+	if d.MaxSize == 0 && d.Ballast > 0 {
+		ballast := c.Path + ".ballast"
+		if err := os.WriteFile(ballast, make([]byte, d.Ballast), 0o644); err != nil {
+			return result, fmt.Errorf("writing ballast: %w", err)
+		}
+		defer os.Remove(ballast)
+		free = func() error { return os.Remove(ballast) }
+	}
+
+	db, err := c.open(ctx)
+	if err != nil {
+		return result, err
+	}
+	defer db.Close()
+	limit(d.MaxSize)
+
+	payloads := NewPayloadPool(c.Seed, 0, c.DataSize)
+	n := 0
+	for {
+		start := time.Now()
+		err := c.writeTx(ctx, db, payloads, n)
+		if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+		if err != nil {
+			result.Failed = time.Since(start)
+			result.Err, result.Full = err.Error(), isFull(err)
+			break
+		}
+		n++
+	}
+	result.Committed = n
+	if result.Size, err = databaseSize(c.Path); err != nil {
+		return result, err
+	}
+
+	if err := free(); err != nil {
+		return result, fmt.Errorf("freeing space: %w", err)
+	}
+	start := time.Now()
+	if err := c.writeTx(ctx, db, payloads, n); err != nil {
+		result.RecoveryErr = err.Error()
+	} else {
+		result.Recovery = time.Since(start)
+		n++
+	}
+	if err := db.Close(); err != nil {
+		return result, err
+	}
+
+	check, err := c.Recover(ctx, n)
+	if err != nil {
+		return result, err
+	}
+	result.Lost, result.Torn, result.Integrity = check.Lost, check.Torn, check.Integrity
+	return result, nil
+}
+
+// databaseSize returns the size of the database file at path with its
+// journal or WAL.
+func databaseSize(path string) (int64, error) {
+	var size int64
+	for _, suffix := range []string{"", "-journal", "-wal"} {
+		info, err := os.Stat(path + suffix)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		size += info.Size()
+	}
+	return size, nil
+}
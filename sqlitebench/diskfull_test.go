@@ -0,0 +1,44 @@
+package sqlitebench
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestDiskFullTest(t *testing.T) {
+	dir := t.TempDir()
+	tested := 0
+	for _, driver := range IOCounterDrivers() {
+		if ioVFSes[driver].limit == nil {
+			continue
+		}
+		tested++
+		for _, journal := range []string{"delete", "wal"} {
+			d := &DiskFullTest{
+				CrashTest: CrashTest{
+					Path:        filepath.Join(dir, driver+"-"+journal+".db"),
+					Driver:      driver,
+					JournalMode: journal,
+					Synchronous: "full",
+					TxRows:      10,
+					DataSize:    1024,
+				},
+				MaxSize: 64 << 10,
+			}
+			r, err := d.Run(context.Background())
+			if err != nil {
+				t.Fatalf("%s %s: %v", driver, journal, err)
+			}
+			if !r.Full || r.Committed == 0 {
+				t.Errorf("%s %s: committed %d, then %q; want SQLITE_FULL after some commits", driver, journal, r.Committed, r.Err)
+			}
+			if r.RecoveryErr != "" || r.Lost != 0 || r.Torn || r.Integrity != "ok" {
+				t.Errorf("%s %s: after freeing space: %+v", driver, journal, r)
+			}
+		}
+	}
+	if tested == 0 {
+		t.Skip("no driver can limit file sizes in this build")
+	}
+}
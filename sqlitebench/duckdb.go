@@ -0,0 +1,11 @@
+//go:build duckdb
+
+package sqlitebench
+
+import (
+	_ "github.com/marcboeker/go-duckdb"
+)
+
+func init() {
+	OLAPBackends["duckdb"] = SQLBackend{"duckdb"}
+}
@@ -0,0 +1,164 @@
+package sqlitebench
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"sync/atomic"
+	"time"
+)
+
+func init() {
+	Register(Benchmark{Category: CategoryConcurrency, New: func() Workload { return &Ecommerce{} }})
+}
+
+const (
+	// ecommerceCustomers is the number of customers and ecommerceOrders
+	// that of the orders Setup places before measuring.
+	ecommerceCustomers = 1000
+	ecommerceOrders    = 1000
+	// ecommerceStock is every product's initial stock, enough for any run
+	// never to sell out.
+	ecommerceStock = 1 << 30
+	// ecommerceMaxItems is the most products an order holds.
+	ecommerceMaxItems = 5
+	// ecommerceReportOrders is the number of latest orders the report of
+	// top products covers.
+	ecommerceReportOrders = 1000
+)
+
+// Ecommerce is an online shop's transactions on customers, products,
+// orders and order items, in a fixed mix per ten operations: three place
+// an order, inserting it and its items and taking them from stock in one
+// transaction; six look an order up, joining its items and products; one
+// reports the ten best-selling products of the latest orders. Params.Rows
+// is the number of products, each with the payload as its description.
+//
+// Its time per operation is the application-shaped score of a driver: the
+// mean cost of the shop's traffic, not of one statement. The time of each
+// transaction is kept in the "place", "lookup" and "report" phases.
+type Ecommerce struct {
+	phaseTimes
+	rowBytes
+
+	products int
+	// orders numbers the orders placed; their ids are 1 to orders.
+	orders atomic.Int64
+	rng    lockedRand
+}
+
+func (*Ecommerce) Name() string { return "ecommerce" }
+func (*Ecommerce) Description() string {
+	return "place orders, look them up and report top products as a shop would"
+}
+
+// ecommercePrice is the price of product i.
+func ecommercePrice(i int) float64 { return float64(100+i*37%9900) / 100 }
+
+func (w *Ecommerce) Setup(ctx context.Context, db Conn, p Params) error {
+	w.reset()
+	w.products = max(p.Rows, 1)
+	w.orders.Store(0)
+	w.rng.seed(p)
+	for _, stmt := range []string{
+		"CREATE TABLE customers (id INTEGER PRIMARY KEY, name TEXT NOT NULL, email TEXT NOT NULL UNIQUE)",
+		"CREATE TABLE products (id INTEGER PRIMARY KEY, name TEXT NOT NULL, price REAL NOT NULL, stock INTEGER NOT NULL, description BLOB)",
+		"CREATE TABLE orders (id INTEGER PRIMARY KEY, customer_id INTEGER NOT NULL REFERENCES customers, placed INTEGER NOT NULL, total REAL NOT NULL)",
+		"CREATE TABLE order_items (order_id INTEGER NOT NULL REFERENCES orders, product_id INTEGER NOT NULL REFERENCES products, quantity INTEGER NOT NULL, price REAL NOT NULL, PRIMARY KEY (order_id, product_id))",
+		"CREATE INDEX orders_customer ON orders (customer_id)",
+		"CREATE INDEX order_items_product ON order_items (product_id)",
+	} {
+		if err := db.Exec(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	err := populate(ctx, db, ecommerceCustomers, func(tx Tx, i int) error {
+		return tx.Exec(ctx, "INSERT INTO customers (id, name, email) VALUES (?, ?, ?)",
+			i+1, fmt.Sprintf("Customer %d", i+1), fmt.Sprintf("customer%d@example.com", i+1))
+	})
+	if err != nil {
+		return err
+	}
+	err = populate(ctx, db, w.products, func(tx Tx, i int) error {
+		return tx.Exec(ctx, "INSERT INTO products (id, name, price, stock, description) VALUES (?, ?, ?, ?, ?)",
+			i+1, fmt.Sprintf("Product %d", i+1), ecommercePrice(i+1), ecommerceStock, p.Payloads.Next())
+	})
+	if err != nil {
+		return err
+	}
+	return populate(ctx, db, ecommerceOrders, func(tx Tx, i int) error {
+		return w.place(ctx, tx)
+	})
+}
+
+func (w *Ecommerce) Run(ctx context.Context, db Conn, n int) error {
+	start := time.Now()
+	switch n % 10 {
+	case 0, 1, 2:
+		tx, err := db.Begin(ctx)
+		if err != nil {
+			return err
+		}
+		if err := w.place(ctx, tx); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		w.record("place", time.Since(start))
+	case 9:
+		err := runStatement(ctx, db, `SELECT p.id, p.name, sum(i.quantity) AS sold, sum(i.quantity * i.price) AS revenue
+			FROM order_items i JOIN products p ON p.id = i.product_id
+			WHERE i.order_id > ? GROUP BY p.id ORDER BY sold DESC LIMIT 10`,
+			[]any{w.orders.Load() - ecommerceReportOrders}, &w.rowBytes)
+		if err != nil {
+			return err
+		}
+		w.record("report", time.Since(start))
+	default:
+		id := w.rng.int64N(w.orders.Load()) + 1
+		err := runStatement(ctx, db, `SELECT o.id, o.placed, o.total, c.name, c.email, p.name, i.quantity, i.price
+			FROM orders o JOIN customers c ON c.id = o.customer_id
+			JOIN order_items i ON i.order_id = o.id JOIN products p ON p.id = i.product_id
+			WHERE o.id = ?`, []any{id}, &w.rowBytes)
+		if err != nil {
+			return err
+		}
+		w.record("lookup", time.Since(start))
+	}
+	return nil
+}
+
+// place places an order of a random customer for up to ecommerceMaxItems
+// random products in tx, taking them from stock.
+func (w *Ecommerce) place(ctx context.Context, tx Tx) error {
+	var customer int
+	quantities := map[int]int{}
+	w.rng.do(func(rng *rand.Rand) {
+		customer = rng.IntN(ecommerceCustomers) + 1
+		for range rng.IntN(ecommerceMaxItems) + 1 {
+			quantities[rng.IntN(w.products)+1] += rng.IntN(3) + 1
+		}
+	})
+
+	id := w.orders.Add(1)
+	var total float64
+	for product, quantity := range quantities {
+		total += float64(quantity) * ecommercePrice(product)
+	}
+	if err := tx.Exec(ctx, "INSERT INTO orders (id, customer_id, placed, total) VALUES (?, ?, ?, ?)", id, customer, time.Now().Unix(), total); err != nil {
+		return err
+	}
+	for product, quantity := range quantities {
+		if err := tx.Exec(ctx, "INSERT INTO order_items (order_id, product_id, quantity, price) VALUES (?, ?, ?, ?)", id, product, quantity, ecommercePrice(product)); err != nil {
+			return err
+		}
+		if err := tx.Exec(ctx, "UPDATE products SET stock = stock - ? WHERE id = ?", quantity, product); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (*Ecommerce) Teardown(db Conn) error { return nil }
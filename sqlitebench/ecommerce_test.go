@@ -0,0 +1,28 @@
+package sqlitebench
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestEcommerce(t *testing.T) {
+	ctx := context.Background()
+	w := &Ecommerce{}
+	db := setupWorkload(t, w, Params{Rows: 100, Seed: 1})
+	runOps(t, w, db, 30)
+
+	phases := w.Phases()
+	if place, lookup, report := len(phases["place"]), len(phases["lookup"]), len(phases["report"]); place != 9 || lookup != 18 || report != 3 {
+		t.Errorf("%d orders placed, %d looked up and %d reports; want 9, 18 and 3", place, lookup, report)
+	}
+	// Every item sold was taken from stock.
+	var orders, sold, taken int
+	if err := QueryRow(ctx, db, fmt.Sprintf("SELECT (SELECT count(*) FROM orders), (SELECT sum(quantity) FROM order_items), (SELECT sum(%d - stock) FROM products)", ecommerceStock),
+		&orders, &sold, &taken); err != nil {
+		t.Fatal(err)
+	}
+	if orders != ecommerceOrders+9 || sold != taken {
+		t.Errorf("%d orders, %d items sold and %d taken from stock; want %d orders and as many items taken as sold", orders, sold, taken, ecommerceOrders+9)
+	}
+}
@@ -0,0 +1,122 @@
+package sqlitebench
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// ErrorCounts counts the operations of a timed loop that failed with an
+// error a workload can expect under contention. Such operations have no
+// sample; the cell goes on, so contended cells report a failure rate
+// instead of failing. Any other error still fails the cell.
+type ErrorCounts struct {
+	// Busy counts operations that found the database locked (SQLITE_BUSY
+	// or SQLITE_LOCKED), e.g. after waiting out the busy timeout.
+	Busy uint64 `json:"busy"`
+	// Constraint counts operations that violated a constraint, e.g.
+	// concurrent inserts of the same key.
+	Constraint uint64 `json:"constraint"`
+	// Timeout counts operations whose own deadline passed, e.g. a request
+	// to a server backend; the cell's timeout ends the cell instead.
+	Timeout uint64 `json:"timeout"`
+
+	// Retried counts operations that found the database locked and were
+	// retried, with Runner.BusyRetries set, and Retries the attempts
+	// repeated. An operation still locked after its last retry counts as
+	// Busy too.
+	Retried uint64 `json:"retried,omitempty"`
+	Retries uint64 `json:"retries,omitempty"`
+	// RetryTime is how long the attempts that were retried took, which
+	// includes the driver's busy timeout.
+	RetryTime time.Duration `json:"retry_ns,omitempty"`
+}
+
+// Total returns the number of failed operations.
+func (c ErrorCounts) Total() uint64 { return c.Busy + c.Constraint + c.Timeout }
+
+func (c *ErrorCounts) add(other ErrorCounts) {
+	c.Busy += other.Busy
+	c.Constraint += other.Constraint
+	c.Timeout += other.Timeout
+	c.Retried += other.Retried
+	c.Retries += other.Retries
+	c.RetryTime += other.RetryTime
+}
+
+// retryCounts counts the retries of a cell's operations on any of its
+// connections, as ErrorCounts does.
+type retryCounts struct {
+	retried, retries atomic.Uint64
+	time             atomic.Int64
+}
+
+func (c *retryCounts) read() ErrorCounts {
+	return ErrorCounts{Retried: c.retried.Load(), Retries: c.retries.Load(), RetryTime: time.Duration(c.time.Load())}
+}
+
+// retry calls op, and again up to retries times while it fails with
+// SQLITE_BUSY or SQLITE_LOCKED and ctx is live, counting the retries.
+func (c *retryCounts) retry(ctx context.Context, retries int, op func() error) error {
+	start := time.Now()
+	err := op()
+	for i := 0; i < retries && err != nil && isBusy(err) && ctx.Err() == nil; i++ {
+		if i == 0 {
+			c.retried.Add(1)
+		}
+		c.retries.Add(1)
+		c.time.Add(int64(time.Since(start)))
+		start = time.Now()
+		err = op()
+	}
+	return err
+}
+
+// count counts err, or each error joined in it as concurrent rounds
+// return, and reports whether all of them were expected ones. Errors are
+// told apart by SQLite's messages, which every driver and server passes
+// on.
+func (c *ErrorCounts) count(err error) bool {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		var counts ErrorCounts
+		for _, err := range joined.Unwrap() {
+			if !counts.count(err) {
+				return false
+			}
+		}
+		c.add(counts)
+		return true
+	}
+
+	var netErr net.Error
+	msg := err.Error()
+	switch {
+	case isBusy(err):
+		c.Busy++
+	case strings.Contains(msg, "constraint failed") || strings.Contains(msg, "SQLITE_CONSTRAINT"):
+		c.Constraint++
+	case errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr) && netErr.Timeout():
+		c.Timeout++
+	default:
+		return false
+	}
+	return true
+}
+
+// isBusy reports whether err is SQLITE_BUSY or SQLITE_LOCKED, a database
+// or table locked by another connection.
+func isBusy(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "database is locked") || strings.Contains(msg, "database table is locked") ||
+		strings.Contains(msg, "SQLITE_BUSY") || strings.Contains(msg, "SQLITE_LOCKED")
+}
+
+// isFull reports whether err is SQLITE_FULL, a write failing for lack of
+// space.
+func isFull(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "database or disk is full") || strings.Contains(msg, "SQLITE_FULL")
+}
@@ -0,0 +1,167 @@
+package sqlitebench
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Extension is a SQLite extension compiled as a shared library, e.g. one
+// of the sqlean modules, measured by the extension.load and extension.call
+// workloads. They need the "load_extension" capability, which drivers that
+// cannot load extensions lack, so their cells are skipped.
+type Extension struct {
+	// Path is the shared library.
+	Path string
+	// Entry is the function initializing the extension. If empty, it is
+	// derived from the file name as SQLite does: "sqlite3_half_init" for
+	// "libhalf.so".
+	Entry string
+	// Call is a statement calling one of the extension's functions, e.g.
+	// "SELECT half(?)". A parameter is bound to the operation number.
+	// Without it, only extension.load runs.
+	Call string
+}
+
+// Validate checks that the extension can be measured.
+func (e *Extension) Validate() error {
+	if e.Path == "" {
+		return errors.New("extension path is empty")
+	}
+	return nil
+}
+
+// Workloads returns the extension.load workload and, if Call is set, the
+// extension.call workload.
+func (e *Extension) Workloads() []Workload {
+	workloads := []Workload{&extensionWorkload{e: e, op: "load"}}
+	if e.Call != "" {
+		workloads = append(workloads, &extensionWorkload{e: e, op: "call"})
+	}
+	return workloads
+}
+
+// entry returns the entry point SQLite would call for the extension.
+func (e *Extension) entry() string {
+	if e.Entry != "" {
+		return e.Entry
+	}
+	name := strings.TrimPrefix(filepath.Base(e.Path), "lib")
+	if i := strings.IndexByte(name, '.'); i >= 0 {
+		name = name[:i]
+	}
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if r >= 'a' && r <= 'z' {
+			b.WriteRune(r)
+		}
+	}
+	return "sqlite3_" + b.String() + "_init"
+}
+
+// canLoadExtensions reports whether db can load extensions: its driver
+// implements loading and its SQLite was built with it.
+func canLoadExtensions(ctx context.Context, db Conn) bool {
+	l, ok := unwrap(db).(ExtensionLoader)
+	if !ok {
+		return false
+	}
+	var omitted bool
+	if err := QueryRow(ctx, db, "SELECT sqlite_compileoption_used('OMIT_LOAD_EXTENSION')", &omitted); err != nil || omitted {
+		return false
+	}
+	// Loading nothing fails either way, but only drivers without loading
+	// fail with errNoExtensions.
+	return !errors.Is(l.LoadExtension(ctx, "", ""), errNoExtensions)
+}
+
+type extensionWorkload struct {
+	e  *Extension
+	op string
+
+	// mu guards loaded, the connections the extension was loaded into,
+	// unwrapped.
+	mu     sync.Mutex
+	loaded map[Conn]bool
+}
+
+func (w *extensionWorkload) Name() string { return "extension." + w.op }
+
+func (w *extensionWorkload) Description() string {
+	if w.op == "load" {
+		return fmt.Sprintf("load %s into the connection per operation", filepath.Base(w.e.Path))
+	}
+	return fmt.Sprintf("run %q per operation", w.e.Call)
+}
+
+func (*extensionWorkload) Requires() []string { return []string{"load_extension"} }
+
+func (w *extensionWorkload) Setup(ctx context.Context, db Conn, p Params) error {
+	w.loaded = map[Conn]bool{}
+	if w.op == "call" {
+		return w.load(ctx, db)
+	}
+	return nil
+}
+
+func (w *extensionWorkload) load(ctx context.Context, db Conn) error {
+	db = unwrap(db)
+	l, ok := db.(ExtensionLoader)
+	if !ok {
+		return errNoExtensions
+	}
+	if err := l.LoadExtension(ctx, w.e.Path, w.e.entry()); err != nil {
+		return fmt.Errorf("loading %s: %w", w.e.Path, err)
+	}
+	w.mu.Lock()
+	w.loaded[db] = true
+	w.mu.Unlock()
+	return nil
+}
+
+// loadOnce loads the extension into db unless it was already.
+func (w *extensionWorkload) loadOnce(ctx context.Context, db Conn) error {
+	w.mu.Lock()
+	loaded := w.loaded[unwrap(db)]
+	w.mu.Unlock()
+	if loaded {
+		return nil
+	}
+	return w.load(ctx, db)
+}
+
+// Run for extension.call loads the extension into connections other than
+// the one given to Setup on their first call, which that call's sample
+// includes.
+func (w *extensionWorkload) Run(ctx context.Context, db Conn, n int) error {
+	if w.op == "load" {
+		return w.load(ctx, db)
+	}
+	if err := w.loadOnce(ctx, db); err != nil {
+		return err
+	}
+
+	var args []any
+	if strings.Contains(w.e.Call, "?") {
+		args = append(args, n)
+	}
+	rows, err := db.Query(ctx, w.e.Call, args...)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return err
+	}
+	return rows.Close()
+}
+
+func (w *extensionWorkload) Teardown(db Conn) error {
+	w.loaded = nil
+	return nil
+}
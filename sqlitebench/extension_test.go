@@ -0,0 +1,71 @@
+package sqlitebench
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestExtensionEntry(t *testing.T) {
+	for path, want := range map[string]string{
+		"/usr/lib/libhalf.so":   "sqlite3_half_init",
+		"ext/Half2.dylib":       "sqlite3_half_init",
+		"crypto.1.0.so":         "sqlite3_crypto_init",
+		"/opt/sqlean/fuzzy.dll": "sqlite3_fuzzy_init",
+	} {
+		if got := (&Extension{Path: path}).entry(); got != want {
+			t.Errorf("entry for %q = %q, want %q", path, got, want)
+		}
+	}
+}
+
+// buildHalf compiles testdata/half.c, skipping the test without a C
+// compiler and SQLite headers.
+func buildHalf(t *testing.T) string {
+	lib := filepath.Join(t.TempDir(), "libhalf.so")
+	out, err := exec.Command("cc", "-shared", "-fPIC", "-o", lib, "testdata/half.c").CombinedOutput()
+	if err != nil {
+		t.Skipf("building the test extension: %v: %s", err, out)
+	}
+	return lib
+}
+
+func TestRunnerExtension(t *testing.T) {
+	ctx := context.Background()
+	caps := map[string][]string{}
+	for _, driver := range []string{"mattn", "modernc"} {
+		info, err := CheckDriver(ctx, driver)
+		if err != nil {
+			t.Fatal(err)
+		}
+		caps[driver] = info.Capabilities
+	}
+	if slices.Contains(caps["modernc"], "load_extension") {
+		t.Errorf("modernc capabilities = %v, want no load_extension", caps["modernc"])
+	}
+	if !slices.Contains(caps["mattn"], "load_extension") {
+		t.Skipf("mattn capabilities = %v, want load_extension", caps["mattn"])
+	}
+
+	r := NewRunner()
+	r.Drivers, r.Sizes, r.Ops = []string{"mattn", "modernc"}, []int{64}, 5
+	r.Capabilities = caps
+	r.Concurrency = []int{2}
+	e := &Extension{Path: buildHalf(t), Call: "SELECT half(?)"}
+	r.Add(e.Workloads()...)
+
+	results, err := r.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, res := range results {
+		names = append(names, res.Driver+"/"+res.Operation)
+	}
+	want := []string{"mattn/extension.load,concurrency=2", "mattn/extension.call,concurrency=2"}
+	if !slices.Equal(names, want) {
+		t.Errorf("results = %v, want %v", names, want)
+	}
+}
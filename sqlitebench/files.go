@@ -0,0 +1,183 @@
+package sqlitebench
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+func init() {
+	Register(Benchmark{Category: CategoryWrite, New: func() Workload { return NewFiles() }})
+	Register(Benchmark{Category: CategoryWrite, New: func() Workload {
+		f := NewFiles()
+		f.Chunked = true
+		return f
+	}})
+}
+
+// FilesStored is the number of files the file store holds, kept small so
+// the database stays a few times FileSize whatever Params.Rows is.
+const FilesStored = 8
+
+// Files uses SQLite as a file store, as applications keeping attachments
+// or documents in their database do, in one of two layouts: files.blob
+// stores each file as a single blob in the files table, and files.chunked
+// splits it into ChunkSize rows of a chunks table beside it. Operations
+// alternate between replacing a random one of FilesStored files and
+// reading one back whole, timed in the "write" and "read" phases, so
+// FileSize divided by a phase's time is its throughput.
+//
+// Reading a blob allocates the whole file at once while reading chunks
+// allocates a chunk at a time, so the allocations per operation show the
+// memory each layout costs with a driver. Files are random bytes, the same
+// for every write; the payload size is not used.
+type Files struct {
+	phaseTimes
+	rowBytes
+
+	// Chunked selects files.chunked instead of files.blob.
+	Chunked bool
+	// FileSize is the size of the files in bytes and ChunkSize that of
+	// files.chunked's chunks.
+	FileSize, ChunkSize int
+
+	content []byte
+	rng     lockedRand
+}
+
+// NewFiles returns a files.blob Files of 4 MiB files, chunked by 256 KiB.
+func NewFiles() *Files { return &Files{FileSize: 4 << 20, ChunkSize: 256 << 10} }
+
+// Validate checks that files and chunks have a size SQLite can store.
+func (w *Files) Validate() error {
+	if w.FileSize < 1 || w.FileSize > 1<<29 {
+		return fmt.Errorf("file size must be between 1 byte and 512 MiB, got %d", w.FileSize)
+	}
+	if w.ChunkSize < 1 {
+		return fmt.Errorf("file chunk size must be positive, got %d", w.ChunkSize)
+	}
+	return nil
+}
+
+func (w *Files) Name() string {
+	if w.Chunked {
+		return "files.chunked"
+	}
+	return "files.blob"
+}
+
+func (w *Files) Description() string {
+	if w.Chunked {
+		return "write or read a file stored as fixed-size chunk rows per operation"
+	}
+	return "write or read a file stored as a single blob per operation"
+}
+
+func (w *Files) Setup(ctx context.Context, db Conn, p Params) error {
+	w.reset()
+	w.rng.seed(p)
+	if len(w.content) != w.FileSize {
+		w.content = make([]byte, w.FileSize)
+	}
+	w.rng.do(func(rng *rand.Rand) {
+		for i := 0; i < len(w.content); i += 8 {
+			v := rng.Uint64()
+			for j := i; j < min(i+8, len(w.content)); j++ {
+				w.content[j] = byte(v)
+				v >>= 8
+			}
+		}
+	})
+	schema := []string{"CREATE TABLE files (id INTEGER PRIMARY KEY, name TEXT NOT NULL, size INTEGER NOT NULL, data BLOB)"}
+	if w.Chunked {
+		schema = append(schema, "CREATE TABLE chunks (file_id INTEGER NOT NULL, seq INTEGER NOT NULL, data BLOB NOT NULL, PRIMARY KEY (file_id, seq))")
+	}
+	for _, stmt := range schema {
+		if err := db.Exec(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return populate(ctx, db, FilesStored, func(tx Tx, i int) error {
+		return w.write(ctx, tx, i+1)
+	})
+}
+
+// write stores the content as file id in tx, replacing the file there.
+func (w *Files) write(ctx context.Context, tx Tx, id int) error {
+	name := fmt.Sprintf("file%d.bin", id)
+	if !w.Chunked {
+		return tx.Exec(ctx, "INSERT OR REPLACE INTO files (id, name, size, data) VALUES (?, ?, ?, ?)", id, name, len(w.content), w.content)
+	}
+	if err := tx.Exec(ctx, "DELETE FROM chunks WHERE file_id = ?", id); err != nil {
+		return err
+	}
+	for seq, off := 0, 0; off < len(w.content); seq, off = seq+1, off+w.ChunkSize {
+		chunk := w.content[off:min(off+w.ChunkSize, len(w.content))]
+		if err := tx.Exec(ctx, "INSERT INTO chunks (file_id, seq, data) VALUES (?, ?, ?)", id, seq, chunk); err != nil {
+			return err
+		}
+	}
+	return tx.Exec(ctx, "INSERT OR REPLACE INTO files (id, name, size) VALUES (?, ?, ?)", id, name, len(w.content))
+}
+
+// file picks a random file.
+func (w *Files) file() int {
+	return w.rng.intN(FilesStored) + 1
+}
+
+func (w *Files) Run(ctx context.Context, db Conn, n int) error {
+	id := w.file()
+	start := time.Now()
+	if n%2 == 0 {
+		tx, err := db.Begin(ctx)
+		if err != nil {
+			return err
+		}
+		if err := w.write(ctx, tx, id); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		w.record("write", time.Since(start))
+		return nil
+	}
+
+	query := "SELECT data FROM files WHERE id = ?"
+	if w.Chunked {
+		query = "SELECT data FROM chunks WHERE file_id = ? ORDER BY seq"
+	}
+	rows, err := db.Query(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	size := 0
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return err
+		}
+		w.add(data)
+		size += len(data)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+	if size == 0 {
+		return fmt.Errorf("file %d: %w", id, sql.ErrNoRows)
+	}
+	if size != w.FileSize {
+		return fmt.Errorf("file %d: read %d bytes, want %d", id, size, w.FileSize)
+	}
+	w.record("read", time.Since(start))
+	return nil
+}
+
+func (*Files) Teardown(db Conn) error { return nil }
@@ -0,0 +1,42 @@
+package sqlitebench
+
+import (
+	"context"
+	"testing"
+)
+
+func TestFiles(t *testing.T) {
+	ctx := context.Background()
+	for _, chunked := range []bool{false, true} {
+		w := NewFiles()
+		w.Chunked = chunked
+		w.FileSize, w.ChunkSize = 100_000, 16<<10
+		t.Run(w.Name(), func(t *testing.T) {
+			db := setupWorkload(t, w, Params{Rows: 100, Seed: 1})
+			runOps(t, w, db, 10)
+			var files, size int
+			if err := QueryRow(ctx, db, "SELECT count(*), sum(size) FROM files", &files, &size); err != nil {
+				t.Fatal(err)
+			}
+			if files != FilesStored || size != FilesStored*100_000 {
+				t.Errorf("%d files of %d bytes, want %d of %d", files, size, FilesStored, FilesStored*100_000)
+			}
+			if chunked {
+				// 100,000 bytes are six full 16 KiB chunks and a partial one.
+				var chunks int
+				if err := QueryRow(ctx, db, "SELECT count(*) FROM chunks", &chunks); err != nil {
+					t.Fatal(err)
+				}
+				if chunks != 7*FilesStored {
+					t.Errorf("%d chunks, want %d", chunks, 7*FilesStored)
+				}
+			}
+			if got := w.BytesRead(); got != 5*100_000 {
+				t.Errorf("read %d bytes, want %d", got, 5*100_000)
+			}
+			if phases := w.Phases(); len(phases["write"]) != 5 || len(phases["read"]) != 5 {
+				t.Errorf("%d writes and %d reads timed, want 5 of each", len(phases["write"]), len(phases["read"]))
+			}
+		})
+	}
+}
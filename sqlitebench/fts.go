@@ -0,0 +1,191 @@
+package sqlitebench
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"sync/atomic"
+	"unicode"
+	"unicode/utf8"
+)
+
+func init() {
+	Register(Benchmark{Category: CategoryWrite, Requires: []string{"fts5"}, New: func() Workload { return &FTS{mode: "build"} }})
+	Register(Benchmark{Category: CategoryWrite, Requires: []string{"fts5"}, New: func() Workload { return &FTS{mode: "update"} }})
+	Register(Benchmark{Category: CategoryRead, Requires: []string{"fts5"}, New: func() Workload { return &FTS{mode: "query"} }})
+}
+
+// ftsQueries is the number of queries fts.query picks from.
+const ftsQueries = 256
+
+// FTS benchmarks SQLite's FTS5 full-text index on real text: Params.Rows
+// documents of a corpus, repeated if it has fewer. In its three modes,
+// fts.build rebuilds the index of a content table per operation; fts.update
+// inserts, updates and deletes documents in turn in an index kept current;
+// and fts.query runs a MATCH query per operation, ranked by bm25 with
+// titles weighing ten times more than the text, and reads the best ten
+// matches. Queries are a word, two words or a prefix taken from the
+// documents, so each matches at least one. The payload size is not used.
+type FTS struct {
+	mode string
+	// Corpus is the path of the corpus, read by LoadCorpus, or empty for
+	// BundledCorpus.
+	Corpus string
+
+	docs []Document
+	// loaded is the corpus docs were read from, with the limit read to.
+	loaded      string
+	loadedLimit int
+	queries     []string
+	// next numbers the documents fts.update inserts.
+	next atomic.Int64
+	rng  lockedRand
+}
+
+func (w *FTS) Name() string { return "fts." + w.mode }
+func (w *FTS) Description() string {
+	switch w.mode {
+	case "build":
+		return "rebuild a full-text index of a text corpus per operation"
+	case "update":
+		return "insert, update or delete a document of a full-text index per operation"
+	}
+	return "run a ranked full-text query on a text corpus per operation"
+}
+
+// doc returns document i, cycling through the corpus.
+func (w *FTS) doc(i int) Document { return w.docs[i%len(w.docs)] }
+
+// load reads the corpus, unless the documents read last time will do.
+func (w *FTS) load(rows int) error {
+	if w.Corpus == "" {
+		if w.docs == nil || w.loaded != "" {
+			w.docs, w.loaded = BundledCorpus(), ""
+		}
+		return nil
+	}
+	if w.docs != nil && w.loaded == w.Corpus && (w.loadedLimit == 0 || w.loadedLimit >= rows) {
+		return nil
+	}
+	docs, err := LoadCorpus(w.Corpus, rows)
+	if err != nil {
+		return err
+	}
+	w.docs, w.loaded, w.loadedLimit = docs, w.Corpus, rows
+	if len(docs) < rows {
+		// The whole corpus was read.
+		w.loadedLimit = 0
+	}
+	return nil
+}
+
+func (w *FTS) Setup(ctx context.Context, db Conn, p Params) error {
+	rows := max(p.Rows, 1)
+	if err := w.load(rows); err != nil {
+		return err
+	}
+	w.next.Store(int64(rows))
+	w.rng.seed(p)
+
+	schema := []string{"CREATE VIRTUAL TABLE search USING fts5(title, body)"}
+	table := "search"
+	if w.mode == "build" {
+		schema = []string{
+			"CREATE TABLE docs (id INTEGER PRIMARY KEY, title TEXT, body TEXT)",
+			"CREATE VIRTUAL TABLE search USING fts5(title, body, content='docs', content_rowid='id')",
+		}
+		table = "docs"
+	}
+	for _, stmt := range schema {
+		if err := db.Exec(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	err := populate(ctx, db, rows, func(tx Tx, i int) error {
+		doc := w.doc(i)
+		return tx.Exec(ctx, "INSERT INTO "+table+" (rowid, title, body) VALUES (?, ?, ?)", i+1, doc.Title, doc.Body)
+	})
+	if err != nil {
+		return err
+	}
+
+	switch w.mode {
+	case "build":
+		return nil
+	case "query":
+		w.queries = w.queries[:0]
+		w.rng.do(func(rng *rand.Rand) {
+			for i := range ftsQueries {
+				w.queries = append(w.queries, ftsQuery(rng, w.doc(rng.IntN(rows)), i%3))
+			}
+		})
+	}
+	// Merge the index into one b-tree, as a freshly built one is.
+	return db.Exec(ctx, "INSERT INTO search (search) VALUES ('optimize')")
+}
+
+// ftsQuery returns a query matching doc: a word of it if kind is 0, two
+// words if 1 and the prefix of a word if 2. Words are letters only, so
+// they need no quoting, and the lower case keeps them from being read as
+// the AND, OR and NOT operators.
+func ftsQuery(rng *rand.Rand, doc Document, kind int) string {
+	var words []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(doc.Body), func(r rune) bool { return !unicode.IsLetter(r) }) {
+		if utf8.RuneCountInString(word) >= 4 {
+			words = append(words, word)
+		}
+	}
+	if len(words) == 0 {
+		return "the"
+	}
+	word := words[rng.IntN(len(words))]
+	switch kind {
+	case 1:
+		return word + " " + words[rng.IntN(len(words))]
+	case 2:
+		return string([]rune(word)[:3]) + "*"
+	}
+	return word
+}
+
+func (w *FTS) Run(ctx context.Context, db Conn, n int) error {
+	switch w.mode {
+	case "build":
+		return db.Exec(ctx, "INSERT INTO search (search) VALUES ('rebuild')")
+	case "update":
+		id := w.rng.int64N(w.next.Load()) + 1
+		switch n % 3 {
+		case 0:
+			i := w.next.Add(1)
+			doc := w.doc(int(i - 1))
+			return db.Exec(ctx, "INSERT INTO search (rowid, title, body) VALUES (?, ?, ?)", i, doc.Title, doc.Body)
+		case 1:
+			// The document may have been deleted, leaving nothing to update.
+			return db.Exec(ctx, "UPDATE search SET body = ? WHERE rowid = ?", w.doc(n).Body, id)
+		default:
+			return db.Exec(ctx, "DELETE FROM search WHERE rowid = ?", id)
+		}
+	}
+
+	query := w.queries[w.rng.intN(len(w.queries))]
+	rows, err := db.Query(ctx, "SELECT rowid, title, bm25(search, 10.0, 1.0) AS score FROM search WHERE search MATCH ? ORDER BY score LIMIT 10", query)
+	if err != nil {
+		return fmt.Errorf("query %q: %w", query, err)
+	}
+	defer rows.Close()
+	var id int64
+	var title string
+	var score float64
+	for rows.Next() {
+		if err := rows.Scan(&id, &title, &score); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	return rows.Close()
+}
+
+func (*FTS) Teardown(db Conn) error { return nil }
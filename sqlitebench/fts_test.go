@@ -0,0 +1,70 @@
+package sqlitebench
+
+import (
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadCorpus(t *testing.T) {
+	dir := t.TempDir()
+	text := filepath.Join(dir, "corpus.txt")
+	if err := os.WriteFile(text, []byte("# comment\n\nIntro\tFirst document.\nSecond document.\nThird.\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	docs, err := LoadCorpus(text, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []Document{{"Intro", "First document."}, {"", "Second document."}}; !reflect.DeepEqual(docs, want) {
+		t.Errorf("text corpus = %v, want %v", docs, want)
+	}
+
+	abstracts := filepath.Join(dir, "abstract.xml.gz")
+	f, err := os.Create(abstracts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz := gzip.NewWriter(f)
+	gz.Write([]byte(`<feed><doc><title>Wikipedia: Anarchism</title><url>x</url><abstract>Anarchism is a political philosophy.</abstract><links/></doc>
+<doc><title>Wikipedia: Empty</title><abstract> </abstract></doc>
+<doc><title>Wikipedia: Autism</title><abstract>Autism is a neurodevelopmental condition.</abstract></doc></feed>`))
+	gz.Close()
+	f.Close()
+	docs, err = LoadCorpus(abstracts, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Document{{"Anarchism", "Anarchism is a political philosophy."}, {"Autism", "Autism is a neurodevelopmental condition."}}
+	if !reflect.DeepEqual(docs, want) {
+		t.Errorf("abstract corpus = %v, want %v", docs, want)
+	}
+
+	if n := len(BundledCorpus()); n != 564 {
+		t.Errorf("bundled corpus has %d documents, want 564", n)
+	}
+}
+
+func TestFTS(t *testing.T) {
+	ctx := context.Background()
+	for _, mode := range []string{"build", "update", "query"} {
+		w := Workloads["fts."+mode].New()
+		db := setupWorkload(t, w, Params{Rows: 700, Seed: 1})
+		runOps(t, w, db, 30)
+		// Setup indexed 700 documents, and fts.update inserted ten and
+		// deleted up to ten.
+		var docs, indexed int
+		if err := QueryRow(ctx, db, "SELECT (SELECT count(*) FROM search), (SELECT count(*) FROM search WHERE search MATCH 'the')", &docs, &indexed); err != nil {
+			t.Fatal(err)
+		}
+		if mode != "update" && docs != 700 || mode == "update" && (docs < 700 || docs > 710) || indexed == 0 {
+			t.Errorf("%s: %d documents, %d matching; want about 700 and some", mode, docs, indexed)
+		}
+		if err := db.Exec(ctx, "INSERT INTO search (search, rank) VALUES ('integrity-check', 1)"); err != nil {
+			t.Errorf("%s: %v", mode, err)
+		}
+	}
+}
@@ -0,0 +1,307 @@
+package sqlitebench
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"runtime/debug"
+	"strconv"
+	"strings"
+)
+
+// FuzzRun is one driver's run of a fuzz sequence.
+type FuzzRun struct {
+	Driver string `json:"driver"`
+	// Outcomes are the statements' outcomes, formatted as a Difference's,
+	// up to the end of the sequence or a panic. Keys compare them, as in
+	// DiffDrivers.
+	Outcomes []string `json:"outcomes"`
+	Keys     []string `json:"keys"`
+	// Panic is the value and stack of the driver's panic that ended the
+	// run early.
+	Panic string `json:"panic,omitempty"`
+}
+
+// Errors returns the number of statements that failed.
+func (r FuzzRun) Errors() int {
+	n := 0
+	for _, key := range r.Keys {
+		if key == "error" {
+			n++
+		}
+	}
+	return n
+}
+
+// Fuzz runs the statements random in seed on a fresh in-memory database of
+// driver: tables created, altered and dropped, indexes, inserts, updates,
+// deletes and queries, mostly valid for the schema built so far. A panic
+// ends the run instead of the process; a crash in a driver's C code still
+// takes the process down, so callers that want to record those run Fuzz
+// in a child process.
+func Fuzz(ctx context.Context, driver string, seed int64, statements int) (FuzzRun, error) {
+	run := FuzzRun{Driver: driver}
+	b, ok := Drivers[driver]
+	if !ok {
+		return run, fmt.Errorf("unknown driver %q", driver)
+	}
+	db, err := b.Open(ctx, memoryDSN())
+	if err != nil {
+		return run, err
+	}
+	defer db.Close()
+
+	for _, st := range fuzzSequence(seed, statements) {
+		if err := ctx.Err(); err != nil {
+			return run, err
+		}
+		out, panicked := runFuzzStatement(ctx, db, st)
+		if panicked != "" {
+			run.Panic = panicked
+			break
+		}
+		run.Outcomes = append(run.Outcomes, out.detail)
+		run.Keys = append(run.Keys, out.key)
+	}
+	return run, nil
+}
+
+func runFuzzStatement(ctx context.Context, db Conn, st diffStatement) (out diffOutcome, panicked string) {
+	defer func() {
+		if p := recover(); p != nil {
+			panicked = fmt.Sprintf("%v\n%s", p, debug.Stack())
+		}
+	}()
+	return runDiffStatement(ctx, db, st), ""
+}
+
+// FuzzDifferences returns the statements of the sequence random in seed
+// whose outcomes differ between runs of it, among the runs that reached
+// them.
+func FuzzDifferences(seed int64, statements int, runs []FuzzRun) []Difference {
+	drivers := make([]string, len(runs))
+	outcomes := make([][]diffOutcome, len(runs))
+	for i, run := range runs {
+		drivers[i] = run.Driver
+		for j := range run.Keys {
+			outcomes[i] = append(outcomes[i], diffOutcome{run.Keys[j], run.Outcomes[j]})
+		}
+	}
+	return differences(fuzzSequence(seed, statements), drivers, outcomes)
+}
+
+// fuzzSequence returns the statements random in seed.
+func fuzzSequence(seed int64, statements int) []diffStatement {
+	g := &fuzzGrammar{rng: rand.New(rand.NewPCG(uint64(seed), uint64(statements)))}
+	seq := make([]diffStatement, statements)
+	for i := range seq {
+		seq[i] = g.statement()
+	}
+	return seq
+}
+
+// fuzzTable is a table of the schema a fuzz sequence builds.
+type fuzzTable struct {
+	name    string
+	columns []string
+}
+
+// fuzzGrammar generates statements for the schema built by those before,
+// assuming they all succeeded. Some fail anyway, e.g. on constraints, the
+// same way on every driver.
+type fuzzGrammar struct {
+	rng     *rand.Rand
+	tables  []*fuzzTable
+	indexes []string
+	// names numbers the tables, columns and indexes created, so names are
+	// never reused.
+	names int
+}
+
+// fuzzTypes are the declared column types, one of each affinity and none.
+var fuzzTypes = []string{"INTEGER", "TEXT", "REAL", "BLOB", "NUMERIC", ""}
+
+// fuzzOperators compare a column with a bound value.
+var fuzzOperators = []string{"=", "<", ">", "<=", "!=", "IS", "IS NOT"}
+
+func (g *fuzzGrammar) name(prefix string) string {
+	g.names++
+	return prefix + strconv.Itoa(g.names)
+}
+
+func (g *fuzzGrammar) table() *fuzzTable { return g.tables[g.rng.IntN(len(g.tables))] }
+
+func (g *fuzzGrammar) column(t *fuzzTable) string { return t.columns[g.rng.IntN(len(t.columns))] }
+
+func (g *fuzzGrammar) statement() diffStatement {
+	k := g.rng.IntN(100)
+	switch {
+	case len(g.tables) == 0 || k < 4:
+		return g.createTable()
+	case k < 8:
+		return g.alterTable()
+	case k < 12:
+		return g.createIndex()
+	case k < 14:
+		return g.drop()
+	case k < 44:
+		return g.insert()
+	case k < 58:
+		return g.update()
+	case k < 65:
+		return g.delete()
+	case k < 93:
+		return g.query()
+	default:
+		st := diffExpressions[g.rng.IntN(len(diffExpressions))]
+		st.args = nil
+		for range strings.Count(st.sql, "?") {
+			st.args = append(st.args, randomDiffValue(g.rng))
+		}
+		return st
+	}
+}
+
+func (g *fuzzGrammar) columnDef(col string) string {
+	def := strings.TrimSpace(col + " " + fuzzTypes[g.rng.IntN(len(fuzzTypes))])
+	switch g.rng.IntN(8) {
+	case 0:
+		def += " UNIQUE"
+	case 1:
+		def += " NOT NULL DEFAULT 0"
+	case 2:
+		def += " DEFAULT 'x'"
+	case 3:
+		def += " COLLATE NOCASE"
+	}
+	return def
+}
+
+func (g *fuzzGrammar) createTable() diffStatement {
+	t := &fuzzTable{name: g.name("t")}
+	// STRICT tables need a type for every column; ANY takes any value.
+	strict := g.rng.IntN(6) == 0
+	var defs []string
+	for range 1 + g.rng.IntN(4) {
+		col := g.name("c")
+		t.columns = append(t.columns, col)
+		if strict {
+			defs = append(defs, col+" ANY")
+		} else {
+			defs = append(defs, g.columnDef(col))
+		}
+	}
+	sql := "CREATE TABLE " + t.name + " (" + strings.Join(defs, ", ") + ")"
+	if strict {
+		sql += " STRICT"
+	}
+	g.tables = append(g.tables, t)
+	return diffStatement{sql: sql}
+}
+
+func (g *fuzzGrammar) alterTable() diffStatement {
+	t := g.table()
+	if g.rng.IntN(3) == 0 {
+		i := g.rng.IntN(len(t.columns))
+		old := t.columns[i]
+		t.columns[i] = g.name("c")
+		return diffStatement{sql: "ALTER TABLE " + t.name + " RENAME COLUMN " + old + " TO " + t.columns[i]}
+	}
+	col := g.name("c")
+	t.columns = append(t.columns, col)
+	// Added columns cannot be UNIQUE, nor NOT NULL without a default;
+	// leave those to fail.
+	return diffStatement{sql: "ALTER TABLE " + t.name + " ADD COLUMN " + g.columnDef(col)}
+}
+
+func (g *fuzzGrammar) createIndex() diffStatement {
+	t := g.table()
+	cols := []string{g.column(t)}
+	if g.rng.IntN(2) == 0 {
+		cols = append(cols, g.column(t)+" DESC")
+	}
+	index := g.name("i")
+	g.indexes = append(g.indexes, index)
+	unique := ""
+	if g.rng.IntN(4) == 0 {
+		unique = "UNIQUE "
+	}
+	return diffStatement{sql: "CREATE " + unique + "INDEX " + index + " ON " + t.name + " (" + strings.Join(cols, ", ") + ")"}
+}
+
+func (g *fuzzGrammar) drop() diffStatement {
+	if len(g.indexes) > 0 && (len(g.tables) == 1 || g.rng.IntN(2) == 0) {
+		i := g.rng.IntN(len(g.indexes))
+		index := g.indexes[i]
+		g.indexes = append(g.indexes[:i], g.indexes[i+1:]...)
+		// The index may have gone with its table; IF EXISTS keeps the
+		// statement valid.
+		return diffStatement{sql: "DROP INDEX IF EXISTS " + index}
+	}
+	if len(g.tables) == 1 {
+		return diffStatement{sql: "VACUUM"}
+	}
+	i := g.rng.IntN(len(g.tables))
+	t := g.tables[i]
+	g.tables = append(g.tables[:i], g.tables[i+1:]...)
+	return diffStatement{sql: "DROP TABLE " + t.name}
+}
+
+func (g *fuzzGrammar) insert() diffStatement {
+	t := g.table()
+	cols := t.columns[:1+g.rng.IntN(len(t.columns))]
+	verb := []string{"INSERT", "INSERT OR IGNORE", "INSERT OR REPLACE"}[g.rng.IntN(3)]
+	st := diffStatement{sql: verb + " INTO " + t.name + " (" + strings.Join(cols, ", ") + ") VALUES (" + strings.TrimSuffix(strings.Repeat("?, ", len(cols)), ", ") + ")"}
+	for range cols {
+		st.args = append(st.args, randomDiffValue(g.rng))
+	}
+	return st
+}
+
+func (g *fuzzGrammar) update() diffStatement {
+	t := g.table()
+	col := g.column(t)
+	set := col + " = ?"
+	if g.rng.IntN(3) == 0 {
+		set = col + " = " + g.column(t) + " || ?"
+	}
+	return diffStatement{
+		sql:  "UPDATE " + t.name + " SET " + set + " WHERE " + g.column(t) + " " + fuzzOperators[g.rng.IntN(len(fuzzOperators))] + " ?",
+		args: []any{randomDiffValue(g.rng), randomDiffValue(g.rng)},
+	}
+}
+
+func (g *fuzzGrammar) delete() diffStatement {
+	t := g.table()
+	return diffStatement{
+		sql:  "DELETE FROM " + t.name + " WHERE " + g.column(t) + " " + fuzzOperators[g.rng.IntN(len(fuzzOperators))] + " ?",
+		args: []any{randomDiffValue(g.rng)},
+	}
+}
+
+// query returns a query ordered fully, so drivers must agree on the rows'
+// order too.
+func (g *fuzzGrammar) query() diffStatement {
+	t := g.table()
+	switch g.rng.IntN(4) {
+	case 0:
+		col := g.column(t)
+		return diffStatement{sql: "SELECT count(*), count(" + col + "), min(" + col + "), max(" + col + "), total(" + col + ") FROM " + t.name, columns: 5}
+	case 1:
+		u := g.table()
+		a, b := g.column(t), g.column(u)
+		return diffStatement{
+			sql:     "SELECT x." + a + ", y." + b + " FROM " + t.name + " x JOIN " + u.name + " y ON x." + a + " = y." + b + " ORDER BY x.rowid, y.rowid LIMIT 20",
+			columns: 2,
+		}
+	case 2:
+		col := g.column(t)
+		return diffStatement{sql: "SELECT " + col + ", count(*) FROM " + t.name + " GROUP BY 1 ORDER BY 1 LIMIT 20", columns: 2}
+	default:
+		return diffStatement{
+			sql:     "SELECT rowid, " + strings.Join(t.columns, ", ") + " FROM " + t.name + " WHERE " + g.column(t) + " " + fuzzOperators[g.rng.IntN(len(fuzzOperators))] + " ? ORDER BY rowid LIMIT 20",
+			args:    []any{randomDiffValue(g.rng)},
+			columns: 1 + len(t.columns),
+		}
+	}
+}
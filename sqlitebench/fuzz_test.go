@@ -0,0 +1,46 @@
+package sqlitebench
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestFuzz(t *testing.T) {
+	const seed, statements = 3, 300
+	if a, b := fuzzSequence(seed, statements), fuzzSequence(seed, statements); a[statements-1].sql != b[statements-1].sql {
+		t.Fatal("sequence differs between calls with the same seed")
+	}
+
+	var runs []FuzzRun
+	for _, driver := range []string{"modernc", "modernc"} {
+		run, err := Fuzz(context.Background(), driver, seed, statements)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if run.Panic != "" || len(run.Keys) != statements {
+			t.Fatalf("ran %d statements, panic %q", len(run.Keys), run.Panic)
+		}
+		// Most statements fit the schema built so far.
+		if run.Errors() > statements/4 {
+			for i, out := range run.Outcomes {
+				if strings.HasPrefix(out, "error") {
+					t.Log(fuzzSequence(seed, statements)[i].sql, out)
+				}
+			}
+			t.Errorf("%d of %d statements failed", run.Errors(), statements)
+		}
+		runs = append(runs, run)
+	}
+	runs[1].Driver = "again"
+	if diffs := FuzzDifferences(seed, statements, runs); len(diffs) != 0 {
+		t.Errorf("same driver differs from itself: %+v", diffs[0])
+	}
+
+	// A run that stopped early is compared as far as it got.
+	runs[1].Keys, runs[1].Outcomes = runs[1].Keys[:10], runs[1].Outcomes[:10]
+	runs[1].Keys[5] = "different"
+	if diffs := FuzzDifferences(seed, statements, runs); len(diffs) != 1 || diffs[0].Step != 5 {
+		t.Errorf("differences = %+v, want step 5 only", diffs)
+	}
+}
@@ -0,0 +1,248 @@
+package sqlitebench
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Generator produces one column value per call. Generators drawing from the
+// same seeded source produce the same sequence on every run.
+type Generator func() any
+
+var generatorExpr = regexp.MustCompile(`^\s*(\w+)\s*(?:\((.*)\))?\s*$`)
+
+// ParseGenerator parses a generator expression:
+//
+//	int(min, max)          uniformly distributed integer in [min, max]
+//	float(min, max)        uniformly distributed float in [min, max)
+//	zipf(max[, s])         integer in [1, max], skewed so that small values
+//	                       are most frequent; s > 1 sets the skew (default 1.1)
+//	text(n)                n random lowercase letters
+//	name                   a person's full name, e.g. "Maria Schmidt"
+//	email                  an email address, e.g. "maria.schmidt42@example.org"
+//	timestamp[(from, to)]  RFC 3339 time between two dates (default 2020-01-01
+//	                       to 2025-01-01)
+//	uuid                   a random version 4 UUID
+//	json                   a small user profile JSON document
+//	blob                   the cell's next payload, of the cell's data size
+//	seq                    1, 2, 3, ... across the whole cell
+//
+// Values are drawn from rng. A nil rng only checks the syntax.
+func ParseGenerator(expr string, rng *rand.Rand, payloads *PayloadPool) (Generator, error) {
+	m := generatorExpr.FindStringSubmatch(expr)
+	if m == nil {
+		return nil, fmt.Errorf("invalid generator %q", expr)
+	}
+	name := m[1]
+	var args []string
+	if strings.TrimSpace(m[2]) != "" {
+		args = strings.Split(m[2], ",")
+		for i := range args {
+			args[i] = strings.TrimSpace(args[i])
+		}
+	}
+
+	nargs := func(lo, hi int) error {
+		if len(args) < lo || len(args) > hi {
+			if lo == hi {
+				return fmt.Errorf("generator %s takes %d arguments, got %d", name, lo, len(args))
+			}
+			return fmt.Errorf("generator %s takes %d to %d arguments, got %d", name, lo, hi, len(args))
+		}
+		return nil
+	}
+	ints := func(want int) ([]int64, error) {
+		if err := nargs(want, want); err != nil {
+			return nil, err
+		}
+		vals := make([]int64, want)
+		for i, arg := range args {
+			v, err := strconv.ParseInt(arg, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("generator %s: invalid argument %q", name, arg)
+			}
+			vals[i] = v
+		}
+		return vals, nil
+	}
+
+	switch name {
+	case "int":
+		vals, err := ints(2)
+		if err != nil {
+			return nil, err
+		}
+		lo, hi := vals[0], vals[1]
+		if hi < lo {
+			return nil, fmt.Errorf("generator int: max %d is below min %d", hi, lo)
+		}
+		return func() any { return lo + rng.Int64N(hi-lo+1) }, nil
+
+	case "float":
+		if err := nargs(2, 2); err != nil {
+			return nil, err
+		}
+		lo, err1 := strconv.ParseFloat(args[0], 64)
+		hi, err2 := strconv.ParseFloat(args[1], 64)
+		if err1 != nil || err2 != nil || hi < lo {
+			return nil, fmt.Errorf("generator float: invalid range %s, %s", args[0], args[1])
+		}
+		return func() any { return lo + rng.Float64()*(hi-lo) }, nil
+
+	case "zipf":
+		if err := nargs(1, 2); err != nil {
+			return nil, err
+		}
+		n, err := strconv.ParseUint(args[0], 10, 64)
+		if err != nil || n == 0 {
+			return nil, fmt.Errorf("generator zipf: invalid max %q", args[0])
+		}
+		s := 1.1
+		if len(args) == 2 {
+			if s, err = strconv.ParseFloat(args[1], 64); err != nil || s <= 1 {
+				return nil, fmt.Errorf("generator zipf: skew must be a number above 1, got %q", args[1])
+			}
+		}
+		if rng == nil {
+			return func() any { return int64(1) }, nil
+		}
+		z := rand.NewZipf(rng, s, 1, n-1)
+		return func() any { return int64(z.Uint64() + 1) }, nil
+
+	case "text":
+		vals, err := ints(1)
+		if err != nil {
+			return nil, err
+		}
+		n := int(vals[0])
+		if n < 0 {
+			return nil, fmt.Errorf("generator text: negative length %d", n)
+		}
+		return func() any { return randomLetters(rng, n) }, nil
+
+	case "name":
+		if err := nargs(0, 0); err != nil {
+			return nil, err
+		}
+		return func() any { return pick(rng, firstNames) + " " + pick(rng, lastNames) }, nil
+
+	case "email":
+		if err := nargs(0, 0); err != nil {
+			return nil, err
+		}
+		return func() any { return randomEmail(rng) }, nil
+
+	case "timestamp":
+		if len(args) == 1 || len(args) > 2 {
+			return nil, fmt.Errorf("generator timestamp takes 0 or 2 arguments, got %d", len(args))
+		}
+		from, to := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+		if len(args) == 2 {
+			var err1, err2 error
+			from, err1 = time.Parse(time.DateOnly, args[0])
+			to, err2 = time.Parse(time.DateOnly, args[1])
+			if err1 != nil || err2 != nil || !to.After(from) {
+				return nil, fmt.Errorf("generator timestamp: invalid range %s, %s (want dates like 2024-01-31)", args[0], args[1])
+			}
+		}
+		span := to.Sub(from)
+		return func() any { return from.Add(time.Duration(rng.Int64N(int64(span)))).Format(time.RFC3339) }, nil
+
+	case "uuid":
+		if err := nargs(0, 0); err != nil {
+			return nil, err
+		}
+		return func() any { return randomUUID(rng) }, nil
+
+	case "json":
+		if err := nargs(0, 0); err != nil {
+			return nil, err
+		}
+		return func() any { return randomProfile(rng) }, nil
+
+	case "blob":
+		if err := nargs(0, 0); err != nil {
+			return nil, err
+		}
+		return func() any { return payloads.Next() }, nil
+
+	case "seq":
+		if err := nargs(0, 0); err != nil {
+			return nil, err
+		}
+		var n int64
+		return func() any { n++; return n }, nil
+	}
+	return nil, fmt.Errorf("unknown generator %q", name)
+}
+
+var firstNames = []string{
+	"James", "Maria", "Wei", "Aisha", "Lukas", "Sofia", "Hiroshi", "Fatima", "Noah", "Olivia",
+	"Mateo", "Chloe", "Arjun", "Emma", "Kwame", "Ingrid", "Diego", "Yuki", "Omar", "Hannah",
+}
+
+var lastNames = []string{
+	"Smith", "Schmidt", "Wang", "Khan", "Garcia", "Rossi", "Tanaka", "Nowak", "Silva", "Johnson",
+	"Müller", "Kim", "Okafor", "Dubois", "Ivanova", "Larsen", "Novak", "Patel", "Cohen", "Hernández",
+}
+
+var emailDomains = []string{"example.com", "example.org", "example.net", "mail.test"}
+
+func pick(rng *rand.Rand, words []string) string {
+	return words[rng.IntN(len(words))]
+}
+
+func randomLetters(rng *rand.Rand, n int) string {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte('a' + rng.IntN(26))
+	}
+	return string(b)
+}
+
+// randomEmail builds an address from ASCII-only name parts, so it is a
+// valid local part without escaping.
+func randomEmail(rng *rand.Rand) string {
+	first := strings.ToLower(pick(rng, firstNames))
+	last := randomLetters(rng, 3+rng.IntN(6))
+	return fmt.Sprintf("%s.%s%d@%s", first, last, rng.IntN(100), pick(rng, emailDomains))
+}
+
+func randomUUID(rng *rand.Rand) string {
+	var b [16]byte
+	for i := range b {
+		b[i] = byte(rng.Uint32())
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// randomProfile returns a JSON document shaped like a typical application
+// record: scalars, a nested object and an array of varying length.
+func randomProfile(rng *rand.Rand) string {
+	tags := make([]string, rng.IntN(5))
+	for i := range tags {
+		tags[i] = randomLetters(rng, 4+rng.IntN(5))
+	}
+	doc := map[string]any{
+		"id":     randomUUID(rng),
+		"name":   pick(rng, firstNames) + " " + pick(rng, lastNames),
+		"email":  randomEmail(rng),
+		"age":    18 + rng.IntN(70),
+		"active": rng.IntN(4) != 0,
+		"score":  float64(rng.IntN(10000)) / 100,
+		"address": map[string]any{
+			"city": pick(rng, lastNames) + "ville",
+			"zip":  fmt.Sprintf("%05d", rng.IntN(100000)),
+		},
+		"tags": tags,
+	}
+	data, _ := json.Marshal(doc)
+	return string(data)
+}
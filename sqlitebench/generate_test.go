@@ -0,0 +1,95 @@
+package sqlitebench
+
+import (
+	"encoding/json"
+	"math/rand/v2"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseGenerator(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 0))
+	valid := []string{
+		"int(1, 3)", "float(0,1)", "zipf(100)", "zipf(100, 2)", "text(5)", "name", "email",
+		"timestamp", "timestamp(2024-01-01, 2024-02-01)", "uuid", "json", "blob", "seq", " seq() ",
+	}
+	for _, expr := range valid {
+		if _, err := ParseGenerator(expr, rng, NewPayloadPool(1, 0, 8)); err != nil {
+			t.Errorf("ParseGenerator(%q): %v", expr, err)
+		}
+		if _, err := ParseGenerator(expr, nil, nil); err != nil {
+			t.Errorf("ParseGenerator(%q) without a source: %v", expr, err)
+		}
+	}
+	invalid := []string{
+		"", "int(3, 1)", "int(1)", "text(x)", "blob(4)", "nope", "int(1, 2",
+		"zipf(0)", "zipf(10, 1)", "timestamp(2024-01-01)", "timestamp(2024-02-01, 2024-01-01)", "name(3)",
+	}
+	for _, expr := range invalid {
+		if _, err := ParseGenerator(expr, rng, nil); err == nil {
+			t.Errorf("ParseGenerator(%q) succeeded, want an error", expr)
+		}
+	}
+}
+
+func TestGeneratorValues(t *testing.T) {
+	gen := func(expr string) Generator {
+		t.Helper()
+		g, err := ParseGenerator(expr, rand.New(rand.NewPCG(7, 0)), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return g
+	}
+
+	ints, zipf := gen("int(1, 3)"), gen("zipf(50)")
+	ones := 0
+	for range 1000 {
+		if v := ints().(int64); v < 1 || v > 3 {
+			t.Fatalf("int(1, 3) generated %d", v)
+		}
+		v := zipf().(int64)
+		if v < 1 || v > 50 {
+			t.Fatalf("zipf(50) generated %d", v)
+		}
+		if v == 1 {
+			ones++
+		}
+	}
+	if ones < 200 {
+		t.Errorf("zipf(50) generated 1 only %d times in 1000, want it most frequent", ones)
+	}
+
+	if s := gen("text(5)")().(string); len(s) != 5 {
+		t.Errorf("text(5) generated %q", s)
+	}
+	if s := gen("email")().(string); !strings.Contains(s, "@") {
+		t.Errorf("email generated %q", s)
+	}
+	if s := gen("name")().(string); len(strings.Fields(s)) != 2 {
+		t.Errorf("name generated %q", s)
+	}
+	if s := gen("uuid")().(string); len(s) != 36 || s[14] != '4' {
+		t.Errorf("uuid generated %q", s)
+	}
+	ts, err := time.Parse(time.RFC3339, gen("timestamp(2024-01-01, 2024-01-02)")().(string))
+	if err != nil || ts.Before(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) || !ts.Before(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("timestamp generated %v, %v", ts, err)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal([]byte(gen("json")().(string)), &doc); err != nil || doc["email"] == nil {
+		t.Errorf("json generated %v, %v", doc, err)
+	}
+}
+
+func TestGeneratorDeterminism(t *testing.T) {
+	draw := func() []any {
+		g, _ := ParseGenerator("json", rand.New(rand.NewPCG(3, 0)), nil)
+		return []any{g(), g(), g()}
+	}
+	if a, b := draw(), draw(); !reflect.DeepEqual(a, b) {
+		t.Errorf("same seed generated %v and %v", a, b)
+	}
+}
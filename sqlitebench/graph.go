@@ -0,0 +1,130 @@
+package sqlitebench
+
+import (
+	"context"
+	"fmt"
+)
+
+func init() {
+	Register(Benchmark{Category: CategoryRead, New: func() Workload { return NewGraph() }})
+	Register(Benchmark{Category: CategoryRead, New: func() Workload {
+		g := NewGraph()
+		g.Path = true
+		return g
+	}})
+}
+
+// graphMaxPath is the longest path graph.path looks for.
+const graphMaxPath = 8
+
+// Graph stores a directed graph of Params.Rows nodes, each with its
+// properties as the payload, in a nodes table and an edges table, and runs
+// a recursive CTE per operation from a random node. Every node has FanOut
+// edges: one to the next node, so all are connected, and the others to
+// random nodes, so paths are short, as in social and link graphs.
+//
+// graph.khop reads the properties of the nodes within Hops hops. graph.path
+// looks for the shortest path to another random node, breadth first, and
+// stops at the first path found or at graphMaxPath hops; it visits about
+// FanOut to the power of the path's length nodes, so large fan-outs make
+// its operations long. Results of different fan-outs share the workloads'
+// names and are only comparable within a run.
+type Graph struct {
+	rowBytes
+
+	// Path selects graph.path instead of graph.khop.
+	Path bool
+	// FanOut is the number of edges leaving each node and Hops how far
+	// graph.khop reaches.
+	FanOut, Hops int
+
+	nodes int
+	rng   lockedRand
+}
+
+// NewGraph returns a graph.khop Graph with a fan-out of 8 reaching 3 hops.
+func NewGraph() *Graph { return &Graph{FanOut: 8, Hops: 3} }
+
+// Validate checks that nodes have edges and the neighbourhood has hops.
+func (w *Graph) Validate() error {
+	if w.FanOut < 1 {
+		return fmt.Errorf("graph fan-out must be positive, got %d", w.FanOut)
+	}
+	if w.Hops < 1 {
+		return fmt.Errorf("graph hops must be positive, got %d", w.Hops)
+	}
+	return nil
+}
+
+func (w *Graph) Name() string {
+	if w.Path {
+		return "graph.path"
+	}
+	return "graph.khop"
+}
+
+func (w *Graph) Description() string {
+	if w.Path {
+		return "find the shortest path between two nodes of a graph per operation"
+	}
+	return "read the k-hop neighbourhood of a node of a graph per operation"
+}
+
+func (w *Graph) Setup(ctx context.Context, db Conn, p Params) error {
+	w.reset()
+	w.nodes = max(p.Rows, 1)
+	w.rng.seed(p)
+	for _, stmt := range []string{
+		"CREATE TABLE nodes (id INTEGER PRIMARY KEY, label TEXT NOT NULL, properties BLOB)",
+		"CREATE TABLE edges (src INTEGER NOT NULL, dst INTEGER NOT NULL, PRIMARY KEY (src, dst)) WITHOUT ROWID",
+	} {
+		if err := db.Exec(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return populate(ctx, db, w.nodes, func(tx Tx, i int) error {
+		id := i + 1
+		if err := tx.Exec(ctx, "INSERT INTO nodes (id, label, properties) VALUES (?, ?, ?)", id, fmt.Sprintf("node %d", id), p.Payloads.Next()); err != nil {
+			return err
+		}
+		// A random edge may repeat one already there.
+		for e := range w.FanOut {
+			dst := id%w.nodes + 1
+			if e > 0 {
+				dst = w.node()
+			}
+			if err := tx.Exec(ctx, "INSERT OR IGNORE INTO edges (src, dst) VALUES (?, ?)", id, dst); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// node picks a random node.
+func (w *Graph) node() int {
+	return w.rng.intN(w.nodes) + 1
+}
+
+func (w *Graph) Run(ctx context.Context, db Conn, n int) error {
+	if w.Path {
+		// UNION drops the paths reaching a node at a depth already
+		// reached, and ordering by depth makes the search breadth first,
+		// so the first row found is a shortest path and ends it.
+		return runStatement(ctx, db, `WITH RECURSIVE bfs(node, depth) AS (
+				SELECT ?, 0
+				UNION
+				SELECT e.dst, b.depth + 1 FROM bfs b JOIN edges e ON e.src = b.node WHERE b.depth < ?
+				ORDER BY 2
+			)
+			SELECT depth FROM bfs WHERE node = ? LIMIT 1`, []any{w.node(), graphMaxPath, w.node()}, &w.rowBytes)
+	}
+	return runStatement(ctx, db, `WITH RECURSIVE reach(node, depth) AS (
+			SELECT ?, 0
+			UNION
+			SELECT e.dst, r.depth + 1 FROM reach r JOIN edges e ON e.src = r.node WHERE r.depth < ?
+		)
+		SELECT id, label, properties FROM nodes WHERE id IN (SELECT node FROM reach)`, []any{w.node(), w.Hops}, &w.rowBytes)
+}
+
+func (*Graph) Teardown(db Conn) error { return nil }
@@ -0,0 +1,44 @@
+package sqlitebench
+
+import (
+	"context"
+	"testing"
+)
+
+func TestGraph(t *testing.T) {
+	ctx := context.Background()
+	w := NewGraph()
+	w.FanOut = 4
+	db := setupWorkload(t, w, Params{Rows: 500, Seed: 1})
+	var nodes, edges, ring int
+	if err := QueryRow(ctx, db, "SELECT (SELECT count(*) FROM nodes), (SELECT count(*) FROM edges), (SELECT count(*) FROM edges WHERE dst = src % 500 + 1)", &nodes, &edges, &ring); err != nil {
+		t.Fatal(err)
+	}
+	if nodes != 500 || edges < 1900 || edges > 2000 || ring != 500 {
+		t.Errorf("%d nodes, %d edges, %d to the next node; want 500, about 2000 and 500", nodes, edges, ring)
+	}
+
+	// Node 1 reaches nodes 2 and 3 through the ring in two hops, and a
+	// path leads from every node to every other.
+	var reached, depth int
+	if err := QueryRow(ctx, db, `WITH RECURSIVE reach(node, depth) AS (
+			SELECT 1, 0 UNION SELECT e.dst, r.depth + 1 FROM reach r JOIN edges e ON e.src = r.node WHERE r.depth < 2
+		) SELECT count(*) FROM nodes WHERE id IN (SELECT node FROM reach) AND id IN (2, 3)`, &reached); err != nil {
+		t.Fatal(err)
+	}
+	if reached != 2 {
+		t.Errorf("node 1 reached %d of nodes 2 and 3 in two hops, want both", reached)
+	}
+	for _, path := range []bool{false, true} {
+		w.Path = path
+		t.Run(w.Name(), func(t *testing.T) { runOps(t, w, db, 20) })
+	}
+	if err := QueryRow(ctx, db, `WITH RECURSIVE bfs(node, depth) AS (
+			SELECT 1, 0 UNION SELECT e.dst, b.depth + 1 FROM bfs b JOIN edges e ON e.src = b.node WHERE b.depth < 8 ORDER BY 2
+		) SELECT depth FROM bfs WHERE node = 250 LIMIT 1`, &depth); err != nil {
+		t.Fatal(err)
+	}
+	if depth < 2 || depth > 8 {
+		t.Errorf("shortest path from node 1 to 250 has %d hops, want 2 to 8", depth)
+	}
+}
@@ -0,0 +1,94 @@
+package sqlitebench
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// IOCounters counts the file operations SQLite made through a driver's
+// counting VFS during the timed loop of a cell, to tell apart drivers that
+// do the same I/O more slowly from those that do more of it.
+type IOCounters struct {
+	Reads        uint64 `json:"reads"`
+	Writes       uint64 `json:"writes"`
+	Syncs        uint64 `json:"syncs"`
+	ReadBytes    uint64 `json:"read_bytes"`
+	WrittenBytes uint64 `json:"written_bytes"`
+}
+
+func (c IOCounters) sub(before IOCounters) IOCounters {
+	return IOCounters{
+		Reads:        c.Reads - before.Reads,
+		Writes:       c.Writes - before.Writes,
+		Syncs:        c.Syncs - before.Syncs,
+		ReadBytes:    c.ReadBytes - before.ReadBytes,
+		WrittenBytes: c.WrittenBytes - before.WrittenBytes,
+	}
+}
+
+func (c *IOCounters) add(other IOCounters) {
+	c.Reads += other.Reads
+	c.Writes += other.Writes
+	c.Syncs += other.Syncs
+	c.ReadBytes += other.ReadBytes
+	c.WrittenBytes += other.WrittenBytes
+}
+
+// countingVFS is a VFS wrapping a driver's default one that counts the
+// reads, writes and syncs passing through it. The counts are process-wide,
+// like the allocation counts, as cells run one at a time.
+type countingVFS struct {
+	// register registers the VFS with the driver's SQLite and returns
+	// the name databases select it by.
+	register func() (name string, err error)
+	// read returns the counts so far.
+	read func() IOCounters
+	// log logs the changes to files, for PowerLossTest; nil if the VFS
+	// cannot.
+	log *fileLog
+	// limit makes writes growing a file beyond maxSize fail with
+	// SQLITE_FULL, for DiskFullTest, or lifts the limit if maxSize is 0.
+	limit func(maxSize int64)
+
+	once sync.Once
+	name string
+	err  error
+}
+
+// ioVFSes maps the drivers with a counting VFS to it. Each is registered
+// the first time a cell opens a database through it.
+var ioVFSes = map[string]*countingVFS{}
+
+// ioVFS returns the name of the driver's counting VFS, registering it if
+// needed. ok is false if the driver has none.
+func ioVFS(driver string) (name string, ok bool, err error) {
+	v, ok := ioVFSes[driver]
+	if !ok {
+		return "", false, nil
+	}
+	v.once.Do(func() { v.name, v.err = v.register() })
+	return v.name, true, v.err
+}
+
+// IOCounterDrivers returns the drivers whose I/O Runner.IOCounters counts.
+func IOCounterDrivers() []string {
+	var drivers []string
+	for name := range Drivers {
+		if _, ok := ioVFSes[name]; ok {
+			drivers = append(drivers, name)
+		}
+	}
+	sort.Strings(drivers)
+	return drivers
+}
+
+// withVFS adds a vfs parameter to dsn, which both drivers understand for
+// file names and URIs.
+func withVFS(dsn, vfs string) string {
+	sep := "?"
+	if strings.Contains(dsn, "?") {
+		sep = "&"
+	}
+	return dsn + sep + "vfs=" + vfs
+}
@@ -0,0 +1,324 @@
+//go:build cgo
+
+package sqlitebench
+
+/*
+#include <stdint.h>
+#include <stdlib.h>
+#include <string.h>
+
+// The declarations below match sqlite3.h. mattn/go-sqlite3 links SQLite
+// into the binary, so its functions resolve at link time.
+
+typedef long long sqlite3_int64;
+typedef struct sqlite3_file sqlite3_file;
+typedef struct sqlite3_io_methods sqlite3_io_methods;
+typedef struct sqlite3_vfs sqlite3_vfs;
+
+struct sqlite3_file {
+	const sqlite3_io_methods *pMethods;
+};
+
+struct sqlite3_io_methods {
+	int iVersion;
+	int (*xClose)(sqlite3_file*);
+	int (*xRead)(sqlite3_file*, void*, int, sqlite3_int64);
+	int (*xWrite)(sqlite3_file*, const void*, int, sqlite3_int64);
+	int (*xTruncate)(sqlite3_file*, sqlite3_int64);
+	int (*xSync)(sqlite3_file*, int);
+	int (*xFileSize)(sqlite3_file*, sqlite3_int64*);
+	int (*xLock)(sqlite3_file*, int);
+	int (*xUnlock)(sqlite3_file*, int);
+	int (*xCheckReservedLock)(sqlite3_file*, int*);
+	int (*xFileControl)(sqlite3_file*, int, void*);
+	int (*xSectorSize)(sqlite3_file*);
+	int (*xDeviceCharacteristics)(sqlite3_file*);
+	int (*xShmMap)(sqlite3_file*, int, int, int, void volatile**);
+	int (*xShmLock)(sqlite3_file*, int, int, int);
+	void (*xShmBarrier)(sqlite3_file*);
+	int (*xShmUnmap)(sqlite3_file*, int);
+	int (*xFetch)(sqlite3_file*, sqlite3_int64, int, void**);
+	int (*xUnfetch)(sqlite3_file*, sqlite3_int64, void*);
+};
+
+struct sqlite3_vfs {
+	int iVersion;
+	int szOsFile;
+	int mxPathname;
+	sqlite3_vfs *pNext;
+	const char *zName;
+	void *pAppData;
+	int (*xOpen)(sqlite3_vfs*, const char*, sqlite3_file*, int, int*);
+	int (*xDelete)(sqlite3_vfs*, const char*, int);
+	// The remaining methods are copied from the base VFS unchanged.
+	void *rest[14];
+};
+
+sqlite3_vfs *sqlite3_vfs_find(const char*);
+int sqlite3_vfs_register(sqlite3_vfs*, int);
+
+static uint64_t counts[5];
+// max_size caps the size of files written to, if positive.
+static sqlite3_int64 max_size;
+static sqlite3_vfs *base;
+static sqlite3_vfs vfs;
+static sqlite3_io_methods methods[3];
+
+typedef struct {
+	sqlite3_file file;
+	sqlite3_file *base;
+	// name is the file's path, which SQLite keeps until it closes the file,
+	// or null for a temporary file.
+	const char *name;
+} counting_file;
+
+#define BASE(f) (((counting_file*)(f))->base)
+#define NAME(f) (((counting_file*)(f))->name)
+#define COUNT(i, n) __atomic_add_fetch(&counts[i], (n), __ATOMIC_RELAXED)
+
+// file_op is a logged change to a file. Its kinds are those of fileOp.
+typedef struct {
+	int kind;
+	int dir_sync;
+	char *name;
+	sqlite3_int64 off;
+	int n;
+	void *data;
+} file_op;
+
+static int logging;
+static char log_lock;
+static file_op *log_ops;
+static int log_len, log_cap;
+
+#define LOCK() while (__atomic_test_and_set(&log_lock, __ATOMIC_ACQUIRE)) {}
+#define UNLOCK() __atomic_clear(&log_lock, __ATOMIC_RELEASE)
+
+static void log_op(int kind, const char *name, sqlite3_int64 off, const void *p, int n, int dir_sync) {
+	if (!name || !__atomic_load_n(&logging, __ATOMIC_RELAXED)) {
+		return;
+	}
+	LOCK();
+	if (log_len == log_cap) {
+		int cap = log_cap ? 2 * log_cap : 1024;
+		file_op *ops = realloc(log_ops, cap * sizeof(file_op));
+		if (!ops) {
+			UNLOCK();
+			return;
+		}
+		log_ops = ops;
+		log_cap = cap;
+	}
+	file_op *op = &log_ops[log_len++];
+	op->kind = kind;
+	op->dir_sync = dir_sync;
+	op->name = strdup(name);
+	op->off = off;
+	op->n = n;
+	op->data = 0;
+	if (n > 0 && (op->data = malloc(n))) {
+		memcpy(op->data, p, n);
+	} else {
+		op->n = 0;
+	}
+	UNLOCK();
+}
+
+static void set_max_size(sqlite3_int64 n) { __atomic_store_n(&max_size, n, __ATOMIC_RELAXED); }
+
+static void set_logging(int on) { __atomic_store_n(&logging, on, __ATOMIC_RELAXED); }
+
+static int log_length(void) {
+	LOCK();
+	int n = log_len;
+	UNLOCK();
+	return n;
+}
+
+// take_log returns the log and starts a new one. The caller frees it with
+// free_log.
+static file_op *take_log(int *n) {
+	LOCK();
+	file_op *ops = log_ops;
+	*n = log_len;
+	log_ops = 0;
+	log_len = log_cap = 0;
+	UNLOCK();
+	return ops;
+}
+
+static void free_log(file_op *ops, int n) {
+	for (int i = 0; i < n; i++) {
+		free(ops[i].name);
+		free(ops[i].data);
+	}
+	free(ops);
+}
+
+static int xClose(sqlite3_file *f) { return BASE(f)->pMethods->xClose(BASE(f)); }
+static int xRead(sqlite3_file *f, void *p, int n, sqlite3_int64 off) {
+	COUNT(0, 1);
+	COUNT(3, n);
+	return BASE(f)->pMethods->xRead(BASE(f), p, n, off);
+}
+static int xWrite(sqlite3_file *f, const void *p, int n, sqlite3_int64 off) {
+	COUNT(1, 1);
+	COUNT(4, n);
+	sqlite3_int64 limit = __atomic_load_n(&max_size, __ATOMIC_RELAXED), size;
+	if (limit > 0 && off + n > limit && BASE(f)->pMethods->xFileSize(BASE(f), &size) == 0 && off + n > size) {
+		return 13; // SQLITE_FULL, as the OS VFS returns on ENOSPC
+	}
+	int rc = BASE(f)->pMethods->xWrite(BASE(f), p, n, off);
+	if (rc == 0) {
+		log_op(0, NAME(f), off, p, n, 0);
+	}
+	return rc;
+}
+static int xTruncate(sqlite3_file *f, sqlite3_int64 size) {
+	int rc = BASE(f)->pMethods->xTruncate(BASE(f), size);
+	if (rc == 0) {
+		log_op(1, NAME(f), size, 0, 0, 0);
+	}
+	return rc;
+}
+static int xSync(sqlite3_file *f, int flags) {
+	COUNT(2, 1);
+	int rc = BASE(f)->pMethods->xSync(BASE(f), flags);
+	if (rc == 0) {
+		log_op(2, NAME(f), 0, 0, 0, 0);
+	}
+	return rc;
+}
+static int xFileSize(sqlite3_file *f, sqlite3_int64 *size) { return BASE(f)->pMethods->xFileSize(BASE(f), size); }
+static int xLock(sqlite3_file *f, int lock) { return BASE(f)->pMethods->xLock(BASE(f), lock); }
+static int xUnlock(sqlite3_file *f, int lock) { return BASE(f)->pMethods->xUnlock(BASE(f), lock); }
+static int xCheckReservedLock(sqlite3_file *f, int *out) { return BASE(f)->pMethods->xCheckReservedLock(BASE(f), out); }
+static int xFileControl(sqlite3_file *f, int op, void *arg) { return BASE(f)->pMethods->xFileControl(BASE(f), op, arg); }
+static int xSectorSize(sqlite3_file *f) { return BASE(f)->pMethods->xSectorSize(BASE(f)); }
+static int xDeviceCharacteristics(sqlite3_file *f) { return BASE(f)->pMethods->xDeviceCharacteristics(BASE(f)); }
+static int xShmMap(sqlite3_file *f, int pg, int pgsz, int extend, void volatile **pp) { return BASE(f)->pMethods->xShmMap(BASE(f), pg, pgsz, extend, pp); }
+static int xShmLock(sqlite3_file *f, int offset, int n, int flags) { return BASE(f)->pMethods->xShmLock(BASE(f), offset, n, flags); }
+static void xShmBarrier(sqlite3_file *f) { BASE(f)->pMethods->xShmBarrier(BASE(f)); }
+static int xShmUnmap(sqlite3_file *f, int del) { return BASE(f)->pMethods->xShmUnmap(BASE(f), del); }
+static int xFetch(sqlite3_file *f, sqlite3_int64 off, int n, void **pp) { return BASE(f)->pMethods->xFetch(BASE(f), off, n, pp); }
+static int xUnfetch(sqlite3_file *f, sqlite3_int64 off, void *p) { return BASE(f)->pMethods->xUnfetch(BASE(f), off, p); }
+
+static int xOpen(sqlite3_vfs *v, const char *name, sqlite3_file *f, int flags, int *outFlags) {
+	counting_file *c = (counting_file*)f;
+	c->file.pMethods = 0;
+	c->base = (sqlite3_file*)(c + 1);
+	c->name = name;
+	int rc = base->xOpen(base, name, c->base, flags, outFlags);
+	if (c->base->pMethods) {
+		int v = c->base->pMethods->iVersion;
+		c->file.pMethods = &methods[(v > 3 ? 3 : v) - 1];
+	}
+	return rc;
+}
+
+static int xDelete(sqlite3_vfs *v, const char *name, int dirSync) {
+	int rc = base->xDelete(base, name, dirSync);
+	if (rc == 0) {
+		log_op(3, name, 0, 0, 0, dirSync);
+	}
+	return rc;
+}
+
+static int register_counting_vfs(const char *name) {
+	if (!(base = sqlite3_vfs_find(0))) {
+		return 1;
+	}
+	vfs = *base;
+	vfs.szOsFile += sizeof(counting_file);
+	vfs.pNext = 0;
+	vfs.zName = name;
+	vfs.xOpen = xOpen;
+	vfs.xDelete = xDelete;
+	for (int i = 0; i < 3; i++) {
+		sqlite3_io_methods m = {i + 1, xClose, xRead, xWrite, xTruncate, xSync, xFileSize, xLock, xUnlock,
+			xCheckReservedLock, xFileControl, xSectorSize, xDeviceCharacteristics};
+		if (i >= 1) {
+			m.xShmMap = xShmMap;
+			m.xShmLock = xShmLock;
+			m.xShmBarrier = xShmBarrier;
+			m.xShmUnmap = xShmUnmap;
+		}
+		if (i >= 2) {
+			m.xFetch = xFetch;
+			m.xUnfetch = xUnfetch;
+		}
+		methods[i] = m;
+	}
+	return sqlite3_vfs_register(&vfs, 0);
+}
+
+static void read_counts(uint64_t *out) {
+	for (int i = 0; i < 5; i++) {
+		out[i] = __atomic_load_n(&counts[i], __ATOMIC_RELAXED);
+	}
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"path/filepath"
+	"unsafe"
+)
+
+// The mattn counting VFS is the same wrapper as the modernc one, in C, so
+// counting adds no cgo calls to the driver's I/O.
+
+func init() {
+	ioVFSes["mattn"] = &countingVFS{
+		register: registerMattnVFS,
+		read:     readMattnIO,
+		limit:    func(maxSize int64) { C.set_max_size(C.sqlite3_int64(maxSize)) },
+		log: &fileLog{
+			start: func() { C.set_logging(1) },
+			len:   func() int { return int(C.log_length()) },
+			stop:  stopMattnLog,
+		},
+	}
+}
+
+// mattnVFSName is in C memory as SQLite keeps the pointer.
+var mattnVFSName = C.CString("sqlitebench-io")
+
+func registerMattnVFS() (string, error) {
+	if rc := C.register_counting_vfs(mattnVFSName); rc != 0 {
+		return "", fmt.Errorf("registering VFS: error code %d", rc)
+	}
+	return C.GoString(mattnVFSName), nil
+}
+
+func readMattnIO() IOCounters {
+	var counts [5]C.uint64_t
+	C.read_counts(&counts[0])
+	return IOCounters{
+		Reads:        uint64(counts[0]),
+		Writes:       uint64(counts[1]),
+		Syncs:        uint64(counts[2]),
+		ReadBytes:    uint64(counts[3]),
+		WrittenBytes: uint64(counts[4]),
+	}
+}
+
+func stopMattnLog() []fileOp {
+	C.set_logging(0)
+	var n C.int
+	log := C.take_log(&n)
+	defer C.free_log(log, n)
+
+	var ops []fileOp
+	for _, op := range unsafe.Slice(log, int(n)) {
+		ops = append(ops, fileOp{
+			kind:    byte(op.kind),
+			name:    filepath.Base(C.GoString(op.name)),
+			off:     int64(op.off),
+			data:    C.GoBytes(op.data, op.n),
+			dirSync: op.dir_sync != 0,
+		})
+	}
+	return ops
+}
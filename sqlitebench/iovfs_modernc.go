@@ -0,0 +1,322 @@
+//go:build linux || darwin || windows || (freebsd && (amd64 || arm64))
+
+package sqlitebench
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+
+	"modernc.org/libc"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// The modernc counting VFS is written against modernc's transpiled SQLite,
+// where C pointers are uintptrs into libc memory and C function pointers
+// are Go func values. Its structs live in Go globals, which never move.
+
+func init() {
+	ioVFSes["modernc"] = &countingVFS{
+		register: registerModerncVFS,
+		read:     moderncIO.read,
+		limit:    moderncMaxSize.Store,
+		log:      &fileLog{start: moderncLog.start, len: moderncLog.length, stop: moderncLog.stop},
+	}
+}
+
+var (
+	moderncIO  ioCounts
+	moderncLog goFileLog
+	// moderncMaxSize caps the size of files written to, if positive.
+	moderncMaxSize atomic.Int64
+)
+
+// ioCounts are counts updated from any connection's goroutine.
+type ioCounts struct {
+	reads, writes, syncs, readBytes, writtenBytes atomic.Uint64
+}
+
+func (c *ioCounts) read() IOCounters {
+	return IOCounters{
+		Reads:        c.reads.Load(),
+		Writes:       c.writes.Load(),
+		Syncs:        c.syncs.Load(),
+		ReadBytes:    c.readBytes.Load(),
+		WrittenBytes: c.writtenBytes.Load(),
+	}
+}
+
+// goFileLog is a fileLog kept in Go.
+type goFileLog struct {
+	on  atomic.Bool
+	mu  sync.Mutex
+	ops []fileOp
+}
+
+func (l *goFileLog) start() { l.on.Store(true) }
+
+func (l *goFileLog) length() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.ops)
+}
+
+func (l *goFileLog) stop() []fileOp {
+	l.on.Store(false)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	ops := l.ops
+	l.ops = nil
+	return ops
+}
+
+// add logs op on the file named by the C string zName, unless logging is
+// off or the file is temporary.
+func (l *goFileLog) add(zName uintptr, op fileOp) {
+	if zName == 0 || !l.on.Load() {
+		return
+	}
+	op.name = filepath.Base(libc.GoString(zName))
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.on.Load() {
+		l.ops = append(l.ops, op)
+	}
+}
+
+var (
+	// moderncBase is the default VFS the counting one wraps.
+	moderncBase uintptr
+	moderncVFS  sqlite3.Tsqlite3_vfs
+	// moderncMethods holds the wrapping methods of each sqlite3_io_methods
+	// version, so files get the same version their base VFS gives them.
+	moderncMethods [3]sqlite3.Tsqlite3_io_methods1
+)
+
+// moderncFile is the sqlite3_file of the counting VFS. The base VFS's file
+// follows it.
+type moderncFile struct {
+	methods uintptr
+	base    uintptr
+	// name is the file's path, which SQLite keeps until it closes the
+	// file, or 0 for a temporary file.
+	name uintptr
+}
+
+func registerModerncVFS() (string, error) {
+	const name = "sqlitebench-io"
+	tls := libc.NewTLS()
+	defer tls.Close()
+
+	if moderncBase = sqlite3.Xsqlite3_vfs_find(tls, 0); moderncBase == 0 {
+		return "", errors.New("no default VFS")
+	}
+	zName, err := libc.CString(name)
+	if err != nil {
+		return "", err
+	}
+	moderncVFS = *(*sqlite3.Tsqlite3_vfs)(cptr(moderncBase))
+	moderncVFS.FszOsFile += int32(unsafe.Sizeof(moderncFile{}))
+	moderncVFS.FpNext = 0
+	moderncVFS.FzName = zName
+	moderncVFS.FxOpen = cfunc(moderncOpen)
+	moderncVFS.FxDelete = cfunc(moderncDelete)
+
+	for i := range moderncMethods {
+		m := &moderncMethods[i]
+		m.FiVersion = int32(i + 1)
+		m.FxClose = cfunc(moderncClose)
+		m.FxRead = cfunc(moderncRead)
+		m.FxWrite = cfunc(moderncWrite)
+		m.FxTruncate = cfunc(moderncTruncate)
+		m.FxSync = cfunc(moderncSync)
+		m.FxFileSize = cfunc(moderncFileSize)
+		m.FxLock = cfunc(moderncLock)
+		m.FxUnlock = cfunc(moderncUnlock)
+		m.FxCheckReservedLock = cfunc(moderncCheckReservedLock)
+		m.FxFileControl = cfunc(moderncFileControl)
+		m.FxSectorSize = cfunc(moderncSectorSize)
+		m.FxDeviceCharacteristics = cfunc(moderncDeviceCharacteristics)
+		if m.FiVersion >= 2 {
+			m.FxShmMap = cfunc(moderncShmMap)
+			m.FxShmLock = cfunc(moderncShmLock)
+			m.FxShmBarrier = cfunc(moderncShmBarrier)
+			m.FxShmUnmap = cfunc(moderncShmUnmap)
+		}
+		if m.FiVersion >= 3 {
+			m.FxFetch = cfunc(moderncFetch)
+			m.FxUnfetch = cfunc(moderncUnfetch)
+		}
+	}
+
+	if rc := sqlite3.Xsqlite3_vfs_register(tls, uintptr(unsafe.Pointer(&moderncVFS)), 0); rc != sqlite3.SQLITE_OK {
+		return "", fmt.Errorf("registering VFS: error code %d", rc)
+	}
+	return name, nil
+}
+
+// cptr converts a libc address to a pointer. It does what a plain
+// conversion does, in a form vet accepts.
+func cptr(p uintptr) unsafe.Pointer {
+	return *(*unsafe.Pointer)(unsafe.Pointer(&p))
+}
+
+// cfunc returns the C function pointer of a Go function, as the
+// transpiled code makes them.
+func cfunc(f any) uintptr {
+	return (*[2]uintptr)(unsafe.Pointer(&f))[1]
+}
+
+// callC returns the C function pointer fp as a Go function of type F.
+func callC[F any](fp uintptr) F {
+	return *(*F)(unsafe.Pointer(&fp))
+}
+
+func moderncOpen(tls *libc.TLS, pVfs, zName, pFile uintptr, flags int32, pOutFlags uintptr) int32 {
+	f := (*moderncFile)(cptr(pFile))
+	f.methods, f.base, f.name = 0, pFile+unsafe.Sizeof(moderncFile{}), zName
+	open := callC[func(*libc.TLS, uintptr, uintptr, uintptr, int32, uintptr) int32]((*sqlite3.Tsqlite3_vfs)(cptr(moderncBase)).FxOpen)
+	rc := open(tls, moderncBase, zName, f.base, flags, pOutFlags)
+	if m := (*sqlite3.Tsqlite3_file)(cptr(f.base)).FpMethods; m != 0 {
+		v := min((*sqlite3.Tsqlite3_io_methods1)(cptr(m)).FiVersion, int32(len(moderncMethods)))
+		f.methods = uintptr(unsafe.Pointer(&moderncMethods[v-1]))
+	}
+	return rc
+}
+
+func moderncDelete(tls *libc.TLS, pVfs, zName uintptr, syncDir int32) int32 {
+	rc := callC[func(*libc.TLS, uintptr, uintptr, int32) int32]((*sqlite3.Tsqlite3_vfs)(cptr(moderncBase)).FxDelete)(tls, moderncBase, zName, syncDir)
+	if rc == sqlite3.SQLITE_OK {
+		moderncLog.add(zName, fileOp{kind: opDelete, dirSync: syncDir != 0})
+	}
+	return rc
+}
+
+// moderncBaseFile returns the base VFS's file behind the counting VFS's
+// file pFile and its methods.
+func moderncBaseFile(pFile uintptr) (uintptr, *sqlite3.Tsqlite3_io_methods1) {
+	f := (*moderncFile)(cptr(pFile)).base
+	return f, (*sqlite3.Tsqlite3_io_methods1)(cptr((*sqlite3.Tsqlite3_file)(cptr(f)).FpMethods))
+}
+
+func moderncClose(tls *libc.TLS, pFile uintptr) int32 {
+	f, m := moderncBaseFile(pFile)
+	return callC[func(*libc.TLS, uintptr) int32](m.FxClose)(tls, f)
+}
+
+func moderncRead(tls *libc.TLS, pFile, zBuf uintptr, iAmt int32, iOfst int64) int32 {
+	moderncIO.reads.Add(1)
+	moderncIO.readBytes.Add(uint64(iAmt))
+	f, m := moderncBaseFile(pFile)
+	return callC[func(*libc.TLS, uintptr, uintptr, int32, int64) int32](m.FxRead)(tls, f, zBuf, iAmt, iOfst)
+}
+
+func moderncWrite(tls *libc.TLS, pFile, zBuf uintptr, iAmt int32, iOfst int64) int32 {
+	moderncIO.writes.Add(1)
+	moderncIO.writtenBytes.Add(uint64(iAmt))
+	f, m := moderncBaseFile(pFile)
+	if limit := moderncMaxSize.Load(); limit > 0 && iOfst+int64(iAmt) > limit {
+		pSize := tls.Alloc(8)
+		rc := callC[func(*libc.TLS, uintptr, uintptr) int32](m.FxFileSize)(tls, f, pSize)
+		size := *(*int64)(cptr(pSize))
+		tls.Free(8)
+		if rc == sqlite3.SQLITE_OK && iOfst+int64(iAmt) > size {
+			// As the OS VFS returns on ENOSPC.
+			return sqlite3.SQLITE_FULL
+		}
+	}
+	rc := callC[func(*libc.TLS, uintptr, uintptr, int32, int64) int32](m.FxWrite)(tls, f, zBuf, iAmt, iOfst)
+	if rc == sqlite3.SQLITE_OK && moderncLog.on.Load() {
+		data := bytes.Clone(unsafe.Slice((*byte)(cptr(zBuf)), iAmt))
+		moderncLog.add((*moderncFile)(cptr(pFile)).name, fileOp{kind: opWrite, off: iOfst, data: data})
+	}
+	return rc
+}
+
+func moderncTruncate(tls *libc.TLS, pFile uintptr, size int64) int32 {
+	f, m := moderncBaseFile(pFile)
+	rc := callC[func(*libc.TLS, uintptr, int64) int32](m.FxTruncate)(tls, f, size)
+	if rc == sqlite3.SQLITE_OK {
+		moderncLog.add((*moderncFile)(cptr(pFile)).name, fileOp{kind: opTruncate, off: size})
+	}
+	return rc
+}
+
+func moderncSync(tls *libc.TLS, pFile uintptr, flags int32) int32 {
+	moderncIO.syncs.Add(1)
+	f, m := moderncBaseFile(pFile)
+	rc := callC[func(*libc.TLS, uintptr, int32) int32](m.FxSync)(tls, f, flags)
+	if rc == sqlite3.SQLITE_OK {
+		moderncLog.add((*moderncFile)(cptr(pFile)).name, fileOp{kind: opSync})
+	}
+	return rc
+}
+
+func moderncFileSize(tls *libc.TLS, pFile, pSize uintptr) int32 {
+	f, m := moderncBaseFile(pFile)
+	return callC[func(*libc.TLS, uintptr, uintptr) int32](m.FxFileSize)(tls, f, pSize)
+}
+
+func moderncLock(tls *libc.TLS, pFile uintptr, lock int32) int32 {
+	f, m := moderncBaseFile(pFile)
+	return callC[func(*libc.TLS, uintptr, int32) int32](m.FxLock)(tls, f, lock)
+}
+
+func moderncUnlock(tls *libc.TLS, pFile uintptr, lock int32) int32 {
+	f, m := moderncBaseFile(pFile)
+	return callC[func(*libc.TLS, uintptr, int32) int32](m.FxUnlock)(tls, f, lock)
+}
+
+func moderncCheckReservedLock(tls *libc.TLS, pFile, pResOut uintptr) int32 {
+	f, m := moderncBaseFile(pFile)
+	return callC[func(*libc.TLS, uintptr, uintptr) int32](m.FxCheckReservedLock)(tls, f, pResOut)
+}
+
+func moderncFileControl(tls *libc.TLS, pFile uintptr, op int32, pArg uintptr) int32 {
+	f, m := moderncBaseFile(pFile)
+	return callC[func(*libc.TLS, uintptr, int32, uintptr) int32](m.FxFileControl)(tls, f, op, pArg)
+}
+
+func moderncSectorSize(tls *libc.TLS, pFile uintptr) int32 {
+	f, m := moderncBaseFile(pFile)
+	return callC[func(*libc.TLS, uintptr) int32](m.FxSectorSize)(tls, f)
+}
+
+func moderncDeviceCharacteristics(tls *libc.TLS, pFile uintptr) int32 {
+	f, m := moderncBaseFile(pFile)
+	return callC[func(*libc.TLS, uintptr) int32](m.FxDeviceCharacteristics)(tls, f)
+}
+
+func moderncShmMap(tls *libc.TLS, pFile uintptr, iPg, pgsz, bExtend int32, pp uintptr) int32 {
+	f, m := moderncBaseFile(pFile)
+	return callC[func(*libc.TLS, uintptr, int32, int32, int32, uintptr) int32](m.FxShmMap)(tls, f, iPg, pgsz, bExtend, pp)
+}
+
+func moderncShmLock(tls *libc.TLS, pFile uintptr, offset, n, flags int32) int32 {
+	f, m := moderncBaseFile(pFile)
+	return callC[func(*libc.TLS, uintptr, int32, int32, int32) int32](m.FxShmLock)(tls, f, offset, n, flags)
+}
+
+func moderncShmBarrier(tls *libc.TLS, pFile uintptr) {
+	f, m := moderncBaseFile(pFile)
+	callC[func(*libc.TLS, uintptr)](m.FxShmBarrier)(tls, f)
+}
+
+func moderncShmUnmap(tls *libc.TLS, pFile uintptr, deleteFlag int32) int32 {
+	f, m := moderncBaseFile(pFile)
+	return callC[func(*libc.TLS, uintptr, int32) int32](m.FxShmUnmap)(tls, f, deleteFlag)
+}
+
+func moderncFetch(tls *libc.TLS, pFile uintptr, iOfst int64, iAmt int32, pp uintptr) int32 {
+	f, m := moderncBaseFile(pFile)
+	return callC[func(*libc.TLS, uintptr, int64, int32, uintptr) int32](m.FxFetch)(tls, f, iOfst, iAmt, pp)
+}
+
+func moderncUnfetch(tls *libc.TLS, pFile uintptr, iOfst int64, p uintptr) int32 {
+	f, m := moderncBaseFile(pFile)
+	return callC[func(*libc.TLS, uintptr, int64, uintptr) int32](m.FxUnfetch)(tls, f, iOfst, p)
+}
@@ -0,0 +1,47 @@
+package sqlitebench
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRunnerIOCounters(t *testing.T) {
+	drivers := IOCounterDrivers()
+	if len(drivers) == 0 {
+		t.Skip("no driver has a counting VFS in this build")
+	}
+
+	r := NewRunner()
+	r.Drivers, r.Sizes, r.Ops = drivers, []int{1024}, 10
+	r.Storage = []string{StorageFile}
+	r.Pragmas = []string{"journal_mode=wal", "synchronous=full"}
+	r.IOCounters = true
+	r.Add(&Write{})
+
+	results, err := r.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, res := range results {
+		io := res.IO
+		if io == nil {
+			t.Errorf("%s: no I/O counts", res.Driver)
+			continue
+		}
+		// Every committed insert appends its page to the WAL and syncs it.
+		if io.Writes < 10 || io.WrittenBytes < 10*1024 || io.Syncs < 10 {
+			t.Errorf("%s: I/O counts = %+v, want at least a write and a sync per insert", res.Driver, *io)
+		}
+	}
+}
+
+func TestWithVFS(t *testing.T) {
+	for dsn, want := range map[string]string{
+		"/tmp/bench.db":       "/tmp/bench.db?vfs=x",
+		"file:db?mode=memory": "file:db?mode=memory&vfs=x",
+	} {
+		if got := withVFS(dsn, "x"); got != want {
+			t.Errorf("withVFS(%q) = %q, want %q", dsn, got, want)
+		}
+	}
+}
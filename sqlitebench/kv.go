@@ -0,0 +1,198 @@
+package sqlitebench
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+)
+
+func init() {
+	Register(Benchmark{Category: CategoryWrite, New: func() Workload { return &KVPut{} }})
+	Register(Benchmark{Category: CategoryRead, New: func() Workload { return &KVGet{} }})
+	Register(Benchmark{Category: CategoryWrite, New: func() Workload { return &KVDelete{} }})
+}
+
+// KVWorkload is implemented by workloads that also run against the
+// embedded key-value stores in KVStores. Their cells on a store call
+// SetupKV and RunKV instead of Setup and Run; stores have no Teardown.
+type KVWorkload interface {
+	Workload
+	// SetupKV prepares an empty store for the cell.
+	SetupKV(ctx context.Context, s KVStore, p Params) error
+	// RunKV performs operation n on the store.
+	RunKV(ctx context.Context, s KVStore, n int) error
+}
+
+// The kv workloads keep SQL rows in a table keyed by an integer id, which
+// is SQLite's rowid, and store entries under the same number, big-endian
+// encoded so keys sort like the ids.
+func kvKey(id int) []byte {
+	return binary.BigEndian.AppendUint64(nil, uint64(id))
+}
+
+// kvRowKey returns the key of row i, stored under id i+1.
+func kvRowKey(i int) []byte { return kvKey(i + 1) }
+
+const kvTable = "CREATE TABLE kv (id INTEGER PRIMARY KEY, data BLOB)"
+
+// setupKVTable creates the kv table and puts rows payloads taken from next
+// under ids 1 to rows.
+func setupKVTable(ctx context.Context, db Conn, rows int, next func() []byte) error {
+	if err := db.Exec(ctx, kvTable); err != nil {
+		return err
+	}
+	return populate(ctx, db, rows, func(tx Tx, i int) error {
+		return tx.Exec(ctx, "INSERT INTO kv (id, data) VALUES (?, ?)", i+1, next())
+	})
+}
+
+// populateKV puts rows payloads taken from next under key(0) to
+// key(rows-1), in batches like Populate.
+func populateKV(s KVStore, rows int, key func(i int) []byte, next func() []byte) error {
+	for done := 0; done < rows; {
+		end := min(done+populateBatch, rows)
+		err := s.Batch(func(put func(key, value []byte) error) error {
+			for ; done < end; done++ {
+				if err := put(key(done), next()); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// KVPut stores one value per operation under a new key.
+type KVPut struct {
+	payloads *PayloadPool
+}
+
+func (*KVPut) Name() string        { return "kv.put" }
+func (*KVPut) Description() string { return "put one value under a new key per operation" }
+
+func (w *KVPut) Setup(ctx context.Context, db Conn, p Params) error {
+	w.payloads = p.Payloads
+	return setupKVTable(ctx, db, 0, nil)
+}
+
+func (w *KVPut) Run(ctx context.Context, db Conn, n int) error {
+	return db.Exec(ctx, "INSERT INTO kv (id, data) VALUES (?, ?)", n+1, w.payloads.Next())
+}
+
+func (*KVPut) Teardown(db Conn) error { return nil }
+
+func (w *KVPut) SetupKV(ctx context.Context, s KVStore, p Params) error {
+	w.payloads = p.Payloads
+	return nil
+}
+
+func (w *KVPut) RunKV(ctx context.Context, s KVStore, n int) error {
+	return s.Put(kvKey(n+1), w.payloads.Next())
+}
+
+// KVGet gets one value per operation by key, cycling through Params.Rows
+// keys.
+type KVGet struct {
+	rowBytes
+
+	rows  int
+	check *verifier
+}
+
+func (*KVGet) Name() string        { return "kv.get" }
+func (*KVGet) Description() string { return "get one value by key per operation" }
+
+func (w *KVGet) Setup(ctx context.Context, db Conn, p Params) error {
+	w.prepare(p)
+	return setupKVTable(ctx, db, p.Rows, p.Payloads.Next)
+}
+
+func (w *KVGet) Run(ctx context.Context, db Conn, n int) error {
+	key := n%w.rows + 1
+	rows, err := db.Query(ctx, "SELECT data FROM kv WHERE id = ?", key)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return fmt.Errorf("key %d not found", key)
+	}
+	var data []byte
+	if err := rows.Scan(&data); err != nil {
+		return err
+	}
+	w.add(data)
+	if w.check != nil {
+		if err := w.check.check(key-1, data); err != nil {
+			return err
+		}
+	}
+	return rows.Close()
+}
+
+func (*KVGet) Teardown(db Conn) error { return nil }
+
+func (w *KVGet) SetupKV(ctx context.Context, s KVStore, p Params) error {
+	w.prepare(p)
+	return populateKV(s, p.Rows, kvRowKey, p.Payloads.Next)
+}
+
+func (w *KVGet) RunKV(ctx context.Context, s KVStore, n int) error {
+	key := n%w.rows + 1
+	data, err := s.Get(kvKey(key))
+	if err != nil {
+		return err
+	}
+	if data == nil {
+		return fmt.Errorf("key %d not found", key)
+	}
+	w.add(data)
+	if w.check != nil {
+		return w.check.check(key-1, data)
+	}
+	return nil
+}
+
+func (w *KVGet) prepare(p Params) {
+	w.reset()
+	w.rows = max(p.Rows, 1)
+	w.check = nil
+	if p.Verify {
+		w.check = p.Payloads.verifier()
+	}
+}
+
+// Verifies reports that KVGet checks values when asked to.
+func (*KVGet) Verifies() bool { return true }
+
+// KVDelete deletes one key per operation from Params.Rows keys. Operations
+// beyond Rows find nothing left to delete, so rows should be at least ops.
+type KVDelete struct{}
+
+func (*KVDelete) Name() string        { return "kv.delete" }
+func (*KVDelete) Description() string { return "delete one key per operation" }
+
+func (*KVDelete) Setup(ctx context.Context, db Conn, p Params) error {
+	return setupKVTable(ctx, db, p.Rows, p.Payloads.Next)
+}
+
+func (*KVDelete) Run(ctx context.Context, db Conn, n int) error {
+	return db.Exec(ctx, "DELETE FROM kv WHERE id = ?", n+1)
+}
+
+func (*KVDelete) Teardown(db Conn) error { return nil }
+
+func (*KVDelete) SetupKV(ctx context.Context, s KVStore, p Params) error {
+	return populateKV(s, p.Rows, kvRowKey, p.Payloads.Next)
+}
+
+func (*KVDelete) RunKV(ctx context.Context, s KVStore, n int) error {
+	return s.Delete(kvKey(n + 1))
+}
@@ -0,0 +1,112 @@
+package sqlitebench
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestKVStores(t *testing.T) {
+	for _, name := range KVStoreNames() {
+		t.Run(name, func(t *testing.T) {
+			s, err := KVStores[name](t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer s.Close()
+
+			err = s.Batch(func(put func(key, value []byte) error) error {
+				return put(kvKey(1), []byte("one"))
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Put(kvKey(2), []byte("two")); err != nil {
+				t.Fatal(err)
+			}
+			for key, want := range map[int][]byte{1: []byte("one"), 2: []byte("two"), 3: nil} {
+				if got, err := s.Get(kvKey(key)); err != nil || !bytes.Equal(got, want) {
+					t.Errorf("Get(%d) = %q, %v; want %q", key, got, err, want)
+				}
+			}
+			if err := s.Delete(kvKey(1)); err != nil {
+				t.Fatal(err)
+			}
+			if got, err := s.Get(kvKey(1)); err != nil || got != nil {
+				t.Errorf("Get after Delete = %q, %v; want nil", got, err)
+			}
+		})
+	}
+}
+
+func TestRunnerKVStores(t *testing.T) {
+	r := NewRunner()
+	r.Drivers, r.Sizes, r.Ops, r.Rows = []string{"modernc"}, []int{64}, 5, 5
+	r.KVStores = []string{"bbolt"}
+	r.JournalModes = []string{"delete", "wal"}
+	r.Verify = true
+	r.Add(&Write{}, &KVGet{})
+
+	results, err := r.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, res := range results {
+		names = append(names, res.Driver+"/"+res.Operation)
+	}
+	want := []string{
+		"modernc/write,journal_mode=delete",
+		"modernc/write,journal_mode=wal",
+		"modernc/kv.get,journal_mode=delete",
+		"modernc/kv.get,journal_mode=wal",
+		"bbolt/kv.get",
+	}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("results = %v, want %v", names, want)
+	}
+}
+
+func TestKVMix(t *testing.T) {
+	w := NewKVMix()
+	w.Skew = 2
+	r := NewRunner()
+	r.Drivers, r.Sizes, r.Ops, r.Rows = []string{"modernc"}, []int{64}, 300, 50
+	r.KVStores = []string{"bbolt"}
+	r.Add(w)
+
+	results, err := r.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2", len(results))
+	}
+	for _, res := range results {
+		if res.BytesRead == 0 {
+			t.Errorf("%s read nothing", res.Driver)
+		}
+	}
+
+	// With a skew of 2, the hottest key takes most operations.
+	w.prepare(Params{Rows: 50, Seed: 1})
+	counts := map[string]int{}
+	ops := map[byte]int{}
+	for range 1000 {
+		op, key := w.next()
+		counts[key]++
+		ops[op]++
+	}
+	if n := counts[kvMixKey(0)]; n < 500 {
+		t.Errorf("hottest key picked %d times in 1000, want most", n)
+	}
+	if ops['g'] < ops['p'] || ops['p'] < ops['d'] || ops['d'] == 0 {
+		t.Errorf("operations = %v, want gets over puts over deletes", ops)
+	}
+
+	w.Skew = 1
+	if err := w.Validate(); err == nil {
+		t.Error("expected an error for a skew of 1")
+	}
+}
@@ -0,0 +1,157 @@
+package sqlitebench
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math/rand/v2"
+	"sync"
+)
+
+func init() {
+	Register(Benchmark{Category: CategoryRead, New: func() Workload { return NewKVMix() }})
+}
+
+// KVMix uses SQLite the way many Go applications do, as a key-value store:
+// a table of text keys and blob values, with gets, puts and deletes of keys
+// picked with a zipfian skew, so a few keys are hot and most are rarely
+// touched. The mix is set by the operations' weights; results of different
+// mixes share the name kv.mix and are only comparable within a run.
+type KVMix struct {
+	rowBytes
+
+	// Gets, Puts and Deletes are the relative weights of the operations.
+	Gets, Puts, Deletes int
+	// Skew is the zipfian exponent, above 1; the larger, the more the
+	// operations go to the hottest keys.
+	Skew float64
+
+	// mu guards rng and zipf, as concurrent cells run on several
+	// connections.
+	mu   sync.Mutex
+	rng  *rand.Rand
+	zipf *rand.Zipf
+	// payloads gives the values put.
+	payloads *PayloadPool
+}
+
+// NewKVMix returns a KVMix of 80% gets, 15% puts and 5% deletes with a
+// skew of 1.1.
+func NewKVMix() *KVMix {
+	return &KVMix{Gets: 80, Puts: 15, Deletes: 5, Skew: 1.1}
+}
+
+// Validate checks that the mix has operations to run and a valid skew.
+func (w *KVMix) Validate() error {
+	if w.Gets < 0 || w.Puts < 0 || w.Deletes < 0 {
+		return errors.New("kv mix weights must not be negative")
+	}
+	if w.Gets+w.Puts+w.Deletes == 0 {
+		return errors.New("kv mix has no operations")
+	}
+	if w.Skew <= 1 {
+		return fmt.Errorf("kv mix skew must be above 1, got %v", w.Skew)
+	}
+	return nil
+}
+
+func (*KVMix) Name() string { return "kv.mix" }
+
+func (w *KVMix) Description() string {
+	return fmt.Sprintf("zipfian gets, puts and deletes (%d:%d:%d) on a text-keyed table", w.Gets, w.Puts, w.Deletes)
+}
+
+const kvMixTable = "CREATE TABLE kv_store (key TEXT PRIMARY KEY, value BLOB)"
+
+// kvMixKey returns the key of the key ranked rank by popularity. Ranks are
+// hashed so the hot keys are spread over the table, as real keys are,
+// rather than sharing its first pages.
+func kvMixKey(rank int) string {
+	h := fnv.New64a()
+	fmt.Fprint(h, rank)
+	return fmt.Sprintf("user%016x", h.Sum64())
+}
+
+// prepare seeds the key and operation choices for a cell of rows keys.
+func (w *KVMix) prepare(p Params) {
+	w.reset()
+	w.payloads = p.Payloads
+	w.rng = rand.New(rand.NewPCG(uint64(p.Seed), uint64(p.Rows)))
+	w.zipf = rand.NewZipf(w.rng, w.Skew, 1, uint64(max(p.Rows, 1)-1))
+}
+
+// next picks the operation, 'g', 'p' or 'd', and the key it acts on.
+func (w *KVMix) next() (op byte, key string) {
+	w.mu.Lock()
+	k := w.rng.IntN(w.Gets + w.Puts + w.Deletes)
+	rank := int(w.zipf.Uint64())
+	w.mu.Unlock()
+	switch {
+	case k < w.Gets:
+		op = 'g'
+	case k < w.Gets+w.Puts:
+		op = 'p'
+	default:
+		op = 'd'
+	}
+	return op, kvMixKey(rank)
+}
+
+func (w *KVMix) Setup(ctx context.Context, db Conn, p Params) error {
+	w.prepare(p)
+	if err := db.Exec(ctx, kvMixTable); err != nil {
+		return err
+	}
+	return populate(ctx, db, p.Rows, func(tx Tx, i int) error {
+		return tx.Exec(ctx, "INSERT INTO kv_store (key, value) VALUES (?, ?)", kvMixKey(i), p.Payloads.Next())
+	})
+}
+
+// Run gets, puts or deletes one key. Getting a deleted key is not an
+// error, as applications look up keys that may be missing.
+func (w *KVMix) Run(ctx context.Context, db Conn, n int) error {
+	op, key := w.next()
+	switch op {
+	case 'p':
+		return db.Exec(ctx, "INSERT INTO kv_store (key, value) VALUES (?, ?) ON CONFLICT (key) DO UPDATE SET value = excluded.value", key, w.payloads.Next())
+	case 'd':
+		return db.Exec(ctx, "DELETE FROM kv_store WHERE key = ?", key)
+	}
+	rows, err := db.Query(ctx, "SELECT value FROM kv_store WHERE key = ?", key)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	if rows.Next() {
+		var value []byte
+		if err := rows.Scan(&value); err != nil {
+			return err
+		}
+		w.add(value)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	return rows.Close()
+}
+
+func (*KVMix) Teardown(db Conn) error { return nil }
+
+func (w *KVMix) SetupKV(ctx context.Context, s KVStore, p Params) error {
+	w.prepare(p)
+	return populateKV(s, p.Rows, func(i int) []byte { return []byte(kvMixKey(i)) }, p.Payloads.Next)
+}
+
+func (w *KVMix) RunKV(ctx context.Context, s KVStore, n int) error {
+	op, key := w.next()
+	switch op {
+	case 'p':
+		return s.Put([]byte(key), w.payloads.Next())
+	case 'd':
+		return s.Delete([]byte(key))
+	}
+	value, err := s.Get([]byte(key))
+	w.add(value)
+	return err
+}
@@ -0,0 +1,191 @@
+package sqlitebench
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/cockroachdb/pebble"
+	"github.com/dgraph-io/badger/v4"
+	bolt "go.etcd.io/bbolt"
+)
+
+// KVStore is an embedded key-value store, measured by the kv workloads
+// alongside the SQLite drivers. Implementations are safe for concurrent
+// use.
+type KVStore interface {
+	Put(key, value []byte) error
+	// Get returns a copy of the value stored under key, or nil if there is
+	// none.
+	Get(key []byte) ([]byte, error)
+	Delete(key []byte) error
+	// Batch calls fn with a put function whose writes are committed
+	// together when fn returns.
+	Batch(fn func(put func(key, value []byte) error) error) error
+	Close() error
+}
+
+// KVStores maps the names used in results to functions opening a store in
+// an empty directory. Writes are not synced to disk, like SQLite with
+// synchronous=off, so the stores are compared on their data structures
+// rather than on fsync.
+var KVStores = map[string]func(dir string) (KVStore, error){
+	"bbolt":  openBolt,
+	"badger": openBadger,
+	"pebble": openPebble,
+}
+
+// KVStoreNames returns the names in KVStores in sorted order.
+func KVStoreNames() []string {
+	names := make([]string, 0, len(KVStores))
+	for name := range KVStores {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+var boltBucket = []byte("test")
+
+type boltStore struct{ db *bolt.DB }
+
+func openBolt(dir string) (KVStore, error) {
+	db, err := bolt.Open(filepath.Join(dir, "bench.bolt"), 0o600, &bolt.Options{NoSync: true, NoFreelistSync: true})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucket(boltBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return boltStore{db}, nil
+}
+
+func (s boltStore) Put(key, value []byte) error {
+	return s.db.Update(func(tx *bolt.Tx) error { return tx.Bucket(boltBucket).Put(key, value) })
+}
+
+func (s boltStore) Get(key []byte) ([]byte, error) {
+	var value []byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		// Values are only valid for the transaction.
+		if v := tx.Bucket(boltBucket).Get(key); v != nil {
+			value = bytes.Clone(v)
+		}
+		return nil
+	})
+	return value, err
+}
+
+func (s boltStore) Delete(key []byte) error {
+	return s.db.Update(func(tx *bolt.Tx) error { return tx.Bucket(boltBucket).Delete(key) })
+}
+
+func (s boltStore) Batch(fn func(put func(key, value []byte) error) error) error {
+	return s.db.Update(func(tx *bolt.Tx) error { return fn(tx.Bucket(boltBucket).Put) })
+}
+
+func (s boltStore) Close() error { return s.db.Close() }
+
+type badgerStore struct{ db *badger.DB }
+
+func openBadger(dir string) (KVStore, error) {
+	db, err := badger.Open(badger.DefaultOptions(dir).WithSyncWrites(false).WithLogger(nil))
+	if err != nil {
+		return nil, err
+	}
+	return badgerStore{db}, nil
+}
+
+func (s badgerStore) Put(key, value []byte) error {
+	return s.db.Update(func(txn *badger.Txn) error { return txn.Set(key, value) })
+}
+
+func (s badgerStore) Get(key []byte) ([]byte, error) {
+	var value []byte
+	err := s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(key)
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		value, err = item.ValueCopy(nil)
+		return err
+	})
+	return value, err
+}
+
+func (s badgerStore) Delete(key []byte) error {
+	return s.db.Update(func(txn *badger.Txn) error { return txn.Delete(key) })
+}
+
+func (s badgerStore) Batch(fn func(put func(key, value []byte) error) error) error {
+	wb := s.db.NewWriteBatch()
+	defer wb.Cancel()
+	if err := fn(wb.Set); err != nil {
+		return err
+	}
+	return wb.Flush()
+}
+
+func (s badgerStore) Close() error { return s.db.Close() }
+
+type pebbleStore struct{ db *pebble.DB }
+
+func openPebble(dir string) (KVStore, error) {
+	db, err := pebble.Open(dir, &pebble.Options{Logger: quietPebbleLogger{}})
+	if err != nil {
+		return nil, err
+	}
+	return pebbleStore{db}, nil
+}
+
+func (s pebbleStore) Put(key, value []byte) error {
+	return s.db.Set(key, value, pebble.NoSync)
+}
+
+func (s pebbleStore) Get(key []byte) ([]byte, error) {
+	value, closer, err := s.db.Get(key)
+	if errors.Is(err, pebble.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer closer.Close()
+	return bytes.Clone(value), nil
+}
+
+func (s pebbleStore) Delete(key []byte) error {
+	return s.db.Delete(key, pebble.NoSync)
+}
+
+func (s pebbleStore) Batch(fn func(put func(key, value []byte) error) error) error {
+	b := s.db.NewBatch()
+	defer b.Close()
+	err := fn(func(key, value []byte) error { return b.Set(key, value, nil) })
+	if err != nil {
+		return err
+	}
+	return b.Commit(pebble.NoSync)
+}
+
+func (s pebbleStore) Close() error { return s.db.Close() }
+
+// quietPebbleLogger drops Pebble's informational logging, which would
+// interleave with the run's progress output. Fatal errors still panic.
+type quietPebbleLogger struct{}
+
+func (quietPebbleLogger) Infof(format string, args ...any)  {}
+func (quietPebbleLogger) Errorf(format string, args ...any) {}
+func (quietPebbleLogger) Fatalf(format string, args ...any) {
+	panic(fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,10 @@
+//go:build !libsql_replica
+
+package sqlitebench
+
+// The libsql server kind speaks the remote protocol through the pure-Go
+// client. Builds with -tags libsql_replica use go-libsql instead, which
+// registers the same driver name.
+import (
+	_ "github.com/tursodatabase/libsql-client-go/libsql"
+)
@@ -0,0 +1,119 @@
+//go:build libsql_replica
+
+package sqlitebench
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"path/filepath"
+
+	"github.com/tursodatabase/go-libsql"
+)
+
+// go-libsql needs cgo and links the prebuilt libsql library it ships. That
+// bundles its own SQLite, which clashes with the copy mattn bundles, so
+// mattn must link the system's with -tags libsqlite3. It registers the
+// "libsql" driver, which also serves the libsql kind over the remote
+// protocol in this build.
+
+func init() {
+	// libsql-replica keeps an embedded replica of a sqld primary, e.g.
+	// "http://127.0.0.1:8080" with an optional authToken parameter, in a
+	// fresh temporary directory. Reads are served by the replica, writes
+	// go to the primary.
+	serverDialects["libsql-replica"] = dialect{
+		driver: "libsql",
+		reset:  dropTables,
+		open:   openReplica,
+	}
+}
+
+func openReplica(ctx context.Context, d dialect, dsn string) (Conn, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, err
+	}
+	var opts []libsql.Option
+	if token := u.Query().Get("authToken"); token != "" {
+		opts = append(opts, libsql.WithAuthToken(token))
+	}
+	u.RawQuery = ""
+	// Writes are synced explicitly, so their cost is measured.
+	opts = append(opts, libsql.WithReadYourWrites(false))
+
+	dir, remove, err := MakeTempDir("", "libsql-replica-")
+	if err != nil {
+		return nil, err
+	}
+	connector, err := libsql.NewEmbeddedReplicaConnector(filepath.Join(dir, "replica.db"), u.String(), opts...)
+	if err != nil {
+		remove()
+		return nil, fmt.Errorf("opening embedded replica: %w", err)
+	}
+	// Closing db closes the connector.
+	db := sql.OpenDB(connector)
+	db.SetMaxOpenConns(1)
+
+	c := &replicaConn{dialectConn: dialectConn{sqlConn{db}, d}, connector: connector, remove: remove}
+	if err := d.reset(ctx, db); err != nil {
+		c.Close()
+		return nil, fmt.Errorf("emptying database: %w", err)
+	}
+	if err := c.sync(); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// replicaConn is a connection to an embedded replica. Every write, a
+// statement outside a transaction or a commit, is followed by a sync
+// pulling it back from the primary, timed with the operation, so results
+// include the cost of the round trip through replication.
+type replicaConn struct {
+	dialectConn
+	connector *libsql.Connector
+	remove    func()
+}
+
+func (c *replicaConn) sync() error {
+	if _, err := c.connector.Sync(); err != nil {
+		return fmt.Errorf("syncing replica: %w", err)
+	}
+	return nil
+}
+
+func (c *replicaConn) Exec(ctx context.Context, query string, args ...any) error {
+	if err := c.dialectConn.Exec(ctx, query, args...); err != nil {
+		return err
+	}
+	return c.sync()
+}
+
+func (c *replicaConn) Begin(ctx context.Context) (Tx, error) {
+	tx, err := c.dialectConn.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return replicaTx{tx, c}, nil
+}
+
+func (c *replicaConn) Close() error {
+	err := c.db.Close()
+	c.remove()
+	return err
+}
+
+type replicaTx struct {
+	Tx
+	c *replicaConn
+}
+
+func (t replicaTx) Commit() error {
+	if err := t.Tx.Commit(); err != nil {
+		return err
+	}
+	return t.c.sync()
+}
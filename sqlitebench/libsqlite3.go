@@ -0,0 +1,8 @@
+//go:build libsqlite3
+
+package sqlitebench
+
+// mattn/go-sqlite3 links the system's SQLite under the same tag.
+func init() {
+	DriverSQLite["mattn"] = "system libsqlite3"
+}
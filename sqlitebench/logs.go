@@ -0,0 +1,101 @@
+package sqlitebench
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+)
+
+func init() {
+	Register(Benchmark{Category: CategoryMaintenance, New: func() Workload { return &Logs{} }})
+}
+
+const (
+	// logRetainEvery makes every logRetainEvery-th operation delete the
+	// lines past retention instead of appending one.
+	logRetainEvery = 50
+	// logCompactEvery makes every logCompactEvery-th deletion also
+	// checkpoint the WAL and hand the freed pages back to the file system.
+	logCompactEvery = 20
+)
+
+// logLevels are the levels of the log lines, by line number.
+var logLevels = []string{"DEBUG", "INFO", "INFO", "INFO", "WARN", "ERROR"}
+
+// Logs models an application logging to SQLite with a retention window:
+// operations append a line with the payload as its attributes, and every
+// fiftieth deletes the lines older than the last Params.Rows, as a cleanup
+// task would, every twentieth deletion also checkpointing and running an
+// incremental vacuum. Setup fills the window first, so from the first
+// operation every line appended is matched by one deleted and the cell
+// measures the throughput the store sustains, not that of an empty table.
+type Logs struct {
+	payloads *PayloadPool
+	// window is the number of lines kept, and lines numbers the lines
+	// appended; the line numbered n is logged at time n.
+	window int64
+	lines  atomic.Int64
+	// retentions counts the deletions.
+	retentions atomic.Int64
+}
+
+func (*Logs) Name() string        { return "logs" }
+func (*Logs) Description() string { return "append log lines while deleting those past retention" }
+
+func (w *Logs) Setup(ctx context.Context, db Conn, p Params) error {
+	w.payloads = p.Payloads
+	w.window = int64(max(p.Rows, 1))
+	w.lines.Store(0)
+	w.retentions.Store(0)
+	// auto_vacuum only changes before the first table is created.
+	for _, stmt := range []string{
+		"PRAGMA auto_vacuum = INCREMENTAL",
+		"CREATE TABLE logs (id INTEGER PRIMARY KEY, ts INTEGER NOT NULL, level TEXT NOT NULL, message TEXT NOT NULL, attrs BLOB)",
+		"CREATE INDEX logs_ts ON logs (ts)",
+	} {
+		if err := db.Exec(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return populate(ctx, db, p.Rows, func(tx Tx, i int) error {
+		return w.append(ctx, tx.Exec)
+	})
+}
+
+// append logs the next line with exec.
+func (w *Logs) append(ctx context.Context, exec func(ctx context.Context, query string, args ...any) error) error {
+	n := w.lines.Add(1) - 1
+	return exec(ctx, "INSERT INTO logs (ts, level, message, attrs) VALUES (?, ?, ?, ?)",
+		n, logLevels[n%int64(len(logLevels))], fmt.Sprintf("handled request %d in %dms", n, n%250), w.payloads.Next())
+}
+
+func (w *Logs) Run(ctx context.Context, db Conn, n int) error {
+	if n%logRetainEvery != logRetainEvery-1 {
+		return w.append(ctx, db.Exec)
+	}
+	if err := db.Exec(ctx, "DELETE FROM logs WHERE ts < ?", w.lines.Load()-w.window); err != nil {
+		return err
+	}
+	if w.retentions.Add(1)%logCompactEvery != 0 {
+		return nil
+	}
+	// The checkpoint does nothing outside WAL mode.
+	if err := db.Exec(ctx, "PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+		return err
+	}
+	// The vacuum frees a page per step, so it is stepped to the end as a
+	// query; Exec would free one.
+	rows, err := db.Query(ctx, "PRAGMA incremental_vacuum")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	return rows.Close()
+}
+
+func (*Logs) Teardown(db Conn) error { return nil }
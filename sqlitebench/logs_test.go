@@ -0,0 +1,28 @@
+package sqlitebench
+
+import (
+	"context"
+	"testing"
+)
+
+func TestLogs(t *testing.T) {
+	ctx := context.Background()
+	w := &Logs{}
+	db := setupWorkload(t, w, Params{Rows: 100, Payloads: NewPayloadPool(1, 0, 512)})
+	// The last of the 1000 operations deletes and compacts.
+	runOps(t, w, db, logRetainEvery*logCompactEvery)
+
+	var lines, oldest, freelist int64
+	if err := QueryRow(ctx, db, "SELECT count(*), min(ts) FROM logs", &lines, &oldest); err != nil {
+		t.Fatal(err)
+	}
+	if want := w.lines.Load() - w.window; lines != w.window || oldest != want {
+		t.Errorf("kept %d lines from %d, want %d from %d", lines, oldest, w.window, want)
+	}
+	if err := QueryRow(ctx, db, "PRAGMA freelist_count", &freelist); err != nil {
+		t.Fatal(err)
+	}
+	if freelist != 0 {
+		t.Errorf("%d free pages left after the incremental vacuum", freelist)
+	}
+}
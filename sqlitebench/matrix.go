@@ -0,0 +1,214 @@
+package sqlitebench
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+)
+
+// Dimension is one axis of a Matrix: a parameter and the values it takes.
+type Dimension struct {
+	Name   string
+	Values []string
+}
+
+// Rule selects the matrix combinations whose parameters have the given
+// values. Parameters the rule does not name match any value.
+type Rule map[string]string
+
+func (r Rule) matches(combo map[string]string) bool {
+	for name, value := range r {
+		if combo[name] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// Matrix is the cross product of its dimensions, with the first dimension
+// outermost. Combinations matching an Exclude rule are dropped; Include
+// rules, which must name every dimension, then add combinations the
+// product lacks.
+type Matrix struct {
+	Dimensions []Dimension
+	Exclude    []Rule
+	Include    []Rule
+}
+
+// Validate reports a rule naming a parameter the matrix does not have, or
+// an include rule that leaves one out.
+func (m Matrix) Validate() error {
+	names := make([]string, len(m.Dimensions))
+	for i, d := range m.Dimensions {
+		names[i] = d.Name
+	}
+	for _, rules := range [][]Rule{m.Exclude, m.Include} {
+		for _, rule := range rules {
+			for name := range rule {
+				if !slices.Contains(names, name) {
+					return fmt.Errorf("rule %v names unknown dimension %q", map[string]string(rule), name)
+				}
+			}
+		}
+	}
+	for _, rule := range m.Include {
+		if len(rule) != len(names) {
+			return fmt.Errorf("include rule %v must give a value for each of %v", map[string]string(rule), names)
+		}
+	}
+	return nil
+}
+
+// Expand returns the matrix's combinations in order, each mapping every
+// dimension name to a value. Include rules that are incomplete are skipped;
+// call Validate to report them.
+func (m Matrix) Expand() []map[string]string {
+	combos := []map[string]string{{}}
+	for _, d := range m.Dimensions {
+		next := make([]map[string]string, 0, len(combos)*len(d.Values))
+		for _, combo := range combos {
+			for _, value := range d.Values {
+				c := make(map[string]string, len(combo)+1)
+				for k, v := range combo {
+					c[k] = v
+				}
+				c[d.Name] = value
+				next = append(next, c)
+			}
+		}
+		combos = next
+	}
+
+	combos = slices.DeleteFunc(combos, func(combo map[string]string) bool {
+		return slices.ContainsFunc(m.Exclude, func(r Rule) bool { return r.matches(combo) })
+	})
+	for _, rule := range m.Include {
+		if len(rule) != len(m.Dimensions) || slices.ContainsFunc(combos, rule.matches) {
+			continue
+		}
+		combo := make(map[string]string, len(rule))
+		for k, v := range rule {
+			combo[k] = v
+		}
+		combos = append(combos, combo)
+	}
+	return combos
+}
+
+// dimensions lists the matrix dimensions a Runner expands, in order from
+// outermost to innermost. Driver, size and workload are always present;
+// the others are added only when the runner sets values for them, so cells
+// that do not vary them keep their short names. A new dimension needs an
+// entry here, a Runner field and a Cell field.
+var dimensions = []struct {
+	name   string
+	values func(r *Runner) []string
+	set    func(c *Cell, value string)
+	get    func(c Cell) string
+}{
+	{
+		name:   "driver",
+		values: func(r *Runner) []string { return slices.Concat(r.Drivers, r.KVStores, r.OLAP, r.serverNames()) },
+		set:    func(c *Cell, v string) { c.Driver = v },
+		get:    func(c Cell) string { return c.Driver },
+	},
+	{
+		name:   "size",
+		values: func(r *Runner) []string { return itoas(r.Sizes) },
+		set:    func(c *Cell, v string) { c.DataSize, _ = strconv.Atoi(v) },
+		get:    func(c Cell) string { return strconv.Itoa(c.DataSize) },
+	},
+	{
+		name: "workload",
+		values: func(r *Runner) []string {
+			names := make([]string, len(r.workloads))
+			for i, w := range r.workloads {
+				names[i] = w.Name()
+			}
+			return names
+		},
+		set: func(c *Cell, v string) { c.Workload = v },
+		get: func(c Cell) string { return c.Workload },
+	},
+	{
+		name:   "journal_mode",
+		values: func(r *Runner) []string { return r.JournalModes },
+		set:    func(c *Cell, v string) { c.JournalMode = v },
+		get:    func(c Cell) string { return c.JournalMode },
+	},
+	{
+		name:   "synchronous",
+		values: func(r *Runner) []string { return r.Synchronous },
+		set:    func(c *Cell, v string) { c.Synchronous = v },
+		get:    func(c Cell) string { return c.Synchronous },
+	},
+	{
+		name:   "page_size",
+		values: func(r *Runner) []string { return itoas(r.PageSizes) },
+		set:    func(c *Cell, v string) { c.PageSize, _ = strconv.Atoi(v) },
+		get: func(c Cell) string {
+			if c.PageSize == 0 {
+				return ""
+			}
+			return strconv.Itoa(c.PageSize)
+		},
+	},
+	{
+		name:   "cache_size",
+		values: func(r *Runner) []string { return itoas(r.CacheSizes) },
+		set:    func(c *Cell, v string) { c.CacheSize, _ = strconv.Atoi(v) },
+		get: func(c Cell) string {
+			if c.CacheSize == 0 {
+				return ""
+			}
+			return strconv.Itoa(c.CacheSize)
+		},
+	},
+	{
+		name:   "concurrency",
+		values: func(r *Runner) []string { return itoas(r.Concurrency) },
+		set:    func(c *Cell, v string) { c.Concurrency, _ = strconv.Atoi(v) },
+		get: func(c Cell) string {
+			if c.Concurrency == 0 {
+				return ""
+			}
+			return strconv.Itoa(c.Concurrency)
+		},
+	},
+	{
+		name:   "storage",
+		values: func(r *Runner) []string { return r.Storage },
+		set:    func(c *Cell, v string) { c.Storage = v },
+		get:    func(c Cell) string { return c.Storage },
+	},
+	{
+		name:   "replication",
+		values: func(r *Runner) []string { return r.Replication },
+		set:    func(c *Cell, v string) { c.Replication = v },
+		get:    func(c Cell) string { return c.Replication },
+	},
+}
+
+// baseDimensions is the number of leading entries of dimensions that every
+// cell name spells out on its own.
+const baseDimensions = 3
+
+// Matrix returns the runner's matrix: its dimensions with values and its
+// include and exclude rules.
+func (r *Runner) Matrix() Matrix {
+	m := Matrix{Exclude: r.Exclude, Include: r.Include}
+	for i, d := range dimensions {
+		if values := d.values(r); i < baseDimensions || len(values) > 0 {
+			m.Dimensions = append(m.Dimensions, Dimension{d.name, values})
+		}
+	}
+	return m
+}
+
+func itoas(ns []int) []string {
+	s := make([]string, len(ns))
+	for i, n := range ns {
+		s[i] = strconv.Itoa(n)
+	}
+	return s
+}
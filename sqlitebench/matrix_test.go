@@ -0,0 +1,106 @@
+package sqlitebench
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestMatrixExpand(t *testing.T) {
+	m := Matrix{
+		Dimensions: []Dimension{{"driver", []string{"a", "b"}}, {"mode", []string{"x", "y"}}},
+		Exclude:    []Rule{{"driver": "b", "mode": "x"}},
+		Include:    []Rule{{"driver": "c", "mode": "x"}, {"driver": "a", "mode": "y"}},
+	}
+	if err := m.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	want := []map[string]string{
+		{"driver": "a", "mode": "x"},
+		{"driver": "a", "mode": "y"},
+		{"driver": "b", "mode": "y"},
+		{"driver": "c", "mode": "x"},
+	}
+	if got := m.Expand(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expand = %v, want %v", got, want)
+	}
+}
+
+func TestMatrixValidate(t *testing.T) {
+	dims := []Dimension{{"driver", []string{"a"}}, {"mode", []string{"x"}}}
+	for _, m := range []Matrix{
+		{Dimensions: dims, Exclude: []Rule{{"storage": "file"}}},
+		{Dimensions: dims, Include: []Rule{{"driver": "a"}}},
+	} {
+		if err := m.Validate(); err == nil {
+			t.Errorf("Validate(%+v) = nil, want an error", m)
+		}
+	}
+}
+
+func TestRunnerCellsDimensions(t *testing.T) {
+	r := NewRunner()
+	r.Drivers, r.Sizes = []string{"modernc"}, []int{64}
+	r.JournalModes = []string{"delete", "wal"}
+	r.Concurrency = []int{1, 4}
+	r.Exclude = []Rule{{"journal_mode": "delete", "concurrency": "4"}}
+	r.Add(&Write{})
+
+	var names []string
+	for _, c := range r.Cells() {
+		names = append(names, c.String())
+	}
+	want := []string{
+		"modernc/write,journal_mode=delete,concurrency=1/64",
+		"modernc/write,journal_mode=wal,concurrency=1/64",
+		"modernc/write,journal_mode=wal,concurrency=4/64",
+	}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("cells = %v, want %v", names, want)
+	}
+}
+
+func TestRunnerConcurrentFile(t *testing.T) {
+	r := NewRunner()
+	r.Drivers, r.Sizes, r.Ops = []string{"modernc"}, []int{64}, 5
+	r.JournalModes = []string{"wal"}
+	r.Synchronous = []string{"normal"}
+	r.Concurrency = []int{3}
+	r.Storage = []string{StorageFile}
+	r.Add(&Write{})
+
+	results, err := r.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || len(results[0].Samples) != 5 {
+		t.Fatalf("results = %+v, want one result with 5 samples", results)
+	}
+	if want := "write,journal_mode=wal,synchronous=normal,concurrency=3,storage=file"; results[0].Operation != want {
+		t.Errorf("operation = %q, want %q", results[0].Operation, want)
+	}
+}
+
+func TestRunnerStorages(t *testing.T) {
+	if _, err := os.Stat(TmpfsDir); err != nil {
+		TmpfsDir = t.TempDir()
+	}
+	r := NewRunner()
+	r.Drivers, r.Sizes, r.Ops, r.Rows = []string{"modernc", "mattn"}, []int{64}, 5, 10
+	r.Storage = Storages
+	// Both connections of a cell must see the one database.
+	r.Concurrency = []int{2}
+	r.Add(&Read{})
+
+	results, err := r.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2*len(Storages) {
+		t.Fatalf("got %d results, want %d", len(results), 2*len(Storages))
+	}
+	if want := "read,concurrency=2,storage=memdb"; results[1].Operation != want {
+		t.Errorf("operation = %q, want %q", results[1].Operation, want)
+	}
+}
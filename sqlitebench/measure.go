@@ -0,0 +1,240 @@
+package sqlitebench
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+// readAllocs returns the process-wide allocation count and allocated bytes.
+func readAllocs() (uint64, uint64) {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return m.Mallocs, m.TotalAlloc
+}
+
+// Storage values for Runner.Storage.
+const (
+	// StorageMemory keeps a cell's database in memory. It is the default.
+	StorageMemory = "memory"
+	// StorageFile puts a cell's database in a file in a fresh temporary
+	// directory, so journaling and syncing reach the disk.
+	StorageFile = "file"
+	// StorageMemDB keeps a cell's database in memory through the memdb
+	// VFS. Unlike the shared-cache database of StorageMemory, it locks and
+	// journals like a file, without a file system below.
+	StorageMemDB = "memdb"
+	// StorageTmpfs puts a cell's database in a file under TmpfsDir, so
+	// the file system is used but syncs do not wait for a disk.
+	StorageTmpfs = "tmpfs"
+)
+
+// Storages lists the valid storage values.
+var Storages = []string{StorageMemory, StorageMemDB, StorageTmpfs, StorageFile}
+
+// TmpfsDir is the RAM-backed directory StorageTmpfs cells create their
+// databases in.
+var TmpfsDir = "/dev/shm"
+
+// memorySeq numbers the in-memory and memdb databases, which are shared
+// by name while a connection has them open and freed when the last one
+// closes, so every cell starts on its own empty database.
+var memorySeq atomic.Int64
+
+// memoryDSN names a fresh in-memory database, as cells use by default.
+// Every connection opened with the name shares the one database.
+func memoryDSN() string {
+	return fmt.Sprintf("file:sqlitebench-%d?mode=memory&cache=shared", memorySeq.Add(1))
+}
+
+// OpenDB opens a fresh in-memory database for a benchmark cell, applies
+// the pragmas and creates the test table.
+func OpenDB(ctx context.Context, driver string, pragmas []string) (Conn, error) {
+	b, ok := Drivers[driver]
+	if !ok {
+		return nil, fmt.Errorf("unknown driver %q", driver)
+	}
+	return openDB(ctx, b, memoryDSN(), pragmas)
+}
+
+// openConn opens a connection to dsn and applies the pragmas.
+func openConn(ctx context.Context, b Backend, dsn string, pragmas []string) (Conn, error) {
+	db, err := b.Open(ctx, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, pragma := range pragmas {
+		if err := db.Exec(ctx, "PRAGMA "+pragma); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("applying pragma %q: %w", pragma, err)
+		}
+	}
+	return db, nil
+}
+
+// testTable holds the rows of the read and write workloads. Its id is
+// SQLite's rowid; the label varies per row like the data.
+const testTable = "CREATE TABLE test (id INTEGER PRIMARY KEY, data BLOB, label TEXT)"
+
+// openDB opens a connection to dsn like openConn and creates the test
+// table.
+func openDB(ctx context.Context, b Backend, dsn string, pragmas []string) (Conn, error) {
+	db, err := openConn(ctx, b, dsn, pragmas)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Exec(ctx, testTable); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("creating table: %w", err)
+	}
+
+	return db, nil
+}
+
+// populateBatch is the number of rows inserted per transaction when
+// preparing the table for read workloads.
+const populateBatch = 10000
+
+// Populate inserts rows payloads taken from next into the test table,
+// labelled with RowLabel. Inserts are batched into transactions so tables
+// with millions of rows can be prepared in reasonable time.
+func Populate(ctx context.Context, db Conn, rows int, next func() []byte) error {
+	return populate(ctx, db, rows, func(tx Tx, i int) error {
+		return tx.Exec(ctx, "INSERT INTO test (data, label) VALUES (?, ?)", next(), RowLabel(i))
+	})
+}
+
+// populate calls insert for rows 0 to rows-1, batched into transactions
+// like Populate.
+func populate(ctx context.Context, db Conn, rows int, insert func(tx Tx, i int) error) error {
+	for done := 0; done < rows; {
+		tx, err := db.Begin(ctx)
+		if err != nil {
+			return err
+		}
+
+		for end := min(done+populateBatch, rows); done < end; done++ {
+			if err := insert(tx, done); err != nil {
+				tx.Rollback()
+				return err
+			}
+		}
+
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DefaultMaxSamples is the number of operation times kept per cell when
+// Runner.MaxSamples is zero.
+const DefaultMaxSamples = 100_000
+
+// measure runs op r.Ops times, or repeatedly for r.Duration if that is
+// set, and returns the timings, allocations and counters of the loop.
+// Operations failing with an error ErrorCounts expects are counted, not
+// sampled, and count towards r.Ops; any other error ends the loop. The
+// loop is profiled under name if the runner has a Profile hook. If ctx
+// ends first, the result is marked as timed out.
+func (r *Runner) measure(ctx context.Context, name string, op func() error) (Result, error) {
+	maxSamples := r.MaxSamples
+	if maxSamples <= 0 {
+		maxSamples = DefaultMaxSamples
+	}
+	var samples []time.Duration
+	if r.Duration == 0 {
+		samples = make([]time.Duration, 0, min(r.Ops, maxSamples))
+	}
+	// Once samples is full, each time replaces a random one with the
+	// chance that keeps samples a uniform sample of all n (reservoir
+	// sampling).
+	var n int
+	var reservoir *rand.Rand
+
+	var stopProfile func()
+	if r.Profile != nil {
+		stopProfile = r.Profile(name)
+	}
+	var perf *PerfGroup
+	if r.PerfCounters {
+		var err error
+		if perf, err = StartPerfCounters(); err != nil {
+			slog.Warn("Disabling hardware counters", "err", err)
+			r.PerfCounters = false
+		}
+	}
+
+	mallocs, allocBytes := readAllocs()
+	start := time.Now()
+	var opErr error
+	var errs ErrorCounts
+	for ctx.Err() == nil {
+		if r.Duration > 0 {
+			if time.Since(start) >= r.Duration {
+				break
+			}
+		} else if uint64(n)+errs.Total() >= uint64(r.Ops) {
+			break
+		}
+
+		opStart := time.Now()
+		if opErr = op(); opErr != nil {
+			if ctx.Err() == nil && errs.count(opErr) {
+				opErr = nil
+				r.liveErrors.Add(1)
+				continue
+			}
+			break
+		}
+		d := time.Since(opStart)
+		n++
+		if len(samples) < maxSamples {
+			samples = append(samples, d)
+		} else {
+			if reservoir == nil {
+				reservoir = rand.New(rand.NewPCG(uint64(r.Seed), 0))
+			}
+			if i := reservoir.IntN(n); i < maxSamples {
+				samples[i] = d
+			}
+		}
+		// The time is stored before the count shows it; only this loop
+		// writes either.
+		r.liveTimes[r.liveOps.Load()%liveWindow].Store(int64(d))
+		r.liveOps.Add(1)
+	}
+	duration := time.Since(start)
+	mallocsAfter, allocBytesAfter := readAllocs()
+
+	var counters *PerfCounters
+	if perf != nil {
+		var err error
+		if counters, err = perf.Stop(); err != nil {
+			slog.Warn("Failed to read hardware counters", "err", err)
+		}
+	}
+	if stopProfile != nil {
+		stopProfile()
+	}
+
+	if opErr != nil && ctx.Err() == nil {
+		return Result{}, opErr
+	}
+	result := Result{Duration: duration, Samples: samples,
+		Allocs: mallocsAfter - mallocs, Bytes: allocBytesAfter - allocBytes, Counters: counters,
+		TimedOut: ctx.Err() != nil}
+	if n > len(samples) {
+		result.Iterations = n
+	}
+	if errs.Total() > 0 {
+		result.Errors = &errs
+	}
+	return result, nil
+}
@@ -0,0 +1,133 @@
+package sqlitebench
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestMeasureOps(t *testing.T) {
+	calls := 0
+	r, _ := (&Runner{Ops: 25}).measure(context.Background(), "", func() error { calls++; return nil })
+	if calls != 25 || len(r.Samples) != 25 {
+		t.Errorf("calls = %d, samples = %d, want 25", calls, len(r.Samples))
+	}
+}
+
+func TestMeasureMaxSamples(t *testing.T) {
+	calls := 0
+	r, _ := (&Runner{Ops: 1000, MaxSamples: 100}).measure(context.Background(), "", func() error { calls++; return nil })
+	if calls != 1000 || len(r.Samples) != 100 || r.Iterations != 1000 {
+		t.Errorf("calls = %d, samples = %d, iterations = %d; want 1000, 100, 1000", calls, len(r.Samples), r.Iterations)
+	}
+}
+
+func TestMeasureDuration(t *testing.T) {
+	r, _ := (&Runner{Ops: 1, Duration: 20 * time.Millisecond}).measure(context.Background(), "", func() error {
+		time.Sleep(time.Millisecond)
+		return nil
+	})
+	if r.Duration < 20*time.Millisecond {
+		t.Errorf("duration = %v, want at least 20ms", r.Duration)
+	}
+	if len(r.Samples) < 2 {
+		t.Errorf("samples = %d, want the loop to repeat until the deadline", len(r.Samples))
+	}
+}
+
+func TestMeasureLiveTimes(t *testing.T) {
+	r := &Runner{Ops: 2000}
+	r.measure(context.Background(), "", func() error { return nil })
+	if r.LiveOps() != 2000 || r.LiveErrors() != 0 {
+		t.Errorf("LiveOps = %d, LiveErrors = %d, want 2000 and 0", r.LiveOps(), r.LiveErrors())
+	}
+	if times := r.LiveTimes(); len(times) != liveWindow {
+		t.Errorf("LiveTimes returned %d times, want the latest %d", len(times), liveWindow)
+	}
+}
+
+// liveObserver records the runner's live operations as each cell starts.
+type liveObserver struct {
+	r       *Runner
+	started []int64
+}
+
+func (o *liveObserver) CellStarted(int, Cell)       { o.started = append(o.started, o.r.LiveOps()) }
+func (o *liveObserver) CellDone(int, Cell, Result)  {}
+func (o *liveObserver) CellFailed(int, Cell, error) {}
+
+func TestRunnerLiveOpsReset(t *testing.T) {
+	r := NewRunner()
+	r.Drivers, r.Sizes, r.Ops = []string{"modernc"}, []int{64, 128}, 5
+	r.Add(&countWorkload{})
+	o := &liveObserver{r: r}
+	r.Observer = o
+	if _, err := r.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(o.started, []int64{0, 0}) || r.LiveOps() != 5 {
+		t.Errorf("live operations %v as the cells started and %d after, want none and 5", o.started, r.LiveOps())
+	}
+}
+
+func TestMeasureTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	r, err := (&Runner{Ops: 1000}).measure(ctx, "", func() error {
+		select {
+		case <-time.After(5 * time.Millisecond):
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+	if err != nil || !r.TimedOut {
+		t.Errorf("err = %v, TimedOut = %v; want a timed out result", err, r.TimedOut)
+	}
+	if len(r.Samples) == 0 || len(r.Samples) >= 1000 {
+		t.Errorf("samples = %d, want the operations completed before the timeout", len(r.Samples))
+	}
+}
+
+func TestPopulate(t *testing.T) {
+	ctx := context.Background()
+	db, err := OpenDB(ctx, "modernc", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	rows := populateBatch*2 + 1
+	if err := Populate(ctx, db, rows, NewPayloadPool(1, 0, 16).Next); err != nil {
+		t.Fatal(err)
+	}
+
+	var n int
+	QueryRow(ctx, db, "SELECT count(*) FROM test", &n)
+	if n != rows {
+		t.Errorf("count = %d, want %d", n, rows)
+	}
+}
+
+func TestOpenDBIsolated(t *testing.T) {
+	ctx := context.Background()
+	for _, driver := range []string{"modernc", "mattn"} {
+		first, err := OpenDB(ctx, driver, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer first.Close()
+		if err := Populate(ctx, first, 3, NewPayloadPool(1, 0, 16).Next); err != nil {
+			t.Fatal(err)
+		}
+
+		// A database shared with the first would already have the table.
+		second, err := OpenDB(ctx, driver, nil)
+		if err != nil {
+			t.Fatalf("%s: opening a second database: %v", driver, err)
+		}
+		second.Close()
+	}
+}
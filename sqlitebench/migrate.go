@@ -0,0 +1,120 @@
+package sqlitebench
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+func init() {
+	Register(Benchmark{Category: CategoryMaintenance, New: func() Workload { return &Migrate{} }})
+}
+
+// Migrate times the schema migrations applications run on a table of
+// Params.Rows rows, as on upgrading a deployed database. Operations apply
+// in turn, so that every fourth returns the table to where it started:
+//
+//   - ALTER TABLE ... ADD COLUMN of a note column with a default;
+//   - CREATE INDEX on it;
+//   - the 12-step rebuild SQLite's documentation prescribes for changes
+//     ALTER TABLE cannot make, here making the note NOT NULL: a new table
+//     is created and filled from the old one, which is dropped, and the
+//     new one is renamed and indexed, in one transaction;
+//   - ALTER TABLE ... DROP COLUMN of the note after dropping its index.
+//
+// The migration statements are timed in the "add_column", "create_index",
+// "rebuild" and "drop_column" phases. SQLite before 3.35 cannot drop
+// columns; there the note is dropped by a rebuild, timed in the
+// "drop_column.rebuild" phase. Migrations run one at a time, as each
+// depends on the one before.
+type Migrate struct {
+	phaseTimes
+
+	// dropColumn is set when SQLite supports DROP COLUMN.
+	dropColumn bool
+	// mu orders the migrations, and step is the next one.
+	mu   sync.Mutex
+	step int
+}
+
+func (*Migrate) Name() string { return "migrate" }
+func (*Migrate) Description() string {
+	return "add, index, rebuild or drop a column of a populated table per operation"
+}
+
+func (w *Migrate) Setup(ctx context.Context, db Conn, p Params) error {
+	w.step = 0
+	w.dropColumn = db.Exec(ctx, "CREATE TEMP TABLE drop_probe (a, b)") == nil &&
+		db.Exec(ctx, "ALTER TABLE drop_probe DROP COLUMN b") == nil
+	if err := db.Exec(ctx, "DROP TABLE IF EXISTS temp.drop_probe"); err != nil {
+		return err
+	}
+	return Populate(ctx, db, p.Rows, p.Payloads.Next)
+}
+
+// rebuild replaces the test table by one with the columns and note given,
+// copying the rows with select. The steps of the pattern for foreign keys
+// are left out, as none refer to or from the table.
+func rebuild(ctx context.Context, db Conn, columns, note, sel string) error {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	for _, stmt := range []string{
+		"CREATE TABLE test_new (id INTEGER PRIMARY KEY, data BLOB, label TEXT" + columns + ")",
+		"INSERT INTO test_new SELECT " + sel + " FROM test",
+		"DROP TABLE test",
+		"ALTER TABLE test_new RENAME TO test",
+		note,
+	} {
+		if stmt == "" {
+			continue
+		}
+		if err := tx.Exec(ctx, stmt); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (w *Migrate) Run(ctx context.Context, db Conn, n int) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	step := w.step
+	w.step = (w.step + 1) % 4
+
+	var phase string
+	var err error
+	start := time.Now()
+	switch step {
+	case 0:
+		phase = "add_column"
+		err = db.Exec(ctx, "ALTER TABLE test ADD COLUMN note TEXT DEFAULT ''")
+	case 1:
+		phase = "create_index"
+		err = db.Exec(ctx, "CREATE INDEX test_note ON test (note)")
+	case 2:
+		phase = "rebuild"
+		err = rebuild(ctx, db, ", note TEXT NOT NULL DEFAULT ''", "CREATE INDEX test_note ON test (note)", "id, data, label, coalesce(note, '')")
+	default:
+		if err := db.Exec(ctx, "DROP INDEX test_note"); err != nil {
+			return err
+		}
+		start = time.Now()
+		if w.dropColumn {
+			phase = "drop_column"
+			err = db.Exec(ctx, "ALTER TABLE test DROP COLUMN note")
+		} else {
+			phase = "drop_column.rebuild"
+			err = rebuild(ctx, db, "", "", "id, data, label")
+		}
+	}
+	if err != nil {
+		return err
+	}
+	w.record(phase, time.Since(start))
+	return nil
+}
+
+func (*Migrate) Teardown(db Conn) error { return nil }
@@ -0,0 +1,64 @@
+package sqlitebench
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMigrate(t *testing.T) {
+	ctx := context.Background()
+	db := openMemory(t)
+	if err := db.Exec(ctx, testTable); err != nil {
+		t.Fatal(err)
+	}
+
+	w := &Migrate{}
+	if err := w.Setup(ctx, db, Params{Rows: 1000, Payloads: NewPayloadPool(1, 0, 64), Seed: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if !w.dropColumn {
+		t.Error("modernc's SQLite supports DROP COLUMN, but it was not found")
+	}
+	for n := range 8 {
+		if err := w.Run(ctx, db, n); err != nil {
+			t.Fatalf("operation %d: %v", n, err)
+		}
+		if n == 2 {
+			// The rebuild kept the rows and made the note NOT NULL.
+			var rows, notNull int
+			if err := QueryRow(ctx, db, `SELECT (SELECT count(*) FROM test WHERE note = ''), (SELECT "notnull" FROM pragma_table_info('test') WHERE name = 'note')`, &rows, &notNull); err != nil {
+				t.Fatal(err)
+			}
+			if rows != 1000 || notNull != 1 {
+				t.Errorf("rebuilt table has %d rows with an empty note, NOT NULL %d; want 1000 and 1", rows, notNull)
+			}
+		}
+	}
+	var columns, indexes int
+	if err := QueryRow(ctx, db, "SELECT (SELECT count(*) FROM pragma_table_info('test')), (SELECT count(*) FROM sqlite_schema WHERE type = 'index')", &columns, &indexes); err != nil {
+		t.Fatal(err)
+	}
+	if columns != 3 || indexes != 0 {
+		t.Errorf("table has %d columns and %d indexes after two rounds, want 3 and none", columns, indexes)
+	}
+	phases := w.Phases()
+	for _, phase := range []string{"add_column", "create_index", "rebuild", "drop_column"} {
+		if got := len(phases[phase]); got != 2 {
+			t.Errorf("%d %s times, want 2", got, phase)
+		}
+	}
+
+	// Without DROP COLUMN, a rebuild drops the note.
+	w.dropColumn = false
+	for n := range 4 {
+		if err := w.Run(ctx, db, n); err != nil {
+			t.Fatalf("operation %d without DROP COLUMN: %v", n, err)
+		}
+	}
+	if err := QueryRow(ctx, db, "SELECT count(*) FROM pragma_table_info('test')", &columns); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(w.Phases()["drop_column.rebuild"]); columns != 3 || got != 1 {
+		t.Errorf("%d columns after %d rebuilds dropping the note, want 3 after 1", columns, got)
+	}
+}
@@ -0,0 +1,22 @@
+package sqlitebench
+
+import "sort"
+
+// OLAPBackends maps the names used in results to embedded analytical
+// databases, compared with the drivers on the analytical workloads. DuckDB
+// is added by building with -tags duckdb, as it links a large cgo library.
+var OLAPBackends = map[string]Backend{}
+
+// OLAPBackendNames returns the names in OLAPBackends in sorted order.
+func OLAPBackendNames() []string {
+	names := make([]string, 0, len(OLAPBackends))
+	for name := range OLAPBackends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// olapDSN opens the in-memory database OLAP cells use; DuckDB takes an
+// empty name for it.
+const olapDSN = ""
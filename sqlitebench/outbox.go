@@ -0,0 +1,104 @@
+package sqlitebench
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+func init() {
+	Register(Benchmark{Category: CategoryConcurrency, New: func() Workload { return &Outbox{} }})
+}
+
+const (
+	// outboxRelayEvery makes every outboxRelayEvery-th operation the relay
+	// publishing up to outboxBatch events; the others are business
+	// transactions. A round of concurrent operations holds at most one
+	// relay, so relays never overlap.
+	outboxRelayEvery = 10
+	outboxBatch      = 100
+)
+
+// Outbox is the transactional outbox pattern: each business transaction
+// inserts an order and the event announcing it in one transaction, and a
+// relay reads the oldest events, as if to publish them, and deletes them.
+// Run with a Concurrency above 1, the relay runs alongside the
+// transactions on another connection.
+//
+// The time from committing an event to the relay reading it is kept in
+// the "event" phase.
+type Outbox struct {
+	phaseTimes
+
+	payloads *PayloadPool
+}
+
+func (*Outbox) Name() string { return "outbox" }
+func (*Outbox) Description() string {
+	return "write orders with outbox events while a relay publishes them"
+}
+
+func (w *Outbox) Setup(ctx context.Context, db Conn, p Params) error {
+	w.payloads = p.Payloads
+	for _, stmt := range []string{
+		"CREATE TABLE orders (id INTEGER PRIMARY KEY, customer INTEGER NOT NULL, total REAL NOT NULL, data BLOB)",
+		"CREATE TABLE outbox (id INTEGER PRIMARY KEY, aggregate_id INTEGER NOT NULL, topic TEXT NOT NULL, payload TEXT NOT NULL, created INTEGER NOT NULL)",
+	} {
+		if err := db.Exec(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *Outbox) Run(ctx context.Context, db Conn, n int) error {
+	if n%outboxRelayEvery == outboxRelayEvery-1 {
+		return w.relay(ctx, db)
+	}
+
+	// Operation numbers are unique, so they serve as order ids.
+	id, customer, total := n+1, n%500, float64(n%10000)/100
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	if err := tx.Exec(ctx, "INSERT INTO orders (id, customer, total, data) VALUES (?, ?, ?, ?)", id, customer, total, w.payloads.Next()); err != nil {
+		tx.Rollback()
+		return err
+	}
+	event := fmt.Sprintf(`{"order":%d,"customer":%d,"total":%.2f}`, id, customer, total)
+	if err := tx.Exec(ctx, "INSERT INTO outbox (aggregate_id, topic, payload, created) VALUES (?, 'order.created', ?, ?)", id, event, time.Now().UnixNano()); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// relay reads the oldest events and deletes those it read.
+func (w *Outbox) relay(ctx context.Context, db Conn) error {
+	rows, err := db.Query(ctx, "SELECT id, topic, payload, created FROM outbox ORDER BY id LIMIT ?", outboxBatch)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	var last, created int64
+	var topic, payload string
+	for rows.Next() {
+		if err := rows.Scan(&last, &topic, &payload, &created); err != nil {
+			return err
+		}
+		w.record("event", time.Since(time.Unix(0, created)))
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+	if last == 0 {
+		return nil
+	}
+	return db.Exec(ctx, "DELETE FROM outbox WHERE id <= ?", last)
+}
+
+func (*Outbox) Teardown(db Conn) error { return nil }
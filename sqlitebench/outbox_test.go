@@ -0,0 +1,23 @@
+package sqlitebench
+
+import (
+	"context"
+	"testing"
+)
+
+func TestOutbox(t *testing.T) {
+	ctx := context.Background()
+	w := &Outbox{}
+	db := setupWorkload(t, w, Params{})
+	runOps(t, w, db, 25)
+
+	// Operations 9 and 19 relayed the 18 events before them; the orders
+	// stay.
+	var orders, pending int
+	if err := QueryRow(ctx, db, "SELECT (SELECT count(*) FROM orders), (SELECT count(*) FROM outbox)", &orders, &pending); err != nil {
+		t.Fatal(err)
+	}
+	if events := len(w.Phases()["event"]); orders != 23 || pending != 5 || events != 18 {
+		t.Errorf("%d orders, %d events pending and %d relayed; want 23, 5 and 18", orders, pending, events)
+	}
+}
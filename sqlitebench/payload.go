@@ -0,0 +1,93 @@
+package sqlitebench
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"sync/atomic"
+)
+
+// payloadPoolSize is the number of payloads a cell's rows are derived
+// from, which keeps memory bounded for large sizes and duration-based runs.
+const payloadPoolSize = 8
+
+// payloadBlock is the granularity at which compressibility is applied.
+const payloadBlock = 256
+
+// PayloadPool hands out pseudo-random payloads of one size. Every row gets
+// its own: one of the pool's payloads with the row number XORed into the
+// random bytes of every block, so no two rows share a page SQLite or the OS could
+// deduplicate or compress away, and a row read back in place of another
+// fails verification. It is safe for concurrent use.
+type PayloadPool struct {
+	payloads [][]byte
+	// random is the number of random bytes leading each block; the rest
+	// is zero-filled.
+	random int
+	i      atomic.Uint64
+}
+
+// NewPayloadPool generates payloads deterministically from seed, so runs
+// with the same seed insert identical data. compressibility is the fraction
+// of each block that is zero-filled: 0 gives incompressible random bytes,
+// 1 gives all zeros, which no row number is stamped into.
+func NewPayloadPool(seed int64, compressibility float64, size int) *PayloadPool {
+	rng := rand.New(rand.NewPCG(uint64(seed), uint64(size)))
+	random := payloadBlock - int(compressibility*payloadBlock)
+
+	p := &PayloadPool{payloads: make([][]byte, payloadPoolSize), random: random}
+	for i := range p.payloads {
+		buf := make([]byte, size)
+		for off := 0; off < size; off += payloadBlock {
+			end := min(off+random, size)
+			for j := off; j < end; j++ {
+				buf[j] = byte(rng.Uint32())
+			}
+		}
+		p.payloads[i] = buf
+	}
+	return p
+}
+
+// Next returns the payload of the next row, counting from row 0.
+func (p *PayloadPool) Next() []byte {
+	return p.Row(int(p.i.Add(1) - 1))
+}
+
+// Row returns row n's payload. Each call allocates a new one.
+func (p *PayloadPool) Row(n int) []byte {
+	buf := bytes.Clone(p.payloads[n%len(p.payloads)])
+	var stamp [8]byte
+	binary.BigEndian.PutUint64(stamp[:], uint64(n))
+	for off := 0; off < len(buf); off += payloadBlock {
+		for j, b := range stamp[:min(p.random, len(buf)-off, len(stamp))] {
+			buf[off+j] ^= b
+		}
+	}
+	return buf
+}
+
+// RowLabel returns the text stored with row n's payload in the test table.
+func RowLabel(n int) string {
+	return "row " + strconv.Itoa(n)
+}
+
+// verifier checks that blobs read back are the payloads written to their
+// rows.
+type verifier struct {
+	p *PayloadPool
+}
+
+func (p *PayloadPool) verifier() *verifier {
+	return &verifier{p: p}
+}
+
+// check returns an error if data is not the payload written to row n.
+func (v *verifier) check(n int, data []byte) error {
+	if !bytes.Equal(data, v.p.Row(n)) {
+		return fmt.Errorf("row %d: read back %d bytes that do not match the payload written", n, len(data))
+	}
+	return nil
+}
@@ -0,0 +1,9 @@
+package sqlitebench
+
+// PerfCounters holds hardware performance counter readings taken around the
+// timed region of a benchmark.
+type PerfCounters struct {
+	Instructions uint64 `json:"instructions"`
+	CacheMisses  uint64 `json:"cache_misses"`
+	BranchMisses uint64 `json:"branch_misses"`
+}
@@ -0,0 +1,89 @@
+//go:build linux
+
+package sqlitebench
+
+import (
+	"encoding/binary"
+	"fmt"
+	"runtime"
+
+	"golang.org/x/sys/unix"
+)
+
+// perfEvents are the hardware counters opened around each measured region.
+// The order matches the fields of PerfCounters.
+var perfEvents = []uint64{
+	unix.PERF_COUNT_HW_INSTRUCTIONS,
+	unix.PERF_COUNT_HW_CACHE_MISSES,
+	unix.PERF_COUNT_HW_BRANCH_MISSES,
+}
+
+// PerfGroup is a set of hardware counters opened by StartPerfCounters.
+type PerfGroup struct {
+	fds []int
+}
+
+// StartPerfCounters opens and enables the hardware counters for the calling
+// goroutine. The goroutine is locked to its OS thread until Stop is called,
+// since perf_event_open counts per thread; threads spawned in the meantime
+// (e.g. by cgo) are covered through the inherit bit.
+func StartPerfCounters() (*PerfGroup, error) {
+	runtime.LockOSThread()
+
+	g := &PerfGroup{}
+	for _, config := range perfEvents {
+		attr := unix.PerfEventAttr{
+			Type:   unix.PERF_TYPE_HARDWARE,
+			Config: config,
+			Bits:   unix.PerfBitDisabled | unix.PerfBitInherit | unix.PerfBitExcludeKernel | unix.PerfBitExcludeHv,
+		}
+		attr.Size = uint32(binary.Size(attr))
+
+		fd, err := unix.PerfEventOpen(&attr, 0, -1, -1, unix.PERF_FLAG_FD_CLOEXEC)
+		if err != nil {
+			g.close()
+			return nil, fmt.Errorf("perf_event_open: %w", err)
+		}
+		g.fds = append(g.fds, fd)
+	}
+
+	for _, fd := range g.fds {
+		unix.IoctlSetInt(fd, unix.PERF_EVENT_IOC_RESET, 0)
+		unix.IoctlSetInt(fd, unix.PERF_EVENT_IOC_ENABLE, 0)
+	}
+
+	return g, nil
+}
+
+// Stop disables the counters, releases the thread lock and returns the
+// accumulated values.
+func (g *PerfGroup) Stop() (*PerfCounters, error) {
+	defer g.close()
+
+	for _, fd := range g.fds {
+		unix.IoctlSetInt(fd, unix.PERF_EVENT_IOC_DISABLE, 0)
+	}
+
+	values := make([]uint64, len(g.fds))
+	buf := make([]byte, 8)
+	for i, fd := range g.fds {
+		if _, err := unix.Read(fd, buf); err != nil {
+			return nil, fmt.Errorf("reading perf counter: %w", err)
+		}
+		values[i] = binary.NativeEndian.Uint64(buf)
+	}
+
+	return &PerfCounters{
+		Instructions: values[0],
+		CacheMisses:  values[1],
+		BranchMisses: values[2],
+	}, nil
+}
+
+func (g *PerfGroup) close() {
+	for _, fd := range g.fds {
+		unix.Close(fd)
+	}
+	g.fds = nil
+	runtime.UnlockOSThread()
+}
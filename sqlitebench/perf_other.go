@@ -0,0 +1,15 @@
+//go:build !linux
+
+package sqlitebench
+
+import "errors"
+
+type PerfGroup struct{}
+
+func StartPerfCounters() (*PerfGroup, error) {
+	return nil, errors.New("hardware performance counters are only supported on Linux")
+}
+
+func (g *PerfGroup) Stop() (*PerfCounters, error) {
+	return nil, nil
+}
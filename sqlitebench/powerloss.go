@@ -0,0 +1,260 @@
+package sqlitebench
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+)
+
+// fileOp is a change to a file logged by a counting VFS.
+type fileOp struct {
+	kind byte
+	// name is the file's base name.
+	name string
+	// off is the offset written at, or the size truncated to.
+	off  int64
+	data []byte
+	// dirSync is set on deletions SQLite asked to make durable.
+	dirSync bool
+}
+
+// The kinds of fileOp.
+const (
+	opWrite byte = iota
+	opTruncate
+	opSync
+	opDelete
+)
+
+// fileLog logs the changes to files made through a counting VFS. Logging
+// is process-wide, like the counts.
+type fileLog struct {
+	start func()
+	// len returns the number of changes logged so far.
+	len func() int
+	// stop stops logging and returns the log, clearing it.
+	stop func() []fileOp
+}
+
+// powerLossMu serializes PowerLossTests, as each driver has one log.
+var powerLossMu sync.Mutex
+
+// PowerLossDrivers returns the drivers PowerLossTest can test.
+func PowerLossDrivers() []string {
+	var drivers []string
+	for name := range Drivers {
+		if v, ok := ioVFSes[name]; ok && v.log != nil {
+			drivers = append(drivers, name)
+		}
+	}
+	sort.Strings(drivers)
+	return drivers
+}
+
+// PowerLossTest checks what a driver's database keeps of its committed
+// transactions when the power fails, which a killed process cannot show.
+// The writer commits Txs transactions through the driver's counting VFS,
+// which logs every write, truncation, sync and deletion. Each trial then
+// cuts the power at a random point of the log: the changes to a file up to
+// its last sync survive, and each later one is lost with probability Drop,
+// the rest landing in random order, as a disk cache may reorder them. The
+// files so rebuilt are recovered and checked like a crashed writer's.
+//
+// A deletion SQLite did not ask to make durable is lost like an unsynced
+// write, with the changes to a file created under its name after it, so
+// journal_mode=delete may roll back the last transaction unless
+// synchronous=extra. Directory entries of new files are assumed durable.
+type PowerLossTest struct {
+	CrashTest
+	// Txs is the number of transactions the writer commits.
+	Txs int `json:"txs"`
+	// Drop is the probability of losing each change not yet synced.
+	Drop float64 `json:"drop"`
+}
+
+// Run writes the file at Path and runs trials power cuts on copies of it,
+// in a directory next to it. A copy that cannot be read back counts as
+// corrupt, with the error as its Integrity.
+func (p *PowerLossTest) Run(ctx context.Context, trials int) ([]CrashResult, error) {
+	if p.Txs <= 0 {
+		return nil, fmt.Errorf("power loss test of %d transactions", p.Txs)
+	}
+	v, ok := ioVFSes[p.Driver]
+	if !ok || v.log == nil {
+		return nil, fmt.Errorf("driver %q has no VFS logging its writes", p.Driver)
+	}
+	name, _, err := ioVFS(p.Driver)
+	if err != nil {
+		return nil, err
+	}
+	powerLossMu.Lock()
+	defer powerLossMu.Unlock()
+
+	c := p.CrashTest
+	c.VFS = name
+	if err := c.Prepare(ctx); err != nil {
+		return nil, fmt.Errorf("preparing %s: %w", c.Path, err)
+	}
+	// The prepared files are on disk when the log starts.
+	base := map[string][]byte{}
+	for _, suffix := range []string{"", "-journal", "-wal"} {
+		data, err := os.ReadFile(c.Path + suffix)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		base[filepath.Base(c.Path)+suffix] = data
+	}
+
+	ops, acks, err := p.write(ctx, &c, v.log)
+	if err != nil {
+		return nil, err
+	}
+
+	trialDir := filepath.Join(filepath.Dir(c.Path), "powerloss")
+	if err := os.MkdirAll(trialDir, 0o755); err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(trialDir)
+	trial := CrashTest{Path: filepath.Join(trialDir, filepath.Base(c.Path)), Driver: p.Driver, TxRows: p.TxRows}
+
+	rng := rand.New(rand.NewPCG(uint64(p.Seed), uint64(p.Txs)))
+	var results []CrashResult
+	for range trials {
+		cut := rng.IntN(len(ops) + 1)
+		files := replayPowerLoss(base, ops[:cut], rng, p.Drop)
+		if err := removeDatabase(trial.Path); err != nil {
+			return results, err
+		}
+		for name, data := range files {
+			if err := os.WriteFile(filepath.Join(trialDir, name), data, 0o644); err != nil {
+				return results, err
+			}
+		}
+		// A transaction is acknowledged once its commit returned, before
+		// the cut.
+		acked, _ := slices.BinarySearch(acks, cut+1)
+		result, err := trial.Recover(ctx, acked)
+		if err != nil {
+			if ctx.Err() != nil {
+				return results, ctx.Err()
+			}
+			result.Integrity = err.Error()
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// write commits Txs transactions with log on and returns the log and the
+// log's length after each commit returned.
+func (p *PowerLossTest) write(ctx context.Context, c *CrashTest, log *fileLog) ([]fileOp, []int, error) {
+	writeCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var acks []int
+	log.start()
+	err := c.Write(writeCtx, func(tx int) {
+		acks = append(acks, log.len())
+		if tx+1 == p.Txs {
+			cancel()
+		}
+	})
+	ops := log.stop()
+	if ctx.Err() != nil {
+		return nil, nil, ctx.Err()
+	}
+	if err != nil && !errors.Is(err, context.Canceled) {
+		return nil, nil, fmt.Errorf("writing: %w", err)
+	}
+	return ops, acks, nil
+}
+
+// replayPowerLoss returns the files as a power cut after ops leaves them,
+// starting from base. Changes to a file up to its last sync are applied in
+// order; each later one is dropped with probability drop and the rest are
+// applied in random order. Deletions made durable apply at once; the others
+// are kept in order, as the changes after one went to a new file.
+func replayPowerLoss(base map[string][]byte, ops []fileOp, rng *rand.Rand, drop float64) map[string][]byte {
+	files := map[string][]byte{}
+	for name, data := range base {
+		files[name] = bytes.Clone(data)
+	}
+	pending := map[string][]fileOp{}
+	for _, op := range ops {
+		switch {
+		case op.kind == opSync:
+			for _, p := range pending[op.name] {
+				applyFileOp(files, p)
+			}
+			delete(pending, op.name)
+		case op.kind == opDelete && op.dirSync:
+			delete(files, op.name)
+			delete(pending, op.name)
+		default:
+			pending[op.name] = append(pending[op.name], op)
+		}
+	}
+
+	names := make([]string, 0, len(pending))
+	for name := range pending {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		unsynced := pending[name]
+		for {
+			i := slices.IndexFunc(unsynced, func(op fileOp) bool { return op.kind == opDelete })
+			if i < 0 {
+				i = len(unsynced)
+			}
+			changes := unsynced[:i]
+			rng.Shuffle(len(changes), func(i, j int) { changes[i], changes[j] = changes[j], changes[i] })
+			for _, op := range changes {
+				if rng.Float64() >= drop {
+					applyFileOp(files, op)
+				}
+			}
+			// Changes after a deletion went to a new file, lost with it.
+			if i == len(unsynced) || rng.Float64() < drop {
+				break
+			}
+			delete(files, name)
+			unsynced = unsynced[i+1:]
+		}
+	}
+	return files
+}
+
+// applyFileOp applies a write, truncation or deletion to files, creating
+// those written to.
+func applyFileOp(files map[string][]byte, op fileOp) {
+	switch op.kind {
+	case opWrite:
+		data := files[op.name]
+		if end := op.off + int64(len(op.data)); end > int64(len(data)) {
+			data = append(data, make([]byte, end-int64(len(data)))...)
+		}
+		copy(data[op.off:], op.data)
+		files[op.name] = data
+	case opTruncate:
+		data := files[op.name]
+		if op.off < int64(len(data)) {
+			data = data[:op.off]
+		} else {
+			data = append(data, make([]byte, op.off-int64(len(data)))...)
+		}
+		files[op.name] = data
+	case opDelete:
+		delete(files, op.name)
+	}
+}
@@ -0,0 +1,75 @@
+package sqlitebench
+
+import (
+	"context"
+	"math/rand/v2"
+	"path/filepath"
+	"testing"
+)
+
+func TestReplayPowerLoss(t *testing.T) {
+	ops := []fileOp{
+		{kind: opWrite, name: "db", off: 0, data: []byte("aaaa")},
+		{kind: opSync, name: "db"},
+		{kind: opWrite, name: "db", off: 2, data: []byte("bb")},
+		{kind: opWrite, name: "db-journal", off: 0, data: []byte("j")},
+		{kind: opSync, name: "db-journal"},
+		{kind: opDelete, name: "db-journal"},
+		{kind: opWrite, name: "db-journal", off: 0, data: []byte("k")},
+	}
+	rng := rand.New(rand.NewPCG(1, 2))
+
+	kept := replayPowerLoss(nil, ops, rng, 0)
+	if string(kept["db"]) != "aabb" || string(kept["db-journal"]) != "k" {
+		t.Errorf("nothing dropped: files = %q", kept)
+	}
+	lost := replayPowerLoss(nil, ops, rng, 1)
+	if string(lost["db"]) != "aaaa" || string(lost["db-journal"]) != "j" {
+		t.Errorf("everything unsynced dropped: files = %q", lost)
+	}
+
+	// The write after the deletion went to a new file, lost with it.
+	ops[len(ops)-2].dirSync = true
+	if files := replayPowerLoss(nil, ops, rng, 1); files["db-journal"] != nil {
+		t.Errorf("durable deletion dropped: files = %q", files)
+	}
+}
+
+func TestPowerLossTest(t *testing.T) {
+	drivers := PowerLossDrivers()
+	if len(drivers) == 0 {
+		t.Skip("no driver can log its writes in this build")
+	}
+
+	dir := t.TempDir()
+	for _, driver := range drivers {
+		p := &PowerLossTest{
+			CrashTest: CrashTest{
+				Path:        filepath.Join(dir, driver+".db"),
+				Driver:      driver,
+				JournalMode: "wal",
+				Synchronous: "full",
+				TxRows:      5,
+				DataSize:    512,
+				Seed:        1,
+			},
+			Txs:  5,
+			Drop: 0.5,
+		}
+		results, err := p.Run(context.Background(), 20)
+		if err != nil {
+			t.Fatalf("%s: %v", driver, err)
+		}
+		acked := 0
+		for _, r := range results {
+			// WAL with synchronous=full syncs every commit.
+			if r.Lost != 0 || r.Torn || r.Integrity != "ok" {
+				t.Errorf("%s: %+v", driver, r)
+			}
+			acked += r.Acked
+		}
+		if acked == 0 {
+			t.Errorf("%s: no trial cut the power after a commit", driver)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package sqlitebench
+
+import (
+	"context"
+	"time"
+)
+
+func init() {
+	Register(Benchmark{Category: CategoryConcurrency, New: func() Workload { return &Queue{} }})
+}
+
+// Queue uses a table as a job queue, as many applications use SQLite:
+// even operations produce a job, and odd ones are a worker claiming the
+// oldest pending job with UPDATE ... RETURNING and deleting it once done.
+// Run with a Concurrency above 1, producers and workers contend for the
+// write lock, which shows in the time per operation and in the busy
+// errors. The queue starts empty, so workers find a job only once one was
+// produced; Params.Rows is not used.
+//
+// The time from producing a job to completing it is kept in the "job"
+// phase.
+type Queue struct {
+	phaseTimes
+
+	payloads *PayloadPool
+}
+
+func (*Queue) Name() string        { return "queue" }
+func (*Queue) Description() string { return "produce jobs and claim and complete them as workers" }
+
+func (w *Queue) Setup(ctx context.Context, db Conn, p Params) error {
+	w.payloads = p.Payloads
+	for _, stmt := range []string{
+		"CREATE TABLE jobs (id INTEGER PRIMARY KEY, payload BLOB NOT NULL, enqueued INTEGER NOT NULL, claimed_by INTEGER)",
+		"CREATE INDEX jobs_pending ON jobs (id) WHERE claimed_by IS NULL",
+	} {
+		if err := db.Exec(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *Queue) Run(ctx context.Context, db Conn, n int) error {
+	if n%2 == 0 {
+		return db.Exec(ctx, "INSERT INTO jobs (payload, enqueued) VALUES (?, ?)", w.payloads.Next(), time.Now().UnixNano())
+	}
+
+	// The claim is one statement, so workers racing for the same job
+	// cannot both get it.
+	rows, err := db.Query(ctx, "UPDATE jobs SET claimed_by = ? WHERE id = (SELECT id FROM jobs WHERE claimed_by IS NULL ORDER BY id LIMIT 1) RETURNING id, payload, enqueued", n)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		// The queue is empty.
+		return rows.Err()
+	}
+	var id, enqueued int64
+	var payload []byte
+	if err := rows.Scan(&id, &payload, &enqueued); err != nil {
+		return err
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+
+	if err := db.Exec(ctx, "DELETE FROM jobs WHERE id = ?", id); err != nil {
+		return err
+	}
+	w.record("job", time.Since(time.Unix(0, enqueued)))
+	return nil
+}
+
+func (*Queue) Teardown(db Conn) error { return nil }
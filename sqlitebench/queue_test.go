@@ -0,0 +1,27 @@
+package sqlitebench
+
+import (
+	"context"
+	"testing"
+)
+
+func TestQueue(t *testing.T) {
+	r := NewRunner()
+	r.Drivers, r.Sizes, r.Ops = []string{"modernc"}, []int{64}, 50
+	r.Concurrency = []int{1, 4}
+	w := &Queue{}
+	r.Add(w)
+
+	results, err := r.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Alone, every worker claims the job produced just before; with four
+	// connections, one round's two workers may race its two producers.
+	if jobs := len(results[0].Phases["job"]); jobs != 25 {
+		t.Errorf("completed %d jobs alone, want 25", jobs)
+	}
+	if jobs := len(results[1].Phases["job"]); jobs == 0 || jobs > 100 {
+		t.Errorf("completed %d jobs with four connections, want up to 100", jobs)
+	}
+}
@@ -0,0 +1,102 @@
+package sqlitebench
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+func init() {
+	Register(Benchmark{Category: CategoryRead, New: func() Workload { return &Read{} }})
+	Register(Benchmark{Category: CategoryRead, New: func() Workload { return &Read{Hot: true} }})
+}
+
+// Read selects one row's blob and label per operation by id from a table
+// of Params.Rows rows. The rows are picked at random, so a table larger
+// than SQLite's page cache is read from the database file, as in a real
+// lookup.
+type Read struct {
+	rowBytes
+
+	// Hot selects the first row every time instead, measuring the
+	// statement overhead of a row that is always cached.
+	Hot bool
+
+	// check is set when rows are verified. Comparing every blob costs
+	// time, so verified cells are marked and not comparable with
+	// unverified ones.
+	check *verifier
+
+	rng  lockedRand
+	rows int
+}
+
+func (r *Read) Name() string {
+	if r.Hot {
+		return "read.hot"
+	}
+	return "read"
+}
+
+func (r *Read) Description() string {
+	if r.Hot {
+		return "select the same blob per operation"
+	}
+	return "select one blob by random rowid per operation"
+}
+
+func (r *Read) Setup(ctx context.Context, db Conn, p Params) error {
+	r.reset()
+	r.check = nil
+	if p.Verify {
+		r.check = p.Payloads.verifier()
+	}
+	r.rng.seed(p)
+	r.rows = max(p.Rows, 1)
+	return Populate(ctx, db, p.Rows, p.Payloads.Next)
+}
+
+// rowid picks the row to read.
+func (r *Read) rowid() int64 {
+	if r.Hot {
+		return 1
+	}
+	return 1 + r.rng.int64N(int64(r.rows))
+}
+
+// Run scans the blob even when not verifying it, as drivers may fetch
+// column values lazily.
+func (r *Read) Run(ctx context.Context, db Conn, n int) error {
+	id := r.rowid()
+	rows, err := db.Query(ctx, "SELECT data, label FROM test WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return sql.ErrNoRows
+	}
+	var data []byte
+	var label string
+	if err := rows.Scan(&data, &label); err != nil {
+		return err
+	}
+	r.add(data)
+	if r.check != nil {
+		if err := r.check.check(int(id-1), data); err != nil {
+			return err
+		}
+		if label != RowLabel(int(id-1)) {
+			return fmt.Errorf("row %d: read back label %q", id-1, label)
+		}
+	}
+	return rows.Close()
+}
+
+func (*Read) Teardown(db Conn) error { return nil }
+
+// Verifies reports that Read checks blobs when asked to.
+func (*Read) Verifies() bool { return true }
@@ -0,0 +1,182 @@
+package sqlitebench
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Replay runs a recorded trace against a driver on a fresh in-memory
+// database, so the trace has to create the schema it uses.
+type Replay struct {
+	Trace []TraceEntry
+	// Realtime waits until each statement's original offset before
+	// issuing it; otherwise the trace is replayed at full speed.
+	Realtime bool
+	// Pragmas are executed as "PRAGMA <p>" before the trace.
+	Pragmas []string
+}
+
+// StatementStats are the latencies of one distinct statement text.
+type StatementStats struct {
+	SQL     string
+	Samples []time.Duration
+	// Errors counts executions that failed; they have no sample.
+	Errors int
+}
+
+// ReplayResult is the outcome of replaying a trace against one driver.
+type ReplayResult struct {
+	Driver string
+	// Duration is the wall time of the whole replay.
+	Duration time.Duration
+	// Statements are in order of first appearance in the trace.
+	Statements []*StatementStats
+}
+
+// Samples returns the latencies of every statement executed.
+func (r *ReplayResult) Samples() []time.Duration {
+	var all []time.Duration
+	for _, s := range r.Statements {
+		all = append(all, s.Samples...)
+	}
+	return all
+}
+
+// Errors returns the number of statements that failed.
+func (r *ReplayResult) Errors() int {
+	n := 0
+	for _, s := range r.Statements {
+		n += s.Errors
+	}
+	return n
+}
+
+// Run replays the trace against driver. Statements that fail are counted
+// and the replay carries on, since application traces often contain
+// statements that failed when recorded too. If ctx is cancelled the
+// statistics so far are returned with ctx's error.
+func (r *Replay) Run(ctx context.Context, driver string) (*ReplayResult, error) {
+	backend, ok := Drivers[driver]
+	if !ok {
+		return nil, fmt.Errorf("unknown driver %q", driver)
+	}
+	db, err := backend.Open(ctx, ":memory:")
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	for _, pragma := range r.Pragmas {
+		if err := db.Exec(ctx, "PRAGMA "+pragma); err != nil {
+			return nil, fmt.Errorf("applying pragma %q: %w", pragma, err)
+		}
+	}
+
+	result := &ReplayResult{Driver: driver}
+	stats := map[string]*StatementStats{}
+	start := time.Now()
+	for _, e := range r.Trace {
+		if r.Realtime {
+			if wait := e.Offset - time.Since(start); wait > 0 {
+				select {
+				case <-time.After(wait):
+				case <-ctx.Done():
+				}
+			}
+		}
+		if ctx.Err() != nil {
+			result.Duration = time.Since(start)
+			return result, ctx.Err()
+		}
+
+		s, ok := stats[e.SQL]
+		if !ok {
+			s = &StatementStats{SQL: e.SQL}
+			stats[e.SQL] = s
+			result.Statements = append(result.Statements, s)
+		}
+
+		opStart := time.Now()
+		if err := runStatement(ctx, db, e.SQL, e.Args, nil); err != nil {
+			s.Errors++
+			continue
+		}
+		s.Samples = append(s.Samples, time.Since(opStart))
+	}
+	result.Duration = time.Since(start)
+	return result, nil
+}
+
+// runStatement executes query, scanning every row if it returns any, as
+// drivers may fetch column values lazily, and counting the values' bytes
+// in read unless it is nil.
+func runStatement(ctx context.Context, db Conn, query string, args []any, read *rowBytes) error {
+	if !returnsRows(query) {
+		return db.Exec(ctx, query, args...)
+	}
+
+	rows, err := db.Query(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return err
+	}
+	dest := make([]any, len(types))
+	for i, t := range types {
+		dest[i] = scanDest(t.DatabaseTypeName())
+	}
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return err
+		}
+		if read != nil {
+			for _, d := range dest {
+				read.addValue(d)
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	return rows.Close()
+}
+
+// scanDest returns a destination for a column of the declared type, as an
+// application would scan it, chosen by SQLite's type affinity rules.
+// Columns without a declared type, such as expressions, and those of other
+// types scan into an any as the driver returns them.
+func scanDest(declType string) any {
+	t := strings.ToUpper(declType)
+	switch {
+	case t == "":
+		return new(any)
+	case strings.Contains(t, "INT"):
+		return new(sql.NullInt64)
+	case strings.Contains(t, "CHAR"), strings.Contains(t, "CLOB"), strings.Contains(t, "TEXT"):
+		return new(sql.NullString)
+	case strings.Contains(t, "BLOB"):
+		return new([]byte)
+	case strings.Contains(t, "REAL"), strings.Contains(t, "FLOA"), strings.Contains(t, "DOUB"):
+		return new(sql.NullFloat64)
+	}
+	return new(any)
+}
+
+// Percentile returns the p-th percentile (0-100) of samples by the
+// nearest-rank method, or zero for no samples.
+func Percentile(samples []time.Duration, p float64) time.Duration {
+	if len(samples) == 0 {
+		return 0
+	}
+	sorted := slices.Clone(samples)
+	slices.Sort(sorted)
+	rank := int(p/100*float64(len(sorted))+0.5) - 1
+	return sorted[min(max(rank, 0), len(sorted)-1)]
+}
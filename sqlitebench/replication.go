@@ -0,0 +1,173 @@
+package sqlitebench
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+// Replication values for Runner.Replication. Every cell of the replication
+// dimension, including ReplicationOff, uses a WAL-mode database file, so
+// the cells differ only in whether a replicator ships the WAL while the
+// workload runs.
+const (
+	// ReplicationOff runs no replicator; it is the baseline the others
+	// are compared with.
+	ReplicationOff = "off"
+	// ReplicationWALCopy copies new WAL frames to a replica directory and
+	// syncs them, in the background, like Litestream does.
+	ReplicationWALCopy = "wal-copy"
+	// ReplicationLitestream runs "litestream replicate" on the database,
+	// replicating to a directory. The litestream binary must be on PATH.
+	ReplicationLitestream = "litestream"
+)
+
+// Replications lists the valid replication values.
+var Replications = []string{ReplicationOff, ReplicationWALCopy, ReplicationLitestream}
+
+// walCopyInterval is how often the WAL copier ships new frames.
+const walCopyInterval = 100 * time.Millisecond
+
+// replicator ships a database's changes while a cell runs.
+type replicator interface {
+	// Stop ends replication and reports any error it ran into.
+	Stop() error
+}
+
+// startReplication starts the replicator mode names for the database at
+// path, replicating into dir.
+func startReplication(mode, path, dir string) (replicator, error) {
+	switch mode {
+	case ReplicationWALCopy:
+		return startWALCopy(path+"-wal", dir), nil
+	case ReplicationLitestream:
+		return startLitestream(path, dir)
+	default:
+		return nil, fmt.Errorf("unknown replication %q", mode)
+	}
+}
+
+// walCopier appends the frames SQLite adds to a WAL file to segment files
+// in a replica directory. When a checkpoint restarts the WAL, which
+// changes its header, copying continues from the start into a new segment.
+type walCopier struct {
+	wal, dir string
+
+	out     *os.File
+	segment int
+	offset  int64
+	header  []byte
+
+	stop chan struct{}
+	done chan error
+}
+
+func startWALCopy(wal, dir string) *walCopier {
+	c := &walCopier{wal: wal, dir: dir, stop: make(chan struct{}), done: make(chan error, 1)}
+	go func() {
+		ticker := time.NewTicker(walCopyInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-c.stop:
+				// Ship what the last operations wrote.
+				err := c.copy()
+				if c.out != nil {
+					err = errors.Join(err, c.out.Close())
+				}
+				c.done <- err
+				return
+			case <-ticker.C:
+				if err := c.copy(); err != nil {
+					c.out.Close()
+					c.done <- err
+					return
+				}
+			}
+		}
+	}()
+	return c
+}
+
+// copy ships the frames written since the last call.
+func (c *walCopier) copy() error {
+	f, err := os.Open(c.wal)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	header := make([]byte, 32)
+	if _, err := io.ReadFull(f, header); err != nil {
+		// No complete header yet.
+		return nil
+	}
+	if c.out == nil || !bytes.Equal(header, c.header) || info.Size() < c.offset {
+		if c.out != nil {
+			if err := c.out.Close(); err != nil {
+				return err
+			}
+		}
+		c.segment++
+		c.out, err = os.Create(filepath.Join(c.dir, fmt.Sprintf("%08d.wal", c.segment)))
+		if err != nil {
+			return err
+		}
+		c.header, c.offset = header, 0
+	}
+
+	n, err := io.Copy(c.out, io.NewSectionReader(f, c.offset, info.Size()-c.offset))
+	c.offset += n
+	if err != nil {
+		return err
+	}
+	return c.out.Sync()
+}
+
+func (c *walCopier) Stop() error {
+	close(c.stop)
+	return <-c.done
+}
+
+// litestream is a running "litestream replicate" process.
+type litestream struct {
+	cmd    *exec.Cmd
+	stderr bytes.Buffer
+}
+
+func startLitestream(path, dir string) (*litestream, error) {
+	bin, err := exec.LookPath("litestream")
+	if err != nil {
+		return nil, fmt.Errorf("litestream replication: %w", err)
+	}
+	l := &litestream{cmd: exec.Command(bin, "replicate", path, "file://"+dir)}
+	l.cmd.Stderr = &l.stderr
+	if err := l.cmd.Start(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Stop interrupts litestream, which makes it sync the replica once more
+// before it exits.
+func (l *litestream) Stop() error {
+	if err := l.cmd.Process.Signal(os.Interrupt); err != nil {
+		return err
+	}
+	if err := l.cmd.Wait(); err != nil {
+		return fmt.Errorf("litestream: %w: %s", err, bytes.TrimSpace(l.stderr.Bytes()))
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package sqlitebench
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWALCopy(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "bench.db")
+	db, err := openDB(ctx, Drivers["modernc"], path, []string{"journal_mode=wal"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	replica := filepath.Join(dir, "replica")
+	if err := os.Mkdir(replica, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	c := startWALCopy(path+"-wal", replica)
+	payloads := NewPayloadPool(1, 0, 1024)
+	for range 20 {
+		if err := db.Exec(ctx, "INSERT INTO test (data) VALUES (?)", payloads.Next()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	wal, err := os.Stat(path + "-wal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	segments, err := filepath.Glob(filepath.Join(replica, "*.wal"))
+	if err != nil || len(segments) != 1 {
+		t.Fatalf("segments = %v, %v; want one", segments, err)
+	}
+	copied, err := os.Stat(segments[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if copied.Size() != wal.Size() {
+		t.Errorf("copied %d bytes of a %d byte WAL", copied.Size(), wal.Size())
+	}
+}
+
+func TestRunnerReplication(t *testing.T) {
+	r := NewRunner()
+	r.Drivers, r.Sizes, r.Ops = []string{"modernc"}, []int{64}, 5
+	r.Replication = []string{ReplicationOff, ReplicationWALCopy}
+	r.Add(&Write{})
+
+	results, err := r.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[1].Operation != "write,replication=wal-copy" {
+		t.Errorf("results = %+v, want write with replication off and wal-copy", results)
+	}
+}
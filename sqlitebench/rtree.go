@@ -0,0 +1,153 @@
+package sqlitebench
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+func init() {
+	Register(Benchmark{Category: CategoryWrite, Requires: []string{"rtree"}, New: func() Workload { return &RTree{} }})
+	Register(Benchmark{Category: CategoryRead, Requires: []string{"rtree"}, New: func() Workload { return &RTree{Query: true} }})
+}
+
+const (
+	// rtreeCities is the number of centres features cluster around, as
+	// they do on a map.
+	rtreeCities = 32
+	// rtreeViewport is the width in degrees of the map views queried, a
+	// few kilometres.
+	rtreeViewport = 0.05
+)
+
+// RTree models the spatial index of an offline map: features with a
+// bounding box in an R*Tree virtual table and their geometry, the payload,
+// in a table beside it. Features cluster around a few cities, and most are
+// the size of a building, a few that of a district or a region.
+//
+// rtree.insert inserts a feature per operation. rtree.query runs, over
+// Params.Rows features, in turn an intersection query for the features
+// overlapping a map view, reading their geometry; a containment query for
+// those lying wholly inside one; and one for the features containing a
+// point, as when finding the areas a location is in. Their times are kept
+// in the "intersect", "within" and "contains" phases. Running it with
+// several rows settings shows how the index scales.
+type RTree struct {
+	phaseTimes
+	rowBytes
+
+	// Query selects rtree.query instead of rtree.insert.
+	Query bool
+
+	payloads *PayloadPool
+	cities   [rtreeCities][2]float64
+	rng      lockedRand
+}
+
+func (w *RTree) Name() string {
+	if w.Query {
+		return "rtree.query"
+	}
+	return "rtree.insert"
+}
+
+func (w *RTree) Description() string {
+	if w.Query {
+		return "query an R-Tree of map features for a view or a point per operation"
+	}
+	return "insert a map feature's bounding box into an R-Tree per operation"
+}
+
+func (w *RTree) Setup(ctx context.Context, db Conn, p Params) error {
+	w.reset()
+	w.payloads = p.Payloads
+	w.rng.seed(p)
+	w.rng.do(func(rng *rand.Rand) {
+		for i := range w.cities {
+			w.cities[i] = [2]float64{rng.Float64()*340 - 170, rng.Float64()*140 - 70}
+		}
+	})
+	for _, stmt := range []string{
+		"CREATE VIRTUAL TABLE features_index USING rtree(id, min_lon, max_lon, min_lat, max_lat)",
+		"CREATE TABLE features (id INTEGER PRIMARY KEY, geometry BLOB)",
+	} {
+		if err := db.Exec(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	if !w.Query {
+		return nil
+	}
+	return populate(ctx, db, p.Rows, func(tx Tx, i int) error {
+		return w.insert(ctx, tx.Exec, int64(i+1))
+	})
+}
+
+// near returns a point near a random city.
+func (w *RTree) near() (lon, lat float64) {
+	w.rng.do(func(rng *rand.Rand) {
+		city := w.cities[rng.IntN(rtreeCities)]
+		lon, lat = city[0]+rng.NormFloat64()*0.2, city[1]+rng.NormFloat64()*0.2
+	})
+	return lon, lat
+}
+
+// insert inserts feature id with exec: its box in the index, sized
+// log-normally from a few metres to a region, and its geometry.
+func (w *RTree) insert(ctx context.Context, exec func(ctx context.Context, query string, args ...any) error, id int64) error {
+	lon, lat := w.near()
+	var width, height float64
+	w.rng.do(func(rng *rand.Rand) {
+		width = min(0.0005*math.Exp(rng.NormFloat64()*1.5), 5)
+		height = width * (0.5 + rng.Float64())
+	})
+	if err := exec(ctx, "INSERT INTO features_index (id, min_lon, max_lon, min_lat, max_lat) VALUES (?, ?, ?, ?, ?)",
+		id, lon-width/2, lon+width/2, lat-height/2, lat+height/2); err != nil {
+		return err
+	}
+	return exec(ctx, "INSERT INTO features (id, geometry) VALUES (?, ?)", id, w.payloads.Next())
+}
+
+func (w *RTree) Run(ctx context.Context, db Conn, n int) error {
+	if !w.Query {
+		// Operation numbers are unique, so they serve as feature ids.
+		tx, err := db.Begin(ctx)
+		if err != nil {
+			return err
+		}
+		if err := w.insert(ctx, tx.Exec, int64(n+1)); err != nil {
+			tx.Rollback()
+			return err
+		}
+		return tx.Commit()
+	}
+
+	lon, lat := w.near()
+	west, east, south, north := lon-rtreeViewport/2, lon+rtreeViewport/2, lat-rtreeViewport/2, lat+rtreeViewport/2
+	var phase, query string
+	var args []any
+	switch n % 3 {
+	case 0:
+		phase = "intersect"
+		query = `SELECT f.id, f.geometry FROM features_index i JOIN features f ON f.id = i.id
+			WHERE i.max_lon >= ? AND i.min_lon <= ? AND i.max_lat >= ? AND i.min_lat <= ?`
+		args = []any{west, east, south, north}
+	case 1:
+		phase = "within"
+		query = "SELECT id FROM features_index WHERE min_lon >= ? AND max_lon <= ? AND min_lat >= ? AND max_lat <= ?"
+		args = []any{west, east, south, north}
+	default:
+		phase = "contains"
+		query = "SELECT id FROM features_index WHERE min_lon <= ? AND max_lon >= ? AND min_lat <= ? AND max_lat >= ?"
+		args = []any{lon, lon, lat, lat}
+	}
+	start := time.Now()
+	if err := runStatement(ctx, db, query, args, &w.rowBytes); err != nil {
+		return err
+	}
+	w.record(phase, time.Since(start))
+	return nil
+}
+
+func (*RTree) Teardown(db Conn) error { return nil }
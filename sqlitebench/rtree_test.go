@@ -0,0 +1,47 @@
+package sqlitebench
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestRTree(t *testing.T) {
+	ctx := context.Background()
+	for _, query := range []bool{false, true} {
+		w := &RTree{Query: query}
+		db := setupWorkload(t, w, Params{Rows: 2000, Seed: 1})
+		runOps(t, w, db, 30)
+
+		var indexed, features int
+		var check string
+		if err := QueryRow(ctx, db, "SELECT (SELECT count(*) FROM features_index), (SELECT count(*) FROM features), rtreecheck('features_index')", &indexed, &features, &check); err != nil {
+			t.Fatal(err)
+		}
+		want := 30
+		if query {
+			want = 2000
+		}
+		if indexed != want || features != want || check != "ok" {
+			t.Errorf("%s: %d indexed, %d features, check %q; want %d, %d and ok", w.Name(), indexed, features, check, want, want)
+		}
+		if !query {
+			continue
+		}
+
+		phases := w.Phases()
+		if len(phases["intersect"]) != 10 || len(phases["within"]) != 10 || len(phases["contains"]) != 10 {
+			t.Errorf("phases = %v, want 10 queries of each kind", phases)
+		}
+		// Features cluster, so a view of a city finds some.
+		lon, lat := w.cities[0][0], w.cities[0][1]
+		var found int
+		if err := QueryRow(ctx, db, fmt.Sprintf("SELECT count(*) FROM features_index WHERE max_lon >= %f AND min_lon <= %f AND max_lat >= %f AND min_lat <= %f",
+			lon-rtreeViewport, lon+rtreeViewport, lat-rtreeViewport, lat+rtreeViewport), &found); err != nil {
+			t.Fatal(err)
+		}
+		if found == 0 {
+			t.Error("a view of a city found no features")
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package sqlitebench
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRunnerSchedule(t *testing.T) {
+	r := NewRunner()
+	r.Add(&Write{}, &Read{})
+	r.Repeat = 3
+
+	fixed := r.Schedule()
+	if len(fixed) != 3*len(r.Cells()) {
+		t.Fatalf("schedule has %d runs, want %d", len(fixed), 3*len(r.Cells()))
+	}
+	for i, idx := range fixed {
+		if idx != i%len(r.Cells()) {
+			t.Fatalf("fixed schedule = %v, want rounds in matrix order", fixed)
+		}
+	}
+
+	r.Shuffle = 42
+	shuffled := r.Schedule()
+	if !reflect.DeepEqual(shuffled, r.Schedule()) {
+		t.Error("shuffled schedule is not reproducible for the same seed")
+	}
+	if reflect.DeepEqual(shuffled, fixed) {
+		t.Error("shuffled schedule equals the fixed order")
+	}
+	counts := map[int]int{}
+	for _, idx := range shuffled {
+		counts[idx]++
+	}
+	for idx, n := range counts {
+		if n != 3 {
+			t.Errorf("cell %d runs %d times, want 3", idx, n)
+		}
+	}
+}
+
+// countWorkload records the calls the runner makes. It fails every
+// operation on failDriver.
+type countWorkload struct {
+	calls      []string
+	ops        []int
+	failDriver string
+	driver     string
+}
+
+func (*countWorkload) Name() string        { return "count" }
+func (*countWorkload) Description() string { return "count calls" }
+
+func (w *countWorkload) Setup(ctx context.Context, db Conn, p Params) error {
+	w.calls = append(w.calls, "setup")
+	return nil
+}
+
+func (w *countWorkload) CellStarted(_ int, c Cell)   { w.driver = c.Driver }
+func (w *countWorkload) CellDone(int, Cell, Result)  {}
+func (w *countWorkload) CellFailed(int, Cell, error) {}
+
+func (w *countWorkload) Run(ctx context.Context, db Conn, n int) error {
+	if w.failDriver != "" && w.driver == w.failDriver {
+		return errors.New("broken driver")
+	}
+	w.ops = append(w.ops, n)
+	return nil
+}
+
+func (w *countWorkload) Teardown(db Conn) error {
+	w.calls = append(w.calls, "teardown")
+	return nil
+}
+
+func TestRunnerWorkload(t *testing.T) {
+	w := &countWorkload{}
+	r := NewRunner()
+	r.Drivers, r.Sizes, r.Ops = []string{"modernc"}, []int{64}, 3
+	r.Add(w)
+
+	results, err := r.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Operation != "count" || len(results[0].Samples) != 3 {
+		t.Fatalf("results = %+v, want one count result with 3 samples", results)
+	}
+	if !reflect.DeepEqual(w.calls, []string{"setup", "teardown"}) {
+		t.Errorf("calls = %v, want setup then teardown", w.calls)
+	}
+	if !reflect.DeepEqual(w.ops, []int{0, 1, 2}) {
+		t.Errorf("operations = %v, want 0, 1, 2", w.ops)
+	}
+}
+
+func TestRunnerBytesRead(t *testing.T) {
+	r := NewRunner()
+	r.Drivers, r.Sizes, r.Ops = []string{"modernc", "mattn"}, []int{64}, 5
+	r.Add(&Read{}, &KVGet{})
+
+	results, err := r.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, res := range results {
+		if res.BytesRead != 5*64 {
+			t.Errorf("%s %s: BytesRead = %d, want %d", res.Driver, res.Operation, res.BytesRead, 5*64)
+		}
+	}
+}
+
+func TestRunnerCellFailure(t *testing.T) {
+	w := &countWorkload{failDriver: "mattn"}
+	r := NewRunner()
+	r.Drivers, r.Sizes, r.Ops = []string{"mattn", "modernc"}, []int{64}, 3
+	r.Observer = w
+	r.Add(w)
+
+	results, err := r.Run(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "mattn/count/64: operation 0: broken driver") {
+		t.Errorf("err = %v, want the failure wrapped with its cell", err)
+	}
+	if len(results) != 1 || results[0].Driver != "modernc" {
+		t.Errorf("results = %+v, want only the modernc cell", results)
+	}
+}
+
+// busyWorkload fails every odd operation as a contended database would.
+type busyWorkload struct{ countWorkload }
+
+func (*busyWorkload) Run(ctx context.Context, db Conn, n int) error {
+	if n%2 == 1 {
+		return errors.New("database is locked (5) (SQLITE_BUSY)")
+	}
+	return nil
+}
+
+func TestRunnerErrorCounts(t *testing.T) {
+	r := NewRunner()
+	r.Drivers, r.Sizes, r.Ops = []string{"modernc"}, []int{64}, 6
+	r.Add(&busyWorkload{})
+
+	results, err := r.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || len(results[0].Samples) != 3 || results[0].Errors == nil || *results[0].Errors != (ErrorCounts{Busy: 3}) {
+		t.Fatalf("results = %+v, want 3 samples and 3 busy errors", results)
+	}
+
+	var counts ErrorCounts
+	if !counts.count(errors.Join(errors.New("UNIQUE constraint failed: kv.id"), context.DeadlineExceeded)) {
+		t.Error("joined constraint and timeout errors were not expected")
+	}
+	if counts.count(errors.Join(errors.New("database is locked"), errors.New("disk I/O error"))) {
+		t.Error("a joined I/O error was expected")
+	}
+	if counts != (ErrorCounts{Constraint: 1, Timeout: 1}) {
+		t.Errorf("counts = %+v, want one constraint and one timeout error", counts)
+	}
+}
+
+// lockedWorkload finds the database locked on the first two attempts of
+// every operation.
+type lockedWorkload struct {
+	countWorkload
+	mu       sync.Mutex
+	attempts map[int]int
+}
+
+func (w *lockedWorkload) Run(ctx context.Context, db Conn, n int) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.attempts[n]++; w.attempts[n] <= 2 {
+		return errors.New("database table is locked")
+	}
+	return nil
+}
+
+func TestRunnerBusyRetries(t *testing.T) {
+	for retries, want := range map[int]ErrorCounts{
+		// Two retries get every operation through.
+		2: {Retried: 4, Retries: 8},
+		// One does not, and the second attempt's failure counts.
+		1: {Busy: 4, Retried: 4, Retries: 4},
+	} {
+		r := NewRunner()
+		r.Drivers, r.Sizes, r.Ops = []string{"modernc"}, []int{64}, 4
+		r.BusyRetries = retries
+		r.Add(&lockedWorkload{attempts: map[int]int{}})
+
+		results, err := r.Run(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := results[0].Errors
+		if got == nil {
+			t.Fatalf("%d retries: no error counts", retries)
+		}
+		got.RetryTime = 0
+		if *got != want {
+			t.Errorf("%d retries: counts = %+v, want %+v", retries, *got, want)
+		}
+	}
+}
+
+func TestRunnerCapabilities(t *testing.T) {
+	Register(Benchmark{Category: CategoryRead, Requires: []string{"fts5"}, New: func() Workload { return &countWorkload{} }})
+	t.Cleanup(func() { delete(Workloads, "count") })
+	if b := Workloads["count"]; b.ID != "count" || b.Description == "" {
+		t.Errorf("registered %+v, want the workload's name and description", b)
+	}
+
+	r := NewRunner()
+	r.Drivers, r.Sizes = []string{"mattn", "modernc"}, []int{64}
+	r.Add(&countWorkload{}, &Write{})
+	r.Capabilities = map[string][]string{"mattn": {"fts5", "json1"}, "modernc": {"json1"}}
+
+	var names []string
+	for _, c := range r.Cells() {
+		names = append(names, c.String())
+	}
+	want := []string{"mattn/count/64", "mattn/write/64", "modernc/write/64"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("cells = %v, want %v", names, want)
+	}
+}
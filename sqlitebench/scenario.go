@@ -0,0 +1,201 @@
+package sqlitebench
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"sync"
+)
+
+// Scenario is a workload described by data instead of code, so an
+// application's own queries can be benchmarked without writing Go. The setup
+// statements run once per cell; every measured operation then runs the
+// steps in order.
+type Scenario struct {
+	Name        string   `yaml:"name" toml:"name" json:"name"`
+	Description string   `yaml:"description" toml:"description" json:"description,omitempty"`
+	Setup       []string `yaml:"setup" toml:"setup" json:"setup,omitempty"`
+	Steps       []Step   `yaml:"steps" toml:"steps" json:"steps"`
+	Teardown    []string `yaml:"teardown" toml:"teardown" json:"teardown,omitempty"`
+}
+
+// Step is one statement of a scenario.
+type Step struct {
+	SQL string `yaml:"sql" toml:"sql" json:"sql"`
+	// Params are generator expressions for the statement's placeholders,
+	// e.g. "int(1, 1000)". See ParseGenerator for the syntax.
+	Params []string `yaml:"params" toml:"params" json:"params,omitempty"`
+	// Loop runs the statement this many times per operation (default 1).
+	Loop int `yaml:"loop" toml:"loop" json:"loop,omitempty"`
+	// Concurrency spreads the loop over this many goroutines sharing the
+	// cell's connection, the way an application's goroutines share a
+	// *sql.DB (default 1).
+	Concurrency int `yaml:"concurrency" toml:"concurrency" json:"concurrency,omitempty"`
+}
+
+// Validate reports the first problem with the scenario's definition.
+func (s *Scenario) Validate() error {
+	if s.Name == "" {
+		return errors.New("scenario has no name")
+	}
+	if len(s.Steps) == 0 {
+		return fmt.Errorf("scenario %s has no steps", s.Name)
+	}
+	for i, step := range s.Steps {
+		if strings.TrimSpace(step.SQL) == "" {
+			return fmt.Errorf("step %d has no sql", i+1)
+		}
+		if step.Loop < 0 || step.Concurrency < 0 {
+			return fmt.Errorf("step %d: loop and concurrency must not be negative", i+1)
+		}
+		for _, expr := range step.Params {
+			if _, err := ParseGenerator(expr, nil, nil); err != nil {
+				return fmt.Errorf("step %d: %w", i+1, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Workload returns the scenario as a workload named after it. Call
+// Validate first.
+func (s *Scenario) Workload() Workload {
+	return &scenarioWorkload{s: s}
+}
+
+type scenarioWorkload struct {
+	rowBytes
+
+	s    *Scenario
+	gens [][]Generator
+	// mu guards the generators, which concurrent steps share.
+	mu sync.Mutex
+}
+
+func (w *scenarioWorkload) Name() string { return w.s.Name }
+
+func (w *scenarioWorkload) Description() string {
+	if w.s.Description != "" {
+		return w.s.Description
+	}
+	return "scenario"
+}
+
+// Setup runs the setup statements and seeds the parameter generators.
+func (w *scenarioWorkload) Setup(ctx context.Context, db Conn, p Params) error {
+	w.reset()
+	rng := rand.New(rand.NewPCG(uint64(p.Seed), uint64(p.DataSize)))
+	w.gens = make([][]Generator, len(w.s.Steps))
+	for i, step := range w.s.Steps {
+		for _, expr := range step.Params {
+			gen, err := ParseGenerator(expr, rng, p.Payloads)
+			if err != nil {
+				return fmt.Errorf("step %d: %w", i+1, err)
+			}
+			w.gens[i] = append(w.gens[i], gen)
+		}
+	}
+
+	for _, stmt := range w.s.Setup {
+		if err := db.Exec(ctx, stmt); err != nil {
+			return fmt.Errorf("%s: %w", stmt, err)
+		}
+	}
+	return nil
+}
+
+// Run runs every step once, with its loop and concurrency.
+func (w *scenarioWorkload) Run(ctx context.Context, db Conn, n int) error {
+	for i, step := range w.s.Steps {
+		loop, workers := max(step.Loop, 1), max(step.Concurrency, 1)
+		if err := runConcurrently(loop, workers, func() error { return w.exec(ctx, db, i) }); err != nil {
+			return fmt.Errorf("step %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// Teardown runs the teardown statements.
+func (w *scenarioWorkload) Teardown(db Conn) error {
+	for _, stmt := range w.s.Teardown {
+		if err := db.Exec(context.Background(), stmt); err != nil {
+			return fmt.Errorf("%s: %w", stmt, err)
+		}
+	}
+	return nil
+}
+
+// exec runs step i once with freshly generated parameters, reading every
+// row of statements that return rows.
+func (w *scenarioWorkload) exec(ctx context.Context, db Conn, i int) error {
+	w.mu.Lock()
+	args := make([]any, len(w.gens[i]))
+	for j, gen := range w.gens[i] {
+		args[j] = gen()
+	}
+	w.mu.Unlock()
+
+	return runStatement(ctx, db, w.s.Steps[i].SQL, args, &w.rowBytes)
+}
+
+// returnsRows reports whether a statement produces a result set that has
+// to be read.
+func returnsRows(query string) bool {
+	fields := strings.Fields(strings.ToUpper(query))
+	if len(fields) == 0 {
+		return false
+	}
+	switch fields[0] {
+	case "SELECT", "WITH", "VALUES", "PRAGMA", "EXPLAIN":
+		return true
+	}
+	return strings.Contains(strings.ToUpper(query), "RETURNING")
+}
+
+// runConcurrently calls fn n times spread over workers goroutines and
+// returns the first error.
+func runConcurrently(n, workers int, fn func() error) error {
+	if workers == 1 {
+		for range n {
+			if err := fn(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		next     int
+		firstErr error
+	)
+	for range min(workers, n) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				mu.Lock()
+				if next == n || firstErr != nil {
+					mu.Unlock()
+					return
+				}
+				next++
+				mu.Unlock()
+
+				if err := fn(); err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					mu.Unlock()
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return firstErr
+}
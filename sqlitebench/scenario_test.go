@@ -0,0 +1,48 @@
+package sqlitebench
+
+import (
+	"context"
+	"testing"
+)
+
+func TestScenario(t *testing.T) {
+	s := &Scenario{
+		Name:  "kv",
+		Setup: []string{"CREATE TABLE kv (k INTEGER, v BLOB)"},
+		Steps: []Step{
+			{SQL: "INSERT INTO kv (k, v) VALUES (?, ?)", Params: []string{"seq", "blob"}, Loop: 4},
+			{SQL: "SELECT v FROM kv WHERE k = ?", Params: []string{"int(1, 4)"}, Loop: 6, Concurrency: 3},
+		},
+		Teardown: []string{"DROP TABLE kv"},
+	}
+	if err := s.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewRunner()
+	r.Drivers, r.Sizes, r.Ops = []string{"modernc"}, []int{16}, 5
+	r.Add(s.Workload())
+	results, err := r.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Operation != "kv" || len(results[0].Samples) != 5 {
+		t.Fatalf("results = %+v, want one kv result with 5 samples", results)
+	}
+	if results[0].BytesRead == 0 {
+		t.Error("BytesRead = 0, want the blobs the steps read counted")
+	}
+}
+
+func TestScenarioValidate(t *testing.T) {
+	for _, s := range []Scenario{
+		{Steps: []Step{{SQL: "SELECT 1"}}},
+		{Name: "empty"},
+		{Name: "nosql", Steps: []Step{{Params: []string{"seq"}}}},
+		{Name: "badgen", Steps: []Step{{SQL: "SELECT ?", Params: []string{"nope"}}}},
+	} {
+		if err := s.Validate(); err == nil {
+			t.Errorf("Validate(%+v) succeeded, want an error", s)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package sqlitebench
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Schema runs the generic insert, read and update workloads against a
+// user's own table instead of the built-in blob table, so results reflect
+// real row shapes. The table must have a rowid, which reads and updates
+// select rows by.
+type Schema struct {
+	// SQL creates the schema; it may hold several statements.
+	SQL string `yaml:"sql" toml:"sql" json:"sql,omitempty"`
+	// Table is the table the workloads insert into, read and update.
+	Table string `yaml:"table" toml:"table" json:"table"`
+	// Columns maps each column to fill to a generator expression, e.g.
+	// {"email": "email", "customer": "zipf(500)"}. See ParseGenerator.
+	Columns map[string]string `yaml:"columns" toml:"columns" json:"columns"`
+}
+
+// Validate reports the first problem with the schema's definition.
+func (s *Schema) Validate() error {
+	if strings.TrimSpace(s.SQL) == "" {
+		return errors.New("schema has no sql")
+	}
+	if s.Table == "" {
+		return errors.New("schema has no table")
+	}
+	if len(s.Columns) == 0 {
+		return fmt.Errorf("schema gives no columns for %s", s.Table)
+	}
+	for col, expr := range s.Columns {
+		if _, err := ParseGenerator(expr, nil, nil); err != nil {
+			return fmt.Errorf("column %s: %w", col, err)
+		}
+	}
+	return nil
+}
+
+// Workloads returns the schema's insert, read and update workloads, named
+// after the table, e.g. "orders.insert". Call Validate first.
+func (s *Schema) Workloads() []Workload {
+	return []Workload{
+		&schemaWorkload{s: s, op: "insert", desc: "insert one generated row into " + s.Table},
+		&schemaWorkload{s: s, op: "read", desc: "select one " + s.Table + " row by rowid", populate: true},
+		&schemaWorkload{s: s, op: "update", desc: "update one " + s.Table + " row by rowid with generated values", populate: true},
+	}
+}
+
+// columns returns the schema's columns in a fixed order, so generators
+// draw from the seeded source in the same order on every run.
+func (s *Schema) columns() []string {
+	cols := make([]string, 0, len(s.Columns))
+	for col := range s.Columns {
+		cols = append(cols, col)
+	}
+	sort.Strings(cols)
+	return cols
+}
+
+type schemaWorkload struct {
+	rowBytes
+
+	s        *Schema
+	op       string
+	desc     string
+	populate bool
+
+	query string
+	gens  []Generator
+	rng   *rand.Rand
+	rows  int
+	// mu guards the generators and rng, which concurrent operations share.
+	mu sync.Mutex
+}
+
+func (w *schemaWorkload) Name() string        { return w.s.Table + "." + w.op }
+func (w *schemaWorkload) Description() string { return w.desc }
+
+func (w *schemaWorkload) Setup(ctx context.Context, db Conn, p Params) error {
+	w.reset()
+	w.rng = rand.New(rand.NewPCG(uint64(p.Seed), uint64(p.DataSize)))
+	w.rows = p.Rows
+	cols := w.s.columns()
+	w.gens = w.gens[:0]
+	for _, col := range cols {
+		gen, err := ParseGenerator(w.s.Columns[col], w.rng, p.Payloads)
+		if err != nil {
+			return fmt.Errorf("column %s: %w", col, err)
+		}
+		w.gens = append(w.gens, gen)
+	}
+
+	if err := db.Exec(ctx, w.s.SQL); err != nil {
+		return fmt.Errorf("creating schema: %w", err)
+	}
+
+	insert := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		w.s.Table, strings.Join(cols, ", "), strings.TrimSuffix(strings.Repeat("?, ", len(cols)), ", "))
+	switch w.op {
+	case "insert":
+		w.query = insert
+	case "read":
+		w.query = fmt.Sprintf("SELECT * FROM %s WHERE rowid = ?", w.s.Table)
+	case "update":
+		w.query = fmt.Sprintf("UPDATE %s SET %s = ? WHERE rowid = ?", w.s.Table, strings.Join(cols, " = ?, "))
+	}
+
+	if !w.populate {
+		return nil
+	}
+	for done := 0; done < p.Rows; {
+		tx, err := db.Begin(ctx)
+		if err != nil {
+			return err
+		}
+		for end := min(done+populateBatch, p.Rows); done < end; done++ {
+			if err := tx.Exec(ctx, insert, w.values()...); err != nil {
+				tx.Rollback()
+				return fmt.Errorf("populating %s: %w", w.s.Table, err)
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// values generates one value per column.
+func (w *schemaWorkload) values() []any {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	vals := make([]any, len(w.gens))
+	for i, gen := range w.gens {
+		vals[i] = gen()
+	}
+	return vals
+}
+
+// rowid picks an existing row at random.
+func (w *schemaWorkload) rowid() int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return 1 + w.rng.Int64N(int64(w.rows))
+}
+
+func (w *schemaWorkload) Run(ctx context.Context, db Conn, n int) error {
+	switch w.op {
+	case "read":
+		return runStatement(ctx, db, w.query, []any{w.rowid()}, &w.rowBytes)
+	case "update":
+		return db.Exec(ctx, w.query, append(w.values(), w.rowid())...)
+	default:
+		return db.Exec(ctx, w.query, w.values()...)
+	}
+}
+
+func (*schemaWorkload) Teardown(db Conn) error { return nil }
@@ -0,0 +1,33 @@
+package sqlitebench
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSchemaWorkloads(t *testing.T) {
+	s := &Schema{
+		SQL:     "CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, email TEXT); CREATE INDEX users_email ON users (email);",
+		Table:   "users",
+		Columns: map[string]string{"name": "name", "email": "email"},
+	}
+	if err := s.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewRunner()
+	r.Drivers, r.Sizes, r.Ops, r.Rows = []string{"mattn", "modernc"}, []int{0}, 10, 50
+	r.Add(s.Workloads()...)
+	results, err := r.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 6 {
+		t.Fatalf("got %d results, want insert, read and update for both drivers", len(results))
+	}
+	for _, res := range results {
+		if len(res.Samples) != 10 {
+			t.Errorf("%s/%s has %d samples, want 10", res.Driver, res.Operation, len(res.Samples))
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package sqlitebench
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+)
+
+// DriverInfo describes a driver as found by its pre-flight self-check.
+type DriverInfo struct {
+	SQLiteVersion string `json:"sqlite_version"`
+	// SQLiteBuild is where the driver's SQLite comes from; see
+	// DriverSQLite.
+	SQLiteBuild string `json:"sqlite_build,omitempty"`
+	Cgo         bool   `json:"cgo"`
+	// Capabilities lists optional SQLite features the driver's build
+	// supports, e.g. "json1" or "fts5", "load_extension" if its
+	// connections can load extensions, and "serialize" and "deserialize"
+	// for the Serializer methods they support.
+	Capabilities []string `json:"capabilities,omitempty"`
+}
+
+// optionalCapabilities are probed by CheckDriver; each statement succeeds
+// only if the feature is compiled in.
+var optionalCapabilities = []struct {
+	name string
+	stmt string
+}{
+	{"json1", "SELECT json('{}')"},
+	{"fts5", "CREATE VIRTUAL TABLE temp.fts5_probe USING fts5(body)"},
+	{"rtree", "CREATE VIRTUAL TABLE temp.rtree_probe USING rtree(id, minx, maxx)"},
+	{"math", "SELECT sqrt(4)"},
+	{"attach", "ATTACH ':memory:' AS attach_probe"},
+}
+
+// CheckDriver runs a quick functional check of a driver: it creates a
+// table, writes a blob and reads it back byte for byte, and verifies that
+// a rolled back insert leaves no row. A driver failing any of these would
+// produce meaningless benchmark numbers.
+func CheckDriver(ctx context.Context, driverName string) (DriverInfo, error) {
+	info := DriverInfo{Cgo: DriverCgo[driverName], SQLiteBuild: DriverSQLite[driverName]}
+
+	db, err := Drivers[driverName].Open(ctx, ":memory:")
+	if err != nil {
+		return info, err
+	}
+	defer db.Close()
+
+	if err := QueryRow(ctx, db, "SELECT sqlite_version()", &info.SQLiteVersion); err != nil {
+		return info, fmt.Errorf("querying version: %w", err)
+	}
+
+	if err := db.Exec(ctx, "CREATE TABLE check_blob (data BLOB)"); err != nil {
+		return info, fmt.Errorf("creating table: %w", err)
+	}
+
+	want := NewPayloadPool(1, 0, 4096).Next()
+	if err := db.Exec(ctx, "INSERT INTO check_blob (data) VALUES (?)", want); err != nil {
+		return info, fmt.Errorf("inserting blob: %w", err)
+	}
+	var got []byte
+	if err := QueryRow(ctx, db, "SELECT data FROM check_blob", &got); err != nil {
+		return info, fmt.Errorf("reading blob: %w", err)
+	}
+	if !bytes.Equal(got, want) {
+		return info, fmt.Errorf("blob read back as %d bytes differs from the %d bytes written", len(got), len(want))
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return info, fmt.Errorf("beginning transaction: %w", err)
+	}
+	if err := tx.Exec(ctx, "INSERT INTO check_blob (data) VALUES (?)", want); err != nil {
+		tx.Rollback()
+		return info, fmt.Errorf("inserting in transaction: %w", err)
+	}
+	if err := tx.Rollback(); err != nil {
+		return info, fmt.Errorf("rolling back: %w", err)
+	}
+	var n int
+	if err := QueryRow(ctx, db, "SELECT count(*) FROM check_blob", &n); err != nil {
+		return info, fmt.Errorf("counting rows: %w", err)
+	}
+	if n != 1 {
+		return info, fmt.Errorf("found %d rows after rollback, want 1", n)
+	}
+
+	for _, c := range optionalCapabilities {
+		if err := db.Exec(ctx, c.stmt); err == nil {
+			info.Capabilities = append(info.Capabilities, c.name)
+		}
+	}
+	if canLoadExtensions(ctx, db) {
+		info.Capabilities = append(info.Capabilities, "load_extension")
+	}
+	if s, ok := db.(Serializer); ok {
+		if data, err := s.Serialize(ctx); err == nil {
+			info.Capabilities = append(info.Capabilities, "serialize")
+			if s.Deserialize(ctx, data) == nil {
+				info.Capabilities = append(info.Capabilities, "deserialize")
+			}
+		}
+	}
+
+	return info, nil
+}
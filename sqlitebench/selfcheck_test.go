@@ -0,0 +1,19 @@
+package sqlitebench
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCheckDriver(t *testing.T) {
+	for name := range Drivers {
+		info, err := CheckDriver(context.Background(), name)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if info.SQLiteVersion == "" || info.Cgo != DriverCgo[name] {
+			t.Errorf("%s: info = %+v", name, info)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package sqlitebench
+
+import (
+	"context"
+	"fmt"
+)
+
+func init() {
+	Register(Benchmark{Category: CategoryMaintenance, Requires: []string{"serialize"}, New: func() Workload { return &Serialize{} }})
+	Register(Benchmark{Category: CategoryMaintenance, Requires: []string{"serialize", "deserialize"}, New: func() Workload { return &Deserialize{} }})
+}
+
+// serializer returns db's Serializer, or an error if it has none.
+func serializer(db Conn) (Serializer, error) {
+	s, ok := unwrap(db).(Serializer)
+	if !ok {
+		return nil, errNoSerialize
+	}
+	return s, nil
+}
+
+// Serialize snapshots a database of Params.Rows rows to a byte slice per
+// operation, as done to cache a database or save a test fixture.
+type Serialize struct {
+	s Serializer
+}
+
+func (*Serialize) Name() string        { return "serialize" }
+func (*Serialize) Description() string { return "snapshot the database to bytes per operation" }
+
+func (w *Serialize) Setup(ctx context.Context, db Conn, p Params) error {
+	var err error
+	if w.s, err = serializer(db); err != nil {
+		return err
+	}
+	return Populate(ctx, db, p.Rows, p.Payloads.Next)
+}
+
+func (w *Serialize) Run(ctx context.Context, db Conn, n int) error {
+	data, err := w.s.Serialize(ctx)
+	if err == nil && len(data) == 0 {
+		err = fmt.Errorf("empty snapshot")
+	}
+	return err
+}
+
+func (*Serialize) Teardown(db Conn) error { return nil }
+
+// Deserialize replaces the database with a snapshot of Params.Rows rows,
+// taken once in Setup, per operation, as done to load a cached database or
+// test fixture.
+type Deserialize struct {
+	s        Serializer
+	snapshot []byte
+}
+
+func (*Deserialize) Name() string        { return "deserialize" }
+func (*Deserialize) Description() string { return "load the database from a snapshot per operation" }
+
+func (w *Deserialize) Setup(ctx context.Context, db Conn, p Params) error {
+	var err error
+	if w.s, err = serializer(db); err != nil {
+		return err
+	}
+	if err := Populate(ctx, db, p.Rows, p.Payloads.Next); err != nil {
+		return err
+	}
+	w.snapshot, err = w.s.Serialize(ctx)
+	return err
+}
+
+func (w *Deserialize) Run(ctx context.Context, db Conn, n int) error {
+	return w.s.Deserialize(ctx, w.snapshot)
+}
+
+func (w *Deserialize) Teardown(db Conn) error {
+	w.snapshot = nil
+	return nil
+}
@@ -0,0 +1,51 @@
+package sqlitebench
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestSerializeRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	for _, driver := range []string{"modernc", "mattn"} {
+		t.Run(driver, func(t *testing.T) {
+			info, err := CheckDriver(ctx, driver)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Contains(info.Capabilities, "deserialize") {
+				t.Skipf("capabilities = %v, want deserialize", info.Capabilities)
+			}
+
+			db, err := openDB(ctx, Drivers[driver], ":memory:", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			payloads := NewPayloadPool(1, 0, 1024)
+			if err := Populate(ctx, db, 10, payloads.Next); err != nil {
+				t.Fatal(err)
+			}
+			s := db.(Serializer)
+			snapshot, err := s.Serialize(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := Populate(ctx, db, 5, payloads.Next); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := s.Deserialize(ctx, snapshot); err != nil {
+				t.Fatal(err)
+			}
+			var n int
+			if err := QueryRow(ctx, db, "SELECT count(*) FROM test", &n); err != nil {
+				t.Fatal(err)
+			}
+			if n != 10 {
+				t.Errorf("%d rows after loading the snapshot, want 10", n)
+			}
+		})
+	}
+}
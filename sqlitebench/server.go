@@ -0,0 +1,212 @@
+package sqlitebench
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+// serverDialects maps the client/server databases NewServerBackend knows
+// to how the workloads' SQLite statements are run on them.
+var serverDialects = map[string]dialect{
+	"postgres": {
+		driver: "pgx",
+		types:  strings.NewReplacer("INTEGER PRIMARY KEY", "SERIAL PRIMARY KEY", "BLOB", "BYTEA", "DOUBLE", "DOUBLE PRECISION"),
+		reset: execAll(
+			"DROP SCHEMA IF EXISTS "+serverNamespace+" CASCADE",
+			"CREATE SCHEMA "+serverNamespace,
+			"SET search_path TO "+serverNamespace,
+		),
+		numbered: true,
+	},
+	"mysql": {
+		driver: "mysql",
+		types:  strings.NewReplacer("INTEGER PRIMARY KEY", "INTEGER PRIMARY KEY AUTO_INCREMENT", "BLOB", "LONGBLOB"),
+		reset: execAll(
+			"DROP DATABASE IF EXISTS "+serverNamespace,
+			"CREATE DATABASE "+serverNamespace,
+			"USE "+serverNamespace,
+		),
+	},
+	// libsql is a sqld server, e.g. "http://127.0.0.1:8080", spoken to
+	// over its remote protocol. It runs SQLite, so statements are left as
+	// they are; as it serves one database, every table in it is dropped
+	// instead of a schema.
+	"libsql": {
+		driver: "libsql",
+		reset:  dropTables,
+	},
+}
+
+// serverNamespace is the schema or database a server backend recreates
+// for every cell, so cells start empty like the SQLite ones. The DSN's user
+// must be allowed to drop and create it.
+const serverNamespace = "sqlitebench"
+
+// ServerKinds returns the kinds NewServerBackend accepts in sorted order.
+func ServerKinds() []string {
+	kinds := make([]string, 0, len(serverDialects))
+	for kind := range serverDialects {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// dialect adapts the workloads' statements, written for SQLite, to a
+// server.
+type dialect struct {
+	// driver is the database/sql driver name.
+	driver string
+	// types, if set, renames column types in CREATE TABLE statements.
+	types *strings.Replacer
+	// reset empties the database for a new cell, e.g. by recreating
+	// serverNamespace and making it the default.
+	reset func(ctx context.Context, db *sql.DB) error
+	// numbered is set for servers taking $1, $2, ... placeholders
+	// instead of ?.
+	numbered bool
+	// open, if set, connects to the database at dsn and resets it in
+	// place of ServerBackend.Open, for servers reached otherwise than
+	// through sql.Open.
+	open func(ctx context.Context, d dialect, dsn string) (Conn, error)
+}
+
+// execAll returns a reset running the statements in order.
+func execAll(stmts ...string) func(ctx context.Context, db *sql.DB) error {
+	return func(ctx context.Context, db *sql.DB) error {
+		for _, stmt := range stmts {
+			if _, err := db.ExecContext(ctx, stmt); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// dropTables is a reset dropping every table of a SQLite database.
+func dropTables(ctx context.Context, db *sql.DB) error {
+	rows, err := db.QueryContext(ctx, "SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%'")
+	if err != nil {
+		return err
+	}
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return err
+		}
+		tables = append(tables, name)
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+	for _, name := range tables {
+		if _, err := db.ExecContext(ctx, `DROP TABLE "`+strings.ReplaceAll(name, `"`, `""`)+`"`); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var createTable = regexp.MustCompile(`(?i)^\s*CREATE\s+TABLE\b`)
+
+func (d dialect) rewrite(query string) string {
+	if d.types != nil && createTable.MatchString(query) {
+		query = d.types.Replace(query)
+	}
+	if d.numbered && strings.Contains(query, "?") {
+		var b strings.Builder
+		n := 0
+		for _, r := range query {
+			if r == '?' {
+				n++
+				b.WriteString("$" + strconv.Itoa(n))
+				continue
+			}
+			b.WriteRune(r)
+		}
+		query = b.String()
+	}
+	return query
+}
+
+// ServerBackend runs the workloads on an external PostgreSQL, MySQL or
+// libsql (sqld) database, as a networked baseline for the SQLite drivers.
+// Scenario and schema workloads run only if their SQL suits the server.
+type ServerBackend struct {
+	kind string
+	dsn  string
+}
+
+// NewServerBackend returns a backend for the database at dsn, of a kind
+// from ServerKinds, e.g. NewServerBackend("postgres",
+// "postgres://bench@localhost/bench").
+func NewServerBackend(kind, dsn string) (*ServerBackend, error) {
+	if _, ok := serverDialects[kind]; !ok {
+		return nil, fmt.Errorf("unknown server kind %q (want one of %s; libsql-replica needs a build with -tags libsql_replica,libsqlite3)", kind, strings.Join(ServerKinds(), ", "))
+	}
+	return &ServerBackend{kind: kind, dsn: dsn}, nil
+}
+
+// Open connects to the backend's DSN, ignoring the one given, and
+// recreates the benchmark namespace, dropping whatever an earlier cell
+// left there. Like SQLBackend it uses a single connection.
+func (b *ServerBackend) Open(ctx context.Context, _ string) (Conn, error) {
+	d := serverDialects[b.kind]
+	if d.open != nil {
+		return d.open(ctx, d, b.dsn)
+	}
+	db, err := sql.Open(d.driver, b.dsn)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+	if err := d.reset(ctx, db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("emptying database: %w", err)
+	}
+	return dialectConn{sqlConn{db}, d}, nil
+}
+
+func (b *ServerBackend) String() string {
+	return b.kind + " server"
+}
+
+type dialectConn struct {
+	sqlConn
+	d dialect
+}
+
+func (c dialectConn) Exec(ctx context.Context, query string, args ...any) error {
+	return c.sqlConn.Exec(ctx, c.d.rewrite(query), args...)
+}
+
+func (c dialectConn) Query(ctx context.Context, query string, args ...any) (Rows, error) {
+	return c.sqlConn.Query(ctx, c.d.rewrite(query), args...)
+}
+
+func (c dialectConn) Begin(ctx context.Context) (Tx, error) {
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return dialectTx{sqlTx{tx}, c.d}, nil
+}
+
+type dialectTx struct {
+	sqlTx
+	d dialect
+}
+
+func (t dialectTx) Exec(ctx context.Context, query string, args ...any) error {
+	return t.sqlTx.Exec(ctx, t.d.rewrite(query), args...)
+}
@@ -0,0 +1,72 @@
+package sqlitebench
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"testing"
+)
+
+func TestDialectRewrite(t *testing.T) {
+	for _, tt := range []struct {
+		kind, query, want string
+	}{
+		{"postgres", "CREATE TABLE facts (id INTEGER PRIMARY KEY, value DOUBLE, data BLOB)", "CREATE TABLE facts (id SERIAL PRIMARY KEY, value DOUBLE PRECISION, data BYTEA)"},
+		{"postgres", "INSERT INTO kv (id, data) VALUES (?, ?)", "INSERT INTO kv (id, data) VALUES ($1, $2)"},
+		{"postgres", "SELECT data FROM test LIMIT 1", "SELECT data FROM test LIMIT 1"},
+		{"mysql", testTable, "CREATE TABLE test (id INTEGER PRIMARY KEY AUTO_INCREMENT, data LONGBLOB, label TEXT)"},
+		{"mysql", "INSERT INTO kv (id, data) VALUES (?, ?)", "INSERT INTO kv (id, data) VALUES (?, ?)"},
+	} {
+		if got := serverDialects[tt.kind].rewrite(tt.query); got != tt.want {
+			t.Errorf("%s rewrite(%q) = %q, want %q", tt.kind, tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestRunnerServerCells(t *testing.T) {
+	b, err := NewServerBackend("postgres", "postgres://localhost/bench")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewServerBackend("oracle", ""); err == nil {
+		t.Error("expected an error for an unknown server kind")
+	}
+
+	r := NewRunner()
+	r.Drivers, r.Sizes = []string{"modernc"}, []int{64}
+	r.JournalModes = []string{"delete", "wal"}
+	r.Servers = map[string]Backend{"postgres": b}
+	r.Add(&Write{})
+
+	var names []string
+	for _, c := range r.Cells() {
+		names = append(names, c.String())
+	}
+	want := []string{"modernc/write,journal_mode=delete/64", "modernc/write,journal_mode=wal/64", "postgres/write/64"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("cells = %v, want %v", names, want)
+	}
+}
+
+func TestDropTables(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	for _, stmt := range []string{"CREATE TABLE test (data BLOB)", `CREATE TABLE "odd ""name" (id INTEGER PRIMARY KEY)`} {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := dropTables(ctx, db); err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	if err := db.QueryRowContext(ctx, "SELECT count(*) FROM sqlite_master WHERE type = 'table'").Scan(&n); err != nil || n != 0 {
+		t.Errorf("%d tables left, %v; want none", n, err)
+	}
+}
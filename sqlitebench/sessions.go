@@ -0,0 +1,173 @@
+package sqlitebench
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+func init() {
+	Register(Benchmark{Category: CategoryConcurrency, New: func() Workload { return &Sessions{} }})
+}
+
+const (
+	// sessionUpsertEvery makes every sessionUpsertEvery-th operation an
+	// upsert and sessionSweepEvery-th a sweep; the others are lookups.
+	sessionUpsertEvery = 5
+	sessionSweepEvery  = 100
+	// sessionKeys is the number of session ids per row of Params.Rows
+	// picked from, so some lookups find no session.
+	sessionKeys = 2
+)
+
+// Sessions models a web session store: operations look sessions up by
+// their random id, every fifth upserts one, extending its expiry, and every
+// hundredth starts a sweep deleting the expired ones in the background, as
+// a cleanup task would. Time is counted in operations and sessions live
+// for Params.Rows of them. Setup stores Params.Rows sessions expiring over
+// the first Params.Rows operations, so until then each sweep deletes about
+// a hundred, and about twenty after. Larger payloads make sweeps longer.
+//
+// The sweep holds its connection, so the connection's next operation waits
+// for it. Lookups are timed by phase, "lookup" and "lookup.sweep" for those
+// that overlapped a sweep; comparing the two shows how much a driver's
+// reads suffer while it deletes, on the sweeping connection and, with a
+// Concurrency above 1, on the others.
+type Sessions struct {
+	phaseTimes
+
+	payloads *PayloadPool
+	ttl      int
+	// mu guards rng and err, as concurrent cells run on several
+	// connections.
+	mu  sync.Mutex
+	rng *rand.Rand
+	// err is the first error of a sweep, returned by the next one or by
+	// Teardown.
+	err error
+	// sweeping counts the sweeps running and swept those done.
+	sweeping atomic.Int32
+	swept    atomic.Int64
+	wg       sync.WaitGroup
+}
+
+func (*Sessions) Name() string { return "sessions" }
+func (*Sessions) Description() string {
+	return "look up and upsert sessions by id while sweeping expired ones"
+}
+
+// sessionID returns the id of session i.
+func sessionID(i int) string {
+	h := fnv.New128a()
+	fmt.Fprint(h, i)
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+// id picks a session id.
+func (w *Sessions) id() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return sessionID(w.rng.IntN(w.ttl * sessionKeys))
+}
+
+func (w *Sessions) Setup(ctx context.Context, db Conn, p Params) error {
+	w.payloads = p.Payloads
+	w.ttl = max(p.Rows, 1)
+	w.rng = rand.New(rand.NewPCG(uint64(p.Seed), uint64(p.Rows)))
+	w.err = nil
+	for _, stmt := range []string{
+		"CREATE TABLE sessions (id TEXT PRIMARY KEY, data BLOB NOT NULL, expires INTEGER NOT NULL)",
+		"CREATE INDEX sessions_expires ON sessions (expires)",
+	} {
+		if err := db.Exec(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return populate(ctx, db, p.Rows, func(tx Tx, i int) error {
+		return tx.Exec(ctx, "INSERT INTO sessions (id, data, expires) VALUES (?, ?, ?)", sessionID(i*sessionKeys), w.payloads.Next(), i)
+	})
+}
+
+func (w *Sessions) Run(ctx context.Context, db Conn, n int) error {
+	switch {
+	case n%sessionSweepEvery == sessionSweepEvery-1:
+		return w.sweep(ctx, db, n)
+	case n%sessionUpsertEvery == sessionUpsertEvery-1:
+		return db.Exec(ctx, "INSERT INTO sessions (id, data, expires) VALUES (?, ?, ?) ON CONFLICT (id) DO UPDATE SET data = excluded.data, expires = excluded.expires",
+			w.id(), w.payloads.Next(), n+w.ttl)
+	}
+
+	id := w.id()
+	sweeping, swept := w.sweeping.Load() > 0, w.swept.Load()
+	start := time.Now()
+	if err := w.lookup(ctx, db, id, n); err != nil {
+		return err
+	}
+	d := time.Since(start)
+	phase := "lookup"
+	if sweeping || w.swept.Load() != swept || w.sweeping.Load() > 0 {
+		phase = "lookup.sweep"
+	}
+	w.record(phase, d)
+	return nil
+}
+
+// sweep starts deleting the sessions expired by n on db and returns the
+// error of an earlier sweep, if one failed.
+func (w *Sessions) sweep(ctx context.Context, db Conn, n int) error {
+	w.mu.Lock()
+	err := w.err
+	w.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		// The sweep counts as running once its goroutine does, which may
+		// be after a few more operations.
+		w.sweeping.Add(1)
+		err := db.Exec(ctx, "DELETE FROM sessions WHERE expires <= ?", n)
+		w.swept.Add(1)
+		w.sweeping.Add(-1)
+		if err != nil {
+			w.mu.Lock()
+			w.err = cmp.Or(w.err, err)
+			w.mu.Unlock()
+		}
+	}()
+	return nil
+}
+
+// lookup reads the session id unless it expired by n. A missing session
+// is not an error, as clients present expired ids.
+func (w *Sessions) lookup(ctx context.Context, db Conn, id string, n int) error {
+	rows, err := db.Query(ctx, "SELECT data FROM sessions WHERE id = ? AND expires > ?", id, n)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	if rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	return rows.Close()
+}
+
+// Teardown waits for the sweeps running.
+func (w *Sessions) Teardown(db Conn) error {
+	w.wg.Wait()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
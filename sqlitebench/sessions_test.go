@@ -0,0 +1,34 @@
+package sqlitebench
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSessions(t *testing.T) {
+	ctx := context.Background()
+	w := &Sessions{}
+	db := setupWorkload(t, w, Params{Rows: 1000})
+	runOps(t, w, db, 500)
+	if err := w.Teardown(db); err != nil {
+		t.Fatal(err)
+	}
+
+	// The sweeps deleted the sessions stored by Setup that expired by
+	// operation 499, the last sweep's.
+	var expired int
+	if err := QueryRow(ctx, db, "SELECT count(*) FROM sessions WHERE expires <= 499", &expired); err != nil {
+		t.Fatal(err)
+	}
+	if w.swept.Load() != 5 || expired != 0 {
+		t.Errorf("%d sweeps left %d expired sessions, want 5 leaving none", w.swept.Load(), expired)
+	}
+	// Every fifth operation is an upsert, or a sweep.
+	phases := w.Phases()
+	if lookups := len(phases["lookup"]) + len(phases["lookup.sweep"]); lookups != 400 {
+		t.Errorf("timed %d lookups, want 400", lookups)
+	}
+	if w.Phases() != nil {
+		t.Error("Phases did not clear the times")
+	}
+}
@@ -0,0 +1,137 @@
+package sqlitebench
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"time"
+)
+
+// SharedFile is a workload run by several OS processes at once against one
+// database file, to measure cross-process locking and journal behaviour
+// that goroutines sharing a connection pool in one process never hit. The
+// caller prepares the file once, then starts one process per worker, each
+// calling Work.
+type SharedFile struct {
+	Path   string `json:"path"`
+	Driver string `json:"driver"`
+	// JournalMode is set on the file when it is prepared, e.g. "wal" or
+	// "delete".
+	JournalMode string `json:"journal_mode"`
+	// BusyTimeout is how long a worker waits for a lock held by another
+	// process before the operation fails with SQLITE_BUSY.
+	BusyTimeout time.Duration `json:"busy_timeout_ns"`
+	// Rows are inserted when the file is prepared, for reads to select.
+	Rows     int `json:"rows"`
+	DataSize int `json:"data_size"`
+	// Ops is the number of operations each worker performs.
+	Ops int `json:"ops"`
+	// WriteRatio is the fraction of operations that insert rather than
+	// read, from 0 to 1.
+	WriteRatio float64 `json:"write_ratio"`
+	Seed       int64   `json:"seed"`
+}
+
+// WorkerResult is what one worker process measured.
+type WorkerResult struct {
+	Worker  int             `json:"worker"`
+	Samples []time.Duration `json:"samples_ns"`
+	Writes  int             `json:"writes"`
+	// Errors counts operations that failed, typically with SQLITE_BUSY
+	// after waiting BusyTimeout; they have no sample.
+	Errors int `json:"errors"`
+}
+
+// open opens the shared file with the busy timeout applied.
+func (f *SharedFile) open(ctx context.Context) (Conn, error) {
+	backend, ok := Drivers[f.Driver]
+	if !ok {
+		return nil, fmt.Errorf("unknown driver %q", f.Driver)
+	}
+	db, err := backend.Open(ctx, "file:"+f.Path)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Exec(ctx, fmt.Sprintf("PRAGMA busy_timeout = %d", f.BusyTimeout.Milliseconds())); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("setting busy timeout: %w", err)
+	}
+	return db, nil
+}
+
+// removeDatabase removes a database file with its journal, WAL and shared
+// memory files, if they exist.
+func removeDatabase(path string) error {
+	for _, suffix := range []string{"", "-wal", "-shm", "-journal"} {
+		if err := os.Remove(path + suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Prepare creates the database file afresh, sets its journal mode and
+// inserts Rows rows.
+func (f *SharedFile) Prepare(ctx context.Context) error {
+	if err := removeDatabase(f.Path); err != nil {
+		return err
+	}
+
+	db, err := f.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if f.JournalMode != "" {
+		if err := QueryRow(ctx, db, "PRAGMA journal_mode = "+f.JournalMode, new(string)); err != nil {
+			return fmt.Errorf("setting journal mode: %w", err)
+		}
+	}
+	if err := db.Exec(ctx, testTable); err != nil {
+		return fmt.Errorf("creating table: %w", err)
+	}
+	if err := Populate(ctx, db, f.Rows, NewPayloadPool(f.Seed, 0, f.DataSize).Next); err != nil {
+		return fmt.Errorf("inserting data: %w", err)
+	}
+	return db.Close()
+}
+
+// Work runs one worker's operations on the prepared file. Each worker
+// draws its own sequence of reads and writes from the seed and its number.
+func (f *SharedFile) Work(ctx context.Context, worker int) (WorkerResult, error) {
+	result := WorkerResult{Worker: worker, Samples: make([]time.Duration, 0, f.Ops)}
+
+	db, err := f.open(ctx)
+	if err != nil {
+		return result, err
+	}
+	defer db.Close()
+
+	rng := rand.New(rand.NewPCG(uint64(f.Seed), uint64(worker)))
+	payloads := NewPayloadPool(f.Seed+int64(worker), 0, f.DataSize)
+	for i := range f.Ops {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		write := rng.Float64() < f.WriteRatio
+		start := time.Now()
+		if write {
+			err = db.Exec(ctx, "INSERT INTO test (data, label) VALUES (?, ?)", payloads.Next(), RowLabel(i))
+		} else {
+			err = runStatement(ctx, db, "SELECT data, label FROM test WHERE id = ?", []any{1 + rng.Int64N(int64(max(f.Rows, 1)))}, nil)
+		}
+		if err != nil {
+			result.Errors++
+			continue
+		}
+		result.Samples = append(result.Samples, time.Since(start))
+		if write {
+			result.Writes++
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,49 @@
+package sqlitebench
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSharedFile(t *testing.T) {
+	ctx := context.Background()
+	f := &SharedFile{
+		Path:        filepath.Join(t.TempDir(), "shared.db"),
+		Driver:      "modernc",
+		JournalMode: "wal",
+		BusyTimeout: 5 * time.Second,
+		Rows:        20,
+		DataSize:    64,
+		Ops:         50,
+		WriteRatio:  0.5,
+		Seed:        1,
+	}
+	if err := f.Prepare(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// Separate connections lock the file the way separate processes do.
+	results := make([]WorkerResult, 2)
+	errs := make([]error, 2)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = f.Work(ctx, i)
+		}()
+	}
+	wg.Wait()
+
+	for i, r := range results {
+		if errs[i] != nil {
+			t.Fatalf("worker %d: %v", i, errs[i])
+		}
+		if len(r.Samples)+r.Errors != 50 || r.Writes == 0 || r.Writes == len(r.Samples) {
+			t.Errorf("worker %d = %d samples, %d writes, %d errors; want a mix of 50 operations", i, len(r.Samples), r.Writes, r.Errors)
+		}
+	}
+}
@@ -0,0 +1,561 @@
+// Package sqlitebench measures Go SQLite drivers against each other.
+//
+// A Runner expands its drivers, payload sizes, added workloads and any
+// further dimensions, such as journal modes, into a matrix of cells and
+// measures each one on a fresh database:
+//
+//	runner := sqlitebench.NewRunner()
+//	runner.Add(&sqlitebench.Write{}, &sqlitebench.Read{})
+//	results, err := runner.Run(ctx)
+package sqlitebench
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"regexp"
+	"slices"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	_ "modernc.org/sqlite"
+)
+
+// Drivers maps the names used in results to the backends that open them.
+var Drivers = map[string]Backend{
+	"modernc": SQLBackend{"sqlite"},
+	"mattn":   SQLBackend{"sqlite3"},
+}
+
+// DriverCgo records which drivers are implemented with cgo rather than in
+// pure Go.
+var DriverCgo = map[string]bool{
+	"modernc": false,
+	"mattn":   true,
+}
+
+// DriverPackages records the package each driver is imported from, for
+// building programs that use one driver alone.
+var DriverPackages = map[string]string{
+	"modernc": "modernc.org/sqlite",
+	"mattn":   "github.com/mattn/go-sqlite3",
+}
+
+// DriverSQLite records where each driver's SQLite comes from. Building
+// with -tags libsqlite3 makes mattn link the system's libsqlite3 instead
+// of its bundled copy, which pins it to another SQLite version.
+var DriverSQLite = map[string]string{
+	"modernc": "transpiled",
+	"mattn":   "bundled",
+}
+
+// DefaultSizes are the payload sizes in bytes measured unless a Runner is
+// given others.
+var DefaultSizes = []int{64, 256, 1024, 4096, 1024 * 1024}
+
+// Result is the measurement of one cell.
+type Result struct {
+	Driver    string `json:"driver"`
+	Operation string `json:"operation"`
+	// Category is the registered workload's category, if it has one.
+	Category string          `json:"category,omitempty"`
+	DataSize int             `json:"data_size"`
+	Duration time.Duration   `json:"duration_ns"`
+	Samples  []time.Duration `json:"samples_ns"`
+	Allocs   uint64          `json:"allocs"`
+	Bytes    uint64          `json:"alloc_bytes"`
+	Counters *PerfCounters   `json:"counters,omitempty"`
+	// IO counts the file operations of the timed loop, for drivers with a
+	// counting VFS when Runner.IOCounters is set.
+	IO *IOCounters `json:"io,omitempty"`
+	// BytesRead is the size of the values the timed loop read, for
+	// workloads implementing ReadCounter.
+	BytesRead uint64 `json:"bytes_read,omitempty"`
+	// Phases are the times of operations of the timed loop by the phase
+	// they ran in, for workloads implementing PhaseTimer.
+	Phases map[string][]time.Duration `json:"phases_ns,omitempty"`
+	// Growth are the sizes of the database sampled during the timed loop,
+	// for workloads implementing GrowthSampler. Merge keeps those of the
+	// first repetition, as each starts from a fresh database.
+	Growth []SizeSample `json:"growth,omitempty"`
+	// Errors counts the operations that failed with an expected error,
+	// if any did.
+	Errors *ErrorCounts `json:"errors,omitempty"`
+	// Setup is how long preparing the cell's database took, e.g.
+	// populating it for reads, summed over repetitions. It is not part of
+	// Duration.
+	Setup time.Duration `json:"setup_ns,omitempty"`
+	// Iterations is the number of operations measured, when Samples holds
+	// fewer: for cells with more than Runner.MaxSamples operations and
+	// results loaded from files that keep no samples. Zero means
+	// len(Samples).
+	Iterations int `json:"iterations,omitempty"`
+	// TimedOut is set when the cell hit its timeout; the samples cover
+	// only the operations completed before that.
+	TimedOut bool `json:"timed_out,omitempty"`
+	// Verified is set when every blob read was checked against the
+	// payloads written.
+	Verified bool `json:"verified,omitempty"`
+	// SQLiteVersion is the version of SQLite the driver ran, if the runner
+	// was told it.
+	SQLiteVersion string `json:"sqlite_version,omitempty"`
+}
+
+// Results are the results of a run, one per cell.
+type Results []Result
+
+// Merge adds the measurements of another repetition of the same cell.
+func (r *Result) Merge(other Result) {
+	if r.Iterations != 0 || other.Iterations != 0 {
+		r.Iterations = max(r.Iterations, len(r.Samples)) + max(other.Iterations, len(other.Samples))
+	}
+	r.Duration += other.Duration
+	r.Setup += other.Setup
+	r.Samples = append(r.Samples, other.Samples...)
+	r.Allocs += other.Allocs
+	r.Bytes += other.Bytes
+	r.BytesRead += other.BytesRead
+	for phase, times := range other.Phases {
+		if r.Phases == nil {
+			r.Phases = map[string][]time.Duration{}
+		}
+		r.Phases[phase] = append(r.Phases[phase], times...)
+	}
+	r.TimedOut = r.TimedOut || other.TimedOut
+	if r.Counters != nil && other.Counters != nil {
+		r.Counters.Instructions += other.Counters.Instructions
+		r.Counters.CacheMisses += other.Counters.CacheMisses
+		r.Counters.BranchMisses += other.Counters.BranchMisses
+	} else {
+		r.Counters = nil
+	}
+	if r.IO != nil && other.IO != nil {
+		r.IO.add(*other.IO)
+	} else {
+		r.IO = nil
+	}
+	if other.Errors != nil {
+		if r.Errors == nil {
+			r.Errors = &ErrorCounts{}
+		}
+		r.Errors.add(*other.Errors)
+	}
+}
+
+// Cell is one combination of the runner's matrix. The fields after
+// DataSize are zero for dimensions the runner does not vary.
+type Cell struct {
+	Driver   string
+	Workload string
+	DataSize int
+
+	JournalMode string
+	Synchronous string
+	PageSize    int
+	CacheSize   int
+	Concurrency int
+	Storage     string
+	Replication string
+}
+
+// Operation returns the name results of the cell are recorded under: the
+// workload, followed by the values of any dimensions beyond driver, size and
+// workload, e.g. "write,journal_mode=wal,concurrency=4".
+func (c Cell) Operation() string {
+	op := c.Workload
+	for _, d := range dimensions[baseDimensions:] {
+		if v := d.get(c); v != "" {
+			op += "," + d.name + "=" + v
+		}
+	}
+	return op
+}
+
+// String returns the name cells are filtered by, e.g. "mattn/write/64".
+func (c Cell) String() string {
+	return fmt.Sprintf("%s/%s/%d", c.Driver, c.Operation(), c.DataSize)
+}
+
+// Observer is notified as a Runner moves through its schedule. seq is the
+// position of the cell run in Runner.Schedule. Every started cell ends in
+// either CellDone or CellFailed, unless the run is cancelled.
+type Observer interface {
+	CellStarted(seq int, c Cell)
+	CellDone(seq int, c Cell, r Result)
+	CellFailed(seq int, c Cell, err error)
+}
+
+// Runner measures a matrix of drivers, sizes, workloads and optional
+// further dimensions. Set its fields before calling Run.
+type Runner struct {
+	// Drivers are names from Drivers, benchmarked in order.
+	Drivers []string
+	// KVStores are names from KVStores, benchmarked after the drivers with
+	// the added workloads that implement KVWorkload. Dimensions that
+	// configure SQLite do not apply to them; their cells leave those out.
+	KVStores []string
+	// OLAP are names from OLAPBackends, benchmarked after the KV stores with
+	// the added analytical workloads and, like them, without the SQLite
+	// dimensions.
+	OLAP []string
+	// Servers maps names to client/server databases, such as ServerBackend,
+	// benchmarked last, in name order, with every workload and without the
+	// SQLite dimensions.
+	Servers map[string]Backend
+	// Sizes are the payload sizes in bytes.
+	Sizes []int
+	// JournalModes and Synchronous, if set, are applied to each cell's
+	// database as PRAGMA journal_mode and PRAGMA synchronous values.
+	JournalModes []string
+	Synchronous  []string
+	// PageSizes and CacheSizes, if set, are applied as PRAGMA page_size and
+	// PRAGMA cache_size values, the page size before the database has
+	// tables. A negative cache size is in KiB, a positive one in pages.
+	PageSizes  []int
+	CacheSizes []int
+	// Concurrency, if set, runs each measured operation as that many
+	// concurrent Run calls on as many connections; a sample then times one
+	// round of them.
+	Concurrency []int
+	// Storage, if set, lists where cell databases live, as Storages
+	// values.
+	Storage []string
+	// Replication, if set, lists Replications values. Its cells use a
+	// WAL-mode database file whatever Storage and JournalModes say, with
+	// the named replicator shipping the WAL while operations run.
+	Replication []string
+	// Exclude and Include adjust the expanded matrix; see Matrix. Rules
+	// name dimensions as "driver", "size", "workload", "journal_mode",
+	// "synchronous", "page_size", "cache_size", "concurrency", "storage"
+	// and "replication".
+	Exclude []Rule
+	Include []Rule
+	// Filter, if set, selects cells by their String name.
+	Filter *regexp.Regexp
+	// Capabilities, if set, maps driver names to their capabilities as
+	// found by CheckDriver. Cells of workloads requiring a capability their
+	// driver lacks are left out.
+	Capabilities map[string][]string
+	// SQLiteVersions, if set, maps driver names to the SQLite version they
+	// run, as found by CheckDriver, to be recorded in their results.
+	SQLiteVersions map[string]string
+
+	// Ops is the number of measured operations per cell.
+	Ops int
+	// Duration, if set, runs each cell for this wall time instead of a
+	// fixed number of operations.
+	Duration time.Duration
+	// MaxSamples caps the operation times kept per cell, so long cells
+	// stay bounded in memory and in the results; zero means
+	// DefaultMaxSamples. Beyond it a uniform random sample of the times is
+	// kept, and Result.Iterations holds the exact count.
+	MaxSamples int
+	// Timeout bounds each cell; zero means no limit. Timeouts overrides
+	// it per workload name.
+	Timeout  time.Duration
+	Timeouts map[string]time.Duration
+	// Rows is the number of rows inserted before read workloads.
+	Rows int
+	// Seed makes the generated payloads reproducible.
+	Seed int64
+	// Compressibility is the fraction of each payload that is zero-filled.
+	Compressibility float64
+	// Pragmas are executed as "PRAGMA <p>" on every benchmark database.
+	Pragmas []string
+	// Verify makes read workloads check every blob read back.
+	Verify bool
+	// BusyRetries retries an operation up to this many times while it
+	// fails with SQLITE_BUSY or SQLITE_LOCKED, as applications do, and
+	// counts the retries in the result's Errors.
+	BusyRetries int
+
+	// Repeat runs every cell this many times, in rounds over the whole
+	// matrix, and merges the repetitions into one result.
+	Repeat int
+	// Shuffle, if non-zero, seeds a random cell order for each round.
+	Shuffle int64
+
+	// PerfCounters collects hardware counters around every timed loop
+	// (Linux only). It is switched off if the counters cannot be opened.
+	PerfCounters bool
+	// IOCounters opens the databases of drivers listed by
+	// IOCounterDrivers through a VFS that counts their reads, writes and
+	// syncs, and records the counts of every timed loop.
+	IOCounters bool
+	// Profile, if set, is called before every timed loop with a name for
+	// the cell; the returned function is called when the loop ends.
+	Profile func(name string) (stop func())
+	// Observer, if set, is notified of progress.
+	Observer Observer
+	// WrapConn, if set, wraps the connections of SQLite and server cells
+	// once their workload is set up, so it sees the statements of the
+	// timed loop and Teardown, e.g. to trace them. Wrappers implement
+	// ConnWrapper.
+	WrapConn func(c Cell, conn Conn) Conn
+	// Done holds results already measured by an earlier, interrupted run
+	// with the same settings, keyed by schedule position. Those cell runs
+	// are reused instead of measured again.
+	Done map[int]Result
+
+	workloads  []Workload
+	liveOps    atomic.Int64
+	liveErrors atomic.Int64
+	// liveTimes holds the times of the latest operations, that of
+	// operation n at n modulo liveWindow.
+	liveTimes [liveWindow]atomic.Int64
+}
+
+// liveWindow is the number of the latest operation times LiveTimes
+// returns.
+const liveWindow = 1024
+
+// NewRunner returns a runner for every registered driver and the default
+// sizes, with no workloads added yet.
+func NewRunner() *Runner {
+	names := make([]string, 0, len(Drivers))
+	for name := range Drivers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return &Runner{
+		Drivers: names,
+		Sizes:   DefaultSizes,
+		Ops:     100,
+		Rows:    100,
+		Seed:    1,
+		Repeat:  1,
+	}
+}
+
+// Add appends workloads to the run.
+func (r *Runner) Add(workloads ...Workload) {
+	r.workloads = append(r.workloads, workloads...)
+}
+
+// LiveOps returns the number of operations completed so far in the cell
+// being measured, for progress displays that poll while it runs.
+func (r *Runner) LiveOps() int64 {
+	return r.liveOps.Load()
+}
+
+// LiveErrors returns the number of operations of the cell being measured
+// that failed with an expected error so far, as counted in Result.Errors.
+func (r *Runner) LiveErrors() int64 {
+	return r.liveErrors.Load()
+}
+
+// LiveTimes returns the times of the latest operations of the cell being
+// measured, up to 1024 of them, in no particular order.
+func (r *Runner) LiveTimes() []time.Duration {
+	n := min(r.liveOps.Load(), liveWindow)
+	times := make([]time.Duration, n)
+	for i := range times {
+		times[i] = time.Duration(r.liveTimes[i].Load())
+	}
+	return times
+}
+
+// Cells expands the runner's matrix into the cells selected by the
+// filter and supported by their driver, ordered by driver, then size, then
+// workload, then the further dimensions that are set.
+func (r *Runner) Cells() []Cell {
+	var cells []Cell
+	// KV store, OLAP and server cells leave the SQLite dimensions out, so each
+	// combination of theirs comes up once per value of those.
+	otherCells := map[Cell]bool{}
+	for _, combo := range r.Matrix().Expand() {
+		var c Cell
+		for _, d := range dimensions {
+			if v, ok := combo[d.name]; ok {
+				d.set(&c, v)
+			}
+		}
+		if _, ok := KVStores[c.Driver]; ok && !r.kvWorkload(c.Workload) {
+			continue
+		}
+		if _, ok := OLAPBackends[c.Driver]; ok && Workloads[c.Workload].Category != CategoryAnalytical {
+			continue
+		}
+		if !slices.Contains(r.Drivers, c.Driver) {
+			c = Cell{Driver: c.Driver, Workload: c.Workload, DataSize: c.DataSize}
+			if otherCells[c] {
+				continue
+			}
+			otherCells[c] = true
+		}
+		if r.Capabilities != nil && !supports(r.Capabilities[c.Driver], r.requires(c.Workload)) {
+			continue
+		}
+		if r.Filter == nil || r.Filter.MatchString(c.String()) {
+			cells = append(cells, c)
+		}
+	}
+	return cells
+}
+
+// serverNames returns the names in Servers in sorted order.
+func (r *Runner) serverNames() []string {
+	names := make([]string, 0, len(r.Servers))
+	for name := range r.Servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// backend returns the backend named, which may be a SQLite driver, an
+// OLAP backend or one of the runner's servers.
+func (r *Runner) backend(name string) (Backend, bool) {
+	if b, ok := Drivers[name]; ok {
+		return b, true
+	}
+	if b, ok := OLAPBackends[name]; ok {
+		return b, true
+	}
+	b, ok := r.Servers[name]
+	return b, ok
+}
+
+// kvWorkload reports whether the added workload named can run against KV
+// stores.
+func (r *Runner) kvWorkload(name string) bool {
+	for _, w := range r.workloads {
+		if _, ok := w.(KVWorkload); ok && w.Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Schedule returns the order in which cells are run, as indexes into
+// Cells. Each of the Repeat rounds covers every cell; with Shuffle set
+// every round gets its own seeded permutation, so ordering effects such as
+// warm caches or thermal throttling average out.
+func (r *Runner) Schedule() []int {
+	n := len(r.Cells())
+	repeat := max(r.Repeat, 1)
+	var rng *rand.Rand
+	if r.Shuffle != 0 {
+		rng = rand.New(rand.NewPCG(uint64(r.Shuffle), 0))
+	}
+
+	order := make([]int, 0, n*repeat)
+	for range repeat {
+		round := make([]int, n)
+		for i := range round {
+			round[i] = i
+		}
+		if rng != nil {
+			rng.Shuffle(n, func(i, j int) { round[i], round[j] = round[j], round[i] })
+		}
+		order = append(order, round...)
+	}
+	return order
+}
+
+// timeoutFor returns the time limit for a cell of the workload, or zero
+// for none.
+func (r *Runner) timeoutFor(workload string) time.Duration {
+	if timeout, ok := r.Timeouts[workload]; ok {
+		return timeout
+	}
+	return r.Timeout
+}
+
+// Run measures every cell in schedule order, merging repetitions of a cell
+// into one result, and returns the results in Cells order. Each cell runs
+// under its timeout. A cell that fails is left out of the results and the
+// run carries on; the returned error joins the failures, each naming its
+// cell. If ctx is cancelled the current cell is abandoned and the results
+// measured so far are returned with ctx's error.
+func (r *Runner) Run(ctx context.Context) (Results, error) {
+	for _, name := range r.Drivers {
+		if _, ok := Drivers[name]; !ok {
+			return nil, fmt.Errorf("unknown driver %q", name)
+		}
+	}
+	for _, name := range r.KVStores {
+		if _, ok := KVStores[name]; !ok {
+			return nil, fmt.Errorf("unknown KV store %q", name)
+		}
+	}
+	for _, name := range r.OLAP {
+		if _, ok := OLAPBackends[name]; !ok {
+			return nil, fmt.Errorf("unknown OLAP backend %q", name)
+		}
+	}
+	if err := r.Matrix().Validate(); err != nil {
+		return nil, err
+	}
+
+	cells := r.Cells()
+
+	measured := make([]*Result, len(cells))
+	add := func(i int, result Result) {
+		if measured[i] == nil {
+			measured[i] = &result
+		} else {
+			measured[i].Merge(result)
+		}
+	}
+	collect := func() Results {
+		results := Results{}
+		for _, m := range measured {
+			if m != nil {
+				results = append(results, *m)
+			}
+		}
+		return results
+	}
+
+	var failures []error
+	for seq, i := range r.Schedule() {
+		c := cells[i]
+		if result, ok := r.Done[seq]; ok {
+			add(i, result)
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return collect(), err
+		}
+
+		// The live counts start over before the cell's Setup, so observers
+		// polling them during it do not see the last cell's.
+		r.liveOps.Store(0)
+		r.liveErrors.Store(0)
+		if r.Observer != nil {
+			r.Observer.CellStarted(seq, c)
+		}
+		cellCtx, cancel := ctx, context.CancelFunc(func() {})
+		if timeout := r.timeoutFor(c.Workload); timeout > 0 {
+			cellCtx, cancel = context.WithTimeout(ctx, timeout)
+		}
+		result, err := r.measureCell(cellCtx, c)
+		cancel()
+		if ctx.Err() != nil {
+			return collect(), ctx.Err()
+		}
+		if err != nil {
+			err = fmt.Errorf("%s: %w", c, err)
+			failures = append(failures, err)
+			if r.Observer != nil {
+				r.Observer.CellFailed(seq, c, err)
+			}
+			continue
+		}
+		if result.TimedOut {
+			slog.Warn("Cell timed out", "cell", c, "timeout", r.timeoutFor(c.Workload))
+		}
+		if r.Observer != nil {
+			r.Observer.CellDone(seq, c, result)
+		}
+		add(i, result)
+	}
+
+	return collect(), errors.Join(failures...)
+}
@@ -0,0 +1,139 @@
+package sqlitebench
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+func init() {
+	for _, q := range starQueries {
+		Register(Benchmark{Category: CategoryAnalytical, New: func() Workload { return &Star{query: q} }})
+	}
+}
+
+// The star schema's dimension tables have fixed sizes, whatever the number
+// of facts: three years of days, products in starCategories categories,
+// stores in starRegions regions and customers.
+const (
+	starDays       = 1096
+	starProducts   = 1000
+	starCategories = 20
+	starBrands     = 50
+	starStores     = 100
+	starRegions    = 5
+	starCustomers  = 10000
+	// starChunk is the number of facts one statement generates.
+	starChunk = 1000
+)
+
+// starSchema is understood by SQLite and by the OLAP backends. The facts
+// table has no primary key, as is usual for one, and an index on its date.
+var starSchema = []string{
+	"CREATE TABLE dim_date (id INTEGER PRIMARY KEY, day TEXT, year INTEGER, quarter INTEGER, month INTEGER, weekday INTEGER)",
+	"CREATE TABLE dim_product (id INTEGER PRIMARY KEY, category TEXT, brand TEXT, price DOUBLE)",
+	"CREATE TABLE dim_store (id INTEGER PRIMARY KEY, region TEXT, city TEXT)",
+	"CREATE TABLE dim_customer (id INTEGER PRIMARY KEY, segment TEXT, country TEXT)",
+	"CREATE TABLE sales (date_id INTEGER, product_id INTEGER, store_id INTEGER, customer_id INTEGER, quantity INTEGER, amount DOUBLE)",
+	"CREATE INDEX sales_date ON sales (date_id)",
+}
+
+var starQueries = []analyticalQuery{
+	{
+		name:        "star.rollup",
+		description: "roll sales up by year, quarter and region per operation",
+		sql: `SELECT d.year, d.quarter, s.region, sum(f.quantity), sum(f.amount)
+			FROM sales f JOIN dim_date d ON d.id = f.date_id JOIN dim_store s ON s.id = f.store_id
+			GROUP BY d.year, d.quarter, s.region ORDER BY d.year, d.quarter, s.region`,
+		columns: 5,
+	},
+	{
+		name:        "star.filter",
+		description: "sum one category's sales in one year by month and brand per operation",
+		sql: `SELECT d.month, p.brand, count(*), sum(f.amount)
+			FROM sales f JOIN dim_date d ON d.id = f.date_id JOIN dim_product p ON p.id = f.product_id
+			WHERE d.year = 2023 AND p.category = 'category07'
+			GROUP BY d.month, p.brand ORDER BY d.month, p.brand`,
+		columns: 4,
+	},
+	{
+		name:        "star.top",
+		description: "rank a quarter's customer segments by country and revenue per operation",
+		sql: `SELECT c.country, c.segment, count(*), sum(f.amount) AS revenue
+			FROM sales f JOIN dim_date d ON d.id = f.date_id JOIN dim_customer c ON c.id = f.customer_id
+			WHERE d.year = 2024 AND d.quarter = 4 AND f.quantity > 1
+			GROUP BY c.country, c.segment HAVING count(*) > 1 ORDER BY revenue DESC LIMIT 10`,
+		columns: 4,
+	},
+}
+
+// Star runs one of the typical rollups of a star schema, filtering and
+// grouping a sales table of Params.Rows facts by the attributes of the
+// date, product, store and customer dimensions it refers to. Facts have no
+// payload, so the size dimension does not change them; tables of millions
+// of facts are what the queries are meant for, and Setup generates them
+// in SQL so they take seconds to fill, not minutes. Like Analytical, it
+// runs on the backends in OLAPBackends too.
+type Star struct {
+	query analyticalQuery
+}
+
+func (w *Star) Name() string        { return w.query.name }
+func (w *Star) Description() string { return w.query.description }
+
+func (w *Star) Setup(ctx context.Context, db Conn, p Params) error {
+	for _, stmt := range starSchema {
+		if err := db.Exec(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	start := time.Date(2022, time.January, 1, 0, 0, 0, 0, time.UTC)
+	dims := []struct {
+		rows   int
+		insert func(tx Tx, i int) error
+	}{
+		{starDays, func(tx Tx, i int) error {
+			day := start.AddDate(0, 0, i)
+			return tx.Exec(ctx, "INSERT INTO dim_date (id, day, year, quarter, month, weekday) VALUES (?, ?, ?, ?, ?, ?)",
+				i+1, day.Format(time.DateOnly), day.Year(), (int(day.Month())+2)/3, int(day.Month()), int(day.Weekday()))
+		}},
+		{starProducts, func(tx Tx, i int) error {
+			return tx.Exec(ctx, "INSERT INTO dim_product (id, category, brand, price) VALUES (?, ?, ?, ?)",
+				i+1, fmt.Sprintf("category%02d", i%starCategories), fmt.Sprintf("brand%02d", i*7%starBrands), float64(100+i*37%9900)/100)
+		}},
+		{starStores, func(tx Tx, i int) error {
+			return tx.Exec(ctx, "INSERT INTO dim_store (id, region, city) VALUES (?, ?, ?)",
+				i+1, fmt.Sprintf("region%d", i%starRegions), fmt.Sprintf("city%02d", i))
+		}},
+		{starCustomers, func(tx Tx, i int) error {
+			return tx.Exec(ctx, "INSERT INTO dim_customer (id, segment, country) VALUES (?, ?, ?)",
+				i+1, []string{"consumer", "corporate", "small business"}[i%3], fmt.Sprintf("country%02d", i*13%40))
+		}},
+	}
+	for _, d := range dims {
+		if err := populate(ctx, db, d.rows, d.insert); err != nil {
+			return err
+		}
+	}
+
+	// Each statement numbers a chunk of facts with a recursive CTE and
+	// derives their keys and measures from the numbers, scrambled by
+	// multiplying with primes and offset by the seed.
+	seed := int64(uint64(p.Seed) % 1_000_003)
+	chunks := (p.Rows + starChunk - 1) / starChunk
+	return populate(ctx, db, chunks, func(tx Tx, i int) error {
+		first, last := i*starChunk, min((i+1)*starChunk, p.Rows)-1
+		return tx.Exec(ctx, `INSERT INTO sales (date_id, product_id, store_id, customer_id, quantity, amount)
+			WITH RECURSIVE n(i) AS (SELECT ? UNION ALL SELECT i + 1 FROM n WHERE i < ?)
+			SELECT 1 + (i * 7919 + ?) % ?, 1 + (i * 104729 + ?) % ?, 1 + (i * 1299709 + ?) % ?, 1 + (i * 15485863 + ?) % ?,
+				1 + (i * 31) % 5, ((i * 2750159) % 10000) / 100.0
+			FROM n`,
+			first, last, seed, starDays, seed, starProducts, seed, starStores, seed, starCustomers)
+	})
+}
+
+func (w *Star) Run(ctx context.Context, db Conn, n int) error {
+	return runAnalytical(ctx, db, w.query)
+}
+
+func (*Star) Teardown(db Conn) error { return nil }
@@ -0,0 +1,61 @@
+package sqlitebench
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestStar(t *testing.T) {
+	ctx := context.Background()
+	db := setupWorkload(t, &Star{query: starQueries[0]}, Params{Rows: 5500, Seed: 1})
+	// Every fact refers to a row of each dimension.
+	var facts, orphans int
+	if err := QueryRow(ctx, db, `SELECT count(*), 4 * count(*) - count(d.id) - count(p.id) - count(s.id) - count(c.id)
+		FROM sales f LEFT JOIN dim_date d ON d.id = f.date_id LEFT JOIN dim_product p ON p.id = f.product_id
+		LEFT JOIN dim_store s ON s.id = f.store_id LEFT JOIN dim_customer c ON c.id = f.customer_id`, &facts, &orphans); err != nil {
+		t.Fatal(err)
+	}
+	if facts != 5500 || orphans != 0 {
+		t.Errorf("%d facts, %d missing dimension rows; want 5500 and none", facts, orphans)
+	}
+
+	for _, q := range starQueries {
+		var groups int
+		if err := QueryRow(ctx, db, "SELECT count(*) FROM ("+q.sql+")", &groups); err != nil {
+			t.Fatalf("%s: %v", q.name, err)
+		}
+		if groups == 0 {
+			t.Errorf("%s found no groups", q.name)
+		}
+		if err := (&Star{query: q}).Run(ctx, db, 0); err != nil {
+			t.Errorf("%s: %v", q.name, err)
+		}
+	}
+}
+
+func TestRunnerPageCacheSizes(t *testing.T) {
+	r := NewRunner()
+	r.Drivers, r.Sizes, r.Ops, r.Rows = []string{"modernc"}, []int{64}, 2, 100
+	r.JournalModes = []string{"wal"}
+	r.PageSizes = []int{1024, 8192}
+	r.CacheSizes = []int{-1000}
+	r.Storage = []string{StorageFile}
+	r.Add(Workloads["star.rollup"].New())
+
+	results, err := r.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, res := range results {
+		names = append(names, res.Operation)
+	}
+	want := []string{
+		"star.rollup,journal_mode=wal,page_size=1024,cache_size=-1000,storage=file",
+		"star.rollup,journal_mode=wal,page_size=8192,cache_size=-1000,storage=file",
+	}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("results = %v, want %v", names, want)
+	}
+}
@@ -0,0 +1,186 @@
+package sqlitebench
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+func init() {
+	Register(Benchmark{Category: CategoryWrite, New: func() Workload { return &Sync{} }})
+}
+
+const (
+	// syncEvery makes every syncEvery-th operation a sync of up to
+	// syncBatch changes; the others are writes.
+	syncEvery = 5
+	syncBatch = 500
+)
+
+// syncSchema creates the notes table, whose changes triggers track as
+// mobile sync frameworks do, and the drafts table, its untracked twin.
+// recursive_triggers is off by default, so the update trigger's own
+// update does not fire it again, and it only fires for the columns a user
+// edits anyway.
+var syncSchema = []string{
+	"CREATE TABLE notes (id INTEGER PRIMARY KEY, title TEXT NOT NULL, body BLOB, version INTEGER NOT NULL DEFAULT 1, dirty INTEGER NOT NULL DEFAULT 1)",
+	"CREATE TABLE drafts (id INTEGER PRIMARY KEY, title TEXT NOT NULL, body BLOB, version INTEGER NOT NULL DEFAULT 1, dirty INTEGER NOT NULL DEFAULT 1)",
+	"CREATE TABLE changes (seq INTEGER PRIMARY KEY AUTOINCREMENT, note_id INTEGER NOT NULL, op TEXT NOT NULL, version INTEGER NOT NULL)",
+	`CREATE TRIGGER notes_insert AFTER INSERT ON notes BEGIN
+		INSERT INTO changes (note_id, op, version) VALUES (new.id, 'insert', new.version);
+	END`,
+	`CREATE TRIGGER notes_update AFTER UPDATE OF title, body ON notes BEGIN
+		UPDATE notes SET version = old.version + 1, dirty = 1 WHERE id = new.id;
+		INSERT INTO changes (note_id, op, version) VALUES (new.id, 'update', old.version + 1);
+	END`,
+	`CREATE TRIGGER notes_delete AFTER DELETE ON notes BEGIN
+		INSERT INTO changes (note_id, op, version) VALUES (old.id, 'delete', old.version + 1);
+	END`,
+}
+
+// Sync models an app keeping a local database it syncs with a server:
+// triggers on the notes table bump a row's version, flag it dirty and log
+// every insert, update and delete to a changes table, and every fifth
+// operation is a sync pulling the oldest changes with the rows they name
+// and acknowledging them, clearing the flags and deleting the log entries.
+// The other operations write, half to the notes and half, as a baseline,
+// to the same writes on a drafts table without triggers: an insert, two
+// updates and a delete of random rows in turn. Setup fills both tables
+// with Params.Rows rows and syncs them.
+//
+// Writes are timed by phase, "write" for drafts and "write.tracked" for
+// notes, so the tracking's write amplification shows as the ratio of the
+// two; with I/O counting, the bytes written show it too. Syncs are timed
+// as "sync".
+type Sync struct {
+	phaseTimes
+
+	payloads *PayloadPool
+	// notes and drafts number the rows inserted in each table.
+	notes, drafts atomic.Int64
+	rng           lockedRand
+}
+
+func (*Sync) Name() string { return "sync" }
+func (*Sync) Description() string {
+	return "write rows whose changes triggers track while syncing the changes"
+}
+
+func (w *Sync) Setup(ctx context.Context, db Conn, p Params) error {
+	w.payloads = p.Payloads
+	w.notes.Store(0)
+	w.drafts.Store(0)
+	w.rng.seed(p)
+	for _, stmt := range syncSchema {
+		if err := db.Exec(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	err := populate(ctx, db, p.Rows, func(tx Tx, i int) error {
+		if err := w.insert(ctx, tx.Exec, "notes", &w.notes); err != nil {
+			return err
+		}
+		return w.insert(ctx, tx.Exec, "drafts", &w.drafts)
+	})
+	if err != nil {
+		return err
+	}
+	for {
+		pulled, err := w.sync(ctx, db)
+		if err != nil || pulled < syncBatch {
+			return err
+		}
+	}
+}
+
+func (w *Sync) Run(ctx context.Context, db Conn, n int) error {
+	start := time.Now()
+	if n%syncEvery == syncEvery-1 {
+		if _, err := w.sync(ctx, db); err != nil {
+			return err
+		}
+		w.record("sync", time.Since(start))
+		return nil
+	}
+
+	table, ids, phase := "drafts", &w.drafts, "write"
+	if n%2 == 0 {
+		table, ids, phase = "notes", &w.notes, "write.tracked"
+	}
+	var err error
+	switch (n / 2) % 4 {
+	case 0:
+		err = w.insert(ctx, db.Exec, table, ids)
+	case 1, 2:
+		err = db.Exec(ctx, "UPDATE "+table+" SET title = ?, body = ? WHERE id = ?", fmt.Sprintf("note %d", n), w.payloads.Next(), w.pick(ids))
+	case 3:
+		err = db.Exec(ctx, "DELETE FROM "+table+" WHERE id = ?", w.pick(ids))
+	}
+	if err != nil {
+		return err
+	}
+	w.record(phase, time.Since(start))
+	return nil
+}
+
+// insert inserts the next row of table with exec, ids numbering its rows.
+func (w *Sync) insert(ctx context.Context, exec func(ctx context.Context, query string, args ...any) error, table string, ids *atomic.Int64) error {
+	id := ids.Add(1)
+	return exec(ctx, "INSERT INTO "+table+" (id, title, body) VALUES (?, ?, ?)", id, fmt.Sprintf("note %d", id), w.payloads.Next())
+}
+
+// pick picks the id of a row inserted, which may have been deleted since.
+func (w *Sync) pick(ids *atomic.Int64) int64 {
+	return w.rng.int64N(max(ids.Load(), 1)) + 1
+}
+
+// sync pulls the oldest changes with the notes they name, as a client
+// sending them to the server would, and acknowledges them. It returns the
+// number of changes pulled.
+func (w *Sync) sync(ctx context.Context, db Conn) (int, error) {
+	rows, err := db.Query(ctx, `SELECT c.seq, c.note_id, c.op, c.version, n.title, n.body
+		FROM changes c LEFT JOIN notes n ON n.id = c.note_id ORDER BY c.seq LIMIT ?`, syncBatch)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	var pulled int
+	var last, id, version int64
+	var op string
+	var title *string
+	var body []byte
+	for rows.Next() {
+		if err := rows.Scan(&last, &id, &op, &version, &title, &body); err != nil {
+			return 0, err
+		}
+		pulled++
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if err := rows.Close(); err != nil {
+		return 0, err
+	}
+	if pulled == 0 {
+		return 0, nil
+	}
+
+	// A note changed again after the last change pulled stays dirty.
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Exec(ctx, `UPDATE notes SET dirty = 0
+		WHERE id IN (SELECT note_id FROM changes WHERE seq <= ?) AND id NOT IN (SELECT note_id FROM changes WHERE seq > ?)`, last, last); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	if err := tx.Exec(ctx, "DELETE FROM changes WHERE seq <= ?", last); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	return pulled, tx.Commit()
+}
+
+func (*Sync) Teardown(db Conn) error { return nil }
@@ -0,0 +1,36 @@
+package sqlitebench
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSync(t *testing.T) {
+	ctx := context.Background()
+	w := &Sync{}
+	db := setupWorkload(t, w, Params{Rows: 600, Seed: 1})
+	// Setup synced the notes it inserted.
+	var changes, dirty int
+	if err := QueryRow(ctx, db, "SELECT (SELECT count(*) FROM changes), (SELECT count(*) FROM notes WHERE dirty)", &changes, &dirty); err != nil {
+		t.Fatal(err)
+	}
+	if changes != 0 || dirty != 0 {
+		t.Fatalf("after Setup: %d changes and %d dirty notes; want none", changes, dirty)
+	}
+
+	runOps(t, w, db, 24)
+	phases := w.Phases()
+	if plain, tracked, syncs := len(phases["write"]), len(phases["write.tracked"]), len(phases["sync"]); plain+tracked != 20 || syncs != 4 {
+		t.Errorf("%d plain and %d tracked writes and %d syncs; want 20 writes and 4 syncs", plain, tracked, syncs)
+	}
+
+	// After the last sync, at 19, operations 20 and 22 updated and deleted
+	// a note, logging two changes; the update bumped a version.
+	var version int
+	if err := QueryRow(ctx, db, "SELECT (SELECT count(*) FROM changes), (SELECT count(*) FROM notes WHERE dirty), (SELECT max(version) FROM notes)", &changes, &dirty, &version); err != nil {
+		t.Fatal(err)
+	}
+	if changes != 2 || dirty != 1 || version < 2 {
+		t.Errorf("%d changes, %d dirty notes, versions up to %d; want 2, 1 and at least 2", changes, dirty, version)
+	}
+}
@@ -0,0 +1,112 @@
+package sqlitebench
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// TempDirPrefix starts the names of the temporary directories cells keep
+// their database files in, with their -wal, -shm and -journal files, and
+// those of the KV stores.
+const TempDirPrefix = "sqlitebench-"
+
+// tempOwnerFile holds the ID of the process that created a temporary
+// directory, so CleanTempDirs leaves those of running processes alone.
+const tempOwnerFile = ".owner"
+
+// liveTempDirs are the temporary directories of open cells, removed by
+// RemoveTempDirs if the process has to exit without closing them.
+var liveTempDirs = struct {
+	sync.Mutex
+	dirs map[string]bool
+}{dirs: map[string]bool{}}
+
+// MakeTempDir creates a temporary directory in parent, or the system's if
+// empty, named TempDirPrefix+kind followed by a random string. The returned
+// function removes it with everything in it; until then RemoveTempDirs
+// does.
+func MakeTempDir(parent, kind string) (string, func(), error) {
+	dir, err := os.MkdirTemp(parent, TempDirPrefix+kind)
+	if err != nil {
+		return "", nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, tempOwnerFile), []byte(strconv.Itoa(os.Getpid())), 0o644); err != nil {
+		os.RemoveAll(dir)
+		return "", nil, err
+	}
+
+	liveTempDirs.Lock()
+	liveTempDirs.dirs[dir] = true
+	liveTempDirs.Unlock()
+	return dir, func() {
+		liveTempDirs.Lock()
+		delete(liveTempDirs.dirs, dir)
+		liveTempDirs.Unlock()
+		os.RemoveAll(dir)
+	}, nil
+}
+
+// RemoveTempDirs removes the temporary directories of cells still open,
+// for a process about to exit without closing them, e.g. on a second
+// interrupt or a fatal error. Their databases are left unusable.
+func RemoveTempDirs() {
+	liveTempDirs.Lock()
+	defer liveTempDirs.Unlock()
+	for dir := range liveTempDirs.dirs {
+		os.RemoveAll(dir)
+		delete(liveTempDirs.dirs, dir)
+	}
+}
+
+// TempDirParents returns the directories cells create temporary
+// directories in.
+func TempDirParents() []string {
+	return []string{os.TempDir(), TmpfsDir}
+}
+
+// CleanTempDirs removes the temporary directories left in parents by
+// processes that exited without removing them, and returns them. Those of
+// processes still running are kept. Parents that do not exist are skipped.
+func CleanTempDirs(parents []string) ([]string, error) {
+	var removed []string
+	var errs []error
+	for _, parent := range parents {
+		entries, err := os.ReadDir(parent)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, e := range entries {
+			if !e.IsDir() || !strings.HasPrefix(e.Name(), TempDirPrefix) {
+				continue
+			}
+			dir := filepath.Join(parent, e.Name())
+			if pid, err := readOwner(dir); err == nil && processRunning(pid) {
+				continue
+			}
+			if err := os.RemoveAll(dir); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			removed = append(removed, dir)
+		}
+	}
+	return removed, errors.Join(errs...)
+}
+
+// readOwner returns the ID of the process that created dir.
+func readOwner(dir string) (int, error) {
+	data, err := os.ReadFile(filepath.Join(dir, tempOwnerFile))
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(data)))
+}
@@ -0,0 +1,7 @@
+//go:build !unix
+
+package sqlitebench
+
+// processRunning cannot tell without signals, so it assumes the process
+// exited. Windows refuses to remove files a running process has open.
+func processRunning(pid int) bool { return false }
@@ -0,0 +1,50 @@
+package sqlitebench
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestCleanTempDirs(t *testing.T) {
+	if !processRunning(os.Getpid()) {
+		t.Skip("cannot tell running processes apart on this platform")
+	}
+	parent := t.TempDir()
+	live, remove, err := MakeTempDir(parent, "db-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer remove()
+
+	// A directory left by a process that exited, with a WAL.
+	dead := filepath.Join(parent, TempDirPrefix+"db-dead")
+	if err := os.Mkdir(dead, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(dead, tempOwnerFile), []byte("2147483647"), 0o644)
+	os.WriteFile(filepath.Join(dead, "bench.db-wal"), nil, 0o644)
+	other := filepath.Join(parent, "unrelated")
+	if err := os.Mkdir(other, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := CleanTempDirs([]string{parent, filepath.Join(parent, "missing")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(removed, []string{dead}) {
+		t.Errorf("removed %v, want only %s", removed, dead)
+	}
+	for _, dir := range []string{live, other} {
+		if _, err := os.Stat(dir); err != nil {
+			t.Errorf("%s: %v", dir, err)
+		}
+	}
+
+	RemoveTempDirs()
+	if _, err := os.Stat(live); !os.IsNotExist(err) {
+		t.Errorf("RemoveTempDirs left %s", live)
+	}
+}
@@ -0,0 +1,12 @@
+//go:build unix
+
+package sqlitebench
+
+import "syscall"
+
+// processRunning reports whether a process with the ID exists. A process
+// of another user that cannot be signalled still counts.
+func processRunning(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
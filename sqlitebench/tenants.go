@@ -0,0 +1,211 @@
+package sqlitebench
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+func init() {
+	Register(Benchmark{Category: CategoryRead, Requires: []string{"attach"}, New: func() Workload { return &Tenants{} }})
+}
+
+const (
+	// tenantDatabases is the number of tenants, each with a database file.
+	tenantDatabases = 200
+	// tenantReportEvery makes every tenantReportEvery-th operation a
+	// cross-tenant report instead of a tenant's request.
+	tenantReportEvery = 50
+	// tenantReportAttach is the number of databases a report attaches,
+	// below SQLite's default limit of ten.
+	tenantReportAttach = 8
+)
+
+// Tenants models a multi-tenant service keeping a small database file per
+// tenant, as many SaaS backends on SQLite do: each operation opens a
+// connection to a random one of tenantDatabases files, reads a row, every
+// fourth also updating it, and closes the connection again. Every
+// fiftieth is instead a report, opening one tenant's file and attaching
+// tenantReportAttach others to sum their rows in one query. The opening,
+// the request, the closing and whole reports are timed in the "open",
+// "query", "close" and "report" phases, so the cost of connection churn
+// shows apart from that of the queries.
+//
+// The files live in a temporary directory of their own, whatever the
+// cell's storage, with Params.Rows rows spread over them, at least one
+// each. On Linux, Teardown fails if the process still has descriptors open
+// on files in the directory, as a driver leaking them on close would.
+type Tenants struct {
+	phaseTimes
+	rowBytes
+
+	payloads *PayloadPool
+	open     func(ctx context.Context, dsn string) (Conn, error)
+	dir      string
+	remove   func()
+	rows     int
+	rng      lockedRand
+}
+
+func (*Tenants) Name() string { return "tenants" }
+func (*Tenants) Description() string {
+	return "open a tenant's database file, query it and close it per operation"
+}
+
+// openFiles returns the number of file descriptors the process has open
+// on files in dir, or -1 where /proc does not tell. Descriptors of the
+// rest of the process, e.g. sockets, are not counted.
+func openFiles(dir string) int {
+	fds, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		return -1
+	}
+	// /proc shows the paths with symbolic links resolved.
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = resolved
+	}
+	n := 0
+	for _, fd := range fds {
+		target, err := os.Readlink(filepath.Join("/proc/self/fd", fd.Name()))
+		if err == nil && strings.HasPrefix(target, dir+string(filepath.Separator)) {
+			n++
+		}
+	}
+	return n
+}
+
+// tenantPath returns the path of tenant i's database.
+func (w *Tenants) tenantPath(i int) string {
+	return filepath.Join(w.dir, fmt.Sprintf("tenant-%d.db", i))
+}
+
+func (w *Tenants) Setup(ctx context.Context, db Conn, p Params) error {
+	w.reset()
+	if p.Open == nil {
+		return errors.New("tenants needs a SQLite driver")
+	}
+	w.payloads, w.open = p.Payloads, p.Open
+	w.rows = max(p.Rows/tenantDatabases, 1)
+	w.rng.seed(p)
+	var err error
+	if w.dir, w.remove, err = MakeTempDir("", "tenants-"); err != nil {
+		return err
+	}
+	for i := range tenantDatabases {
+		if err := w.create(ctx, i); err != nil {
+			w.remove()
+			return fmt.Errorf("tenant %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// create creates tenant i's database with its rows.
+func (w *Tenants) create(ctx context.Context, i int) error {
+	tenant, err := w.open(ctx, "file:"+w.tenantPath(i))
+	if err != nil {
+		return err
+	}
+	defer tenant.Close()
+	// Syncing hundreds of files would make Setup slow for nothing.
+	for _, stmt := range []string{
+		"PRAGMA synchronous = OFF",
+		"CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT NOT NULL, data BLOB)",
+	} {
+		if err := tenant.Exec(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	err = populate(ctx, tenant, w.rows, func(tx Tx, j int) error {
+		return tx.Exec(ctx, "INSERT INTO items (id, name, data) VALUES (?, ?, ?)", j+1, RowLabel(j), w.payloads.Next())
+	})
+	if err != nil {
+		return err
+	}
+	return tenant.Close()
+}
+
+// pick returns a random tenant and one of its rows.
+func (w *Tenants) pick() (tenant, row int) {
+	w.rng.do(func(rng *rand.Rand) {
+		tenant, row = rng.IntN(tenantDatabases), rng.IntN(w.rows)+1
+	})
+	return tenant, row
+}
+
+func (w *Tenants) Run(ctx context.Context, db Conn, n int) error {
+	i, row := w.pick()
+	start := time.Now()
+	tenant, err := w.open(ctx, "file:"+w.tenantPath(i))
+	if err != nil {
+		return err
+	}
+	defer tenant.Close()
+	// Drivers connect on the first statement, which reading the schema
+	// version makes that of the opening.
+	var version int
+	if err := QueryRow(ctx, tenant, "PRAGMA schema_version", &version); err != nil {
+		return err
+	}
+
+	if n%tenantReportEvery == tenantReportEvery-1 {
+		if err := w.report(ctx, tenant, i); err != nil {
+			return err
+		}
+		if err := tenant.Close(); err != nil {
+			return err
+		}
+		w.record("report", time.Since(start))
+		return nil
+	}
+
+	w.record("open", time.Since(start))
+	start = time.Now()
+	if n%4 == 0 {
+		if err := tenant.Exec(ctx, "UPDATE items SET data = ? WHERE id = ?", w.payloads.Next(), row); err != nil {
+			return err
+		}
+	}
+	if err := runStatement(ctx, tenant, "SELECT name, data FROM items WHERE id = ?", []any{row}, &w.rowBytes); err != nil {
+		return err
+	}
+	w.record("query", time.Since(start))
+	start = time.Now()
+	if err := tenant.Close(); err != nil {
+		return err
+	}
+	w.record("close", time.Since(start))
+	return nil
+}
+
+// report sums the rows of tenant i and the tenantReportAttach tenants
+// after it, attached to its connection.
+func (w *Tenants) report(ctx context.Context, tenant Conn, i int) error {
+	selects := []string{"SELECT count(*) AS n, sum(length(data)) AS bytes FROM main.items"}
+	for j := 1; j <= tenantReportAttach; j++ {
+		schema := fmt.Sprintf("t%d", j)
+		path := strings.ReplaceAll(w.tenantPath((i+j)%tenantDatabases), "'", "''")
+		if err := tenant.Exec(ctx, fmt.Sprintf("ATTACH '%s' AS %s", path, schema)); err != nil {
+			return err
+		}
+		selects = append(selects, fmt.Sprintf("SELECT count(*), sum(length(data)) FROM %s.items", schema))
+	}
+	return runStatement(ctx, tenant, "SELECT sum(n), sum(bytes) FROM ("+strings.Join(selects, " UNION ALL ")+")", nil, &w.rowBytes)
+}
+
+func (w *Tenants) Teardown(db Conn) error {
+	var err error
+	if w.remove != nil {
+		if files := openFiles(w.dir); files > 0 {
+			err = fmt.Errorf("%d file descriptors still open on tenant files after the cell: connections leak them", files)
+		}
+		w.remove()
+		w.remove = nil
+	}
+	return err
+}
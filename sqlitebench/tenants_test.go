@@ -0,0 +1,53 @@
+package sqlitebench
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTenants(t *testing.T) {
+	r := NewRunner()
+	r.Drivers, r.Sizes, r.Ops, r.Rows = []string{"mattn", "modernc"}, []int{64}, 50, 1000
+	r.Add(&Tenants{})
+	results, err := r.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, res := range results {
+		for phase, want := range map[string]int{"open": 49, "query": 49, "close": 49, "report": 1} {
+			if got := len(res.Phases[phase]); got != want {
+				t.Errorf("%s: %d %s times, want %d", res.Driver, got, phase, want)
+			}
+		}
+	}
+
+	// Servers and other backends cannot open tenant files.
+	if err := (&Tenants{}).Setup(context.Background(), nil, Params{}); err == nil {
+		t.Error("expected an error without Params.Open")
+	}
+}
+
+func TestOpenFiles(t *testing.T) {
+	dir := t.TempDir()
+	if openFiles(dir) < 0 {
+		t.Skip("/proc/self/fd is not available")
+	}
+	other, err := os.Create(filepath.Join(t.TempDir(), "other"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	f, err := os.Create(filepath.Join(dir, "tenant.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := openFiles(dir); n != 1 {
+		t.Errorf("%d files open in the directory, want 1", n)
+	}
+	f.Close()
+	if n := openFiles(dir); n != 0 {
+		t.Errorf("%d files open in the directory after closing, want none", n)
+	}
+}
@@ -0,0 +1,16 @@
+/* A minimal loadable extension for the extension workload tests, adding
+ * half(x), which returns x / 2.0. Build with:
+ *
+ *	cc -shared -fPIC -o libhalf.so half.c
+ */
+#include <sqlite3ext.h>
+SQLITE_EXTENSION_INIT1
+
+static void half(sqlite3_context *ctx, int argc, sqlite3_value **argv) {
+	sqlite3_result_double(ctx, sqlite3_value_double(argv[0]) / 2.0);
+}
+
+int sqlite3_half_init(sqlite3 *db, char **pzErrMsg, const sqlite3_api_routines *pApi) {
+	SQLITE_EXTENSION_INIT2(pApi);
+	return sqlite3_create_function(db, "half", 1, SQLITE_UTF8 | SQLITE_DETERMINISTIC, 0, half, 0, 0);
+}
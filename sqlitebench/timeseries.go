@@ -0,0 +1,203 @@
+package sqlitebench
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+func init() {
+	Register(Benchmark{Category: CategoryConcurrency, New: func() Workload { return &TimeSeries{} }})
+	Register(Benchmark{Category: CategoryConcurrency, New: func() Workload { return &TimeSeries{Hourly: true} }})
+}
+
+// The time series workloads' metrics: each scrape appends one point per
+// series, tsInterval seconds after the previous scrape, starting at
+// tsEpoch.
+const (
+	tsSeries   = 16
+	tsInterval = 10
+	tsEpoch    = 1_700_000_000
+	// tsQueryEvery makes every tsQueryEvery-th operation a query. With a
+	// Concurrency of tsQueryEvery, one connection queries while the
+	// others append.
+	tsQueryEvery = 4
+	// tsWindow is the range queried, ending at the last scrape, in
+	// seconds, and tsBucket the width of the buckets it is grouped into.
+	tsWindow = 3600
+	tsBucket = 300
+)
+
+// TimeSeries models a local metrics store: operations append a scrape of
+// timestamped points, and every fourth one instead aggregates the last
+// hour into five-minute buckets per series, as a dashboard does. Run with
+// a Concurrency above 1, the queries run alongside the appends on other
+// connections. Setup appends Params.Rows scrapes of history. The payload
+// size is not used.
+type TimeSeries struct {
+	// Hourly keeps each hour's points in a table of its own, created as
+	// the hour's first scrape arrives, instead of one table for all.
+	Hourly bool
+
+	// scrapes numbers the scrapes appended.
+	scrapes atomic.Int64
+	// mu guards hours, the hours whose tables exist, and serializes
+	// creating them.
+	mu    sync.Mutex
+	hours map[int64]bool
+}
+
+func (w *TimeSeries) Name() string {
+	if w.Hourly {
+		return "timeseries.hourly"
+	}
+	return "timeseries"
+}
+
+func (w *TimeSeries) Description() string {
+	if w.Hourly {
+		return "append metrics into hourly tables while aggregating time ranges"
+	}
+	return "append metrics while aggregating time ranges"
+}
+
+// table returns the table holding the points of the hour starting at
+// hour*3600.
+func (w *TimeSeries) table(hour int64) string {
+	if w.Hourly {
+		return fmt.Sprintf("points_%d", hour)
+	}
+	return "points"
+}
+
+// createTable creates the table of hour's points unless it was. It runs
+// outside the append, whose first statement then takes the write lock at
+// once.
+func (w *TimeSeries) createTable(ctx context.Context, db Conn, hour int64) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.Hourly {
+		hour = 0
+	}
+	if w.hours[hour] {
+		return nil
+	}
+	t := w.table(hour)
+	for _, stmt := range []string{
+		"CREATE TABLE " + t + " (ts INTEGER NOT NULL, series INTEGER NOT NULL, value REAL NOT NULL)",
+		"CREATE INDEX " + t + "_ts ON " + t + " (ts)",
+	} {
+		if err := db.Exec(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	w.hours[hour] = true
+	return nil
+}
+
+// existing returns the hours from first to last whose tables exist.
+func (w *TimeSeries) existing(first, last int64) []int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.Hourly {
+		first, last = 0, 0
+	}
+	var hours []int64
+	for hour := first; hour <= last; hour++ {
+		if w.hours[hour] {
+			hours = append(hours, hour)
+		}
+	}
+	return hours
+}
+
+func (w *TimeSeries) Setup(ctx context.Context, db Conn, p Params) error {
+	w.scrapes.Store(0)
+	w.hours = map[int64]bool{}
+	// The first table exists before anything queries it.
+	if err := w.createTable(ctx, db, tsEpoch/3600); err != nil {
+		return err
+	}
+	for done := 0; done < p.Rows; {
+		// The hour's table is created before the transaction appending
+		// to it.
+		hour := (tsEpoch + int64(done)*tsInterval) / 3600
+		if err := w.createTable(ctx, db, hour); err != nil {
+			return err
+		}
+		tx, err := db.Begin(ctx)
+		if err != nil {
+			return err
+		}
+		for end := min(done+populateBatch, p.Rows); done < end && (tsEpoch+int64(done)*tsInterval)/3600 == hour; done++ {
+			if err := w.append(ctx, tx.Exec, w.scrapes.Add(1)-1); err != nil {
+				tx.Rollback()
+				return err
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// append inserts the points of scrape with exec.
+func (w *TimeSeries) append(ctx context.Context, exec func(ctx context.Context, query string, args ...any) error, scrape int64) error {
+	ts := tsEpoch + scrape*tsInterval
+	args := make([]any, 0, 3*tsSeries)
+	for series := range int64(tsSeries) {
+		args = append(args, ts, series, float64((scrape*31+series*17)%1000)/10)
+	}
+	values := strings.TrimSuffix(strings.Repeat("(?, ?, ?), ", tsSeries), ", ")
+	return exec(ctx, "INSERT INTO "+w.table(ts/3600)+" (ts, series, value) VALUES "+values, args...)
+}
+
+func (w *TimeSeries) Run(ctx context.Context, db Conn, n int) error {
+	if n%tsQueryEvery == tsQueryEvery-1 {
+		return w.query(ctx, db)
+	}
+	scrape := w.scrapes.Add(1) - 1
+	if err := w.createTable(ctx, db, (tsEpoch+scrape*tsInterval)/3600); err != nil {
+		return err
+	}
+	return w.append(ctx, db.Exec, scrape)
+}
+
+// query aggregates the window ending at the last scrape numbered, over
+// the tables existing, into buckets per series.
+func (w *TimeSeries) query(ctx context.Context, db Conn) error {
+	end := tsEpoch + w.scrapes.Load()*tsInterval
+	start := end - tsWindow
+	hours := w.existing(start/3600, end/3600)
+	if len(hours) == 0 {
+		return nil
+	}
+	var from []string
+	var args []any
+	for _, hour := range hours {
+		from = append(from, "SELECT ts, series, value FROM "+w.table(hour)+" WHERE ts >= ? AND ts < ?")
+		args = append(args, start, end)
+	}
+	rows, err := db.Query(ctx, fmt.Sprintf("SELECT series, ts / %d * %d AS bucket, count(*), avg(value), min(value), max(value) FROM (%s) AS p GROUP BY series, bucket",
+		tsBucket, tsBucket, strings.Join(from, " UNION ALL ")), args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var series, bucket, count int64
+		var avg, lo, hi float64
+		if err := rows.Scan(&series, &bucket, &count, &avg, &lo, &hi); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	return rows.Close()
+}
+
+func (*TimeSeries) Teardown(db Conn) error { return nil }
@@ -0,0 +1,39 @@
+package sqlitebench
+
+import (
+	"context"
+	"testing"
+)
+
+func TestTimeSeries(t *testing.T) {
+	ctx := context.Background()
+	for _, hourly := range []bool{false, true} {
+		w := &TimeSeries{Hourly: hourly}
+		t.Run(w.Name(), func(t *testing.T) {
+			// 1000 scrapes ten seconds apart span four hours from
+			// tsEpoch, 22:13:20.
+			db := setupWorkload(t, w, Params{Rows: 1000})
+			runOps(t, w, db, 8)
+
+			wantTables := 1
+			if hourly {
+				wantTables = 4
+			}
+			var tables, points int
+			if err := QueryRow(ctx, db, "SELECT count(*) FROM sqlite_schema WHERE type = 'table'", &tables); err != nil {
+				t.Fatal(err)
+			}
+			for hour := range w.hours {
+				var n int
+				if err := QueryRow(ctx, db, "SELECT count(*) FROM "+w.table(hour), &n); err != nil {
+					t.Fatal(err)
+				}
+				points += n
+			}
+			// Two of the eight operations were queries.
+			if want := (1000 + 6) * tsSeries; tables != wantTables || points != want {
+				t.Errorf("%d tables of %d points, want %d of %d", tables, points, wantTables, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,240 @@
+package sqlitebench
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TraceEntry is one statement of a recorded application trace. Traces are
+// stored as JSON Lines, one entry per line:
+//
+//	{"offset_ns":1500000,"sql":"SELECT * FROM users WHERE id = ?","args":[42]}
+//
+// Blob arguments are written as {"blob":"<base64>"}.
+type TraceEntry struct {
+	// Offset is the time since the trace started at which the statement
+	// was issued.
+	Offset time.Duration
+	SQL    string
+	Args   []any
+}
+
+type traceEntryJSON struct {
+	Offset int64             `json:"offset_ns"`
+	SQL    string            `json:"sql"`
+	Args   []json.RawMessage `json:"args,omitempty"`
+}
+
+type traceBlob struct {
+	Blob []byte `json:"blob"`
+}
+
+func (e TraceEntry) MarshalJSON() ([]byte, error) {
+	out := traceEntryJSON{Offset: e.Offset.Nanoseconds(), SQL: e.SQL}
+	for _, arg := range e.Args {
+		if b, ok := arg.([]byte); ok {
+			arg = traceBlob{b}
+		}
+		data, err := json.Marshal(arg)
+		if err != nil {
+			return nil, err
+		}
+		out.Args = append(out.Args, data)
+	}
+	return json.Marshal(out)
+}
+
+func (e *TraceEntry) UnmarshalJSON(data []byte) error {
+	var in traceEntryJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	e.Offset, e.SQL, e.Args = time.Duration(in.Offset), in.SQL, nil
+
+	for _, raw := range in.Args {
+		var arg any
+		if bytes.HasPrefix(bytes.TrimSpace(raw), []byte("{")) {
+			var b traceBlob
+			if err := json.Unmarshal(raw, &b); err != nil {
+				return err
+			}
+			arg = b.Blob
+		} else {
+			dec := json.NewDecoder(bytes.NewReader(raw))
+			dec.UseNumber()
+			if err := dec.Decode(&arg); err != nil {
+				return err
+			}
+			if n, ok := arg.(json.Number); ok {
+				if i, err := n.Int64(); err == nil {
+					arg = i
+				} else if arg, err = n.Float64(); err != nil {
+					return err
+				}
+			}
+		}
+		e.Args = append(e.Args, arg)
+	}
+	return nil
+}
+
+// ReadTrace reads a trace in either of two formats: JSON Lines as written
+// by TraceDriver, or a plain SQL log such as the sqlite3 shell's .trace
+// output, with one statement per line and no timing. Lines starting with
+// "--" are skipped in plain logs.
+func ReadTrace(r io.Reader) ([]TraceEntry, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 64<<20)
+
+	var entries []TraceEntry
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimSpace(sc.Text())
+		if text == "" || strings.HasPrefix(text, "--") {
+			continue
+		}
+
+		var e TraceEntry
+		if strings.HasPrefix(text, "{") {
+			if err := json.Unmarshal([]byte(text), &e); err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+		} else {
+			e.SQL = strings.TrimSuffix(text, ";")
+		}
+		entries = append(entries, e)
+	}
+	return entries, sc.Err()
+}
+
+// TraceDriver wraps a database/sql driver so every statement an application
+// runs through it is appended to w as a trace entry. Register the result
+// under a new name and open the application's database with that name:
+//
+//	sql.Register("sqlite3-traced", sqlitebench.TraceDriver(&sqlite3.SQLiteDriver{}, f))
+func TraceDriver(d driver.Driver, w io.Writer) driver.Driver {
+	return &traceDriver{Driver: d, rec: &traceRecorder{enc: json.NewEncoder(w), start: time.Now()}}
+}
+
+// traceRecorder serializes entries from all connections of a driver.
+type traceRecorder struct {
+	mu    sync.Mutex
+	enc   *json.Encoder
+	start time.Time
+}
+
+func (t *traceRecorder) record(query string, args []driver.NamedValue) {
+	e := TraceEntry{Offset: time.Since(t.start), SQL: query}
+	for _, arg := range args {
+		e.Args = append(e.Args, arg.Value)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	// A trace that cannot be written must not break the application.
+	_ = t.enc.Encode(e)
+}
+
+type traceDriver struct {
+	driver.Driver
+	rec *traceRecorder
+}
+
+func (d *traceDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.Driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &traceConn{Conn: conn, rec: d.rec}, nil
+}
+
+type traceConn struct {
+	driver.Conn
+	rec *traceRecorder
+}
+
+func (c *traceConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *traceConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var stmt driver.Stmt
+	var err error
+	if p, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = p.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.Conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &traceStmt{Stmt: stmt, query: query, rec: c.rec}, nil
+}
+
+func (c *traceConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if b, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return b.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+func (c *traceConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	e, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	res, err := e.ExecContext(ctx, query, args)
+	if err != driver.ErrSkip {
+		c.rec.record(query, args)
+	}
+	return res, err
+}
+
+func (c *traceConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	q, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	rows, err := q.QueryContext(ctx, query, args)
+	if err != driver.ErrSkip {
+		c.rec.record(query, args)
+	}
+	return rows, err
+}
+
+type traceStmt struct {
+	driver.Stmt
+	query string
+	rec   *traceRecorder
+}
+
+func (s *traceStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	s.rec.record(s.query, args)
+	if e, ok := s.Stmt.(driver.StmtExecContext); ok {
+		return e.ExecContext(ctx, args)
+	}
+	return s.Stmt.Exec(values(args))
+}
+
+func (s *traceStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	s.rec.record(s.query, args)
+	if q, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		return q.QueryContext(ctx, args)
+	}
+	return s.Stmt.Query(values(args))
+}
+
+func values(args []driver.NamedValue) []driver.Value {
+	vals := make([]driver.Value, len(args))
+	for i, arg := range args {
+		vals[i] = arg.Value
+	}
+	return vals
+}
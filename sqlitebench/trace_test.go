@@ -0,0 +1,104 @@
+package sqlitebench
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTraceDriverReplay(t *testing.T) {
+	plain, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer plain.Close()
+
+	var buf bytes.Buffer
+	sql.Register("sqlite-trace-test", TraceDriver(plain.Driver(), &buf))
+	db, err := sql.Open("sqlite-trace-test", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	db.Exec("CREATE TABLE kv (k INTEGER, v BLOB)")
+	db.Exec("INSERT INTO kv (k, v) VALUES (?, ?)", 1, []byte{0, 1, 2})
+	var v []byte
+	db.QueryRow("SELECT v FROM kv WHERE k = ?", 1).Scan(&v)
+	db.Close()
+
+	trace, err := ReadTrace(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trace) != 3 {
+		t.Fatalf("recorded %d statements, want 3:\n%s", len(trace), buf.String())
+	}
+	if want := []any{int64(1), []byte{0, 1, 2}}; !reflect.DeepEqual(trace[1].Args, want) {
+		t.Errorf("insert args = %#v, want %#v", trace[1].Args, want)
+	}
+
+	result, err := (&Replay{Trace: trace}).Run(context.Background(), "modernc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Statements) != 3 || result.Errors() != 0 || len(result.Samples()) != 3 {
+		t.Errorf("replay = %+v, want 3 statements without errors", result)
+	}
+}
+
+func TestReadTracePlain(t *testing.T) {
+	trace, err := ReadTrace(strings.NewReader("-- comment\nCREATE TABLE t (x);\n\nSELECT * FROM t\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []TraceEntry{{SQL: "CREATE TABLE t (x)"}, {SQL: "SELECT * FROM t"}}
+	if !reflect.DeepEqual(trace, want) {
+		t.Errorf("trace = %+v, want %+v", trace, want)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	samples := []time.Duration{5, 1, 4, 2, 3}
+	if got := Percentile(samples, 50); got != 3 {
+		t.Errorf("p50 = %v, want 3", got)
+	}
+	if got := Percentile(samples, 100); got != 5 {
+		t.Errorf("p100 = %v, want 5", got)
+	}
+	if got := Percentile(nil, 50); got != 0 {
+		t.Errorf("p50 of nothing = %v, want 0", got)
+	}
+}
+
+func TestRunStatement(t *testing.T) {
+	ctx := context.Background()
+	for _, name := range []string{"mattn", "modernc"} {
+		db, err := Drivers[name].Open(ctx, memoryDSN())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+		for _, stmt := range []string{
+			"CREATE TABLE t (id INTEGER PRIMARY KEY, name TEXT, data BLOB, score REAL, note TEXT)",
+			"INSERT INTO t VALUES (1, 'abc', x'0102030405', 1.5, NULL)",
+		} {
+			if err := db.Exec(ctx, stmt); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		// 8 bytes each for id, score and count(*), 3 for name, 5 for
+		// data and none for the NULL note.
+		var read rowBytes
+		if err := runStatement(ctx, db, "SELECT id, name, data, score, note, (SELECT count(*) FROM t) FROM t", nil, &read); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if got := read.BytesRead(); got != 32 {
+			t.Errorf("%s: read %d bytes, want 32", name, got)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package sqlitebench
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+)
+
+const (
+	// vectorClusters is the number of topics embeddings cluster around,
+	// as those of a document collection do.
+	vectorClusters = 64
+	// vectorK is the number of nearest neighbours queried.
+	vectorK = 10
+)
+
+// Vector measures local vector search, as retrieval-augmented generation
+// keeps it in SQLite, with the sqlite-vec extension: the vector.insert
+// workload stores a chunk of text, the payload, with its embedding per
+// operation, and vector.query finds the chunks of the vectorK embeddings
+// nearest to a query embedding among Params.Rows. Embeddings are random
+// unit vectors clustered around vectorClusters topics, stored as float32
+// blobs in a vec0 virtual table.
+//
+// Like extension.call, the workloads load the extension into every
+// connection and need the "load_extension" capability, so drivers that
+// cannot load extensions skip them.
+type Vector struct {
+	// Path is the sqlite-vec shared library, e.g. ./vec0.so, and Entry its
+	// entry point, derived from the file name if empty as for Extension.
+	Path  string
+	Entry string
+	// Dimensions is the length of the embeddings.
+	Dimensions int
+}
+
+// Validate checks that the extension and embeddings can be measured.
+func (v *Vector) Validate() error {
+	if v.Path == "" {
+		return errors.New("vector extension path is empty")
+	}
+	if v.Dimensions < 1 {
+		return fmt.Errorf("vector dimensions must be positive, got %d", v.Dimensions)
+	}
+	return nil
+}
+
+// Workloads returns the vector.insert and vector.query workloads.
+func (v *Vector) Workloads() []Workload {
+	return []Workload{newVectorWorkload(v, false), newVectorWorkload(v, true)}
+}
+
+type vectorWorkload struct {
+	rowBytes
+
+	v     *Vector
+	query bool
+	ext   extensionWorkload
+
+	payloads *PayloadPool
+	topics   [][]float32
+	rng      lockedRand
+}
+
+func newVectorWorkload(v *Vector, query bool) *vectorWorkload {
+	return &vectorWorkload{v: v, query: query, ext: extensionWorkload{e: &Extension{Path: v.Path, Entry: v.Entry}, op: "call"}}
+}
+
+func (w *vectorWorkload) Name() string {
+	if w.query {
+		return "vector.query"
+	}
+	return "vector.insert"
+}
+
+func (w *vectorWorkload) Description() string {
+	if w.query {
+		return fmt.Sprintf("find the %d nearest of %d-dimensional embeddings per operation", vectorK, w.v.Dimensions)
+	}
+	return fmt.Sprintf("insert a chunk with a %d-dimensional embedding per operation", w.v.Dimensions)
+}
+
+func (*vectorWorkload) Requires() []string { return []string{"load_extension"} }
+
+func (w *vectorWorkload) Setup(ctx context.Context, db Conn, p Params) error {
+	w.reset()
+	if err := w.ext.Setup(ctx, db, p); err != nil {
+		return err
+	}
+	w.payloads = p.Payloads
+	w.rng.seed(p)
+	w.topics = make([][]float32, vectorClusters)
+	for i := range w.topics {
+		w.topics[i] = w.embedding(nil, 1)
+	}
+	for _, stmt := range []string{
+		fmt.Sprintf("CREATE VIRTUAL TABLE embeddings USING vec0(embedding float[%d])", w.v.Dimensions),
+		"CREATE TABLE chunks (id INTEGER PRIMARY KEY, text BLOB)",
+	} {
+		if err := db.Exec(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	if !w.query {
+		return nil
+	}
+	return populate(ctx, db, p.Rows, func(tx Tx, i int) error {
+		return w.insert(ctx, tx.Exec, int64(i+1))
+	})
+}
+
+// embedding returns a random unit vector: near a random topic, if there
+// are topics, with noise of the given spread.
+func (w *vectorWorkload) embedding(topics [][]float32, noise float64) []float32 {
+	e := make([]float32, w.v.Dimensions)
+	var norm float64
+	w.rng.do(func(rng *rand.Rand) {
+		var topic []float32
+		if len(topics) > 0 {
+			topic = topics[rng.IntN(len(topics))]
+		}
+		for i := range e {
+			x := rng.NormFloat64() * noise
+			if topic != nil {
+				x += float64(topic[i])
+			}
+			e[i] = float32(x)
+			norm += x * x
+		}
+	})
+	for i := range e {
+		e[i] /= float32(math.Sqrt(norm))
+	}
+	return e
+}
+
+// vectorBlob encodes an embedding as sqlite-vec's float32 blobs.
+func vectorBlob(e []float32) []byte {
+	b := make([]byte, 4*len(e))
+	for i, x := range e {
+		binary.LittleEndian.PutUint32(b[4*i:], math.Float32bits(x))
+	}
+	return b
+}
+
+// insert inserts chunk id with exec.
+func (w *vectorWorkload) insert(ctx context.Context, exec func(ctx context.Context, query string, args ...any) error, id int64) error {
+	e := w.embedding(w.topics, 0.5/math.Sqrt(float64(w.v.Dimensions)))
+	if err := exec(ctx, "INSERT INTO embeddings (rowid, embedding) VALUES (?, ?)", id, vectorBlob(e)); err != nil {
+		return err
+	}
+	return exec(ctx, "INSERT INTO chunks (id, text) VALUES (?, ?)", id, w.payloads.Next())
+}
+
+// Run loads the extension into connections other than the one given to
+// Setup on their first operation, which that operation's sample includes.
+func (w *vectorWorkload) Run(ctx context.Context, db Conn, n int) error {
+	if err := w.ext.loadOnce(ctx, db); err != nil {
+		return err
+	}
+	if !w.query {
+		// Operation numbers are unique, so they serve as chunk ids.
+		tx, err := db.Begin(ctx)
+		if err != nil {
+			return err
+		}
+		if err := w.insert(ctx, tx.Exec, int64(n+1)); err != nil {
+			tx.Rollback()
+			return err
+		}
+		return tx.Commit()
+	}
+	e := w.embedding(w.topics, 0.5/math.Sqrt(float64(w.v.Dimensions)))
+	return runStatement(ctx, db, `SELECT c.id, c.text, v.distance
+		FROM (SELECT rowid, distance FROM embeddings WHERE embedding MATCH ? AND k = ?) v JOIN chunks c ON c.id = v.rowid
+		ORDER BY v.distance`, []any{vectorBlob(e), vectorK}, &w.rowBytes)
+}
+
+func (w *vectorWorkload) Teardown(db Conn) error { return w.ext.Teardown(db) }
@@ -0,0 +1,45 @@
+package sqlitebench
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestVectorWorkloads(t *testing.T) {
+	v := &Vector{Path: "vec0.so", Dimensions: 3}
+	if err := v.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, w := range v.Workloads() {
+		names = append(names, w.Name())
+		if req := w.(Requirer).Requires(); !slices.Equal(req, []string{"load_extension"}) {
+			t.Errorf("%s requires %v, want load_extension", w.Name(), req)
+		}
+	}
+	if want := []string{"vector.insert", "vector.query"}; !slices.Equal(names, want) {
+		t.Errorf("workloads = %v, want %v", names, want)
+	}
+	if got := v.Workloads()[0].(*vectorWorkload).ext.e.entry(); got != "sqlite3_vec_init" {
+		t.Errorf("entry = %q, want sqlite3_vec_init", got)
+	}
+
+	// sqlite-vec reads embeddings as little-endian float32s.
+	if got, want := vectorBlob([]float32{1, -2}), []byte{0, 0, 0x80, 0x3f, 0, 0, 0, 0xc0}; !slices.Equal(got, want) {
+		t.Errorf("blob = %x, want %x", got, want)
+	}
+	w := newVectorWorkload(v, true)
+	w.rng.seed(Params{Seed: 1, Rows: 2})
+	var norm float64
+	for _, x := range w.embedding([][]float32{{1, 0, 0}}, 0.1) {
+		norm += float64(x) * float64(x)
+	}
+	if math.Abs(norm-1) > 1e-6 {
+		t.Errorf("embedding norm = %g, want 1", norm)
+	}
+
+	if err := (&Vector{Path: "vec0.so"}).Validate(); err == nil {
+		t.Error("expected an error for no dimensions")
+	}
+}
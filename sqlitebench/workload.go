@@ -0,0 +1,666 @@
+package sqlitebench
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Workload is one kind of operation measured per driver and payload size.
+// The runner opens a fresh database for every cell, calls Setup once, times
+// Run for each operation and calls Teardown before closing the database. A
+// Workload value is used for one cell at a time, so it may keep the state
+// Setup prepares in its fields.
+type Workload interface {
+	// Name identifies the workload in configs, filters and results.
+	Name() string
+	// Description is a short summary shown by the list subcommand.
+	Description() string
+	// Setup prepares db, which already has the test table, for the cell.
+	Setup(ctx context.Context, db Conn, p Params) error
+	// Run performs operation n, counting from zero. In cells with a
+	// Concurrency above 1 it is called from that many goroutines at once,
+	// each passing its own connection to the cell's database.
+	Run(ctx context.Context, db Conn, n int) error
+	// Teardown releases whatever Setup acquired.
+	Teardown(db Conn) error
+}
+
+// Params describe the cell a workload is set up for.
+type Params struct {
+	DataSize int
+	// Rows is the number of rows to insert before reading.
+	Rows int
+	// Payloads generates the cell's payloads from the runner's seed and
+	// compressibility.
+	Payloads *PayloadPool
+	// Verify asks the workload to check the data it reads back.
+	Verify bool
+	// Seed is the runner's seed, for workloads generating their own data.
+	Seed int64
+	// Open opens a connection to another database, e.g. "file:" and a
+	// path, with the cell's driver and pragmas, for workloads managing
+	// databases of their own. It is nil for backends other than SQLite
+	// drivers.
+	Open func(ctx context.Context, dsn string) (Conn, error)
+}
+
+// Verifier is implemented by workloads that check the data they read back
+// when Params.Verify is set; their results are marked Verified.
+type Verifier interface {
+	Verifies() bool
+}
+
+// ReadCounter is implemented by workloads that count the bytes of the
+// values they read; the count of the timed loop is kept in
+// Result.BytesRead.
+type ReadCounter interface {
+	BytesRead() uint64
+}
+
+// rowBytes implements ReadCounter for workloads embedding it. The count is
+// atomic as concurrent cells run a workload on several connections.
+type rowBytes struct {
+	n atomic.Uint64
+}
+
+func (b *rowBytes) BytesRead() uint64 { return b.n.Load() }
+func (b *rowBytes) add(data []byte)   { b.n.Add(uint64(len(data))) }
+func (b *rowBytes) reset()            { b.n.Store(0) }
+
+// addValue counts a value scanned into dest by runStatement: the length of
+// text and blobs and 8 bytes for numbers.
+func (b *rowBytes) addValue(dest any) {
+	switch d := dest.(type) {
+	case *[]byte:
+		b.add(*d)
+	case *sql.NullString:
+		b.n.Add(uint64(len(d.String)))
+	case *sql.NullInt64:
+		if d.Valid {
+			b.n.Add(8)
+		}
+	case *sql.NullFloat64:
+		if d.Valid {
+			b.n.Add(8)
+		}
+	case *any:
+		switch v := (*d).(type) {
+		case []byte:
+			b.add(v)
+		case string:
+			b.n.Add(uint64(len(v)))
+		case int64, float64:
+			b.n.Add(8)
+		}
+	}
+}
+
+// PhaseTimer is implemented by workloads that time some operations by the
+// phase they ran in, e.g. lookups during a cleanup and outside one; the
+// times of the timed loop are kept in Result.Phases.
+type PhaseTimer interface {
+	// Phases returns the times recorded since the last call and clears
+	// them.
+	Phases() map[string][]time.Duration
+}
+
+// phaseTimes implements PhaseTimer for workloads embedding it.
+type phaseTimes struct {
+	mu    sync.Mutex
+	times map[string][]time.Duration
+}
+
+func (p *phaseTimes) Phases() map[string][]time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	times := p.times
+	p.times = nil
+	return times
+}
+
+func (p *phaseTimes) record(phase string, d time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.times == nil {
+		p.times = map[string][]time.Duration{}
+	}
+	p.times[phase] = append(p.times[phase], d)
+}
+
+// lockedRand is the random source of a workload's operations, locked as
+// concurrent cells run them on several connections.
+type lockedRand struct {
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+// seed restarts the source from the seed and rows of p, so cells with the
+// same parameters draw the same values.
+func (r *lockedRand) seed(p Params) {
+	r.do(func(*rand.Rand) { r.rng = rand.New(rand.NewPCG(uint64(p.Seed), uint64(p.Rows))) })
+}
+
+// do calls f with the source locked, for draws that belong together.
+func (r *lockedRand) do(f func(rng *rand.Rand)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	f(r.rng)
+}
+
+// intN returns a random int in [0, n).
+func (r *lockedRand) intN(n int) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rng.IntN(n)
+}
+
+// int64N returns a random int64 in [0, n).
+func (r *lockedRand) int64N(n int64) int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rng.Int64N(n)
+}
+
+// GrowthSampler is implemented by workloads that sample the size of their
+// database as operations run, e.g. to show an index bloating; the samples
+// of the timed loop are kept in Result.Growth.
+type GrowthSampler interface {
+	// Growth returns the samples taken since the last call and clears
+	// them.
+	Growth() []SizeSample
+}
+
+// SizeSample is the size of a database at an operation.
+type SizeSample struct {
+	// Op is the number of the operation the sample was taken at.
+	Op int `json:"op"`
+	// Bytes is the size of the database's pages and FreeBytes that of the
+	// pages on its freelist.
+	Bytes     int64 `json:"bytes"`
+	FreeBytes int64 `json:"free_bytes"`
+	// IndexBytes is the size of the pages of the index the workload
+	// samples and IndexPayload that of the entries on them, the rest being
+	// unused. They are zero for drivers without the dbstat table.
+	IndexBytes   int64 `json:"index_bytes,omitempty"`
+	IndexPayload int64 `json:"index_payload,omitempty"`
+}
+
+// sizeSamples implements GrowthSampler for workloads embedding it.
+type sizeSamples struct {
+	mu      sync.Mutex
+	samples []SizeSample
+	// dbstat is set when the database has the dbstat table.
+	dbstat bool
+}
+
+func (s *sizeSamples) Growth() []SizeSample {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	samples := s.samples
+	s.samples = nil
+	return samples
+}
+
+// probe checks whether db has the dbstat table, which SQLite only has when
+// built with it.
+func (s *sizeSamples) probe(ctx context.Context, db Conn) {
+	var n int
+	s.dbstat = QueryRow(ctx, db, "SELECT count(*) FROM dbstat WHERE name = 'sqlite_schema'", &n) == nil
+}
+
+// sample samples the size of db and of index at operation op.
+func (s *sizeSamples) sample(ctx context.Context, db Conn, op int, index string) error {
+	sample := SizeSample{Op: op}
+	var pages, free, pageSize int64
+	if err := QueryRow(ctx, db, "SELECT * FROM pragma_page_count, pragma_freelist_count, pragma_page_size", &pages, &free, &pageSize); err != nil {
+		return err
+	}
+	sample.Bytes, sample.FreeBytes = pages*pageSize, free*pageSize
+	if s.dbstat {
+		q := fmt.Sprintf("SELECT coalesce(sum(pgsize), 0), coalesce(sum(payload), 0) FROM dbstat WHERE name = '%s'", index)
+		if err := QueryRow(ctx, db, q, &sample.IndexBytes, &sample.IndexPayload); err != nil {
+			return err
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.samples = append(s.samples, sample)
+	return nil
+}
+
+// Workload categories, as given in Benchmark.Category.
+const (
+	CategoryWrite       = "write"
+	CategoryRead        = "read"
+	CategoryMaintenance = "maintenance"
+	CategoryConcurrency = "concurrency"
+	CategoryAnalytical  = "analytical"
+)
+
+// Categories lists the valid categories.
+var Categories = []string{CategoryWrite, CategoryRead, CategoryMaintenance, CategoryConcurrency, CategoryAnalytical}
+
+// Benchmark is a registry entry: how to create a workload and what the
+// tooling needs to know about it before running it.
+type Benchmark struct {
+	// ID names the workload in configs, filters and results. It must stay
+	// the same across versions so results remain comparable.
+	ID string
+	// Description is a short summary shown by the list subcommand.
+	Description string
+	// Category is one of Categories.
+	Category string
+	// Requires lists driver capabilities, as found by CheckDriver, the
+	// workload needs, e.g. "fts5".
+	Requires []string
+	// New returns a workload ready for Setup.
+	New func() Workload
+}
+
+// Workloads maps IDs to the registered workloads.
+var Workloads = map[string]Benchmark{}
+
+// Register makes a workload selectable by ID. Workloads call it from an
+// init function in their own file. ID and Description default to those of
+// the workload New returns.
+func Register(b Benchmark) {
+	w := b.New()
+	if b.ID == "" {
+		b.ID = w.Name()
+	}
+	if b.Description == "" {
+		b.Description = w.Description()
+	}
+	if b.ID != w.Name() {
+		panic("sqlitebench: workload " + w.Name() + " registered as " + b.ID)
+	}
+	if !slices.Contains(Categories, b.Category) {
+		panic("sqlitebench: workload " + b.ID + " has unknown category " + strconv.Quote(b.Category))
+	}
+	if _, ok := Workloads[b.ID]; ok {
+		panic("sqlitebench: workload " + b.ID + " registered twice")
+	}
+	Workloads[b.ID] = b
+}
+
+// Requirer is implemented by workloads that are added to a runner without
+// being registered and need driver capabilities, like Benchmark.Requires.
+type Requirer interface {
+	Requires() []string
+}
+
+// requires returns the capabilities the workload named needs.
+func (r *Runner) requires(workload string) []string {
+	if b, ok := Workloads[workload]; ok {
+		return b.Requires
+	}
+	for _, w := range r.workloads {
+		if req, ok := w.(Requirer); ok && w.Name() == workload {
+			return req.Requires()
+		}
+	}
+	return nil
+}
+
+// supports reports whether a driver with the capabilities has everything
+// required.
+func supports(capabilities, required []string) bool {
+	for _, req := range required {
+		if !slices.Contains(capabilities, req) {
+			return false
+		}
+	}
+	return true
+}
+
+// WorkloadNames returns the registered workload names in sorted order.
+func WorkloadNames() []string {
+	names := make([]string, 0, len(Workloads))
+	for name := range Workloads {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CellSession is one cell's workload set up on a fresh database, ready to
+// run operations. The runner times it; other harnesses, such as go test
+// benchmarks, drive it with their own loop.
+type CellSession struct {
+	ctx   context.Context
+	w     Workload
+	db    Conn
+	conns []Conn
+	// kv is set instead of db in cells on a KV store.
+	kv KVStore
+	// repl ships the database's WAL in cells with replication.
+	repl replicator
+	// cleanup closes the connections and removes the database files.
+	cleanup []func()
+	// setup is how long OpenCell took.
+	setup time.Duration
+	// busyRetries is Runner.BusyRetries; retries counts the retries.
+	busyRetries int
+	retries     retryCounts
+}
+
+// SetupTime returns how long opening the database and setting up the
+// workload took, which operations are timed without.
+func (s *CellSession) SetupTime() time.Duration { return s.setup }
+
+// OpenCell opens the cell's database with its pragmas, storage and
+// connections and sets up its workload. Close the session when done.
+func (r *Runner) OpenCell(ctx context.Context, c Cell) (*CellSession, error) {
+	start := time.Now()
+	i := slices.IndexFunc(r.workloads, func(w Workload) bool { return w.Name() == c.Workload })
+	if i < 0 {
+		return nil, fmt.Errorf("unknown workload %q", c.Workload)
+	}
+	s := &CellSession{ctx: ctx, w: r.workloads[i], busyRetries: r.BusyRetries}
+	p := Params{
+		DataSize: c.DataSize,
+		Rows:     r.Rows,
+		Payloads: NewPayloadPool(r.Seed, r.Compressibility, c.DataSize),
+		Verify:   r.Verify,
+		Seed:     r.Seed,
+	}
+	if open, ok := KVStores[c.Driver]; ok {
+		if err := s.openKV(open, p); err != nil {
+			return nil, err
+		}
+		s.setup = time.Since(start)
+		return s, nil
+	}
+
+	dsn, dir, memdb := memoryDSN(), "", false
+	switch {
+	case c.Storage == StorageFile || c.Storage == StorageTmpfs || c.Replication != "":
+		parent := ""
+		if c.Storage == StorageTmpfs {
+			parent = TmpfsDir
+		}
+		var remove func()
+		var err error
+		if dir, remove, err = MakeTempDir(parent, "db-"); err != nil {
+			return nil, err
+		}
+		s.cleanup = append(s.cleanup, remove)
+		dsn = filepath.Join(dir, "bench.db")
+	case c.Storage == StorageMemDB:
+		dsn, memdb = fmt.Sprintf("file:/sqlitebench-%d?vfs=memdb", memorySeq.Add(1)), true
+	}
+	// A memdb database has no files whose I/O could be counted.
+	if r.IOCounters && !memdb {
+		if vfs, ok, err := ioVFS(c.Driver); ok {
+			if err != nil {
+				s.close()
+				return nil, fmt.Errorf("counting VFS: %w", err)
+			}
+			dsn = withVFS(dsn, vfs)
+		}
+	}
+	pragmas := r.cellPragmas(c)
+	if _, ok := OLAPBackends[c.Driver]; ok {
+		dsn, pragmas = olapDSN, nil
+	}
+	if _, ok := r.Servers[c.Driver]; ok {
+		// Server backends connect to their own DSN.
+		dsn, pragmas = "", nil
+	}
+	b, ok := r.backend(c.Driver)
+	if !ok {
+		s.close()
+		return nil, fmt.Errorf("unknown driver %q", c.Driver)
+	}
+	db, err := openDB(ctx, b, dsn, pragmas)
+	if err != nil {
+		s.close()
+		return nil, err
+	}
+	if _, ok := Drivers[c.Driver]; ok {
+		p.Open = func(ctx context.Context, dsn string) (Conn, error) { return openConn(ctx, b, dsn, pragmas) }
+	}
+	s.db, s.conns = db, []Conn{db}
+	s.cleanup = append(s.cleanup, func() { db.Close() })
+	for range c.Concurrency - 1 {
+		conn, err := openConn(ctx, b, dsn, pragmas)
+		if err != nil {
+			s.close()
+			return nil, fmt.Errorf("opening connection %d: %w", len(s.conns), err)
+		}
+		s.conns = append(s.conns, conn)
+		s.cleanup = append(s.cleanup, func() { conn.Close() })
+	}
+
+	if err := s.w.Setup(ctx, db, p); err != nil {
+		s.close()
+		return nil, fmt.Errorf("setup: %w", err)
+	}
+	if r.WrapConn != nil {
+		for i, conn := range s.conns {
+			s.conns[i] = r.WrapConn(c, conn)
+		}
+		s.db = s.conns[0]
+	}
+
+	// Replication starts once the table is prepared, so it covers the
+	// measured operations only.
+	if c.Replication != "" && c.Replication != ReplicationOff {
+		replica := filepath.Join(dir, "replica")
+		if err := os.Mkdir(replica, 0o755); err != nil {
+			s.close()
+			return nil, err
+		}
+		repl, err := startReplication(c.Replication, filepath.Join(dir, "bench.db"), replica)
+		if err != nil {
+			s.close()
+			return nil, err
+		}
+		s.repl = repl
+		s.cleanup = append(s.cleanup, func() {
+			if s.repl != nil {
+				s.repl.Stop()
+			}
+		})
+	}
+	s.setup = time.Since(start)
+	return s, nil
+}
+
+// openKV opens a KV store in a temporary directory and sets up the
+// session's workload on it.
+func (s *CellSession) openKV(open func(dir string) (KVStore, error), p Params) error {
+	w, ok := s.w.(KVWorkload)
+	if !ok {
+		return fmt.Errorf("workload %s does not run on KV stores", s.w.Name())
+	}
+	dir, remove, err := MakeTempDir("", "kv-")
+	if err != nil {
+		return err
+	}
+	s.cleanup = append(s.cleanup, remove)
+	kv, err := open(dir)
+	if err != nil {
+		s.close()
+		return err
+	}
+	s.kv = kv
+	s.cleanup = append(s.cleanup, func() { kv.Close() })
+	if err := w.SetupKV(s.ctx, kv, p); err != nil {
+		s.close()
+		return fmt.Errorf("setup: %w", err)
+	}
+	return nil
+}
+
+// Run performs operation n. In cells with a Concurrency above 1 it is a
+// round of concurrent operations, one per connection, each retried on its
+// own when it finds the database locked.
+func (s *CellSession) Run(n int) error {
+	if s.kv != nil {
+		return s.w.(KVWorkload).RunKV(s.ctx, s.kv, n)
+	}
+	if len(s.conns) == 1 {
+		return s.run(s.db, n)
+	}
+
+	errs := make([]error, len(s.conns))
+	var wg sync.WaitGroup
+	for i, conn := range s.conns {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = s.run(conn, n*len(s.conns)+i)
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// run performs operation n on conn, with the session's busy retries.
+func (s *CellSession) run(conn Conn, n int) error {
+	if s.busyRetries == 0 {
+		return s.w.Run(s.ctx, conn, n)
+	}
+	return s.retries.retry(s.ctx, s.busyRetries, func() error { return s.w.Run(s.ctx, conn, n) })
+}
+
+// Close stops replication, tears the workload down and closes the
+// database.
+func (s *CellSession) Close() error {
+	defer s.close()
+	if s.repl != nil {
+		err := s.repl.Stop()
+		s.repl = nil
+		if err != nil {
+			return fmt.Errorf("replication: %w", err)
+		}
+	}
+	if s.kv != nil {
+		return nil
+	}
+	if err := s.w.Teardown(s.db); err != nil {
+		return fmt.Errorf("teardown: %w", err)
+	}
+	return nil
+}
+
+func (s *CellSession) close() {
+	for i := len(s.cleanup) - 1; i >= 0; i-- {
+		s.cleanup[i]()
+	}
+	s.cleanup = nil
+}
+
+// measureCell runs one cell on a fresh database.
+func (r *Runner) measureCell(ctx context.Context, c Cell) (Result, error) {
+	s, err := r.OpenCell(ctx, c)
+	if err != nil {
+		if ctx.Err() != nil {
+			return Result{Driver: c.Driver, Operation: c.Operation(), DataSize: c.DataSize, TimedOut: true}, nil
+		}
+		return Result{}, err
+	}
+	defer s.close()
+
+	var io *countingVFS
+	var ioBefore IOCounters
+	if r.IOCounters {
+		if io = ioVFSes[c.Driver]; io != nil {
+			ioBefore = io.read()
+		}
+	}
+	rc, _ := s.w.(ReadCounter)
+	var readBefore uint64
+	if rc != nil {
+		readBefore = rc.BytesRead()
+	}
+	pt, _ := s.w.(PhaseTimer)
+	if pt != nil {
+		// Drop the times recorded by Setup.
+		pt.Phases()
+	}
+	gs, _ := s.w.(GrowthSampler)
+	if gs != nil {
+		gs.Growth()
+	}
+	n := 0
+	result, err := r.measure(ctx, fmt.Sprintf("%s_%s_%dBytes", c.Driver, c.Operation(), c.DataSize), func() error {
+		err := s.Run(n)
+		n++
+		return err
+	})
+	if err != nil {
+		return Result{}, fmt.Errorf("operation %d: %w", n-1, err)
+	}
+	if io != nil {
+		counts := io.read().sub(ioBefore)
+		result.IO = &counts
+	}
+	if rc != nil {
+		result.BytesRead = rc.BytesRead() - readBefore
+	}
+	if pt != nil {
+		result.Phases = pt.Phases()
+	}
+	if gs != nil {
+		result.Growth = gs.Growth()
+	}
+	if retries := s.retries.read(); retries != (ErrorCounts{}) {
+		if result.Errors == nil {
+			result.Errors = &ErrorCounts{}
+		}
+		result.Errors.add(retries)
+	}
+	if err := s.Close(); err != nil {
+		return Result{}, err
+	}
+
+	result.Driver, result.Operation, result.DataSize = c.Driver, c.Operation(), c.DataSize
+	result.Category = Workloads[c.Workload].Category
+	result.SQLiteVersion = r.SQLiteVersions[c.Driver]
+	result.Setup = s.SetupTime()
+	if v, ok := s.w.(Verifier); ok && r.Verify {
+		result.Verified = v.Verifies()
+	}
+	return result, nil
+}
+
+// cellPragmas returns the runner's pragmas followed by those the cell's
+// dimensions set.
+func (r *Runner) cellPragmas(c Cell) []string {
+	pragmas := slices.Clone(r.Pragmas)
+	// The page size cannot change once the database is in WAL mode.
+	if c.PageSize != 0 {
+		pragmas = append(pragmas, "page_size="+strconv.Itoa(c.PageSize))
+	}
+	if c.CacheSize != 0 {
+		pragmas = append(pragmas, "cache_size="+strconv.Itoa(c.CacheSize))
+	}
+	if c.JournalMode != "" {
+		pragmas = append(pragmas, "journal_mode="+c.JournalMode)
+	}
+	if c.Replication != "" {
+		pragmas = append(pragmas, "journal_mode=wal")
+	}
+	if c.Synchronous != "" {
+		pragmas = append(pragmas, "synchronous="+c.Synchronous)
+	}
+	if c.Concurrency > 1 {
+		// Concurrent writers wait for each other's locks instead of
+		// failing at once.
+		pragmas = append(pragmas, "busy_timeout=5000")
+	}
+	return pragmas
+}
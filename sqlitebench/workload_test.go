@@ -0,0 +1,43 @@
+package sqlitebench
+
+import (
+	"context"
+	"testing"
+)
+
+// openMemory opens an in-memory modernc database for a test, closed when
+// the test ends.
+func openMemory(t *testing.T) Conn {
+	t.Helper()
+	db, err := Drivers["modernc"].Open(context.Background(), memoryDSN())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// setupWorkload sets w up with p on a database from openMemory and returns
+// the database. Without Payloads, p gets a pool of 64-byte payloads.
+func setupWorkload(t *testing.T, w Workload, p Params) Conn {
+	t.Helper()
+	db := openMemory(t)
+	if p.Payloads == nil {
+		p.Payloads = NewPayloadPool(1, 0, 64)
+	}
+	if err := w.Setup(context.Background(), db, p); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// runOps runs operations 0 to ops-1 of w on db, failing the test at the
+// first error.
+func runOps(t *testing.T, w Workload, db Conn, ops int) {
+	t.Helper()
+	for n := range ops {
+		if err := w.Run(context.Background(), db, n); err != nil {
+			t.Fatalf("operation %d: %v", n, err)
+		}
+	}
+}
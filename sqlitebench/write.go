@@ -0,0 +1,29 @@
+package sqlitebench
+
+import (
+	"context"
+)
+
+func init() {
+	Register(Benchmark{Category: CategoryWrite, New: func() Workload { return &Write{} }})
+}
+
+// Write inserts one row per operation, with the blob and label of the
+// operation's row number.
+type Write struct {
+	payloads *PayloadPool
+}
+
+func (*Write) Name() string        { return "write" }
+func (*Write) Description() string { return "insert one blob per operation" }
+
+func (w *Write) Setup(ctx context.Context, db Conn, p Params) error {
+	w.payloads = p.Payloads
+	return nil
+}
+
+func (w *Write) Run(ctx context.Context, db Conn, n int) error {
+	return db.Exec(ctx, "INSERT INTO test (data, label) VALUES (?, ?)", w.payloads.Row(n), RowLabel(n))
+}
+
+func (*Write) Teardown(db Conn) error { return nil }
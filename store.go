@@ -0,0 +1,394 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"sqlite_benchmark/sqlitebench"
+)
+
+// resultsDBPath is the results store every run is appended to.
+var resultsDBPath = flag.String("db", "results.db", "results store every run is appended to (empty to disable)")
+
+// The results store is written with the pure-Go modernc driver so it works
+// without cgo, whichever drivers are being benchmarked.
+//
+// storeMigrations bring a store up to date when applied in order. The
+// number applied is the store's version, kept in PRAGMA user_version.
+// Stores from before versioning are at version 0 but already have the
+// tables of the first migration, which only creates those missing. A
+// released migration is never edited; changes go in a new one.
+var storeMigrations = []string{
+	resultsStoreSchema,
+	// Version 2 keeps whether a result timed out or was verified.
+	`ALTER TABLE results ADD COLUMN timed_out INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE results ADD COLUMN verified INTEGER NOT NULL DEFAULT 0;`,
+	// Version 3 keeps the I/O counts of runs with -io.
+	`ALTER TABLE results ADD COLUMN io_reads INTEGER;
+ALTER TABLE results ADD COLUMN io_writes INTEGER;
+ALTER TABLE results ADD COLUMN io_syncs INTEGER;
+ALTER TABLE results ADD COLUMN io_read_bytes INTEGER;
+ALTER TABLE results ADD COLUMN io_written_bytes INTEGER;`,
+	// Version 4 keeps the SQLite version each result was measured on.
+	`ALTER TABLE results ADD COLUMN sqlite_version TEXT NOT NULL DEFAULT '';`,
+	// Version 5 keeps the bytes read workloads read.
+	`ALTER TABLE results ADD COLUMN bytes_read INTEGER NOT NULL DEFAULT 0;`,
+	// Version 6 keeps the counts of operations that failed with an
+	// expected error.
+	`ALTER TABLE results ADD COLUMN errors_busy INTEGER;
+ALTER TABLE results ADD COLUMN errors_constraint INTEGER;
+ALTER TABLE results ADD COLUMN errors_timeout INTEGER;`,
+	// Version 7 keeps the retries of operations that found the database
+	// locked.
+	`ALTER TABLE results ADD COLUMN errors_retried INTEGER;
+ALTER TABLE results ADD COLUMN errors_retries INTEGER;
+ALTER TABLE results ADD COLUMN errors_retry_ns INTEGER;`,
+}
+
+const resultsStoreSchema = `
+CREATE TABLE IF NOT EXISTS environments (
+	id         INTEGER PRIMARY KEY,
+	go_version TEXT NOT NULL,
+	goos       TEXT NOT NULL,
+	goarch     TEXT NOT NULL,
+	num_cpu    INTEGER NOT NULL,
+	cpu        TEXT NOT NULL,
+	hostname   TEXT NOT NULL,
+	modules    TEXT NOT NULL,
+	UNIQUE (go_version, goos, goarch, num_cpu, cpu, hostname, modules)
+);
+
+CREATE TABLE IF NOT EXISTS runs (
+	id             INTEGER PRIMARY KEY,
+	started_at     TEXT NOT NULL,
+	environment_id INTEGER NOT NULL REFERENCES environments (id)
+);
+
+CREATE TABLE IF NOT EXISTS run_tags (
+	run_id INTEGER NOT NULL REFERENCES runs (id),
+	key    TEXT NOT NULL,
+	value  TEXT NOT NULL,
+	PRIMARY KEY (run_id, key)
+);
+
+CREATE TABLE IF NOT EXISTS results (
+	id            INTEGER PRIMARY KEY,
+	run_id        INTEGER NOT NULL REFERENCES runs (id),
+	driver        TEXT NOT NULL,
+	operation     TEXT NOT NULL,
+	data_size     INTEGER NOT NULL,
+	duration_ns   INTEGER NOT NULL,
+	iterations    INTEGER NOT NULL,
+	allocs        INTEGER NOT NULL,
+	alloc_bytes   INTEGER NOT NULL,
+	instructions  INTEGER,
+	cache_misses  INTEGER,
+	branch_misses INTEGER
+);
+
+CREATE TABLE IF NOT EXISTS samples (
+	result_id   INTEGER NOT NULL REFERENCES results (id),
+	seq         INTEGER NOT NULL,
+	duration_ns INTEGER NOT NULL,
+	PRIMARY KEY (result_id, seq)
+);
+
+CREATE TABLE IF NOT EXISTS checkpoints (
+	id         INTEGER PRIMARY KEY,
+	config     TEXT NOT NULL,
+	started_at TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS checkpoint_cells (
+	checkpoint_id INTEGER NOT NULL REFERENCES checkpoints (id) ON DELETE CASCADE,
+	seq           INTEGER NOT NULL,
+	result        TEXT NOT NULL,
+	PRIMARY KEY (checkpoint_id, seq)
+);
+`
+
+func openResultsStore(path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, fmt.Errorf("opening results store: %w", err)
+	}
+
+	if err := migrateStore(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("creating results store schema in %s: %w", path, err)
+	}
+
+	return db, nil
+}
+
+// migrateStore applies the migrations the store lacks, each in its own
+// transaction with the version update.
+func migrateStore(db *sql.DB) error {
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return err
+	}
+	if version > len(storeMigrations) {
+		return fmt.Errorf("store version %d is newer than this build knows (up to %d)", version, len(storeMigrations))
+	}
+
+	for ; version < len(storeMigrations); version++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(storeMigrations[version]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migrating to version %d: %w", version+1, err)
+		}
+		if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", version+1)); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordHistory appends the run to the results store unless it is disabled.
+func recordHistory(meta RunMetadata, results []BenchmarkResult) error {
+	if *resultsDBPath == "" {
+		return nil
+	}
+
+	db, err := openResultsStore(*resultsDBPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	runID, err := saveRun(db, meta, results)
+	if err != nil {
+		return err
+	}
+	slog.Info("Stored run", "id", runID, "db", *resultsDBPath)
+	return nil
+}
+
+// saveRun records a run with its environment, results and samples in a
+// single transaction and returns the new run ID.
+func saveRun(db *sql.DB, meta RunMetadata, results []BenchmarkResult) (int64, error) {
+	ctx := context.Background()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("beginning results transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	modules, err := json.Marshal(meta.Modules)
+	if err != nil {
+		return 0, fmt.Errorf("encoding module versions: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT OR IGNORE INTO environments (go_version, goos, goarch, num_cpu, cpu, hostname, modules) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		meta.GoVersion, meta.GOOS, meta.GOARCH, meta.NumCPU, meta.CPU, meta.Hostname, string(modules))
+	if err != nil {
+		return 0, fmt.Errorf("storing environment: %w", err)
+	}
+
+	var envID int64
+	err = tx.QueryRowContext(ctx, `SELECT id FROM environments WHERE go_version = ? AND goos = ? AND goarch = ? AND num_cpu = ? AND cpu = ? AND hostname = ? AND modules = ?`,
+		meta.GoVersion, meta.GOOS, meta.GOARCH, meta.NumCPU, meta.CPU, meta.Hostname, string(modules)).Scan(&envID)
+	if err != nil {
+		return 0, fmt.Errorf("looking up environment: %w", err)
+	}
+
+	res, err := tx.ExecContext(ctx, "INSERT INTO runs (started_at, environment_id) VALUES (?, ?)", meta.Timestamp.Format(timeFormat), envID)
+	if err != nil {
+		return 0, fmt.Errorf("storing run: %w", err)
+	}
+	runID, _ := res.LastInsertId()
+
+	for key, value := range meta.Tags {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO run_tags (run_id, key, value) VALUES (?, ?, ?)", runID, key, value); err != nil {
+			return 0, fmt.Errorf("storing run tag: %w", err)
+		}
+	}
+
+	for _, r := range results {
+		var instructions, cacheMisses, branchMisses sql.NullInt64
+		if r.Counters != nil {
+			instructions = sql.NullInt64{Int64: int64(r.Counters.Instructions), Valid: true}
+			cacheMisses = sql.NullInt64{Int64: int64(r.Counters.CacheMisses), Valid: true}
+			branchMisses = sql.NullInt64{Int64: int64(r.Counters.BranchMisses), Valid: true}
+		}
+		var ioCounts [5]sql.NullInt64
+		if r.IO != nil {
+			for i, v := range []uint64{r.IO.Reads, r.IO.Writes, r.IO.Syncs, r.IO.ReadBytes, r.IO.WrittenBytes} {
+				ioCounts[i] = sql.NullInt64{Int64: int64(v), Valid: true}
+			}
+		}
+
+		var errCounts [6]sql.NullInt64
+		if r.Errors != nil {
+			for i, v := range []uint64{r.Errors.Busy, r.Errors.Constraint, r.Errors.Timeout, r.Errors.Retried, r.Errors.Retries, uint64(r.Errors.RetryTime)} {
+				errCounts[i] = sql.NullInt64{Int64: int64(v), Valid: true}
+			}
+		}
+
+		res, err := tx.ExecContext(ctx, `INSERT INTO results (run_id, driver, operation, data_size, duration_ns, iterations, allocs, alloc_bytes, instructions, cache_misses, branch_misses, timed_out, verified, io_reads, io_writes, io_syncs, io_read_bytes, io_written_bytes, sqlite_version, bytes_read, errors_busy, errors_constraint, errors_timeout, errors_retried, errors_retries, errors_retry_ns) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			runID, r.Driver, r.Operation, r.DataSize, r.Duration.Nanoseconds(), iterations(r), r.Allocs, r.Bytes, instructions, cacheMisses, branchMisses, r.TimedOut, r.Verified,
+			ioCounts[0], ioCounts[1], ioCounts[2], ioCounts[3], ioCounts[4], r.SQLiteVersion, r.BytesRead,
+			errCounts[0], errCounts[1], errCounts[2], errCounts[3], errCounts[4], errCounts[5])
+		if err != nil {
+			return 0, fmt.Errorf("storing result: %w", err)
+		}
+		resultID, _ := res.LastInsertId()
+
+		for i, sample := range r.Samples {
+			_, err := tx.ExecContext(ctx, "INSERT INTO samples (result_id, seq, duration_ns) VALUES (?, ?, ?)", resultID, i, sample.Nanoseconds())
+			if err != nil {
+				return 0, fmt.Errorf("storing sample: %w", err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("committing results: %w", err)
+	}
+
+	return runID, nil
+}
+
+// latestRunID returns the ID of the most recently stored run, or 0 if the
+// store is empty. Runs are ordered by ID rather than start time, which
+// clocks of other machines or imported results can put out of order.
+func latestRunID(db *sql.DB) (int64, error) {
+	var id int64
+	err := db.QueryRow("SELECT id FROM runs ORDER BY id DESC LIMIT 1").Scan(&id)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, fmt.Errorf("looking up latest run: %w", err)
+	}
+	return id, nil
+}
+
+// loadRun returns the results of a stored run, including samples.
+func loadRun(db *sql.DB, runID int64) ([]BenchmarkResult, error) {
+	rows, err := db.Query(`SELECT id, driver, operation, data_size, duration_ns, iterations, allocs, alloc_bytes, instructions, cache_misses, branch_misses, timed_out, verified, io_reads, io_writes, io_syncs, io_read_bytes, io_written_bytes, sqlite_version, bytes_read, errors_busy, errors_constraint, errors_timeout, errors_retried, errors_retries, errors_retry_ns FROM results WHERE run_id = ? ORDER BY id`, runID)
+	if err != nil {
+		return nil, fmt.Errorf("querying run %d: %w", runID, err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	var iters []int
+	var results []BenchmarkResult
+	for rows.Next() {
+		var id, durationNs int64
+		var n int
+		var r BenchmarkResult
+		var instructions, cacheMisses, branchMisses sql.NullInt64
+		var io [5]sql.NullInt64
+		var errCounts [6]sql.NullInt64
+		if err := rows.Scan(&id, &r.Driver, &r.Operation, &r.DataSize, &durationNs, &n, &r.Allocs, &r.Bytes, &instructions, &cacheMisses, &branchMisses, &r.TimedOut, &r.Verified,
+			&io[0], &io[1], &io[2], &io[3], &io[4], &r.SQLiteVersion, &r.BytesRead,
+			&errCounts[0], &errCounts[1], &errCounts[2], &errCounts[3], &errCounts[4], &errCounts[5]); err != nil {
+			return nil, fmt.Errorf("reading run %d: %w", runID, err)
+		}
+		r.Duration = time.Duration(durationNs)
+		if instructions.Valid {
+			r.Counters = &sqlitebench.PerfCounters{
+				Instructions: uint64(instructions.Int64),
+				CacheMisses:  uint64(cacheMisses.Int64),
+				BranchMisses: uint64(branchMisses.Int64),
+			}
+		}
+		if io[0].Valid {
+			r.IO = &sqlitebench.IOCounters{
+				Reads:        uint64(io[0].Int64),
+				Writes:       uint64(io[1].Int64),
+				Syncs:        uint64(io[2].Int64),
+				ReadBytes:    uint64(io[3].Int64),
+				WrittenBytes: uint64(io[4].Int64),
+			}
+		}
+		if errCounts[0].Valid {
+			r.Errors = &sqlitebench.ErrorCounts{
+				Busy:       uint64(errCounts[0].Int64),
+				Constraint: uint64(errCounts[1].Int64),
+				Timeout:    uint64(errCounts[2].Int64),
+				Retried:    uint64(errCounts[3].Int64),
+				Retries:    uint64(errCounts[4].Int64),
+				RetryTime:  time.Duration(errCounts[5].Int64),
+			}
+		}
+		ids = append(ids, id)
+		iters = append(iters, n)
+		results = append(results, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("reading run %d: %w", runID, err)
+	}
+
+	for i, id := range ids {
+		if results[i].Samples, err = loadSamples(db, id); err != nil {
+			return nil, err
+		}
+		if len(results[i].Samples) != iters[i] {
+			// Stored from a file without samples.
+			results[i].Iterations = iters[i]
+		}
+	}
+
+	return results, nil
+}
+
+func loadSamples(db *sql.DB, resultID int64) ([]time.Duration, error) {
+	rows, err := db.Query("SELECT duration_ns FROM samples WHERE result_id = ? ORDER BY seq", resultID)
+	if err != nil {
+		return nil, fmt.Errorf("querying samples: %w", err)
+	}
+	defer rows.Close()
+
+	var samples []time.Duration
+	for rows.Next() {
+		var ns int64
+		if err := rows.Scan(&ns); err != nil {
+			return nil, fmt.Errorf("reading sample: %w", err)
+		}
+		samples = append(samples, time.Duration(ns))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("reading samples: %w", err)
+	}
+	return samples, nil
+}
+
+// loadRunTags returns the tags of every stored run that has any.
+func loadRunTags(db *sql.DB) (map[int64]tagSet, error) {
+	rows, err := db.Query("SELECT run_id, key, value FROM run_tags")
+	if err != nil {
+		return nil, fmt.Errorf("querying run tags: %w", err)
+	}
+	defer rows.Close()
+
+	tags := map[int64]tagSet{}
+	for rows.Next() {
+		var runID int64
+		var key, value string
+		if err := rows.Scan(&runID, &key, &value); err != nil {
+			return nil, fmt.Errorf("reading run tags: %w", err)
+		}
+		if tags[runID] == nil {
+			tags[runID] = tagSet{}
+		}
+		tags[runID][key] = value
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("reading run tags: %w", err)
+	}
+	return tags, nil
+}
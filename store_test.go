@@ -0,0 +1,230 @@
+package main
+
+import (
+	"database/sql"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"sqlite_benchmark/sqlitebench"
+)
+
+// openTestStore opens a results store in a temporary directory.
+func openTestStore(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := openResultsStore(filepath.Join(t.TempDir(), "results.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// storeRun saves a run, failing the test if that fails.
+func storeRun(t *testing.T, db *sql.DB, meta RunMetadata, results []BenchmarkResult) int64 {
+	t.Helper()
+	id, err := saveRun(db, meta, results)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+// storedHistory loads the stored history, failing the test if that fails.
+func storedHistory(t *testing.T, db *sql.DB) []historyPoint {
+	t.Helper()
+	points, err := loadHistory(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return points
+}
+
+// storedRun loads the latest stored run, failing the test if that fails.
+func storedRun(t *testing.T, db *sql.DB) []BenchmarkResult {
+	t.Helper()
+	id, err := latestRunID(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := loadRun(db, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return results
+}
+
+func TestSaveRun(t *testing.T) {
+	db := openTestStore(t)
+
+	meta := RunMetadata{Timestamp: time.Now(), GoVersion: "go1.22.0", Modules: map[string]string{}}
+	results := []BenchmarkResult{
+		{Driver: "mattn", Operation: "write", DataSize: 64, Duration: time.Millisecond, Samples: make([]time.Duration, 3)},
+		{Driver: "modernc", Operation: "write", DataSize: 64, Duration: time.Millisecond, Samples: make([]time.Duration, 3)},
+	}
+
+	first := storeRun(t, db, meta, results)
+	second := storeRun(t, db, meta, results)
+	if first == second {
+		t.Fatalf("expected distinct run IDs, got %d twice", first)
+	}
+
+	var envs, samples int
+	db.QueryRow("SELECT count(*) FROM environments").Scan(&envs)
+	db.QueryRow("SELECT count(*) FROM samples").Scan(&samples)
+	if envs != 1 {
+		t.Errorf("environments = %d, want 1", envs)
+	}
+	if samples != 12 {
+		t.Errorf("samples = %d, want 12", samples)
+	}
+}
+
+func TestLoadHistory(t *testing.T) {
+	db := openTestStore(t)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, d := range []time.Duration{time.Millisecond, 2 * time.Millisecond} {
+		meta := RunMetadata{Timestamp: start.Add(time.Duration(i) * time.Hour), Modules: map[string]string{}}
+		storeRun(t, db, meta, []BenchmarkResult{
+			{Driver: "mattn", Operation: "write", DataSize: 64, Duration: d, Samples: make([]time.Duration, 10)},
+		})
+	}
+
+	keys, series := groupHistory(storedHistory(t, db))
+	if len(keys) != 1 {
+		t.Fatalf("got %d series, want 1", len(keys))
+	}
+	s := series[keys[0]]
+	if len(s) != 2 || s[0].PerOp != 100*time.Microsecond || s[1].PerOp != 200*time.Microsecond {
+		t.Errorf("unexpected series: %+v", s)
+	}
+}
+
+func TestLatestRunID(t *testing.T) {
+	db := openTestStore(t)
+	if id, err := latestRunID(db); err != nil || id != 0 {
+		t.Fatalf("latestRunID of an empty store = %d, %v", id, err)
+	}
+
+	// The second run is stored last but started first, as with a clock
+	// behind the first one's.
+	now := time.Now()
+	storeRun(t, db, RunMetadata{Timestamp: now, Modules: map[string]string{}}, nil)
+	second := storeRun(t, db, RunMetadata{Timestamp: now.Add(-time.Hour), Modules: map[string]string{}}, nil)
+	if id, err := latestRunID(db); err != nil || id != second {
+		t.Errorf("latestRunID = %d, %v, want %d", id, err, second)
+	}
+}
+
+func TestLoadRun(t *testing.T) {
+	db := openTestStore(t)
+
+	want := BenchmarkResult{Driver: "modernc", Operation: "read", DataSize: 256, Duration: 3 * time.Microsecond,
+		Samples: []time.Duration{time.Microsecond, 2 * time.Microsecond}, Allocs: 9, Bytes: 300,
+		IO: &sqlitebench.IOCounters{Reads: 4, Writes: 2, Syncs: 1, ReadBytes: 16384, WrittenBytes: 8192}, SQLiteVersion: "3.46.1", BytesRead: 512,
+		Errors: &sqlitebench.ErrorCounts{Busy: 3, Constraint: 1, Retried: 2, Retries: 5, RetryTime: time.Millisecond}}
+	storeRun(t, db, RunMetadata{Timestamp: time.Now(), Modules: map[string]string{}}, []BenchmarkResult{want})
+
+	got := storedRun(t, db)
+	if len(got) != 1 || !reflect.DeepEqual(got[0], want) {
+		t.Errorf("loadRun = %+v, want %+v", got, want)
+	}
+}
+
+func TestCheckpointResume(t *testing.T) {
+	db := openTestStore(t)
+
+	cfg := defaultConfig()
+	cp, err := openCheckpoint(db, cfg, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := BenchmarkResult{Driver: "mattn", Operation: "write", DataSize: 64, Duration: time.Millisecond, Samples: []time.Duration{time.Millisecond}}
+	cp.CellDone(3, sqlitebench.Cell{}, want)
+
+	other := cfg
+	other.Ops = 7
+	if fresh, err := openCheckpoint(db, other, true); err != nil || len(fresh.done) != 0 {
+		t.Errorf("resumed checkpoint of a different config with %d runs", len(fresh.done))
+	}
+
+	cfg.Formats = []string{"json"}
+	resumed, err := openCheckpoint(db, cfg, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cp.err != nil {
+		t.Fatal(cp.err)
+	}
+	if resumed.id != cp.id || !reflect.DeepEqual(resumed.done, map[int]BenchmarkResult{3: want}) {
+		t.Fatalf("resumed checkpoint %d with %v, want %d with run 3", resumed.id, resumed.done, cp.id)
+	}
+
+	resumed.remove()
+	var cells int
+	db.QueryRow("SELECT count(*) FROM checkpoint_cells").Scan(&cells)
+	if cells != 0 {
+		t.Errorf("checkpoint_cells = %d after remove, want 0", cells)
+	}
+}
+
+func TestRunTags(t *testing.T) {
+	db := openTestStore(t)
+
+	result := []BenchmarkResult{{Driver: "mattn", Operation: "write", DataSize: 64, Duration: time.Millisecond}}
+	storeRun(t, db, RunMetadata{Timestamp: time.Now(), Modules: map[string]string{}, Tags: tagSet{"branch": "main", "nvme": ""}}, result)
+	storeRun(t, db, RunMetadata{Timestamp: time.Now(), Modules: map[string]string{}, Tags: tagSet{"branch": "wal-fix"}}, result)
+
+	points := storedHistory(t, db)
+	for _, tc := range []struct {
+		filter tagSet
+		want   int
+	}{
+		{tagSet{}, 2},
+		{tagSet{"branch": ""}, 2},
+		{tagSet{"branch": "wal-fix"}, 1},
+		{tagSet{"nvme": ""}, 1},
+		{tagSet{"nvme": "", "branch": "wal-fix"}, 0},
+	} {
+		if got := len(filterHistory(points, tc.filter)); got != tc.want {
+			t.Errorf("filter %v matched %d points, want %d", tc.filter, got, tc.want)
+		}
+	}
+}
+
+func TestMigrateStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.db")
+	old, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A store from before versioning, holding one run.
+	if _, err := old.Exec(resultsStoreSchema + `
+INSERT INTO environments (id, go_version, goos, goarch, num_cpu, cpu, hostname, modules) VALUES (1, '', '', '', 1, '', '', '{}');
+INSERT INTO runs (id, started_at, environment_id) VALUES (1, '2024-01-01T00:00:00Z', 1);
+INSERT INTO results (run_id, driver, operation, data_size, duration_ns, iterations, allocs, alloc_bytes) VALUES (1, 'mattn', 'write', 64, 1000, 0, 0, 0);`); err != nil {
+		t.Fatal(err)
+	}
+	old.Close()
+
+	db, err := openResultsStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var version int
+	db.QueryRow("PRAGMA user_version").Scan(&version)
+	if version != len(storeMigrations) {
+		t.Errorf("version = %d, want %d", version, len(storeMigrations))
+	}
+	if results, err := loadRun(db, 1); err != nil || len(results) != 1 || results[0].Driver != "mattn" || results[0].TimedOut {
+		t.Errorf("results = %+v, want the old mattn result", results)
+	}
+
+	storeRun(t, db, RunMetadata{Timestamp: time.Now(), Modules: map[string]string{}}, []BenchmarkResult{{Driver: "modernc", TimedOut: true, Verified: true}})
+	if r := storedRun(t, db); len(r) != 1 || !r[0].TimedOut || !r[0].Verified {
+		t.Errorf("results = %+v, want a timed out, verified result", r)
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// tagSet holds run tags. A label such as "nvme" is a tag with an empty
+// value.
+type tagSet map[string]string
+
+func (t tagSet) String() string {
+	keys := make([]string, 0, len(t))
+	for key := range t {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		parts[i] = key
+		if t[key] != "" {
+			parts[i] += "=" + t[key]
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// match reports whether a run with these tags is selected by filter: every
+// label in the filter must be present and every key=value tag must match.
+func (t tagSet) match(filter tagSet) bool {
+	for key, value := range filter {
+		got, ok := t[key]
+		if !ok || (value != "" && got != value) {
+			return false
+		}
+	}
+	return true
+}
+
+// tagFlag is a repeatable flag adding key=value tags to a tagSet.
+type tagFlag tagSet
+
+func (f tagFlag) String() string { return tagSet(f).String() }
+
+func (f tagFlag) Set(s string) error {
+	key, value, ok := strings.Cut(s, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("invalid tag %q, want key=value", s)
+	}
+	f[key] = value
+	return nil
+}
+
+// labelFlag is a repeatable flag adding labels to a tagSet.
+type labelFlag tagSet
+
+func (f labelFlag) String() string { return tagSet(f).String() }
+
+func (f labelFlag) Set(s string) error {
+	if s == "" || strings.Contains(s, "=") {
+		return fmt.Errorf("invalid label %q; use -tag for key=value", s)
+	}
+	f[s] = ""
+	return nil
+}
+
+// addTagFlags registers -tag and -label on fs, collecting into tags.
+func addTagFlags(fs *flag.FlagSet, tags tagSet, purpose string) {
+	fs.Var(labelFlag(tags), "label", purpose+" label, e.g. nvme (repeatable)")
+	fs.Var(tagFlag(tags), "tag", purpose+" key=value tag, e.g. branch=wal-fix (repeatable)")
+}
+
+// runTags are the tags stored with a run.
+var runTags = tagSet{}
+
+func init() {
+	addTagFlags(flag.CommandLine, runTags, "run")
+}